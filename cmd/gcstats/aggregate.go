@@ -0,0 +1,81 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aclements/go-gcstats/gcstats/report"
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+)
+
+// doAggregate implements the aggregate subcommand and returns the exit
+// status.
+func doAggregate(args []string) int {
+	fs := flag.NewFlagSet("aggregate", flag.ExitOnError)
+	var (
+		flagFormat     = fs.String("format", "text", "Output `format`: text or json")
+		flagSkipWarmup = fs.Bool("skipwarmup", false, "Exclude the warm-up cycles at the beginning of each trace")
+		flagMmap       = fs.Bool("mmap", false, "Memory-map the input files rather than reading them")
+	)
+	addLabelFlag(fs)
+	addPhasesFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s aggregate [flags] input...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nSummarize the metrics of the ci subcommand across repeated runs.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 || *flagFormat != "text" && *flagFormat != "json" {
+		fs.Usage()
+		return 2
+	}
+
+	runs := parseRuns(fs.Args(), *flagMmap, *flagSkipWarmup)
+	samples := make(map[string]*stats.Sample)
+	for _, s := range runs {
+		for name, v := range ciMetricValues(s) {
+			if samples[name] == nil {
+				samples[name] = &stats.Sample{}
+			}
+			samples[name].Xs = append(samples[name].Xs, v)
+		}
+	}
+	agg := report.Aggregate{SchemaVersion: report.SchemaVersion, Runs: len(runs), Metrics: make(map[string]report.AggregateMetric), Labels: flagLabels.Map()}
+	for name, sample := range samples {
+		m := report.AggregateMetric{N: len(sample.Xs), Mean: sample.Mean()}
+		m.Min, m.Max = sample.Bounds()
+		if m.N > 1 {
+			m.StdDev = sample.StdDev()
+		}
+		agg.Metrics[name] = m
+	}
+
+	if *flagFormat == "json" {
+		data, err := json.MarshalIndent(agg, "", "\t")
+		if err != nil {
+			panic(err)
+		}
+		os.Stdout.Write(append(data, '\n'))
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "metric\truns\tmean\tstddev\tmin\tmax\t\n")
+	for _, cm := range ciMetrics {
+		m, ok := agg.Metrics[cm.name]
+		if !ok {
+			continue
+		}
+		f := func(v float64) string { return ciFormat(cm.name, v) }
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t\n", cm.name, m.N, f(m.Mean), f(m.StdDev), f(m.Min), f(m.Max))
+	}
+	w.Flush()
+	return 0
+}
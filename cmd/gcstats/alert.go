@@ -0,0 +1,150 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/gcstats/report"
+)
+
+// alerter checks a growing trace against the thresholds of -alert-pause
+// and -alert-mu and fires -alert-cmd and -alert-webhook when a
+// threshold is crossed.
+type alerter struct {
+	pause   time.Duration
+	mu      float64
+	window  time.Duration
+	cmd     string
+	webhook string
+
+	// nphases is the number of phases already checked and
+	// pauses are their pauses.
+	nphases int
+	pauses  gcstats.Pauses
+	// lowMU is set while utilization is below mu, so the alert
+	// fires again only after it recovers.
+	lowMU bool
+	// quiet suppresses alerts while catching up on the existing
+	// contents of a file, so only thresholds crossed after that
+	// fire.
+	quiet bool
+}
+
+// enabled returns whether any threshold is set.
+func (a *alerter) enabled() bool {
+	return a != nil && (a.pause > 0 || a.mu > 0)
+}
+
+// check checks the cycles added to s since the last check.
+func (a *alerter) check(s *gcstats.GcStats) {
+	if !a.enabled() {
+		return
+	}
+	phases := s.Phases()
+	if a.pause > 0 {
+		// Only the new pauses are checked, so following a
+		// trace stays linear.
+		for _, stop := range a.pauses.Add(phases[a.nphases:]) {
+			if stop.Duration > int64(a.pause) {
+				a.fire(s, report.Alert{
+					Condition: "pause",
+					Message:   fmt.Sprintf("GC %d paused for %s, more than %s", stop.N, ns(float64(stop.Duration)), a.pause),
+					GC:        stop.N,
+					Value:     float64(stop.Duration),
+					Threshold: float64(a.pause),
+					TimeNS:    stop.Begin,
+				})
+			}
+		}
+	}
+	a.nphases = len(phases)
+
+	if a.mu > 0 && s.HaveProgTimes() {
+		last := phases[len(phases)-1]
+		end := last.End()
+		if end-phases[0].Begin < int64(a.window) {
+			return
+		}
+		mu := s.MutatorUtilizationBetween(end-int64(a.window), end)
+		switch {
+		case mu < a.mu && !a.lowMU:
+			a.lowMU = true
+			a.fire(s, report.Alert{
+				Condition: "mu",
+				Message:   fmt.Sprintf("mutator utilization over the last %s fell to %s at GC %d, below %s", a.window, pct(mu), last.N, pct(a.mu)),
+				GC:        last.N,
+				Value:     mu,
+				Threshold: a.mu,
+				TimeNS:    end,
+			})
+		case mu >= a.mu:
+			a.lowMU = false
+		}
+	}
+}
+
+// fire reports alert on stderr and runs the alert command and webhook,
+// unless a.quiet is set. Failures are reported but don't stop following
+// the trace.
+func (a *alerter) fire(s *gcstats.GcStats, alert report.Alert) {
+	if a.quiet {
+		return
+	}
+	alert.SchemaVersion = report.SchemaVersion
+	alert.Labels = flagLabels.Map()
+	if !s.HaveProgTimes() {
+		alert.TimeNS = 0
+	}
+	fmt.Fprintf(os.Stderr, "%s ALERT: %s\n", time.Now().Format("15:04:05"), alert.Message)
+	body, err := json.Marshal(alert)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error encoding alert: %s\n", err)
+		return
+	}
+
+	if a.cmd != "" {
+		// The command gets the alert as JSON on stdin and
+		// its fields and labels in the environment.
+		cmd := exec.Command("/bin/sh", "-c", a.cmd)
+		cmd.Stdin = bytes.NewReader(body)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		cmd.Env = append(os.Environ(),
+			"GCSTATS_CONDITION="+alert.Condition,
+			"GCSTATS_MESSAGE="+alert.Message,
+			"GCSTATS_GC="+strconv.Itoa(alert.GC),
+			"GCSTATS_VALUE="+strconv.FormatFloat(alert.Value, 'g', -1, 64),
+			"GCSTATS_THRESHOLD="+strconv.FormatFloat(alert.Threshold, 'g', -1, 64),
+		)
+		for _, l := range flagLabels {
+			cmd.Env = append(cmd.Env, "GCSTATS_LABEL_"+strings.ToUpper(l.name)+"="+l.value)
+		}
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "error running -alert-cmd: %s\n", err)
+		}
+	}
+
+	if a.webhook != "" {
+		client := http.Client{Timeout: 10 * time.Second}
+		resp, err := client.Post(a.webhook, "application/json", bytes.NewReader(body))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error posting alert: %s\n", err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			fmt.Fprintf(os.Stderr, "error posting alert: %s: %s\n", a.webhook, resp.Status)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// alignStarts returns the wall-clock times at which to place the
+// program starts of runs so their curves can be superimposed. starts
+// are the runs' own start times, such as from their files'
+// modification times. mode is "wall" to keep starts, "start" to
+// start every run at the earliest start, or "gc" to begin the first
+// GC cycle of every run at the earliest such time. offsets, if not
+// empty, has a duration for each run that is then added to its start,
+// for example to correct known clock skew between hosts.
+func alignStarts(runs []*gcstats.GcStats, starts []time.Time, mode string, offsets []time.Duration) ([]time.Time, error) {
+	if len(offsets) != 0 && len(offsets) != len(runs) {
+		return nil, fmt.Errorf("got %d offsets for %d traces", len(offsets), len(runs))
+	}
+	// anchor returns the offset from run i's start of the event
+	// to align.
+	var anchor func(i int) time.Duration
+	switch mode {
+	case "wall":
+	case "start":
+		anchor = func(i int) time.Duration { return 0 }
+	case "gc":
+		anchor = func(i int) time.Duration { return time.Duration(runs[i].Phases()[0].Begin) }
+	default:
+		return nil, fmt.Errorf("alignment must be wall, start, or gc")
+	}
+
+	out := append([]time.Time(nil), starts...)
+	if anchor != nil {
+		var t0 time.Time
+		for i, start := range starts {
+			if t := start.Add(anchor(i)); i == 0 || t.Before(t0) {
+				t0 = t
+			}
+		}
+		for i := range out {
+			out[i] = t0.Add(-anchor(i))
+		}
+	}
+	for i, off := range offsets {
+		out[i] = out[i].Add(off)
+	}
+	return out, nil
+}
+
+// parseOffsets parses a comma-separated list of durations, which may
+// be negative.
+func parseOffsets(spec string) ([]time.Duration, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	var out []time.Duration
+	for _, f := range strings.Split(spec, ",") {
+		d, err := time.ParseDuration(f)
+		if err != nil {
+			return nil, fmt.Errorf("bad offset %q", f)
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// doAllocRate correlates the interval between consecutive GC cycles
+// with the allocation rate over that interval.
+//
+// The allocation between cycles is estimated as the growth from the
+// live heap at the end of one cycle to the heap trigger of the next.
+// If the GC is pacing on allocation, the heap grows by a similar
+// amount between each cycle, so the interval is inversely
+// proportional to the allocation rate and a log-log fit has a slope
+// near -1. Forced cycles don't follow allocation and are reported
+// separately.
+func doAllocRate(s *gcstats.GcStats) {
+	var rates, intervals []float64
+	var frates, fintervals []float64
+	cycles := s.Cycles()
+	for i := 0; i+1 < len(cycles); i++ {
+		c, next := cycles[i], cycles[i+1]
+		interval := next.Begin - c.Begin
+		alloc := next.HeapTrigger - c.HeapLive
+		if interval <= 0 || alloc <= 0 {
+			continue
+		}
+		// MB/s and ms.
+		rate := float64(alloc) / (1 << 20) / (float64(interval) / 1e9)
+		if next.Forced {
+			frates, fintervals = append(frates, rate), append(fintervals, float64(interval)/1e6)
+		} else {
+			rates, intervals = append(rates, rate), append(intervals, float64(interval)/1e6)
+		}
+	}
+
+	if len(frates) > 0 {
+		fmt.Printf("Forced GCs: %d of %d (excluded from fit)\n", len(frates), len(frates)+len(rates))
+	}
+	if len(rates) < 3 {
+		fmt.Fprintf(os.Stderr, "need at least 3 unforced cycles with known heap sizes\n")
+		os.Exit(1)
+	}
+	var lrates, lintervals []float64
+	var meanRate, meanInterval float64
+	for i := range rates {
+		lrates = append(lrates, math.Log(rates[i]))
+		lintervals = append(lintervals, math.Log(intervals[i]))
+		meanRate += rates[i]
+		meanInterval += intervals[i]
+	}
+	n := float64(len(rates))
+	fmt.Printf("GC interval vs. allocation rate over %d intervals:\n", len(rates))
+	fmt.Printf("  mean rate      %.3g MB/s\n", meanRate/n)
+	fmt.Printf("  mean interval  %s\n", ns(meanInterval/n*1e6))
+	fit := fitLine(lrates, lintervals)
+	if math.IsNaN(fit.r2) {
+		fmt.Printf("  allocation rate does not vary between cycles\n")
+	} else {
+		fmt.Printf("  log-log slope  %.2f ± %.2f (95%% CI; -1 means GC frequency tracks allocation)\n", fit.slope, fit.slopeCI)
+		fmt.Printf("  R²             %.3f\n", fit.r2)
+	}
+
+	if *flagShow {
+		// Forced cycles are a separate series. Each row of
+		// the plot table is one interval, so the other
+		// series is NaN.
+		xs := append(append([]float64(nil), rates...), frates...)
+		ys, fys := make([]float64, len(xs)), make([]float64, len(xs))
+		for i := range xs {
+			if i < len(rates) {
+				ys[i], fys[i] = intervals[i], math.NaN()
+			} else {
+				ys[i], fys[i] = math.NaN(), fintervals[i-len(rates)]
+			}
+		}
+		plot := newPlot("allocation rate (MB/s)", "GC interval (ms)", xs, "--style", "scatter", "--log")
+		plot.addColumn("GC cycles", ys)
+		if len(frates) > 0 {
+			plot.addColumn("forced", fys)
+		}
+		showPlot(plot)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/gcstats/analysis"
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+)
+
+// loadPlugins opens the plugins listed in $GCSTATS_PLUGINS, which
+// register analyses from their init functions, and adds the metrics
+// among the registered analyses to ciMetrics. This must run before
+// flags are defined.
+func loadPlugins() {
+	for _, path := range filepath.SplitList(os.Getenv("GCSTATS_PLUGINS")) {
+		if path == "" {
+			continue
+		}
+		if err := openPlugin(path); err != nil {
+			fmt.Fprintf(os.Stderr, "loading plugin %s: %s\n", path, err)
+			os.Exit(2)
+		}
+	}
+
+	for _, a := range analysis.All() {
+		if a.Kind != analysis.Metric {
+			continue
+		}
+		for _, m := range ciMetrics {
+			if m.name == a.Name {
+				fmt.Fprintf(os.Stderr, "analysis %s conflicts with a built-in ci metric\n", a.Name)
+				os.Exit(2)
+			}
+		}
+		a := a
+		ciMetrics = append(ciMetrics, ciMetric{name: a.Name, lowerIsWorse: a.LowerIsWorse, get: func(s *gcstats.GcStats, pauses *stats.Sample) (float64, bool) {
+			if a.NeedProgTimes && !s.HaveProgTimes() {
+				return 0, false
+			}
+			v, err := a.Value(s)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", a.Name, err)
+				return 0, false
+			}
+			return v, true
+		}})
+	}
+}
+
+// addAnalysisFlags adds a flag to fs for each registered analysis and
+// returns the flags by analysis name.
+func addAnalysisFlags(fs *flag.FlagSet) map[string]*bool {
+	flags := make(map[string]*bool)
+	for _, a := range analysis.All() {
+		if fs.Lookup(a.Name) != nil {
+			fmt.Fprintf(os.Stderr, "analysis %s conflicts with a built-in flag\n", a.Name)
+			os.Exit(2)
+		}
+		usage := a.Usage
+		if usage == "" {
+			usage = "Run the " + a.Name + " analysis"
+		}
+		flags[a.Name] = fs.Bool(a.Name, false, usage+" (plug-in)")
+	}
+	return flags
+}
+
+// anyAnalysis returns whether any of the analysis flags is set.
+func anyAnalysis(flags map[string]*bool) bool {
+	for _, f := range flags {
+		if *f {
+			return true
+		}
+	}
+	return false
+}
+
+// runAnalyses runs the registered analyses whose flags are set on s
+// and prints their results according to their kinds.
+func runAnalyses(s *gcstats.GcStats, flags map[string]*bool) {
+	for _, a := range analysis.All() {
+		if !*flags[a.Name] || a.NeedProgTimes && !needProgTimes(s, "-"+a.Name) {
+			continue
+		}
+		v, err := a.Compute(s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", a.Name, err)
+			os.Exit(1)
+		}
+		switch a.Kind {
+		case analysis.Text:
+			fmt.Println(v)
+		case analysis.Metric:
+			fmt.Printf("%s: %v\n", a.Name, v)
+		case analysis.JSON:
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(v); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", a.Name, err)
+				os.Exit(1)
+			}
+		}
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// badgeKind is how a badge metric is formatted and how its threshold
+// is parsed.
+type badgeKind int
+
+const (
+	badgeDuration badgeKind = iota // nanoseconds, threshold like "10ms"
+	badgeFraction                  // threshold like "0.5" or "50%"
+	badgeCount                     // threshold like "100"
+)
+
+// badgeMetrics are the metrics a badge can show. Each is one of
+// ciMetrics under a name that's easier to type.
+var badgeMetrics = []struct {
+	name, ci, label string
+	kind            badgeKind
+}{
+	{"max-stw", "pause_max_ns", "GC max STW", badgeDuration},
+	{"p99-stw", "pause_p99_ns", "GC p99 STW", badgeDuration},
+	{"p95-stw", "pause_p95_ns", "GC p95 STW", badgeDuration},
+	{"mean-stw", "pause_mean_ns", "GC mean STW", badgeDuration},
+	{"mu", "mutator_utilization", "mutator utilization", badgeFraction},
+	{"mmu-10ms", "mmu_10ms", "MMU 10ms", badgeFraction},
+	{"blackout", "blackout_max_ns", "GC max blackout", badgeDuration},
+	{"cycles", "cycles", "GC cycles", badgeCount},
+}
+
+// Badge colors, matching shields.io.
+const (
+	badgePass    = "#4c1"
+	badgeFail    = "#e05d44"
+	badgeNeutral = "#007ec6"
+	badgeUnknown = "#9f9f9f"
+)
+
+// doBadge implements the badge subcommand and returns the exit
+// status.
+func doBadge(args []string) int {
+	fs := flag.NewFlagSet("badge", flag.ExitOnError)
+	var (
+		flagMetric    = fs.String("metric", "p99-stw", "Show the metric called `name`")
+		flagThreshold = fs.String("threshold", "", "Color the badge green if the metric is no worse than `value` and red otherwise, for example 10ms or 90%")
+		flagTitle     = fs.String("title", "", "Label the badge with `text` instead of the metric's name")
+		flagOut       = fs.String("o", "badge.svg", "Write the badge to `file`, or - for stdout")
+		flagMmap      = fs.Bool("mmap", false, "Memory-map the input file rather than reading it")
+	)
+	addPhasesFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s badge [-metric name] [-threshold value] [flags] [input]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nWrite an SVG status badge showing one metric of a GC trace.\nMetrics: ")
+		for i, m := range badgeMetrics {
+			if i > 0 {
+				fmt.Fprintf(os.Stderr, ", ")
+			}
+			fmt.Fprintf(os.Stderr, "%s", m.name)
+		}
+		fmt.Fprintf(os.Stderr, "\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	mi := -1
+	for i, m := range badgeMetrics {
+		if m.name == *flagMetric {
+			mi = i
+		}
+	}
+	if mi < 0 {
+		fmt.Fprintf(os.Stderr, "-metric: unknown metric %q\n", *flagMetric)
+		return 2
+	}
+	metric := badgeMetrics[mi]
+	var cm ciMetric
+	for _, m := range ciMetrics {
+		if m.name == metric.ci {
+			cm = m
+		}
+	}
+	threshold := math.NaN()
+	if *flagThreshold != "" {
+		var err error
+		if threshold, err = parseBadgeValue(*flagThreshold, metric.kind); err != nil {
+			fmt.Fprintf(os.Stderr, "-threshold: %s\n", err)
+			return 2
+		}
+	}
+	title := *flagTitle
+	if title == "" {
+		title = metric.label
+	}
+
+	var input io.Reader = os.Stdin
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		defer f.Close()
+		input = f
+	}
+	s, err := parseInput(input, *flagMmap)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error parsing log: %s\n", err)
+		return 2
+	}
+	if len(s.Phases()) == 0 {
+		fmt.Fprintf(os.Stderr, "no GC recorded; did you set GODEBUG=gctrace=1?\n")
+		return 2
+	}
+
+	text, color := "n/a", badgeUnknown
+	if v, ok := ciMetricValues(s)[cm.name]; ok {
+		text, color = formatBadgeValue(v, metric.kind), badgeNeutral
+		if !math.IsNaN(threshold) {
+			if (cm.lowerIsWorse && v >= threshold) || (!cm.lowerIsWorse && v <= threshold) {
+				color = badgePass
+			} else {
+				color = badgeFail
+			}
+		}
+	} else {
+		fmt.Fprintf(os.Stderr, "warning: %s is not available for this trace\n", metric.name)
+	}
+
+	badge := renderBadge(title, text, color)
+	if *flagOut == "-" {
+		_, err = io.WriteString(os.Stdout, badge)
+	} else {
+		err = os.WriteFile(*flagOut, []byte(badge), 0666)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// parseBadgeValue parses a threshold for a metric of the given kind.
+func parseBadgeValue(s string, kind badgeKind) (float64, error) {
+	switch kind {
+	case badgeDuration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, err
+		}
+		return float64(d), nil
+	case badgeFraction:
+		scale := 1.0
+		if t, ok := strings.CutSuffix(s, "%"); ok {
+			s, scale = t, 0.01
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, fmt.Errorf("bad fraction %q", s)
+		}
+		return v * scale, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad number %q", s)
+	}
+	return v, nil
+}
+
+// formatBadgeValue formats the value v of a metric of the given kind.
+func formatBadgeValue(v float64, kind badgeKind) string {
+	switch kind {
+	case badgeDuration:
+		return ns(v)
+	case badgeFraction:
+		return pct(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// badgeTextWidth estimates the width in pixels of s in 11px Verdana,
+// which is all a badge needs to size its boxes.
+func badgeTextWidth(s string) int {
+	w := 0.0
+	for _, r := range s {
+		switch {
+		case strings.ContainsRune("iIjlft.,:;'|!() ", r):
+			w += 4
+		case strings.ContainsRune("mwMW%", r):
+			w += 10.5
+		case r >= 'A' && r <= 'Z':
+			w += 7.5
+		default:
+			w += 7
+		}
+	}
+	return int(math.Ceil(w))
+}
+
+// renderBadge returns a flat, shields.io-style SVG badge with title
+// on a grey background on the left and text on a background of color
+// on the right.
+func renderBadge(title, text, color string) string {
+	const pad = 10
+	lw, rw := badgeTextWidth(title)+pad, badgeTextWidth(text)+pad
+	w := lw + rw
+	title, text = html.EscapeString(title), html.EscapeString(text)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`+"\n", w, title, text)
+	fmt.Fprintf(&b, "<title>%s: %s</title>\n", title, text)
+	fmt.Fprintf(&b, `<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`+"\n")
+	fmt.Fprintf(&b, `<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`+"\n", w)
+	fmt.Fprintf(&b, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`+"\n", lw, lw, rw, color, w)
+	fmt.Fprintf(&b, `<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+"\n")
+	for _, t := range []struct {
+		x    float64
+		text string
+	}{{float64(lw) / 2, title}, {float64(lw) + float64(rw)/2, text}} {
+		fmt.Fprintf(&b, `<text x="%.1f" y="15" fill="#010101" fill-opacity=".3">%s</text><text x="%.1f" y="14">%s</text>`+"\n", t.x, t.text, t.x, t.text)
+	}
+	fmt.Fprintf(&b, "</g>\n</svg>\n")
+	return b.String()
+}
@@ -0,0 +1,52 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// doBench prints a GC summary for each benchmark in the output of
+// "go test -bench" in input.
+func doBench(input io.Reader) {
+	benchmarks, err := gcstats.ParseBenchLog(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error parsing log: %s\n", err)
+		os.Exit(1)
+	}
+	if len(benchmarks) == 0 {
+		fmt.Fprintln(os.Stderr, "no benchmarks found; is this the output of go test -bench?")
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "benchmark\tGCs\tSTW total\tSTW max\tSTW/GC\tmutator util\n")
+	for _, b := range benchmarks {
+		s := b.Stats
+		name := b.Name
+		if b.Result == "" {
+			name += " (unfinished)"
+		}
+		if s.Count() == 0 {
+			fmt.Fprintf(w, "%s\t0\t-\t-\t-\t-\n", name)
+			continue
+		}
+		var total int64
+		for _, stop := range s.Stops() {
+			total += stop.Duration
+		}
+		mu := "-"
+		if s.HaveProgTimes() {
+			mu = pct(s.MutatorUtilization())
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\n", name, s.Count(), ns(float64(total)), ns(float64(s.MaxPause())), ns(float64(total)/float64(s.Count())), mu)
+	}
+	w.Flush()
+}
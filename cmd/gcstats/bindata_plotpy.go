@@ -11,10 +11,12 @@ var plotpy = `#!/usr/bin/env python3
 
 import sys
 import argparse
+import datetime
 
 import numpy as np
 import matplotlib as mpl
-mpl.use('GTK3Cairo')
+if '--output' not in sys.argv:
+    mpl.use('GTK3Cairo')
 mpl.rc('figure', facecolor='1')
 import matplotlib.pyplot as plt
 import matplotlib.ticker as ticker
@@ -27,9 +29,25 @@ except ImportError as e:
 
 def main():
     parser = argparse.ArgumentParser()
-    parser.add_argument('--style', choices=('mmu', 'mut', 'stopkde', 'stopcdf', 'mud'),
+    parser.add_argument('--style', choices=('mmu', 'mut', 'stopkde', 'stopcdf', 'mud', 'stwrate', 'cumgc', 'pausepct', 'phasetime', 'scatter', 'muddiff', 'qq', 'stacked', 'memory', 'heappause'),
                         help='Plot style', required=True)
     parser.add_argument('--ylabel', help='Y axis label')
+    parser.add_argument('--bands', action='store_true', help='Series are (line, low, high) triples to plot as a line and band')
+    parser.add_argument('--log', action='store_true', help='Use log scales for both axes')
+    parser.add_argument('--fit', action='store_true', help='Draw the last series of a scatter plot as a line')
+    parser.add_argument('--y2label', help='Secondary Y axis label for the last series')
+    parser.add_argument('--title', help='Plot title')
+    parser.add_argument('--legend-title', help='Legend title, such as the labels of the trace')
+    parser.add_argument('--width', type=float, help='Figure width in inches')
+    parser.add_argument('--height', type=float, help='Figure height in inches')
+    parser.add_argument('--xrange', type=parseRange, help='X axis range as lo,hi; either may be empty')
+    parser.add_argument('--yrange', type=parseRange, help='Y axis range as lo,hi; either may be empty')
+    parser.add_argument('--logx', action='store_true', help='Use a log scale for the X axis')
+    parser.add_argument('--logy', action='store_true', help='Use a log scale for the Y axis')
+    parser.add_argument('--xaxis', choices=('rel', 'wall', 'gc'), default='rel',
+                        help='X axis of plots over execution time')
+    parser.add_argument('--spans', help='File of label, begin, end lines giving X ranges to shade')
+    parser.add_argument('--output', help='Save the plot to this file rather than showing it')
     args = parser.parse_args()
 
     rows = [line.strip('\n').split('\t') for line in sys.stdin]
@@ -40,31 +58,137 @@ def main():
         if sns is not None:
             sns.set_palette("Blues_r")
 
-    fig, ax = plt.subplots(1, 1)
+    figsize = list(mpl.rcParams['figure.figsize'])
+    if args.width:
+        figsize[0] = args.width
+    if args.height:
+        figsize[1] = args.height
+    fig, ax = plt.subplots(1, 1, figsize=figsize)
 
     if args.style in ('mmu', 'mut'):
         ax.set_xscale('log')
 
+    if args.log:
+        ax.set_xscale('log')
+        ax.set_yscale('log')
+
     if args.style in ('mmu', 'mut', 'stopcdf', 'mud'):
         ax.set_ylim(bottom=0, top=1)
 
+    if args.style == 'muddiff':
+        ax.set_xlim(left=0, right=1)
+        ax.axhline(0, color='gray', linewidth=0.5)
+
+    if args.style == 'qq':
+        # Points on the diagonal are unchanged.
+        ax.set_xlim(left=0, right=1)
+        ax.set_ylim(bottom=0, top=1)
+        ax.plot([0, 1], [0, 1], color='gray', linewidth=0.5)
+
     if args.style in ('mmu', 'mut', 'stopkde', 'stopcdf'):
         ax.xaxis.set_major_formatter(tickerSec)
 
+    if args.style in ('stwrate', 'cumgc', 'pausepct', 'phasetime', 'memory', 'heappause'):
+        # X is execution time.
+        if args.xaxis == 'rel':
+            ax.xaxis.set_major_formatter(tickerSec)
+        elif args.xaxis == 'wall':
+            ax.xaxis.set_major_formatter(tickerWall)
+
+    if args.style == 'phasetime':
+        # Phase durations span orders of magnitude.
+        ax.set_yscale('log')
+
+    if args.style in ('cumgc', 'pausepct', 'phasetime'):
+        ax.yaxis.set_major_formatter(tickerSec)
+
+    if args.style == 'heappause':
+        ax.set_ylim(bottom=0)
+
     ax.set_xlabel(table[0][0])
     if args.ylabel:
         ax.set_ylabel(args.ylabel)
 
-    for col in table[1:]:
-        ax.plot(table[0][1:], col[1:], label=col[0])
-    ax.legend(loc='best')
+    series = table[1:]
+    if args.y2label:
+        # Plot the last series against its own axis.
+        ax2 = ax.twinx()
+        ax2.set_ylabel(args.y2label)
+        if args.style == 'heappause':
+            # Each pause is a point rather than a level.
+            line2 = [ax2.scatter(table[0][1:], series[-1][1:], marker='.',
+                                 color='C3', label=series[-1][0])]
+            ax2.set_ylim(bottom=0)
+            ax2.yaxis.set_major_formatter(tickerSec)
+        else:
+            line2 = ax2.step(table[0][1:], series[-1][1:], where='post',
+                             color='C1', label=series[-1][0])
+        series = series[:-1]
+    if args.style == 'stacked':
+        # Each row is a horizontal bar of the series stacked.
+        left = np.zeros(len(table[0]) - 1)
+        for col in series:
+            ax.barh(table[0][1:], col[1:], left=left, label=col[0])
+            left += col[1:]
+        ax.set_xlabel(args.ylabel)
+        ax.set_ylabel('')
+        ax.set_yticks([])
+        series = []
+    if args.bands:
+        for mean, lo, hi in zip(series[0::3], series[1::3], series[2::3]):
+            line, = ax.plot(table[0][1:], mean[1:], label=mean[0])
+            ax.fill_between(table[0][1:], lo[1:], hi[1:], color=line.get_color(), alpha=0.25)
+        series = []
+    for col in series:
+        if args.style in ('stwrate', 'memory'):
+            ax.step(table[0][1:], col[1:], where='post', label=col[0])
+        elif args.style == 'scatter' and not (args.fit and col is series[-1]):
+            ax.scatter(table[0][1:], col[1:], label=col[0])
+        elif args.style == 'phasetime':
+            ax.plot(table[0][1:], col[1:], marker='.', label=col[0])
+        else:
+            ax.plot(table[0][1:], col[1:], label=col[0])
+    if args.spans:
+        colors = {}
+        for line in open(args.spans):
+            label, begin, end = line.rstrip('\n').split('\t')
+            if label not in colors:
+                colors[label] = 'C%d' % (7 + len(colors))
+                kw = {'label': label}
+            else:
+                kw = {}
+            ax.axvspan(float(begin), float(end), color=colors[label], alpha=0.2, linewidth=0, **kw)
+    if args.y2label:
+        handles, labels = ax.get_legend_handles_labels()
+        ax.legend(handles + line2, labels + [line2[0].get_label()], loc='best', title=args.legend_title)
+    else:
+        ax.legend(loc='best', title=args.legend_title)
 
     if args.style == 'mut':
         # Reverse legend order
         handles, labels = ax.get_legend_handles_labels()
-        ax.legend(handles[::-1], labels[::-1], loc='best')
+        ax.legend(handles[::-1], labels[::-1], loc='best', title=args.legend_title)
 
-    plt.show()
+    # Explicit options override the defaults of the style.
+    if args.title:
+        ax.set_title(args.title)
+    if args.logx:
+        ax.set_xscale('log')
+    if args.logy:
+        ax.set_yscale('log')
+    if args.xrange:
+        ax.set_xlim(*args.xrange)
+    if args.yrange:
+        ax.set_ylim(*args.yrange)
+
+    if args.output:
+        fig.savefig(args.output, bbox_inches='tight')
+    else:
+        plt.show()
+
+def parseRange(s):
+    lo, hi = s.split(',')
+    return (float(lo) if lo else None, float(hi) if hi else None)
 
 def prettySec(x):
     if x == 0:
@@ -82,6 +206,8 @@ def prettySec(x):
     s = ('%.3f' % (x / unit[1])).rstrip('0').rstrip('.')
     return neg + s + unit[0]
 tickerSec = ticker.FuncFormatter(lambda x, pos: prettySec(x))
+tickerWall = ticker.FuncFormatter(
+    lambda x, pos: datetime.datetime.fromtimestamp(x).strftime('%H:%M:%S'))
 
 if __name__ == '__main__':
     main()
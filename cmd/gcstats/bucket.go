@@ -0,0 +1,48 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+)
+
+// doBuckets prints a summary row for each interval of width of
+// execution time, turning a long trace into a coarse time series.
+func doBuckets(s *gcstats.GcStats, width time.Duration) {
+	// Use the same buckets as STWRate, which also counts
+	// pauses in each.
+	bins := s.STWRate(int64(width))
+	first := bins[0].Begin
+	pauses := make([]stats.Sample, len(bins))
+	for _, stop := range s.Stops() {
+		i := (stop.Begin - first) / int64(width)
+		pauses[i].Xs = append(pauses[i].Xs, float64(stop.Duration))
+	}
+	gcs := make([]int, len(bins))
+	phases := s.Phases()
+	for i, p := range phases {
+		if i == 0 || p.N != phases[i-1].N {
+			gcs[(p.Begin-first)/int64(width)]++
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "time\tGCs\tpauses\tpause 99%%ile\tGC CPU\t\n")
+	for i, bin := range bins {
+		p99 := "-"
+		if bin.Count > 0 {
+			p99 = ns(pauses[i].Percentile(0.99))
+		}
+		gcCPU := 1 - s.MutatorUtilizationBetween(bin.Begin, bin.Begin+int64(width))
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t\n", time.Duration(bin.Begin), gcs[i], bin.Count, p99, pct(gcCPU))
+	}
+	w.Flush()
+}
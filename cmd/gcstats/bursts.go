@@ -0,0 +1,59 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+)
+
+// burstTop is the number of longest bursts listed by -bursts.
+const burstTop = 10
+
+// doBursts prints the bursts of STW pauses in s that are at most gap
+// apart: the distribution of their sizes and combined durations, and
+// the bursts with the longest combined durations.
+func doBursts(s *gcstats.GcStats, gap time.Duration) {
+	bursts := s.Bursts(int64(gap))
+	if len(bursts) == 0 {
+		return
+	}
+
+	var sizes, durs stats.Sample
+	multi := 0
+	for _, b := range bursts {
+		sizes.Xs = append(sizes.Xs, float64(b.Pauses))
+		durs.Xs = append(durs.Xs, float64(b.Duration))
+		if b.Pauses > 1 {
+			multi++
+		}
+	}
+	sizes.Sort()
+	durs.Sort()
+
+	fmt.Printf("STW bursts (pauses at most %s apart): %d bursts, %d with more than one pause\n", gap, len(bursts), multi)
+	_, maxSize := sizes.Bounds()
+	fmt.Printf("Pauses per burst: max=%d mean=%.2f\n", int(maxSize), sizes.Mean())
+	fmt.Printf("Combined pause: max=%s 99%%ile=%s 95%%ile=%s mean=%s\n", ns(durs.Percentile(1)), ns(durs.Percentile(0.99)), ns(durs.Percentile(0.95)), ns(durs.Mean()))
+
+	// List the worst bursts by combined duration.
+	sort.SliceStable(bursts, func(i, j int) bool { return bursts[i].Duration > bursts[j].Duration })
+	if len(bursts) > burstTop {
+		bursts = bursts[:burstTop]
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "begin\tpauses\tcombined\tspan\t\n")
+	for _, b := range bursts {
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t\n", time.Duration(b.Begin), b.Pauses, ns(float64(b.Duration)), ns(float64(b.End-b.Begin)))
+	}
+	w.Flush()
+}
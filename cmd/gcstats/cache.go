@@ -0,0 +1,136 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+var flagCache = flag.String("cache", os.Getenv("GCSTATS_CACHE"), "Cache parsed traces and MMU and MUD results under `dir`, keyed by the trace's contents, so repeated runs on the same trace are fast (default $GCSTATS_CACHE)")
+
+// cacheKeys maps each trace loaded through the cache to the key of
+// its contents and parse settings, which keys its cached analyses.
+var cacheKeys = make(map[*gcstats.GcStats]string)
+
+// cacheKey returns the hex SHA-256 of parts.
+func cacheKey(parts ...interface{}) string {
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%v\x00", part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// traceCacheKey returns the cache key of the trace with contents data
+// parsed with the -overlap and -phases settings.
+func traceCacheKey(data []byte) string {
+	h := sha256.Sum256(data)
+	parts := []interface{}{"trace", hex.EncodeToString(h[:]), overlapPolicy, *flagOverlapTol}
+	for _, path := range flagPhases {
+		// The layouts were already loaded, so an error here
+		// only means the file changed since.
+		layout, _ := os.ReadFile(path)
+		parts = append(parts, string(layout))
+	}
+	return cacheKey(parts...)
+}
+
+// cachePath returns the path of the cache entry with key in the
+// -cache directory.
+func cachePath(key string) string {
+	return filepath.Join(*flagCache, key[:2], key)
+}
+
+// cacheLoad decodes the cache entry with key into v. It returns
+// false if there is no usable entry.
+func cacheLoad(key string, v encoding.BinaryUnmarshaler) bool {
+	data, err := os.ReadFile(cachePath(key))
+	if err != nil {
+		return false
+	}
+	return v.UnmarshalBinary(data) == nil
+}
+
+// cacheStore saves v as the cache entry with key. Since the cache
+// only saves time, errors are reported as warnings.
+func cacheStore(key string, v encoding.BinaryMarshaler) {
+	data, err := v.MarshalBinary()
+	if err == nil {
+		err = writeCacheFile(cachePath(key), data)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: writing cache: %s\n", err)
+	}
+}
+
+// writeCacheFile writes data to path by renaming a temporary file,
+// so concurrent runs never read a partial entry.
+func writeCacheFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// parseCached returns the trace with contents data from the -cache
+// directory, or parses it with parse and caches the result.
+func parseCached(data []byte, parse func() (*gcstats.GcStats, error)) (*gcstats.GcStats, error) {
+	key := traceCacheKey(data)
+	s := new(gcstats.GcStats)
+	if !cacheLoad(key, s) {
+		var err error
+		if s, err = parse(); err != nil {
+			return nil, err
+		}
+		cacheStore(key, s)
+	}
+	cacheKeys[s] = key
+	return s, nil
+}
+
+// analysisCacheKey returns the cache key of the analysis of s
+// identified by parts, including the flags that affect mutator
+// utilization, or "" if s was not loaded through the cache.
+func analysisCacheKey(s *gcstats.GcStats, parts ...interface{}) string {
+	trace, ok := cacheKeys[s]
+	if !ok {
+		return ""
+	}
+	return cacheKey(append([]interface{}{trace, *flagPerP, *flagMutTime, *flagApprox}, parts...)...)
+}
+
+// cachedFloat is a float64 cache entry.
+type cachedFloat float64
+
+func (f cachedFloat) MarshalBinary() ([]byte, error) {
+	return []byte(fmt.Sprint(float64(f))), nil
+}
+
+func (f *cachedFloat) UnmarshalBinary(data []byte) error {
+	_, err := fmt.Sscan(string(data), (*float64)(f))
+	return err
+}
@@ -0,0 +1,300 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/gcstats/report"
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+)
+
+// ciMetric is a trace metric compared against the baseline by the ci
+// subcommand.
+type ciMetric struct {
+	name string
+
+	// lowerIsWorse indicates that a decrease in this metric is a
+	// regression. Otherwise, an increase is a regression.
+	lowerIsWorse bool
+
+	// pause indicates that this metric is a pause time, subject
+	// to -pause-slack.
+	pause bool
+
+	// get returns the metric for s, where pauses is the sorted
+	// STW pause times of s. It returns false if the metric is not
+	// available for s.
+	get func(s *gcstats.GcStats, pauses *stats.Sample) (float64, bool)
+}
+
+func pauseMetric(pctile float64) func(*gcstats.GcStats, *stats.Sample) (float64, bool) {
+	return func(s *gcstats.GcStats, pauses *stats.Sample) (float64, bool) {
+		return pauses.Percentile(pctile), len(pauses.Xs) > 0
+	}
+}
+
+var ciMetrics = []ciMetric{
+	{name: "pause_max_ns", pause: true, get: pauseMetric(1)},
+	{name: "pause_p99_ns", pause: true, get: pauseMetric(.99)},
+	{name: "pause_p95_ns", pause: true, get: pauseMetric(.95)},
+	{name: "pause_mean_ns", pause: true, get: func(s *gcstats.GcStats, pauses *stats.Sample) (float64, bool) {
+		return pauses.Mean(), len(pauses.Xs) > 0
+	}},
+	{name: "cycles", get: func(s *gcstats.GcStats, pauses *stats.Sample) (float64, bool) {
+		return float64(s.Count()), true
+	}},
+	{name: "mutator_utilization", lowerIsWorse: true, get: func(s *gcstats.GcStats, pauses *stats.Sample) (float64, bool) {
+		if !s.HaveProgTimes() {
+			return 0, false
+		}
+		return s.MutatorUtilization(), true
+	}},
+	{name: "mmu_10ms", lowerIsWorse: true, get: func(s *gcstats.GcStats, pauses *stats.Sample) (float64, bool) {
+		if !s.HaveProgTimes() {
+			return 0, false
+		}
+		return s.MMU(10e6), true
+	}},
+	{name: "blackout_max_ns", pause: true, get: func(s *gcstats.GcStats, pauses *stats.Sample) (float64, bool) {
+		if !s.HaveProgTimes() {
+			return 0, false
+		}
+		_, dur := s.LongestBlackout(0)
+		return float64(dur), true
+	}},
+}
+
+// ciMetricValues returns the values of ciMetrics for s.
+func ciMetricValues(s *gcstats.GcStats) map[string]float64 {
+	pauses := stats.Sample{}
+	for _, stop := range s.Stops() {
+		pauses.Xs = append(pauses.Xs, float64(stop.Duration))
+	}
+	pauses.Sort()
+
+	vals := make(map[string]float64)
+	for _, m := range ciMetrics {
+		if v, ok := m.get(s, &pauses); ok {
+			vals[m.name] = v
+		}
+	}
+	return vals
+}
+
+// parseTolerances parses a comma-separated list of tolerances. Each
+// element is either a default tolerance, or name=tolerance for a
+// specific metric. Tolerances are fractions of the baseline value and
+// may also be written as percentages.
+func parseTolerances(spec string) (def float64, byName map[string]float64, err error) {
+	parse := func(s string) (float64, error) {
+		num, scale := s, 1.0
+		if t, ok := strings.CutSuffix(s, "%"); ok {
+			num, scale = t, 0.01
+		}
+		v, err := strconv.ParseFloat(num, 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("bad tolerance %q", s)
+		}
+		return v * scale, nil
+	}
+
+	byName = make(map[string]float64)
+	for _, elt := range strings.Split(spec, ",") {
+		name, val, ok := strings.Cut(elt, "=")
+		if !ok {
+			if def, err = parse(elt); err != nil {
+				return
+			}
+			continue
+		}
+		known := false
+		for _, m := range ciMetrics {
+			known = known || m.name == name
+		}
+		if !known {
+			return 0, nil, fmt.Errorf("unknown metric %q", name)
+		}
+		if byName[name], err = parse(val); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// ciCompare compares current against baseline.
+func ciCompare(baseline, current map[string]float64, def float64, tolerances map[string]float64, pauseSlack float64) *report.CIVerdict {
+	v := &report.CIVerdict{SchemaVersion: report.SchemaVersion, Pass: true, Metrics: []report.CIMetricCheck{}}
+	for _, m := range ciMetrics {
+		base, ok := baseline[m.name]
+		if !ok {
+			continue
+		}
+		tol, ok := tolerances[m.name]
+		if !ok {
+			tol = def
+		}
+		check := report.CIMetricCheck{Name: m.name, Baseline: base, Tolerance: tol}
+		if m.lowerIsWorse {
+			check.Limit = base * (1 - tol)
+		} else {
+			check.Limit = base * (1 + tol)
+			if m.pause {
+				check.Limit = max(check.Limit, base+pauseSlack)
+			}
+		}
+		check.Current, ok = current[m.name]
+		switch {
+		case !ok:
+			check.Missing, check.Regressed = true, true
+		case m.lowerIsWorse:
+			check.Regressed = check.Current < check.Limit
+		default:
+			check.Regressed = check.Current > check.Limit
+		}
+		if check.Regressed {
+			v.Pass = false
+		}
+		v.Metrics = append(v.Metrics, check)
+	}
+	return v
+}
+
+// doCI implements the ci subcommand and returns the exit status.
+func doCI(args []string) int {
+	fs := flag.NewFlagSet("ci", flag.ExitOnError)
+	var (
+		flagBaseline   = fs.String("baseline", "", "Compare against the baseline summary in `file`")
+		flagUpdate     = fs.Bool("update", false, "Write the summary of the trace to the -baseline file instead of comparing")
+		flagTolerance  = fs.String("tolerance", "10%", "Allowed regression as a fraction or percentage of the baseline, optionally as a comma-separated `list` of metric=tolerance overrides")
+		flagPauseSlack = fs.Duration("pause-slack", 100*time.Microsecond, "Never flag pause time increases smaller than `duration`")
+		flagVerdict    = fs.String("verdict", "-", "Write the JSON verdict to `file`")
+		flagMmap       = fs.Bool("mmap", false, "Memory-map the input file rather than reading it")
+	)
+	addLabelFlag(fs)
+	addPhasesFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s ci -baseline baseline.json [flags] [input]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nCompare a GC trace against a baseline and exit with status 1 on regression.\nMetrics: ")
+		for i, m := range ciMetrics {
+			if i > 0 {
+				fmt.Fprintf(os.Stderr, ", ")
+			}
+			fmt.Fprintf(os.Stderr, "%s", m.name)
+		}
+		fmt.Fprintf(os.Stderr, "\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *flagBaseline == "" || fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	def, tolerances, err := parseTolerances(*flagTolerance)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-tolerance: %s\n", err)
+		return 2
+	}
+
+	var input io.Reader = os.Stdin
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		defer f.Close()
+		input = f
+	}
+	s, err := parseInput(input, *flagMmap)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error parsing log: %s\n", err)
+		return 2
+	}
+	if len(s.Phases()) == 0 {
+		fmt.Fprintf(os.Stderr, "no GC recorded; did you set GODEBUG=gctrace=1?\n")
+		return 2
+	}
+	current := ciMetricValues(s)
+
+	if *flagUpdate {
+		data, err := json.MarshalIndent(report.CIBaseline{SchemaVersion: report.SchemaVersion, Metrics: current}, "", "\t")
+		if err == nil {
+			err = os.WriteFile(*flagBaseline, append(data, '\n'), 0666)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		return 0
+	}
+
+	var baseline report.CIBaseline
+	data, err := os.ReadFile(*flagBaseline)
+	if err == nil {
+		err = json.Unmarshal(data, &baseline)
+	}
+	if err == nil && baseline.SchemaVersion > report.SchemaVersion {
+		err = fmt.Errorf("schema version %d is newer than %d", baseline.SchemaVersion, report.SchemaVersion)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading baseline: %s\n", err)
+		return 2
+	}
+
+	v := ciCompare(baseline.Metrics, current, def, tolerances, float64(*flagPauseSlack))
+	v.Labels = flagLabels.Map()
+
+	data, err = json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		panic(err)
+	}
+	data = append(data, '\n')
+	if *flagVerdict == "-" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(*flagVerdict, data, 0666)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	for _, check := range v.Metrics {
+		if !check.Regressed {
+			continue
+		}
+		if check.Missing {
+			fmt.Fprintf(os.Stderr, "REGRESSION %s: missing from trace\n", check.Name)
+		} else {
+			fmt.Fprintf(os.Stderr, "REGRESSION %s: %s (baseline %s, limit %s)\n", check.Name, ciFormat(check.Name, check.Current), ciFormat(check.Name, check.Baseline), ciFormat(check.Name, check.Limit))
+		}
+	}
+	if !v.Pass {
+		return 1
+	}
+	return 0
+}
+
+// ciFormat formats value v of the named metric for display.
+func ciFormat(name string, v float64) string {
+	switch {
+	case strings.HasSuffix(name, "_ns"):
+		return ns(v)
+	case name == "cycles":
+		return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64)
+	}
+	return pct(v)
+}
@@ -0,0 +1,160 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseTolerances(t *testing.T) {
+	tests := []struct {
+		spec   string
+		def    float64
+		byName map[string]float64
+		err    string
+	}{
+		{spec: "10%", def: 0.1, byName: map[string]float64{}},
+		{spec: "0.25", def: 0.25, byName: map[string]float64{}},
+		{spec: "0", def: 0, byName: map[string]float64{}},
+		{spec: "5%,pause_max_ns=50%,mmu_10ms=0.02", def: 0.05, byName: map[string]float64{"pause_max_ns": 0.5, "mmu_10ms": 0.02}},
+		{spec: "cycles=1", def: 0, byName: map[string]float64{"cycles": 1}},
+		{spec: "pause_p100_ns=10%", err: `unknown metric "pause_p100_ns"`},
+		{spec: "x%", err: `bad tolerance "x%"`},
+		{spec: "-1", err: `bad tolerance "-1"`},
+		{spec: "cycles=", err: `bad tolerance ""`},
+		{spec: "", err: `bad tolerance ""`},
+	}
+	for _, test := range tests {
+		def, byName, err := parseTolerances(test.spec)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%q: want error %q, got %v", test.spec, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.spec, err)
+			continue
+		}
+		if math.Abs(def-test.def) > 1e-12 || !reflect.DeepEqual(byName, test.byName) {
+			t.Errorf("%q: want %v, %v; got %v, %v", test.spec, test.def, test.byName, def, byName)
+		}
+	}
+}
+
+func TestCICompare(t *testing.T) {
+	baseline := map[string]float64{
+		"pause_max_ns":        1e6,
+		"cycles":              100,
+		"mutator_utilization": 0.8,
+	}
+	const slack = 200e3
+	tests := []struct {
+		name       string
+		current    map[string]float64
+		tolerances map[string]float64
+		// regressed lists the regressed metrics.
+		regressed []string
+		missing   []string
+	}{
+		{
+			name:    "unchanged",
+			current: map[string]float64{"pause_max_ns": 1e6, "cycles": 100, "mutator_utilization": 0.8},
+		},
+		{
+			// Higher utilization is better, and more cycles
+			// are within the 10% tolerance.
+			name:    "within tolerance",
+			current: map[string]float64{"pause_max_ns": 1e6, "cycles": 110, "mutator_utilization": 0.9},
+		},
+		{
+			name:      "more cycles",
+			current:   map[string]float64{"pause_max_ns": 1e6, "cycles": 111, "mutator_utilization": 0.8},
+			regressed: []string{"cycles"},
+		},
+		{
+			// The limit of a metric for which lower is
+			// worse is below the baseline.
+			name:      "lower utilization",
+			current:   map[string]float64{"pause_max_ns": 1e6, "cycles": 100, "mutator_utilization": 0.71},
+			regressed: []string{"mutator_utilization"},
+		},
+		{
+			name:    "fewer cycles",
+			current: map[string]float64{"pause_max_ns": 1e6, "cycles": 50, "mutator_utilization": 0.73},
+		},
+		{
+			// 10% of 1ms is less than the slack, so pauses
+			// may grow by the slack.
+			name:    "pause within slack",
+			current: map[string]float64{"pause_max_ns": 1.2e6, "cycles": 100, "mutator_utilization": 0.8},
+		},
+		{
+			name:      "pause beyond slack",
+			current:   map[string]float64{"pause_max_ns": 1.2e6 + 1, "cycles": 100, "mutator_utilization": 0.8},
+			regressed: []string{"pause_max_ns"},
+		},
+		{
+			// The slack doesn't apply to other metrics.
+			name:       "no slack for cycles",
+			current:    map[string]float64{"pause_max_ns": 1e6, "cycles": 101, "mutator_utilization": 0.8},
+			tolerances: map[string]float64{"cycles": 0},
+			regressed:  []string{"cycles"},
+		},
+		{
+			name:       "per-metric tolerance",
+			current:    map[string]float64{"pause_max_ns": 1.9e6, "cycles": 100, "mutator_utilization": 0.8},
+			tolerances: map[string]float64{"pause_max_ns": 1},
+		},
+		{
+			name:      "missing",
+			current:   map[string]float64{"pause_max_ns": 1e6, "cycles": 100},
+			regressed: []string{"mutator_utilization"},
+			missing:   []string{"mutator_utilization"},
+		},
+		{
+			// Metrics not in the baseline aren't checked.
+			name:    "not in baseline",
+			current: map[string]float64{"pause_max_ns": 1e6, "cycles": 100, "mutator_utilization": 0.8, "mmu_10ms": 0},
+		},
+	}
+	for _, test := range tests {
+		v := ciCompare(baseline, test.current, 0.1, test.tolerances, slack)
+		var names, regressed, missing []string
+		for _, check := range v.Metrics {
+			names = append(names, check.Name)
+			if check.Regressed {
+				regressed = append(regressed, check.Name)
+			}
+			if check.Missing {
+				missing = append(missing, check.Name)
+			}
+		}
+		if want := []string{"pause_max_ns", "cycles", "mutator_utilization"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("%s: want checks of %v, got %v", test.name, want, names)
+		}
+		if !reflect.DeepEqual(regressed, test.regressed) || !reflect.DeepEqual(missing, test.missing) {
+			t.Errorf("%s: want regressed %v and missing %v, got %v and %v", test.name, test.regressed, test.missing, regressed, missing)
+		}
+		if v.Pass != (len(test.regressed) == 0) {
+			t.Errorf("%s: want pass %v, got %v", test.name, len(test.regressed) == 0, v.Pass)
+		}
+	}
+
+	// Check the limits themselves.
+	v := ciCompare(baseline, baseline, 0.1, nil, slack)
+	limits := map[string]float64{}
+	for _, check := range v.Metrics {
+		limits[check.Name] = check.Limit
+	}
+	want := map[string]float64{"pause_max_ns": 1.2e6, "cycles": 110, "mutator_utilization": 0.72}
+	for name, lim := range want {
+		if math.Abs(limits[name]-lim) > 1e-9*lim {
+			t.Errorf("%s: want limit %v, got %v", name, lim, limits[name])
+		}
+	}
+}
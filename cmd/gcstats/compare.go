@@ -0,0 +1,198 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+	"github.com/aclements/go-gcstats/internal/go-moremath/vec"
+)
+
+// compareMetric is a distribution compared between two traces by the
+// compare subcommand.
+type compareMetric struct {
+	name   string
+	format func(float64) string
+	// sample returns the distribution of the metric in s, or nil
+	// if s lacks the data to compute it.
+	sample func(s *gcstats.GcStats) []float64
+	// correlated indicates that nearby samples are strongly
+	// correlated, so a U-test, which assumes independent samples,
+	// would overstate the significance of a difference.
+	correlated bool
+}
+
+var compareMetrics = []compareMetric{
+	{"STW pause", ns, func(s *gcstats.GcStats) []float64 {
+		var xs []float64
+		for _, stop := range s.Stops() {
+			xs = append(xs, float64(stop.Duration))
+		}
+		return xs
+	}, false},
+	{"GC interval", ns, func(s *gcstats.GcStats) []float64 {
+		if !s.HaveProgTimes() {
+			return nil
+		}
+		var xs []float64
+		cycles := s.Cycles()
+		for i := 1; i < len(cycles); i++ {
+			xs = append(xs, float64(cycles[i].Begin-cycles[i-1].Begin))
+		}
+		return xs
+	}, false},
+	{"10ms mutator utilization", pct, func(s *gcstats.GcStats) []float64 {
+		if !s.HaveProgTimes() {
+			return nil
+		}
+		// Even disjoint windows aren't independent, since
+		// adjacent windows fall in the same phase of the GC
+		// cycle, so this has no p-value.
+		return s.WindowedMutatorUtilization(10e6, 10e6)
+	}, true},
+	{"live heap", mb, func(s *gcstats.GcStats) []float64 {
+		var xs []float64
+		for _, c := range s.Cycles() {
+			if c.HeapTrigger != 0 {
+				xs = append(xs, float64(c.HeapLive))
+			}
+		}
+		return xs
+	}, false},
+}
+
+func init() {
+	// Compare each phase's durations after the metrics above.
+	for kind := gcstats.PhaseSweepTerm; kind <= gcstats.PhaseSweep; kind++ {
+		kind := kind
+		compareMetrics = append(compareMetrics, compareMetric{kind.String()[len("Phase"):] + " phase", ns, func(s *gcstats.GcStats) []float64 {
+			var xs []float64
+			for _, p := range s.Phases() {
+				if p.Kind == kind && p.Duration >= 0 {
+					xs = append(xs, float64(p.Duration))
+				}
+			}
+			return xs
+		}, false})
+	}
+}
+
+// doCompare implements the compare subcommand and returns the exit
+// status.
+func doCompare(args []string) int {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	var (
+		flagAlpha      = fs.Float64("alpha", 0.05, "Report differences with p-values of at least `alpha` as not significant")
+		flagSkipWarmup = fs.Bool("skipwarmup", false, "Exclude the warm-up cycles at the beginning of each trace")
+		flagMmap       = fs.Bool("mmap", false, "Memory-map the input files rather than reading them")
+		flagMUD        = fs.Duration("mud", 0, "Instead of comparing metrics, plot CDF(new) - CDF(old) of mutator utilization in windows of `duration`")
+		flagQQ         = fs.Bool("qq", false, "With -mud, plot the quantiles of new against the quantiles of old")
+	)
+	fs.BoolVar(flagShow, "show", false, "Show plot in a window")
+	fs.StringVar(flagPlot, "plot", "", "Save plot to image `file` rather than showing it")
+	addPhasesFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s compare [flags] old new\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nCompare the distributions of GC metrics between two traces.\n")
+		fmt.Fprintf(os.Stderr, "Each delta is the change in the median, annotated with the p-value of\n")
+		fmt.Fprintf(os.Stderr, "a Mann-Whitney U-test and Cliff's delta effect size, which ranges from\n")
+		fmt.Fprintf(os.Stderr, "-1 (new is always smaller) to 1 (new is always larger). Differences\n")
+		fmt.Fprintf(os.Stderr, "that are not significant are shown as ~. Mutator utilization windows\n")
+		fmt.Fprintf(os.Stderr, "are correlated, so their deltas have only an effect size.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	if *flagPlot != "" {
+		*flagShow = true
+	}
+
+	runs := parseRuns(fs.Args(), *flagMmap, *flagSkipWarmup)
+	old, cur := runs[0], runs[1]
+
+	if *flagMUD != 0 {
+		requireProgTimes(old)
+		requireProgTimes(cur)
+		doMUDDiff(old, cur, *flagMUD, *flagQQ)
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "metric\told\tnew\tdelta\n")
+	for _, m := range compareMetrics {
+		xs1, xs2 := m.sample(old), m.sample(cur)
+		if len(xs1) == 0 || len(xs2) == 0 {
+			continue
+		}
+		med1 := stats.Sample{Xs: xs1}.Percentile(0.5)
+		med2 := stats.Sample{Xs: xs2}.Percentile(0.5)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", m.name, m.format(med1), m.format(med2), compareDelta(med1, med2, xs1, xs2, *flagAlpha, m.correlated))
+	}
+	w.Flush()
+	return 0
+}
+
+// compareDelta formats the change from sample xs1 with median med1 to
+// sample xs2 with median med2 and its significance. If the samples are
+// correlated, it formats only the change and its effect size.
+func compareDelta(med1, med2 float64, xs1, xs2 []float64, alpha float64, correlated bool) string {
+	res, err := stats.MannWhitneyUTest(xs2, xs1, stats.LocationDiffers)
+	if err == stats.ErrSamplesEqual {
+		return "~ (all equal)"
+	} else if err != nil {
+		return "-"
+	}
+	// Cliff's delta is the probability that a value from xs2 is
+	// larger than one from xs1 minus the reverse.
+	cliff := 2*res.U/float64(res.N1*res.N2) - 1
+	if !correlated && res.P >= alpha {
+		return fmt.Sprintf("~ (p=%.3f δ=%+.2f)", res.P, cliff)
+	}
+	delta := "?"
+	if med1 != 0 {
+		delta = fmt.Sprintf("%+.1f%%", 100*(med2-med1)/med1)
+	}
+	if correlated {
+		return fmt.Sprintf("%s (δ=%+.2f)", delta, cliff)
+	}
+	return fmt.Sprintf("%s (p=%.3f δ=%+.2f)", delta, res.P, cliff)
+}
+
+// doMUDDiff plots the difference between the mutator utilization
+// distributions of old and cur for windows of the given duration,
+// either as the difference of their CDFs or, if qq is set, as a
+// quantile-quantile plot. This shows the range of utilizations where
+// the distributions differ.
+func doMUDDiff(old, cur *gcstats.GcStats, window time.Duration, qq bool) {
+	mud1, mud2 := computeMUD(old, int(window)), computeMUD(cur, int(window))
+	if qq {
+		ps := vec.Linspace(0, 1, 101)
+		xs := make([]float64, len(ps))
+		ys := make([]float64, len(ps))
+		for i, p := range ps {
+			xs[i], ys[i] = mud1.InvCDF(p), mud2.InvCDF(p)
+		}
+		plot := newPlot(fmt.Sprintf("old mutator utilization at %s", window), fmt.Sprintf("new mutator utilization at %s", window), xs, "--style", "qq")
+		plot.addColumn("quantiles", ys)
+		showPlot(plot)
+		return
+	}
+	utils := vec.Linspace(0, 1, 101)
+	plot := newPlot(fmt.Sprintf("mutator utilization at %s", window), "CDF(new) - CDF(old)", utils, "--style", "muddiff")
+	plot.addSeries("", func(util float64) float64 {
+		return mud2.CDF(util) - mud1.CDF(util)
+	})
+	showPlot(plot)
+}
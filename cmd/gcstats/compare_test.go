@@ -0,0 +1,42 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import "testing"
+
+func TestCompareDelta(t *testing.T) {
+	// seq returns n consecutive integers starting at lo.
+	seq := func(lo float64, n int) []float64 {
+		xs := make([]float64, n)
+		for i := range xs {
+			xs[i] = lo + float64(i)
+		}
+		return xs
+	}
+	tests := []struct {
+		name       string
+		med1, med2 float64
+		xs1, xs2   []float64
+		correlated bool
+		want       string
+	}{
+		// Cliff's delta is positive if new is larger.
+		{"larger", 10, 20, seq(5, 10), seq(15, 10), false, "+100.0% (p=0.000 δ=+1.00)"},
+		{"smaller", 20, 10, seq(15, 10), seq(5, 10), false, "-50.0% (p=0.000 δ=-1.00)"},
+		{"same", 10, 10, seq(5, 10), seq(5, 10), false, "~ (p=1.000 δ=+0.00)"},
+		{"not significant", 10, 11, seq(5, 10), seq(6, 10), false, "~ (p=0.510 δ=+0.19)"},
+		{"all equal", 1, 1, []float64{1, 1}, []float64{1, 1, 1}, false, "~ (all equal)"},
+		{"zero median", 0, 20, seq(-5, 10), seq(15, 10), false, "? (p=0.000 δ=+1.00)"},
+		// Correlated samples have no p-value, so even small
+		// changes aren't marked ~.
+		{"correlated", 10, 11, seq(5, 10), seq(6, 10), true, "+10.0% (δ=+0.19)"},
+		{"correlated larger", 10, 20, seq(5, 10), seq(15, 10), true, "+100.0% (δ=+1.00)"},
+	}
+	for _, test := range tests {
+		if got := compareDelta(test.med1, test.med2, test.xs1, test.xs2, 0.05, test.correlated); got != test.want {
+			t.Errorf("%s: want %q, got %q", test.name, test.want, got)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// doCPU prints how the CPU time available over the trace, GOMAXPROCS
+// times the execution time, was divided between the garbage
+// collector's STW phases, mark assists, dedicated, fractional, and
+// idle mark workers, and the mutator. With -show, it also plots these
+// as a stacked bar.
+//
+// Mutator time is what remains, so it includes time Ps were idle
+// without an idle mark worker.
+func doCPU(s *gcstats.GcStats) {
+	a := accountCPU(s)
+	gc := a.gc()
+	rows := []struct {
+		label string
+		cpu   int64
+	}{
+		{"STW", a.stw},
+		{"assist", a.assist},
+		{"dedicated", a.dedicated},
+		{"fractional", a.fractional},
+		{"idle", a.idle},
+		{"mutator", a.total - gc},
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "\tCPU-seconds\tshare\t\n")
+	for _, r := range rows {
+		fmt.Fprintf(w, "%s\t%.3f\t%s\t\n", r.label, float64(r.cpu)/1e9, pct(float64(r.cpu)/float64(a.total)))
+	}
+	fmt.Fprintf(w, "GC total\t%.3f\t%s\t\n", float64(gc)/1e9, pct(float64(gc)/float64(a.total)))
+	fmt.Fprintf(w, "available\t%.3f\t\t\n", float64(a.total)/1e9)
+	w.Flush()
+	if gc == a.stw {
+		fmt.Println("\nThis trace does not report mark CPU time by worker type (requires Go 1.5 or later).")
+	}
+
+	if *flagShow {
+		plot := newPlot("", "CPU-seconds", []float64{0}, "--style", "stacked")
+		for _, r := range rows {
+			plot.addColumn(r.label, []float64{float64(r.cpu) / 1e9})
+		}
+		showPlot(plot)
+	}
+}
+
+// cpuAccount is the CPU time in nanoseconds available over a trace and
+// spent by each category of GC work.
+type cpuAccount struct {
+	// total is GOMAXPROCS times the execution time.
+	total int64
+
+	stw, assist, dedicated, fractional, idle int64
+}
+
+// gc returns the total CPU time spent by the garbage collector.
+func (a cpuAccount) gc() int64 {
+	return a.stw + a.assist + a.dedicated + a.fractional + a.idle
+}
+
+// accountCPU divides the CPU time of s between categories of GC work.
+func accountCPU(s *gcstats.GcStats) cpuAccount {
+	var a cpuAccount
+	marks := make(map[int]gcstats.Phase)
+	for _, p := range s.Phases() {
+		if p.Duration < 0 {
+			continue
+		}
+		cpu := p.Duration * int64(p.Gomaxprocs)
+		a.total += cpu
+		if p.STW {
+			a.stw += cpu
+		}
+		if p.Kind == gcstats.PhaseMark {
+			marks[p.N] = p
+		}
+	}
+	for _, c := range s.Cycles() {
+		a.assist += c.AssistCPU
+		a.idle += c.IdleCPU
+		if mark, ok := marks[c.N]; ok {
+			d, f := c.SplitBackground(mark)
+			a.dedicated += d
+			a.fractional += f
+		} else {
+			a.dedicated += c.BackgroundCPU
+		}
+	}
+	return a
+}
+
+// printCost prints the GC CPU time of s in core-hours and its cost at
+// rate dollars per core-hour, both over the trace and extrapolated to
+// an hour and a month (730 hours) of execution.
+func printCost(s *gcstats.GcStats, rate float64) {
+	a := accountCPU(s)
+	phases := s.Phases()
+	elapsed := phases[len(phases)-1].End() - phases[0].Begin
+	gcHours, totalHours := float64(a.gc())/3600e9, float64(a.total)/3600e9
+	perHour := gcHours / (float64(elapsed) / 3600e9)
+
+	fmt.Println()
+	fmt.Printf("GC CPU: %s core-hours of %s over %s (%s)\n", formatNum(gcHours, 3), formatNum(totalHours, 3), time.Duration(elapsed), pct(gcHours/totalHours))
+	fmt.Printf("  %s core-hours per hour of execution\n", formatNum(perHour, 3))
+	fmt.Printf("  cost: %s at $%g/core-hour (%s per hour of execution, %s per month)\n", dollars(gcHours*rate), rate, dollars(perHour*rate), dollars(perHour*rate*730))
+}
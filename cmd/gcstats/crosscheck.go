@@ -0,0 +1,127 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// readTraceSTWs returns the stop-the-world pauses in the execution
+// trace at path. path may be either a binary trace, which is decoded
+// using "go tool trace", or the output of "go tool trace -d=parsed".
+func readTraceSTWs(path string) ([]gcstats.TraceSTW, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	if magic, _ := r.Peek(5); !bytes.Equal(magic, []byte("go 1.")) {
+		return gcstats.ParseTraceSTWs(r)
+	}
+
+	// Binary trace.
+	cmd := exec.Command("go", "tool", "trace", "-d=parsed", path)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	stws, err := gcstats.ParseTraceSTWs(out)
+	io.Copy(io.Discard, out)
+	if werr := cmd.Wait(); err == nil && werr != nil {
+		err = fmt.Errorf("go tool trace: %v", werr)
+	}
+	return stws, err
+}
+
+func doCrossCheck(s *gcstats.GcStats, path string) {
+	stws, err := readTraceSTWs(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading execution trace: %s\n", err)
+		os.Exit(1)
+	}
+	cc := s.CrossCheckTrace(stws)
+
+	// underThreshold is the fraction by which the GC trace must
+	// underestimate a pause to be flagged.
+	const underThreshold = 0.1
+
+	fmt.Printf("GC pauses in execution trace: %d (%d matched, offset %s)\n", len(cc.Matched)+len(cc.Missed), len(cc.Matched), time.Duration(cc.Offset))
+	if len(cc.Missed) > 0 {
+		var total, max int64
+		for _, stw := range cc.Missed {
+			total += stw.Duration
+			max = int64Max(max, stw.Duration)
+		}
+		fmt.Printf("Missed by GC trace:   %d pauses, total=%s max=%s\n", len(cc.Missed), ns(float64(total)), ns(float64(max)))
+	}
+	if len(cc.Unmatched) > 0 {
+		fmt.Printf("Missing from execution trace: %d STW phases\n", len(cc.Unmatched))
+	}
+
+	if len(cc.Matched) > 0 {
+		var driftSum, driftMax, underSum int64
+		underMax := cc.Matched[0].Underestimate()
+		var flagged []gcstats.STWMatch
+		for _, m := range cc.Matched {
+			drift := m.BeginDrift(cc.Offset)
+			if drift < 0 {
+				drift = -drift
+			}
+			driftSum += drift
+			driftMax = int64Max(driftMax, drift)
+			under := m.Underestimate()
+			underSum += under
+			underMax = int64Max(underMax, under)
+			if float64(under) > underThreshold*float64(m.Trace.Duration) {
+				flagged = append(flagged, m)
+			}
+		}
+		n := float64(len(cc.Matched))
+		fmt.Printf("Begin time drift:     mean=%s max=%s\n", ns(float64(driftSum)/n), ns(float64(driftMax)))
+		fmt.Printf("Pause underestimate:  mean=%s max=%s\n", signedNS(float64(underSum)/n), signedNS(float64(underMax)))
+		if len(flagged) > 0 {
+			fmt.Printf("GC trace underestimates %d of %d pauses by more than %s:\n", len(flagged), len(cc.Matched), pct(underThreshold))
+			for _, m := range flagged {
+				fmt.Printf("  gc %d %s: %s in GC trace, %s in execution trace\n", m.Phase.N, m.Phase.Kind, ns(float64(m.Phase.Duration)), ns(float64(m.Trace.Duration)))
+			}
+		}
+	}
+
+	if len(cc.Other) > 0 {
+		var total int64
+		for _, stw := range cc.Other {
+			total += stw.Duration
+		}
+		fmt.Printf("Non-GC pauses (not in GC trace): %d, total=%s\n", len(cc.Other), ns(float64(total)))
+	}
+}
+
+func signedNS(x float64) string {
+	if x < 0 {
+		return "-" + ns(-x)
+	}
+	return ns(x)
+}
+
+func int64Max(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,229 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/gcstats/parquet"
+	"github.com/aclements/go-gcstats/gcstats/report"
+)
+
+// cycleRecord is one row of the cycles subcommand's output. Times are
+// in nanoseconds and heap sizes are in bytes.
+type cycleRecord report.CycleRecord
+
+var cycleCSVHeader = []string{
+	"gc", "begin_ns",
+	"sweep_term_ns", "scan_ns", "install_wb_ns", "mark_ns", "mark_term_ns",
+	"pause_ns", "max_pause_ns", "gomaxprocs",
+	"heap_trigger", "heap_marked", "heap_live", "heap_goal",
+	"assist_cpu_ns", "background_cpu_ns", "idle_cpu_ns",
+	"forced", "cause",
+}
+
+// cycleHeader returns the CSV header of the cycles subcommand, which
+// has a column for each -label after cycleCSVHeader.
+func cycleHeader() []string {
+	hdr := cycleCSVHeader[:len(cycleCSVHeader):len(cycleCSVHeader)]
+	for _, l := range flagLabels {
+		hdr = append(hdr, l.name)
+	}
+	return hdr
+}
+
+func (r *cycleRecord) csv() []string {
+	i := func(x int64) string { return strconv.FormatInt(x, 10) }
+	begin := ""
+	if r.BeginNS != nil {
+		begin = i(*r.BeginNS)
+	}
+	row := []string{
+		strconv.Itoa(r.GC), begin,
+		i(r.SweepTermNS), i(r.ScanNS), i(r.InstallWBNS), i(r.MarkNS), i(r.MarkTermNS),
+		i(r.PauseNS), i(r.MaxPauseNS), strconv.Itoa(r.Gomaxprocs),
+		i(r.HeapTrigger), i(r.HeapMarked), i(r.HeapLive), i(r.HeapGoal),
+		i(r.AssistCPUNS), i(r.BackgroundCPUNS), i(r.IdleCPUNS),
+		strconv.FormatBool(r.Forced), r.Cause,
+	}
+	for _, l := range flagLabels {
+		row = append(row, r.Labels[l.name])
+	}
+	return row
+}
+
+// cycleParquetColumns returns the columns of recs for a Parquet file.
+// The columns are those of the CSV output, with the same names.
+// begin_ns is null if the trace lacks program execution times.
+func cycleParquetColumns(recs []*cycleRecord) []parquet.Column {
+	rows := make([][]string, len(recs))
+	for i, r := range recs {
+		rows[i] = r.csv()
+	}
+	hdr := cycleHeader()
+	cols := make([]parquet.Column, len(hdr))
+	for j, name := range hdr {
+		c := &cols[j]
+		c.Name = name
+		switch {
+		case name == "forced":
+			c.Bool = make([]bool, len(rows))
+			for i, row := range rows {
+				c.Bool[i] = row[j] == "true"
+			}
+		case name == "cause", j >= len(cycleCSVHeader):
+			c.String = make([]string, len(rows))
+			for i, row := range rows {
+				c.String[i] = row[j]
+			}
+		default:
+			c.Int64 = make([]int64, len(rows))
+			if name == "begin_ns" {
+				c.Valid = make([]bool, len(rows))
+			}
+			for i, row := range rows {
+				if row[j] == "" {
+					continue
+				}
+				c.Int64[i], _ = strconv.ParseInt(row[j], 10, 64)
+				if c.Valid != nil {
+					c.Valid[i] = true
+				}
+			}
+		}
+	}
+	return cols
+}
+
+// cycleRecords returns a record for each cycle of s.
+func cycleRecords(s *gcstats.GcStats) []*cycleRecord {
+	phases := s.Phases()
+	var recs []*cycleRecord
+	labels := flagLabels.Map()
+	j := 0
+	for _, c := range s.Cycles() {
+		r := &cycleRecord{
+			SchemaVersion:   report.SchemaVersion,
+			GC:              c.N,
+			HeapTrigger:     c.HeapTrigger,
+			HeapMarked:      c.HeapMarked,
+			HeapLive:        c.HeapLive,
+			HeapGoal:        c.HeapGoal,
+			AssistCPUNS:     c.AssistCPU,
+			BackgroundCPUNS: c.BackgroundCPU,
+			IdleCPUNS:       c.IdleCPU,
+			Forced:          c.Forced,
+			Cause:           c.Cause.String(),
+			Labels:          labels,
+		}
+		if s.HaveProgTimes() {
+			begin := c.Begin
+			r.BeginNS = &begin
+		}
+
+		// Find this cycle's phases, skipping the sweep of the
+		// previous cycle.
+		for j < len(phases) && phases[j].N < c.N {
+			j++
+		}
+		first := j
+		for ; j < len(phases) && phases[j].N == c.N; j++ {
+			p := phases[j]
+			r.Gomaxprocs = p.Gomaxprocs
+			if p.Duration < 0 {
+				continue
+			}
+			switch p.Kind {
+			case gcstats.PhaseSweepTerm:
+				r.SweepTermNS += p.Duration
+			case gcstats.PhaseScan:
+				r.ScanNS += p.Duration
+			case gcstats.PhaseInstallWB:
+				r.InstallWBNS += p.Duration
+			case gcstats.PhaseMark:
+				r.MarkNS += p.Duration
+			case gcstats.PhaseMarkTerm:
+				r.MarkTermNS += p.Duration
+			}
+			if p.STW {
+				r.PauseNS += p.Duration
+			}
+		}
+		for _, stop := range gcstats.JoinStops(phases[first:j]) {
+			r.MaxPauseNS = max(r.MaxPauseNS, stop.Duration)
+		}
+		recs = append(recs, r)
+	}
+	return recs
+}
+
+// doCycles implements the cycles subcommand and returns the exit
+// status.
+func doCycles(args []string) int {
+	fs := flag.NewFlagSet("cycles", flag.ExitOnError)
+	flagFormat := fs.String("format", "csv", "Output `format`: csv, json (one object per line), or parquet")
+	addLabelFlag(fs)
+	addPhasesFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s cycles [flags] [input]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nPrint one record per GC cycle. Times are in nanoseconds and heap sizes in bytes.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 || *flagFormat != "csv" && *flagFormat != "json" && *flagFormat != "parquet" {
+		fs.Usage()
+		return 2
+	}
+
+	var input io.Reader = os.Stdin
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		input = f
+	}
+	s, err := parseInput(input, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error parsing log: %s\n", err)
+		return 1
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	switch *flagFormat {
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(cycleHeader())
+		for _, r := range cycleRecords(s) {
+			cw.Write(r.csv())
+		}
+		cw.Flush()
+	case "json":
+		enc := json.NewEncoder(w)
+		for _, r := range cycleRecords(s) {
+			enc.Encode(r)
+		}
+	case "parquet":
+		if err := parquet.Write(w, cycleParquetColumns(cycleRecords(s)), "gcstats"); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
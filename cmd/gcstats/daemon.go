@@ -0,0 +1,433 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/gcstatshttp"
+)
+
+// A daemon follows the GC traces in a directory and serves statistics
+// aggregated by service, which it derives from the trace file names.
+type daemon struct {
+	dir, pattern string
+	serviceRE    *regexp.Regexp
+
+	// mu protects services and the traces and aggregates of each
+	// service, which poll updates as it parses. The handlers serve
+	// snapshots of the traces, so they don't need mu.
+	mu       sync.Mutex
+	services map[string]*daemonService
+}
+
+// daemonService is the open traces of one service and the aggregate
+// statistics of all of its traces.
+type daemonService struct {
+	name   string
+	traces []*daemonTrace
+
+	// closed is the files of closed traces that are still in the
+	// directory, so they aren't followed again. nclosed is the
+	// number of closed traces and closedCycles is their total GC
+	// cycles.
+	closed       []os.FileInfo
+	nclosed      int
+	closedCycles int
+
+	// handler serves the analyses of a snapshot of the newest
+	// trace.
+	handler *gcstatshttp.Handler
+
+	// cycles is the total GC cycles of traces and pauses are
+	// their STW pauses.
+	cycles int
+	pauses gcstats.Pauses
+}
+
+// daemonTrace is a trace file being followed.
+type daemonTrace struct {
+	path string
+	f    *os.File
+	fi   os.FileInfo
+	p    *gcstats.Parser
+
+	// nphases is the number of phases incorporated into the
+	// service's aggregates.
+	nphases int
+}
+
+// service returns the service name of the trace file named name: the
+// first submatch of the -service regexp, or the whole match if it has
+// no submatches.
+func (d *daemon) service(name string) string {
+	m := d.serviceRE.FindStringSubmatch(name)
+	switch {
+	case m == nil:
+		return name
+	case len(m) > 1:
+		return m[1]
+	}
+	return m[0]
+}
+
+// poll discovers new trace files and parses what has been added to
+// each open trace.
+func (d *daemon) poll() {
+	paths, err := filepath.Glob(filepath.Join(d.dir, d.pattern))
+	if err != nil {
+		log.Printf("bad -pattern: %s", err)
+		return
+	}
+	type file struct {
+		path string
+		fi   os.FileInfo
+	}
+	var files []file
+	for _, path := range paths {
+		if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() {
+			files = append(files, file{path, fi})
+		}
+	}
+	// Open older files first, so each service's last trace is
+	// its newest.
+	sort.SliceStable(files, func(i, j int) bool { return files[i].fi.ModTime().Before(files[j].fi.ModTime()) })
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// Open files not already followed or closed. Rotated files
+	// keep their identity under a new name, so compare files, not
+	// paths.
+	present := make(map[*daemonTrace]bool)
+	closed := make(map[*daemonService][]os.FileInfo)
+	for _, f := range files {
+		if t := d.find(f.path, f.fi); t != nil {
+			present[t] = true
+			continue
+		}
+		if svc := d.services[d.service(filepath.Base(f.path))]; svc != nil && svc.isClosed(f.fi) {
+			closed[svc] = append(closed[svc], f.fi)
+			continue
+		}
+		if err := d.open(f.path, f.fi); err != nil {
+			log.Print(err)
+		}
+	}
+
+	for _, svc := range d.services {
+		// Forget closed files that have been removed.
+		svc.closed = closed[svc]
+		open := svc.traces[:0]
+		for i, t := range svc.traces {
+			for t.p.Next() {
+			}
+			grew := svc.update(t)
+			if grew && i == len(svc.traces)-1 {
+				svc.handler.SetStats(t.p.Stats().Snapshot())
+			}
+			if err := t.p.Err(); err != nil {
+				log.Printf("%s: %s", t.path, err)
+				svc.close(t)
+				continue
+			}
+			// Stop following a trace that has been
+			// superseded or removed once it stops growing.
+			if (i < len(svc.traces)-1 || !present[t]) && !grew {
+				svc.close(t)
+				continue
+			}
+			open = append(open, t)
+		}
+		clear(svc.traces[len(open):])
+		svc.traces = open
+	}
+}
+
+// find returns the trace of the file at path with info fi, or nil if
+// the file is new. Since the file may have been renamed, find updates
+// the trace's path.
+func (d *daemon) find(path string, fi os.FileInfo) *daemonTrace {
+	svc := d.services[d.service(filepath.Base(path))]
+	if svc == nil {
+		return nil
+	}
+	for _, t := range svc.traces {
+		if os.SameFile(t.fi, fi) {
+			t.path = path
+			return t
+		}
+	}
+	return nil
+}
+
+// open starts following the trace at path, which becomes the newest
+// trace of its service.
+func (d *daemon) open(path string, fi os.FileInfo) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	p := newParser(gcstats.NewParser(f))
+	p.Follow = true
+	name := d.service(filepath.Base(path))
+	svc := d.services[name]
+	snap := p.Stats().Snapshot()
+	if svc == nil {
+		svc = &daemonService{name: name, handler: gcstatshttp.NewHandler(snap)}
+		svc.handler.Labels = flagLabels.Map()
+		svc.handler.AllowUpload = false
+		d.services[name] = svc
+	}
+	svc.traces = append(svc.traces, &daemonTrace{path: path, f: f, fi: fi, p: p})
+	svc.handler.SetStats(snap)
+	log.Printf("following %s for service %s", path, name)
+	return nil
+}
+
+// isClosed reports whether fi is the file of a closed trace of svc.
+func (svc *daemonService) isClosed(fi os.FileInfo) bool {
+	for _, fi2 := range svc.closed {
+		if os.SameFile(fi2, fi) {
+			return true
+		}
+	}
+	return false
+}
+
+// close stops following t, which the caller removes from svc.traces.
+// Its statistics remain in svc's aggregates, but its parser is
+// dropped, except that svc's handler keeps serving it if it was the
+// newest trace.
+func (svc *daemonService) close(t *daemonTrace) {
+	t.f.Close()
+	svc.closed = append(svc.closed, t.fi)
+	svc.nclosed++
+	svc.closedCycles += t.p.Stats().Count()
+	log.Printf("closed %s", t.path)
+}
+
+// update incorporates the phases parsed from t since the last update
+// into svc's aggregates. It reports whether there were any.
+func (svc *daemonService) update(t *daemonTrace) bool {
+	phases := t.p.Stats().Phases()
+	if len(phases) == t.nphases {
+		return false
+	}
+	svc.pauses.Add(phases[t.nphases:])
+	svc.cycles = svc.closedCycles
+	for _, t2 := range svc.traces {
+		svc.cycles += t2.p.Stats().Count()
+	}
+	t.nphases = len(phases)
+	return true
+}
+
+// serviceStatus is an entry of /services.json.
+type serviceStatus struct {
+	Service            string            `json:"service"`
+	Traces             []string          `json:"traces"`
+	ClosedTraces       int               `json:"closedTraces"`
+	Labels             map[string]string `json:"labels,omitempty"`
+	Cycles             int               `json:"cycles"`
+	Pauses             int               `json:"pauses"`
+	PauseTotalNS       int64             `json:"pauseTotalNS"`
+	PauseMaxNS         int64             `json:"pauseMaxNS"`
+	PauseP50NS         int64             `json:"pauseP50NS"`
+	PauseP99NS         int64             `json:"pauseP99NS"`
+	MutatorUtilization *float64          `json:"mutatorUtilization,omitempty"`
+}
+
+// status returns the status of each service, sorted by name.
+func (d *daemon) status() []serviceStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []serviceStatus
+	for _, svc := range d.services {
+		st := serviceStatus{
+			Service:      svc.name,
+			Labels:       flagLabels.Map(),
+			ClosedTraces: svc.nclosed,
+			Cycles:       svc.cycles,
+			Pauses:       svc.pauses.Count(),
+			PauseTotalNS: svc.pauses.Total(),
+			PauseMaxNS:   svc.pauses.Max(),
+			PauseP50NS:   svc.pauses.Percentile(0.5),
+			PauseP99NS:   svc.pauses.Percentile(0.99),
+		}
+		for _, t := range svc.traces {
+			st.Traces = append(st.Traces, t.path)
+		}
+		// Utilization is of the newest trace, since
+		// utilization across restarts is meaningless.
+		if s := svc.handler.Stats(); s.HaveProgTimes() && len(s.Phases()) > 0 {
+			mu := s.MutatorUtilization()
+			st.MutatorUtilization = &mu
+		}
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Service < out[j].Service })
+	return out
+}
+
+var daemonIndex = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html><head><title>gcstats daemon</title></head>
+<body>
+<h1>GC traces in {{.Dir}}</h1>
+<table>
+<tr><th>service</th><th>traces</th><th>closed</th><th>GCs</th><th>max pause</th><th>p99 pause</th></tr>
+{{range .Services}}<tr><td><a href="s/{{.Service}}/">{{.Service}}</a></td><td>{{len .Traces}}</td><td>{{.ClosedTraces}}</td><td>{{.Cycles}}</td><td>{{.PauseMaxNS}}ns</td><td>{{.PauseP99NS}}ns</td></tr>
+{{end}}</table>
+<p><a href="services.json">services.json</a> · <a href="metrics">metrics</a></p>
+</body></html>
+`))
+
+func (d *daemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		daemonIndex.Execute(w, struct {
+			Dir      string
+			Services []serviceStatus
+		}{d.dir, d.status()})
+	case r.URL.Path == "/services.json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(d.status())
+	case r.URL.Path == "/metrics":
+		d.serveMetrics(w)
+	case strings.HasPrefix(r.URL.Path, "/s/"):
+		name, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/s/"), "/")
+		d.mu.Lock()
+		svc := d.services[name]
+		d.mu.Unlock()
+		if svc == nil {
+			http.NotFound(w, r)
+			return
+		}
+		http.StripPrefix("/s/"+name, svc.handler).ServeHTTP(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveMetrics serves the aggregates of each service in the Prometheus
+// text exposition format.
+func (d *daemon) serveMetrics(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	status := d.status()
+	// labels returns the label set of service's samples, including
+	// the -label labels.
+	labels := func(service string) string {
+		set := fmt.Sprintf("service=%q", service)
+		for _, l := range flagLabels {
+			set += fmt.Sprintf(",%s=%q", l.name, l.value)
+		}
+		return set
+	}
+	metric := func(name, typ, help string, value func(st serviceStatus) (float64, bool)) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+		for _, st := range status {
+			if v, ok := value(st); ok {
+				fmt.Fprintf(w, "%s{%s} %g\n", name, labels(st.Service), v)
+			}
+		}
+	}
+	metric("gcstats_traces", "gauge", "Trace files seen for the service.", func(st serviceStatus) (float64, bool) {
+		return float64(len(st.Traces) + st.ClosedTraces), true
+	})
+	metric("gcstats_cycles_total", "counter", "GC cycles in the service's traces.", func(st serviceStatus) (float64, bool) {
+		return float64(st.Cycles), true
+	})
+	metric("gcstats_pause_max_seconds", "gauge", "Longest STW pause in the service's traces.", func(st serviceStatus) (float64, bool) {
+		return float64(st.PauseMaxNS) / 1e9, true
+	})
+	metric("gcstats_mutator_utilization", "gauge", "Mean mutator utilization of the service's newest trace.", func(st serviceStatus) (float64, bool) {
+		if st.MutatorUtilization == nil {
+			return 0, false
+		}
+		return *st.MutatorUtilization, true
+	})
+	fmt.Fprintf(w, "# HELP gcstats_pause_seconds STW pauses in the service's traces.\n# TYPE gcstats_pause_seconds summary\n")
+	for _, st := range status {
+		set := labels(st.Service)
+		fmt.Fprintf(w, "gcstats_pause_seconds{%s,quantile=\"0.5\"} %g\n", set, float64(st.PauseP50NS)/1e9)
+		fmt.Fprintf(w, "gcstats_pause_seconds{%s,quantile=\"0.99\"} %g\n", set, float64(st.PauseP99NS)/1e9)
+		fmt.Fprintf(w, "gcstats_pause_seconds_sum{%s} %g\n", set, float64(st.PauseTotalNS)/1e9)
+		fmt.Fprintf(w, "gcstats_pause_seconds_count{%s} %d\n", set, st.Pauses)
+	}
+}
+
+// doDaemon implements the daemon subcommand and returns the exit
+// status.
+func doDaemon(args []string) int {
+	fs := flag.NewFlagSet("daemon", flag.ExitOnError)
+	var (
+		flagDir     = fs.String("dir", "", "Follow the GC traces in `directory`")
+		flagPattern = fs.String("pattern", "*", "Follow the files in -dir matching the glob `pattern`")
+		flagService = fs.String("service", `^[^.]+`, "Derive each file's service from the first submatch, or the whole match, of `regexp` in its name")
+		flagHTTP    = fs.String("http", "localhost:8080", "Serve statistics at `addr`")
+		flagPoll    = fs.Duration("poll", 5*time.Second, "Check for new files and new cycles every `interval`")
+	)
+	addLabelFlag(fs)
+	addPhasesFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s daemon -dir directory [flags]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nFollow the GC traces in a directory, including new and rotated files,\n")
+		fmt.Fprintf(os.Stderr, "and serve statistics aggregated by service over HTTP: an index at /,\n")
+		fmt.Fprintf(os.Stderr, "/services.json, Prometheus metrics at /metrics, and the analyses of\n")
+		fmt.Fprintf(os.Stderr, "each service's newest trace at /s/<service>/.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *flagDir == "" || fs.NArg() != 0 || *flagPoll <= 0 {
+		fs.Usage()
+		return 2
+	}
+	for _, l := range flagLabels {
+		if l.name == "service" || l.name == "quantile" {
+			fmt.Fprintf(os.Stderr, "bad -label: %s is reserved for /metrics\n", l.name)
+			return 2
+		}
+	}
+	re, err := regexp.Compile(*flagService)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bad -service: %s\n", err)
+		return 2
+	}
+	if _, err := filepath.Match(*flagPattern, ""); err != nil {
+		fmt.Fprintf(os.Stderr, "bad -pattern: %s\n", err)
+		return 2
+	}
+
+	d := &daemon{dir: *flagDir, pattern: *flagPattern, serviceRE: re, services: make(map[string]*daemonService)}
+	d.poll()
+	go func() {
+		for range time.Tick(*flagPoll) {
+			d.poll()
+		}
+	}()
+
+	ln, err := net.Listen("tcp", *flagHTTP)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("Serving GC statistics for %s at http://%s/\n", *flagDir, ln.Addr())
+	fmt.Fprintln(os.Stderr, http.Serve(ln, d))
+	return 1
+}
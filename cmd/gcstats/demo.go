@@ -0,0 +1,47 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aclements/go-gcstats/gcstats/corpus"
+)
+
+// doDemo implements the demo subcommand and returns the exit status.
+func doDemo(args []string) int {
+	fs := flag.NewFlagSet("demo", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s demo [name]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nList the built-in example traces, or write the one called name to\n")
+		fmt.Fprintf(os.Stderr, "stdout to be analyzed, as in\n\n")
+		fmt.Fprintf(os.Stderr, "\t%s demo runtime-compile | %s -mmu\n", os.Args[0], os.Args[0])
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	switch fs.NArg() {
+	case 0:
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintf(w, "name\tversion\tdescription\n")
+		for _, t := range corpus.All() {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", t.Name, t.GoVersion, t.Description)
+		}
+		w.Flush()
+	case 1:
+		t, ok := corpus.Lookup(fs.Arg(0))
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown demo trace %q; run %s demo for a list\n", fs.Arg(0), os.Args[0])
+			return 1
+		}
+		os.Stdout.Write(t.Bytes())
+	default:
+		fs.Usage()
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,128 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// parseGCRange parses a GC cycle number or an inclusive range of
+// cycle numbers "lo-hi", where either bound may be omitted.
+func parseGCRange(spec string) (lo, hi int, err error) {
+	lo, hi = 0, int(^uint(0)>>1)
+	if spec == "" {
+		return
+	}
+	loS, hiS, isRange := strings.Cut(spec, "-")
+	if !isRange {
+		hiS = loS
+	}
+	if loS != "" {
+		if lo, err = strconv.Atoi(loS); err != nil {
+			return 0, 0, fmt.Errorf("bad GC range %q", spec)
+		}
+	}
+	if hiS != "" {
+		if hi, err = strconv.Atoi(hiS); err != nil {
+			return 0, 0, fmt.Errorf("bad GC range %q", spec)
+		}
+	}
+	return
+}
+
+// dumpTrace prints the cycles of s numbered lo through hi and their
+// phases to w.
+func dumpTrace(w io.Writer, s *gcstats.GcStats, lo, hi int) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	at := func(t int64) string {
+		if !s.HaveProgTimes() {
+			return ""
+		}
+		return "@" + ns(float64(t))
+	}
+	cycles := make(map[int]gcstats.Cycle)
+	for _, c := range s.Cycles() {
+		cycles[c.N] = c
+	}
+
+	n := -1
+	for _, p := range s.Phases() {
+		if p.N < lo || p.N > hi {
+			continue
+		}
+		if p.N != n {
+			// Start of a new cycle.
+			if n != -1 {
+				fmt.Fprintln(tw)
+			}
+			n = p.N
+			fmt.Fprintf(tw, "gc %d\t%s\t\t%d P", n, at(p.Begin), p.Gomaxprocs)
+			if c, ok := cycles[n]; ok && c.HeapTrigger != 0 {
+				fmt.Fprintf(tw, "\theap %d->%d->%d MB", c.HeapTrigger>>20, c.HeapMarked>>20, c.HeapLive>>20)
+				if c.HeapGoal != 0 {
+					fmt.Fprintf(tw, ", %d MB goal", c.HeapGoal>>20)
+				}
+				if c.Cause != gcstats.CausePaced {
+					fmt.Fprintf(tw, ", %s", c.Cause)
+				}
+			}
+			fmt.Fprintln(tw)
+		}
+		stw := ""
+		if p.STW {
+			stw = "STW"
+		}
+		dur := "?"
+		if p.Duration >= 0 {
+			dur = ns(float64(p.Duration))
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%.2f GC procs\n", p.Kind.String()[len("Phase"):], at(p.Begin), stw, dur, p.GCProcs)
+	}
+	tw.Flush()
+}
+
+// doDump implements the dump subcommand and returns the exit status.
+func doDump(args []string) int {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	flagGC := fs.String("gc", "", "Only print GC cycle `n`, or cycles in the inclusive range lo-hi")
+	addPhasesFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s dump [flags] [input]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nPrint each GC cycle and its phases as parsed.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	lo, hi, err := parseGCRange(*flagGC)
+	if err != nil || fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	var input io.Reader = os.Stdin
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		input = f
+	}
+	s, err := parseInput(input, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error parsing log: %s\n", err)
+		return 1
+	}
+	dumpTrace(os.Stdout, s, lo, hi)
+	return 0
+}
@@ -0,0 +1,491 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+)
+
+// evalHelp describes the expression language of the eval subcommand.
+const evalHelp = `An expression is a number, a duration such as 50ms (in nanoseconds),
+a function call, a method call on the result of a function, or
+arithmetic (+, -, *, /, and parentheses) on these.
+
+Functions:
+  mmu(window)    minimum mutator utilization in windows of duration window
+  mud(window)    mutator utilization distribution in windows of duration window
+  mu()           mean mutator utilization
+  pauses()       STW pause durations
+  intervals()    times between the beginnings of consecutive GC cycles
+  cycles()       number of GC cycles
+
+Methods of mud(window):
+  quantile(p) cdf(util) min() max() lost(target)
+
+Methods of pauses() and intervals():
+  quantile(p) min() max() mean() stddev() sum() count()
+`
+
+// doEval implements the eval subcommand and returns the exit status.
+func doEval(args []string) int {
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	var (
+		flagSkipWarmup = fs.Bool("skipwarmup", false, "Exclude the warm-up cycles at the beginning of the trace")
+		flagMmap       = fs.Bool("mmap", false, "Memory-map the input file rather than reading it")
+	)
+	addPhasesFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s eval [flags] expr [input]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nEvaluate expr over a GC trace and print the resulting number, such as\n")
+		fmt.Fprintf(os.Stderr, "%s eval 'mud(50ms).quantile(0.01)' gctrace.\n\n", os.Args[0])
+		fmt.Fprint(os.Stderr, evalHelp, "\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return 2
+	}
+
+	var input io.Reader = os.Stdin
+	if fs.NArg() == 2 {
+		f, err := os.Open(fs.Arg(1))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		input = f
+	}
+	s, err := parseInput(input, *flagMmap)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error parsing log: %s\n", err)
+		return 1
+	}
+	if *flagSkipWarmup {
+		s = s.SkipCycles(s.Warmup())
+	}
+
+	v, err := evalExpr(s, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "eval: %s\n", err)
+		return 1
+	}
+	fmt.Println(strconv.FormatFloat(v, 'g', -1, 64))
+	return 0
+}
+
+// evalExpr evaluates expr over s.
+func evalExpr(s *gcstats.GcStats, expr string) (float64, error) {
+	toks, err := evalLex(expr)
+	if err != nil {
+		return 0, err
+	}
+	p := &evalParser{s: s, toks: toks}
+	v, err := p.sum()
+	if err != nil {
+		return 0, err
+	}
+	if p.peek() != "" {
+		return 0, fmt.Errorf("unexpected %q", p.peek())
+	}
+	f, ok := v.(float64)
+	if !ok {
+		return 0, fmt.Errorf("expression is a %s, not a number; call one of its methods", evalTypeName(v))
+	}
+	return f, nil
+}
+
+// evalLex splits expr into tokens: numbers and durations, identifiers,
+// and single-character punctuation.
+func evalLex(expr string) ([]string, error) {
+	var toks []string
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(expr) && expr[i+1] >= '0' && expr[i+1] <= '9':
+			// A number, possibly with an exponent or a
+			// duration unit.
+			j := i
+			for j < len(expr) && (strings.IndexByte("0123456789.", expr[j]) >= 0 || (expr[j] == 'e' || expr[j] == 'E') && j+1 < len(expr) && strings.IndexByte("0123456789+-", expr[j+1]) >= 0) {
+				if expr[j] == 'e' || expr[j] == 'E' {
+					j++
+				}
+				j++
+			}
+			for j < len(expr) && (unicode.IsLetter(rune(expr[j])) || expr[j] >= 0x80) {
+				j++
+			}
+			toks = append(toks, expr[i:j])
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(expr) && (unicode.IsLetter(rune(expr[j])) || unicode.IsDigit(rune(expr[j])) || expr[j] == '_') {
+				j++
+			}
+			toks = append(toks, expr[i:j])
+			i = j
+		case strings.ContainsRune("().,+-*/", c):
+			toks = append(toks, string(c))
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+	return toks, nil
+}
+
+// evalParser evaluates a token stream by recursive descent.
+type evalParser struct {
+	s    *gcstats.GcStats
+	toks []string
+}
+
+func (p *evalParser) peek() string {
+	if len(p.toks) == 0 {
+		return ""
+	}
+	return p.toks[0]
+}
+
+func (p *evalParser) next() string {
+	t := p.peek()
+	if len(p.toks) > 0 {
+		p.toks = p.toks[1:]
+	}
+	return t
+}
+
+func (p *evalParser) expect(tok string) error {
+	if t := p.next(); t != tok {
+		if t == "" {
+			return fmt.Errorf("want %q, got end of expression", tok)
+		}
+		return fmt.Errorf("want %q, got %q", tok, t)
+	}
+	return nil
+}
+
+// sum parses term (('+' | '-') term)*.
+func (p *evalParser) sum() (interface{}, error) {
+	return p.binary(p.term, "+", "-")
+}
+
+// term parses unary (('*' | '/') unary)*.
+func (p *evalParser) term() (interface{}, error) {
+	return p.binary(p.unary, "*", "/")
+}
+
+func (p *evalParser) binary(operand func() (interface{}, error), ops ...string) (interface{}, error) {
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != ops[0] && op != ops[1] {
+			return x, nil
+		}
+		p.next()
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		a, aok := x.(float64)
+		b, bok := y.(float64)
+		if !aok || !bok {
+			return nil, fmt.Errorf("%s needs numbers, not a %s and a %s", op, evalTypeName(x), evalTypeName(y))
+		}
+		switch op {
+		case "+":
+			x = a + b
+		case "-":
+			x = a - b
+		case "*":
+			x = a * b
+		case "/":
+			x = a / b
+		}
+	}
+}
+
+// unary parses '-'? postfix.
+func (p *evalParser) unary() (interface{}, error) {
+	if p.peek() == "-" {
+		p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		f, ok := x.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot negate a %s", evalTypeName(x))
+		}
+		return -f, nil
+	}
+	return p.postfix()
+}
+
+// postfix parses primary ('.' ident '(' args ')')*.
+func (p *evalParser) postfix() (interface{}, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "." {
+		p.next()
+		name := p.next()
+		args, err := p.args()
+		if err != nil {
+			return nil, err
+		}
+		if x, err = evalMethod(x, name, args); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+// primary parses a number, a duration, a parenthesized expression,
+// or a function call.
+func (p *evalParser) primary() (interface{}, error) {
+	tok := p.next()
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case tok == "(":
+		x, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	case tok[0] >= '0' && tok[0] <= '9' || tok[0] == '.':
+		if f, err := strconv.ParseFloat(tok, 64); err == nil {
+			return f, nil
+		}
+		d, err := time.ParseDuration(tok)
+		if err != nil {
+			return nil, fmt.Errorf("bad number or duration %q", tok)
+		}
+		return float64(d), nil
+	case unicode.IsLetter(rune(tok[0])) || tok[0] == '_':
+		args, err := p.args()
+		if err != nil {
+			return nil, err
+		}
+		return evalFunc(p.s, tok, args)
+	}
+	return nil, fmt.Errorf("unexpected %q", tok)
+}
+
+// args parses '(' (sum (',' sum)*)? ')' and returns the arguments,
+// which must be numbers.
+func (p *evalParser) args() ([]float64, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var args []float64
+	for p.peek() != ")" {
+		if p.peek() == "" {
+			return nil, fmt.Errorf("want \")\", got end of expression")
+		}
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		x, err := p.sum()
+		if err != nil {
+			return nil, err
+		}
+		f, ok := x.(float64)
+		if !ok {
+			return nil, fmt.Errorf("arguments must be numbers, not a %s", evalTypeName(x))
+		}
+		args = append(args, f)
+	}
+	p.next()
+	return args, nil
+}
+
+func evalTypeName(v interface{}) string {
+	switch v.(type) {
+	case float64:
+		return "number"
+	case *gcstats.MUD:
+		return "distribution"
+	case stats.Sample:
+		return "sample"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func evalArgs(name string, args []float64, n int) error {
+	if len(args) != n {
+		plural := "s"
+		if n == 1 {
+			plural = ""
+		}
+		return fmt.Errorf("%s takes %d argument%s, got %d", name, n, plural, len(args))
+	}
+	return nil
+}
+
+// evalFraction checks that x, the argument of name, is between 0 and 1.
+func evalFraction(name string, x float64) error {
+	if !(0 <= x && x <= 1) {
+		return fmt.Errorf("%s argument must be between 0 and 1, got %v", name, x)
+	}
+	return nil
+}
+
+// evalFunc calls the function name of the expression language.
+func evalFunc(s *gcstats.GcStats, name string, args []float64) (interface{}, error) {
+	window := func() (int, error) {
+		if err := evalArgs(name, args, 1); err != nil {
+			return 0, err
+		}
+		if !s.HaveProgTimes() {
+			return 0, fmt.Errorf("%s requires program execution times, which are missing from this trace", name)
+		}
+		if args[0] <= 0 {
+			return 0, fmt.Errorf("%s window must be positive", name)
+		}
+		return int(args[0]), nil
+	}
+	switch name {
+	case "mmu":
+		w, err := window()
+		if err != nil {
+			return nil, err
+		}
+		return s.MMU(w), nil
+	case "mud":
+		w, err := window()
+		if err != nil {
+			return nil, err
+		}
+		return s.MutatorUtilizationDistribution(w), nil
+	case "mu":
+		if err := evalArgs(name, args, 0); err != nil {
+			return nil, err
+		}
+		if !s.HaveProgTimes() {
+			return nil, fmt.Errorf("mu requires program execution times, which are missing from this trace")
+		}
+		return s.MutatorUtilization(), nil
+	case "pauses":
+		if err := evalArgs(name, args, 0); err != nil {
+			return nil, err
+		}
+		var xs stats.Sample
+		for _, stop := range s.Stops() {
+			xs.Xs = append(xs.Xs, float64(stop.Duration))
+		}
+		return *xs.Sort(), nil
+	case "intervals":
+		if err := evalArgs(name, args, 0); err != nil {
+			return nil, err
+		}
+		if !s.HaveProgTimes() {
+			return nil, fmt.Errorf("intervals requires program execution times, which are missing from this trace")
+		}
+		var xs stats.Sample
+		cycles := s.Cycles()
+		for i := 1; i < len(cycles); i++ {
+			xs.Xs = append(xs.Xs, float64(cycles[i].Begin-cycles[i-1].Begin))
+		}
+		return *xs.Sort(), nil
+	case "cycles":
+		if err := evalArgs(name, args, 0); err != nil {
+			return nil, err
+		}
+		return float64(s.Count()), nil
+	}
+	return nil, fmt.Errorf("unknown function %s", name)
+}
+
+// evalMethod calls method name of x.
+func evalMethod(x interface{}, name string, args []float64) (interface{}, error) {
+	switch x := x.(type) {
+	case *gcstats.MUD:
+		switch name {
+		case "quantile", "cdf", "lost":
+			if err := evalArgs(name, args, 1); err != nil {
+				return nil, err
+			}
+			switch name {
+			case "quantile", "cdf":
+				if err := evalFraction(name, args[0]); err != nil {
+					return nil, err
+				}
+				if name == "quantile" {
+					return x.InvCDF(args[0]), nil
+				}
+				return x.CDF(args[0]), nil
+			}
+			return x.LostWork(args[0]), nil
+		case "min", "max":
+			if err := evalArgs(name, args, 0); err != nil {
+				return nil, err
+			}
+			if name == "min" {
+				return x.InvCDF(0), nil
+			}
+			return x.InvCDF(1), nil
+		}
+	case stats.Sample:
+		switch name {
+		case "count":
+			if err := evalArgs(name, args, 0); err != nil {
+				return nil, err
+			}
+			return float64(len(x.Xs)), nil
+		case "quantile":
+			if err := evalArgs(name, args, 1); err != nil {
+				return nil, err
+			}
+			if err := evalFraction(name, args[0]); err != nil {
+				return nil, err
+			}
+		case "min", "max", "mean", "stddev", "sum":
+			if err := evalArgs(name, args, 0); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("a %s has no method %s", evalTypeName(x), name)
+		}
+		if len(x.Xs) == 0 {
+			return nil, fmt.Errorf("%s of an empty sample", name)
+		}
+		switch name {
+		case "quantile":
+			return x.Percentile(args[0]), nil
+		case "min":
+			return x.Xs[0], nil
+		case "max":
+			return x.Xs[len(x.Xs)-1], nil
+		case "mean":
+			return x.Mean(), nil
+		case "stddev":
+			return x.StdDev(), nil
+		case "sum":
+			return x.Sum(), nil
+		}
+	}
+	return nil, fmt.Errorf("a %s has no method %s", evalTypeName(x), name)
+}
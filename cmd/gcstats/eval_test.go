@@ -0,0 +1,115 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+func TestEvalExpr(t *testing.T) {
+	// Ten cycles, each a 1ms pause followed by 9ms of sweeping.
+	// The trace has no cycle records, so intervals() is empty.
+	var phases []gcstats.Phase
+	for n := 1; n <= 10; n++ {
+		begin := int64(n-1) * 10e6
+		phases = append(phases,
+			gcstats.Phase{Begin: begin, Duration: 1e6, Kind: gcstats.PhaseSweepTerm, N: n, Gomaxprocs: 4, GCProcs: 4, STW: true},
+			gcstats.Phase{Begin: begin + 1e6, Duration: 9e6, Kind: gcstats.PhaseSweep, N: n, Gomaxprocs: 4})
+	}
+	s := gcstats.NewFromPhases(phases, 10)
+	mud := s.MutatorUtilizationDistribution(50e6)
+
+	tests := []struct {
+		expr string
+		want float64
+		err  string
+	}{
+		// Precedence and associativity.
+		{expr: "1+2*3", want: 7},
+		{expr: "(1+2)*3", want: 9},
+		{expr: "10-4-3", want: 3},
+		{expr: "8/4/2", want: 1},
+
+		// Unary minus.
+		{expr: "-2*3", want: -6},
+		{expr: "2*-3", want: -6},
+		{expr: "--2", want: 2},
+		{expr: "1-(-2)", want: 3},
+
+		// Numbers, exponents, and durations in nanoseconds.
+		{expr: ".5", want: 0.5},
+		{expr: "1e3", want: 1000},
+		{expr: "2.5E-1", want: 0.25},
+		{expr: "1e+2 + 1", want: 101},
+		{expr: "50ms", want: 50e6},
+		{expr: "1.5us", want: 1500},
+		{expr: "1ms/1us", want: 1000},
+		{expr: "2s - 1e9", want: 1e9},
+
+		// Functions and methods.
+		{expr: "cycles()", want: 10},
+		{expr: "mu()", want: 0.9},
+		{expr: "mmu(1ms)", want: 0},
+		{expr: "mud(50ms).quantile(0.01)", want: mud.InvCDF(0.01)},
+		{expr: "mud(50ms).cdf(0.5)", want: mud.CDF(0.5)},
+		{expr: "mud(50ms).min()", want: mud.InvCDF(0)},
+		{expr: "pauses().count()", want: 10},
+		{expr: "pauses().max() / 1ms", want: 1},
+		{expr: "pauses().sum()", want: 10e6},
+		{expr: "pauses().quantile(0.5)", want: 1e6},
+		{expr: "intervals().count()", want: 0},
+
+		// Type errors.
+		{expr: "pauses() + 1", err: "+ needs numbers, not a sample and a number"},
+		{expr: "-mud(50ms)", err: "cannot negate a distribution"},
+		{expr: "mud(50ms)", err: "expression is a distribution, not a number; call one of its methods"},
+		{expr: "mmu(pauses())", err: "arguments must be numbers, not a sample"},
+		{expr: "cycles().max()", err: "a number has no method max"},
+		{expr: "pauses().lost(0.5)", err: "a sample has no method lost"},
+
+		// Arity errors.
+		{expr: "mmu()", err: "mmu takes 1 argument, got 0"},
+		{expr: "cycles(1)", err: "cycles takes 0 arguments, got 1"},
+		{expr: "mud(50ms).quantile()", err: "quantile takes 1 argument, got 0"},
+		{expr: "pauses().max(1)", err: "max takes 0 arguments, got 1"},
+
+		// Out of range arguments.
+		{expr: "mmu(0)", err: "mmu window must be positive"},
+		{expr: "mud(50ms).quantile(1.5)", err: "quantile argument must be between 0 and 1, got 1.5"},
+		{expr: "mud(50ms).cdf(-0.1)", err: "cdf argument must be between 0 and 1, got -0.1"},
+		{expr: "pauses().quantile(2)", err: "quantile argument must be between 0 and 1, got 2"},
+
+		// Empty samples.
+		{expr: "intervals().max()", err: "max of an empty sample"},
+		{expr: "intervals().quantile(0.5)", err: "quantile of an empty sample"},
+
+		// Syntax errors and trailing tokens.
+		{expr: "1 2", err: `unexpected "2"`},
+		{expr: "1)", err: `unexpected ")"`},
+		{expr: "cycles() cycles()", err: `unexpected "cycles"`},
+		{expr: "(1", err: `want ")", got end of expression`},
+		{expr: "1 +", err: "unexpected end of expression"},
+		{expr: "mmu(1ms", err: `want ")", got end of expression`},
+		{expr: "1 $ 2", err: `unexpected character '$'`},
+		{expr: "5parsecs", err: `bad number or duration "5parsecs"`},
+		{expr: "foo()", err: "unknown function foo"},
+	}
+	for _, test := range tests {
+		got, err := evalExpr(s, test.expr)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: want error %q, got %v, %v", test.expr, test.err, got, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+		} else if got != test.want {
+			t.Errorf("%s: want %v, got %v", test.expr, test.want, got)
+		}
+	}
+}
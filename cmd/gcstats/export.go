@@ -0,0 +1,315 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/gcstats/hdrlog"
+)
+
+// doExport implements the export subcommand and returns the exit
+// status.
+func doExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	var (
+		flagHdr        = fs.Bool("hdr", false, "Write the STW pause and mutator utilization distributions as an HdrHistogram interval log")
+		flagPerfetto   = fs.Bool("perfetto", false, "Write GC cycles, phases, and heap sizes as Chrome trace-event JSON for the Perfetto UI")
+		flagOTLP       = fs.Bool("otlp", false, "Write each GC cycle as an OpenTelemetry span in OTLP/JSON, with its STW phases as child spans")
+		flagEndpoint   = fs.String("otlp-endpoint", "", "With -otlp, post the spans to the OTLP/HTTP traces `url`, such as http://localhost:4318/v1/traces, rather than writing them")
+		flagService    = fs.String("service", "unknown_service", "With -otlp, the service.name of the spans")
+		flagInterval   = fs.Duration("interval", 0, "With -hdr, write a histogram for each `duration` of execution time rather than one for the whole trace")
+		flagMU         = fs.String("mu", "10ms", "With -hdr, also write mutator utilization histograms for the comma-separated `windows`")
+		flagStart      = fs.String("start", "", "The trace started at `time` in RFC 3339 format; with -otlp, the default is to end the trace at the input's modification time")
+		flagSkipWarmup = fs.Bool("skipwarmup", false, "Exclude the warm-up cycles at the beginning of the trace")
+		flagMmap       = fs.Bool("mmap", false, "Memory-map the input file rather than reading it")
+	)
+	addLabelFlag(fs)
+	addPhasesFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s export -hdr|-perfetto|-otlp [flags] [input]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nWrite a GC trace in a format for other tools.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	formats := 0
+	for _, f := range []bool{*flagHdr, *flagPerfetto, *flagOTLP} {
+		if f {
+			formats++
+		}
+	}
+	if formats != 1 || fs.NArg() > 1 || *flagInterval < 0 {
+		fs.Usage()
+		return 2
+	}
+	var windows []time.Duration
+	if *flagMU != "" {
+		for _, w := range strings.Split(*flagMU, ",") {
+			d, err := time.ParseDuration(w)
+			if err != nil || d <= 0 {
+				fmt.Fprintf(os.Stderr, "bad -mu window %q\n", w)
+				return 2
+			}
+			windows = append(windows, d)
+		}
+	}
+	var start time.Time
+	if *flagStart != "" {
+		var err error
+		if start, err = time.Parse(time.RFC3339, *flagStart); err != nil {
+			fmt.Fprintf(os.Stderr, "bad -start: %s\n", err)
+			return 2
+		}
+	}
+
+	var input io.Reader = os.Stdin
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		input = f
+	}
+	s, err := parseInput(input, *flagMmap)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error parsing log: %s\n", err)
+		return 1
+	}
+	if len(s.Phases()) == 0 {
+		fmt.Fprintf(os.Stderr, "no GC recorded; did you set GODEBUG=gctrace=1?\n")
+		return 1
+	}
+	if *flagSkipWarmup {
+		s = s.SkipCycles(s.Warmup())
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	if *flagPerfetto {
+		if !s.HaveProgTimes() {
+			fmt.Fprintln(os.Stderr, "-perfetto requires program execution times")
+			return 1
+		}
+		if err := writeTraceEvents(w, s); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	if *flagOTLP {
+		if !s.HaveProgTimes() {
+			fmt.Fprintln(os.Stderr, "-otlp requires program execution times")
+			return 1
+		}
+		start, err := traceStart(s, input, *flagStart)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if err := writeOTLP(w, otlpSpans(s, start, *flagService), *flagEndpoint); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	if !s.HaveProgTimes() && (*flagInterval != 0 || len(windows) != 0) {
+		fmt.Fprintln(os.Stderr, "trace lacks program execution times; writing a single pause histogram without mutator utilization")
+		*flagInterval, windows = 0, nil
+	}
+
+	if err := writeHdrLog(w, s, *flagInterval, windows, start); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// hdrPauseMax is the largest STW pause recorded in HdrHistogram
+// exports.
+const hdrPauseMax = int64(time.Hour)
+
+// writeHdrLog writes an HdrHistogram interval log of s to w. The log
+// has a histogram of STW pause times in nanoseconds tagged "stw" and,
+// for each window, a histogram of the mutator utilization of disjoint
+// windows tagged "mu-<window>". Utilizations are recorded in basis
+// points with a conversion ratio of 1e-4, so HdrHistogram tools report
+// them as fractions.
+//
+// If interval is 0, the log has one histogram of each kind for the
+// whole trace. Otherwise, it has one for each interval of execution
+// time, and pauses and windows are assigned to the interval they
+// begin in.
+func writeHdrLog(w io.Writer, s *gcstats.GcStats, interval time.Duration, windows []time.Duration, start time.Time) error {
+	phases := s.Phases()
+	begin, end := phases[0].Begin, phases[len(phases)-1].End()
+	if !s.HaveProgTimes() {
+		begin, end = 0, 0
+	}
+	nIntervals, length := 1, time.Duration(end-begin)
+	if interval != 0 {
+		nIntervals, length = int((end-begin+int64(interval)-1)/int64(interval)), interval
+	}
+	intervalOf := func(t int64) int {
+		if interval == 0 {
+			return 0
+		}
+		return min(int((t-begin)/int64(interval)), nIntervals-1)
+	}
+
+	type series struct {
+		tag   string
+		ratio float64
+		hists []*hdrlog.Histogram
+	}
+	newSeries := func(tag string, highest int64, ratio float64) *series {
+		ser := &series{tag: tag, ratio: ratio}
+		for range nIntervals {
+			h := hdrlog.New(1, highest, 3)
+			h.Ratio = ratio
+			ser.hists = append(ser.hists, h)
+		}
+		return ser
+	}
+	stw := newSeries("stw", hdrPauseMax, 1)
+	for stop := range s.StopsSeq() {
+		stw.hists[intervalOf(stop.Begin)].Record(stop.Duration)
+	}
+	all := []*series{stw}
+	for _, window := range windows {
+		mu := newSeries("mu-"+window.String(), 10000, 1e-4)
+		for i, util := range s.WindowedMutatorUtilization(int64(window), int64(window)) {
+			t := begin + int64(i)*int64(window)
+			mu.hists[intervalOf(t)].Record(int64(util*10000 + 0.5))
+		}
+		all = append(all, mu)
+	}
+
+	lw := hdrlog.NewWriter(w)
+	lw.Start = start
+	lw.Comment = "Written by gcstats export -hdr. stw is in nanoseconds with Interval_Max in milliseconds; mu-* are fractions."
+	if len(flagLabels) > 0 {
+		lw.Comment += " Labels: " + flagLabels.String() + "."
+	}
+	for i := range nIntervals {
+		for _, ser := range all {
+			h := ser.hists[i]
+			max := float64(h.Max()) * ser.ratio
+			if ser == stw {
+				max /= 1e6
+			}
+			iv := hdrlog.Interval{Tag: ser.tag, Start: time.Duration(i) * length, Length: length, Max: max, Hist: h}
+			if i == nIntervals-1 && interval != 0 {
+				iv.Length = time.Duration(end-begin) - iv.Start
+			}
+			if err := lw.Write(iv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// traceEvent is an event in the Chrome trace-event format, which the
+// Perfetto UI and chrome://tracing load. Times are in microseconds.
+type traceEvent struct {
+	Name  string                 `json:"name"`
+	Cat   string                 `json:"cat,omitempty"`
+	Phase string                 `json:"ph"`
+	TS    float64                `json:"ts"`
+	Dur   float64                `json:"dur,omitempty"`
+	PID   int                    `json:"pid"`
+	TID   int                    `json:"tid"`
+	Args  map[string]interface{} `json:"args,omitempty"`
+}
+
+// Threads of the trace-event export. Each is a track in the Perfetto
+// UI.
+const (
+	traceTIDCycles = 1 + iota
+	traceTIDPhases
+	traceTIDSTW
+)
+
+// writeTraceEvents writes s to w as a trace-event JSON object. Each GC
+// cycle, from sweep termination to the end of mark termination, is a
+// span on the "GC cycles" track, and its phases, including concurrent
+// sweep, are spans on the "GC phases" track. STW pauses are spans on
+// their own track, and heap sizes are counters.
+func writeTraceEvents(w io.Writer, s *gcstats.GcStats) error {
+	us := func(ns int64) float64 { return float64(ns) / 1e3 }
+	events := []traceEvent{{Name: "process_name", Phase: "M", PID: 1, Args: map[string]interface{}{"name": "Go GC"}}}
+	if len(flagLabels) > 0 {
+		events = append(events, traceEvent{Name: "process_labels", Phase: "M", PID: 1, Args: map[string]interface{}{"labels": flagLabels.String()}})
+	}
+	for i, name := range []string{"GC cycles", "GC phases", "STW"} {
+		tid := traceTIDCycles + i
+		events = append(events,
+			traceEvent{Name: "thread_name", Phase: "M", PID: 1, TID: tid, Args: map[string]interface{}{"name": name}},
+			traceEvent{Name: "thread_sort_index", Phase: "M", PID: 1, TID: tid, Args: map[string]interface{}{"sort_index": tid}})
+	}
+
+	cycles := make(map[int]gcstats.Cycle)
+	for _, c := range s.Cycles() {
+		cycles[c.N] = c
+	}
+	phases := s.Phases()
+	var begin int64
+	for i, p := range phases {
+		if i == 0 || p.N != phases[i-1].N {
+			begin = p.Begin
+		}
+		if p.Duration < 0 {
+			continue
+		}
+		name := p.Kind.String()[len("Phase"):]
+		events = append(events, traceEvent{Name: name, Cat: "gc", Phase: "X", TS: us(p.Begin), Dur: us(p.Duration), PID: 1, TID: traceTIDPhases,
+			Args: map[string]interface{}{"gc": p.N, "stw": p.STW, "gomaxprocs": p.Gomaxprocs, "gc_procs": p.GCProcs}})
+		// The cycle span ends with mark termination.
+		last := i+1 == len(phases) || phases[i+1].N != p.N || phases[i+1].Kind == gcstats.PhaseSweep
+		if p.Kind != gcstats.PhaseSweep && last {
+			args := map[string]interface{}{"gc": p.N}
+			if c, ok := cycles[p.N]; ok {
+				args["cause"] = c.Cause.String()
+				if c.HeapTrigger != 0 {
+					args["heap_trigger"], args["heap_marked"], args["heap_live"] = c.HeapTrigger, c.HeapMarked, c.HeapLive
+				}
+				if c.HeapGoal != 0 {
+					args["heap_goal"] = c.HeapGoal
+				}
+			}
+			events = append(events, traceEvent{Name: fmt.Sprintf("GC %d", p.N), Cat: "gc", Phase: "X", TS: us(begin), Dur: us(p.End() - begin), PID: 1, TID: traceTIDCycles, Args: args})
+		}
+	}
+	for stop := range s.StopsSeq() {
+		events = append(events, traceEvent{Name: "STW", Cat: "gc", Phase: "X", TS: us(stop.Begin), Dur: us(stop.Duration), PID: 1, TID: traceTIDSTW, Args: map[string]interface{}{"gc": stop.N}})
+	}
+
+	// Heap sizes change at the start and end of each cycle.
+	for _, c := range s.Cycles() {
+		if c.HeapTrigger == 0 {
+			continue
+		}
+		events = append(events, traceEvent{Name: "heap", Phase: "C", TS: us(c.Begin), PID: 1, Args: map[string]interface{}{"MB": float64(c.HeapTrigger) / (1 << 20)}})
+		if c.HeapGoal != 0 {
+			events = append(events, traceEvent{Name: "heap goal", Phase: "C", TS: us(c.Begin), PID: 1, Args: map[string]interface{}{"MB": float64(c.HeapGoal) / (1 << 20)}})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	return enc.Encode(struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{events, "ns"})
+}
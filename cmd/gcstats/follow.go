@@ -0,0 +1,68 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// follow parses input as it grows and prints an updated summary at
+// most every interval. Only newly parsed cycles are incorporated into
+// each summary. If input is a regular file, follow polls for new data
+// at the end of the file until killed; otherwise, it exits at the end
+// of the input. After each new cycle, it checks alerts, except while
+// catching up on the existing contents of a regular file.
+func follow(input io.Reader, interval time.Duration, alerts *alerter) {
+	p := newParser(gcstats.NewParser(input))
+	if f, ok := input.(*os.File); ok {
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+			p.Follow = true
+		}
+	}
+	if alerts != nil {
+		alerts.quiet = p.Follow
+	}
+
+	s := p.Stats()
+	var sum summary
+	var lastPrint time.Time
+	printed := 0
+	report := func() {
+		if s.Count() == printed || len(s.Phases()) == 0 {
+			return
+		}
+		sum.update(s)
+		fmt.Printf("--- %s: %d GCs\n", time.Now().Format("15:04:05"), s.Count())
+		sum.print(s)
+		fmt.Println()
+		lastPrint, printed = time.Now(), s.Count()
+	}
+
+	for {
+		for p.Next() {
+			alerts.check(s)
+			if time.Since(lastPrint) >= interval {
+				report()
+			}
+		}
+		if err := p.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "error parsing log: %s\n", err)
+			os.Exit(1)
+		}
+		report()
+		if !p.Follow {
+			return
+		}
+		if alerts != nil {
+			alerts.quiet = false
+		}
+		time.Sleep(interval)
+	}
+}
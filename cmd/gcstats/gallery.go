@@ -0,0 +1,271 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/internal/go-moremath/vec"
+)
+
+// doGallery implements the gallery subcommand and returns the exit
+// status.
+func doGallery(args []string) int {
+	fs := flag.NewFlagSet("gallery", flag.ExitOnError)
+	var (
+		flagOut        = fs.String("o", "gallery.html", "Write the gallery to `file`")
+		flagSkipWarmup = fs.Bool("skipwarmup", false, "Exclude the warm-up cycles at the beginning of each trace")
+		flagMmap       = fs.Bool("mmap", false, "Memory-map the input files rather than reading them")
+	)
+	addPhasesFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s gallery [flags] input...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nWrite an HTML page with a row of small plots of the MMU, pause\n")
+		fmt.Fprintf(os.Stderr, "histogram, and heap size of each trace, drawn on shared axes.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	runs := parseRuns(fs.Args(), *flagMmap, *flagSkipWarmup)
+	f, err := os.Create(*flagOut)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	err = writeGallery(f, fs.Args(), runs)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// Gallery panel geometry in pixels.
+const (
+	galleryWidth, galleryHeight = 280, 170
+	galleryLeft, galleryRight   = 45, 10
+	galleryTop, galleryBottom   = 10, 35
+	galleryPauseBins            = 20
+)
+
+// galleryAxes are the axes shared by every trace's plots in a gallery.
+type galleryAxes struct {
+	// mmuWindows are the MMU windows in nanoseconds.
+	mmuWindows []float64
+
+	// pauseBins are the log-spaced pause histogram bin edges in
+	// nanoseconds and pauseMax is the largest bin count.
+	pauseBins []float64
+	pauseMax  int
+
+	// heapByTime indicates that heaps are plotted against time
+	// rather than cycle number. heapX and heapMB are the X and Y
+	// axis limits.
+	heapByTime bool
+	heapX      float64
+	heapMB     float64
+}
+
+func writeGallery(w io.Writer, names []string, runs []*gcstats.GcStats) error {
+	axes := galleryAxes{mmuWindows: vec.Logspace(6, 9, 50, 10), heapByTime: true}
+
+	// Find the shared ranges.
+	pauseLo, pauseHi := math.Inf(1), math.Inf(-1)
+	for _, s := range runs {
+		for _, stop := range s.Stops() {
+			d := math.Max(float64(stop.Duration), 1)
+			pauseLo, pauseHi = math.Min(pauseLo, d), math.Max(pauseHi, d)
+		}
+		axes.heapByTime = axes.heapByTime && s.HaveProgTimes()
+	}
+	if !(pauseLo < pauseHi) {
+		pauseLo, pauseHi = pauseLo/2, pauseLo*2
+	}
+	axes.pauseBins = vec.Logspace(math.Log10(pauseLo), math.Log10(pauseHi), galleryPauseBins+1, 10)
+	hists := make([][]int, len(runs))
+	for i, s := range runs {
+		hists[i] = axes.histogram(s)
+		for _, n := range hists[i] {
+			axes.pauseMax = max(axes.pauseMax, n)
+		}
+		paced := s.Pacing() == gcstats.PacingHeap
+		for _, c := range s.Cycles() {
+			goal := c.HeapGoal
+			if !paced {
+				goal = 0
+			}
+			axes.heapMB = math.Max(axes.heapMB, float64(max(c.HeapTrigger, c.HeapMarked, goal))/(1<<20))
+			axes.heapX = math.Max(axes.heapX, axes.heapXOf(s, c))
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprint(bw, `<!DOCTYPE html>
+<html>
+<head>
+<title>GC gallery</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+table { border-collapse: collapse; }
+th { text-align: left; font-weight: normal; padding-right: 1em; max-width: 20em; overflow-wrap: anywhere; }
+td, th { border-bottom: 1px solid #eee; }
+</style>
+</head>
+<body>
+<h1>GC gallery</h1>
+<table>
+<tr><th></th><th>MMU</th><th>STW pauses</th><th>heap</th></tr>
+`)
+	for i, s := range runs {
+		fmt.Fprintf(bw, "<tr><th>%s<br><small>%d cycles</small></th>\n", html.EscapeString(filepath.Base(names[i])), s.Count())
+		fmt.Fprint(bw, "<td>")
+		axes.mmu(bw, s)
+		fmt.Fprint(bw, "</td><td>")
+		axes.pauses(bw, hists[i])
+		fmt.Fprint(bw, "</td><td>")
+		axes.heap(bw, s)
+		fmt.Fprint(bw, "</td></tr>\n")
+	}
+	fmt.Fprint(bw, "</table>\n</body>\n</html>\n")
+	return bw.Flush()
+}
+
+// histogram returns the number of s's pauses in each pause bin.
+func (a *galleryAxes) histogram(s *gcstats.GcStats) []int {
+	counts := make([]int, len(a.pauseBins)-1)
+	for _, stop := range s.Stops() {
+		d := math.Max(float64(stop.Duration), 1)
+		for b := range counts {
+			if d <= a.pauseBins[b+1] || b == len(counts)-1 {
+				counts[b]++
+				break
+			}
+		}
+	}
+	return counts
+}
+
+// heapXOf returns the heap plot's X coordinate of cycle c of s.
+func (a *galleryAxes) heapXOf(s *gcstats.GcStats, c gcstats.Cycle) float64 {
+	if a.heapByTime {
+		return float64(c.Begin-s.Phases()[0].Begin) / 1e9
+	}
+	return float64(c.N)
+}
+
+func (a *galleryAxes) mmu(w io.Writer, s *gcstats.GcStats) {
+	p := galleryPanel{w: w, xlo: 6, xhi: 9, yhi: 1}
+	p.begin()
+	if !s.HaveProgTimes() {
+		p.note("no program execution times")
+	} else {
+		xs, ys := make([]float64, len(a.mmuWindows)), make([]float64, len(a.mmuWindows))
+		for i, window := range a.mmuWindows {
+			xs[i], ys[i] = math.Log10(window), s.MMU(int(window))
+		}
+		p.line(xs, ys, "#1f77b4")
+	}
+	p.axes("window", []float64{6, 7, 8, 9}, []string{"1ms", "10ms", "100ms", "1s"}, "0", "1")
+}
+
+func (a *galleryAxes) pauses(w io.Writer, counts []int) {
+	lo, hi := math.Log10(a.pauseBins[0]), math.Log10(a.pauseBins[len(a.pauseBins)-1])
+	p := galleryPanel{w: w, xlo: lo, xhi: hi, yhi: float64(max(a.pauseMax, 1))}
+	p.begin()
+	for b, n := range counts {
+		if n > 0 {
+			p.bar(math.Log10(a.pauseBins[b]), math.Log10(a.pauseBins[b+1]), float64(n), "#d62728")
+		}
+	}
+	p.axes("pause", []float64{lo, hi}, []string{ns(a.pauseBins[0]), ns(a.pauseBins[len(a.pauseBins)-1])}, "0", fmt.Sprint(a.pauseMax))
+}
+
+func (a *galleryAxes) heap(w io.Writer, s *gcstats.GcStats) {
+	p := galleryPanel{w: w, xhi: math.Max(a.heapX, 1), yhi: math.Max(a.heapMB, 1)}
+	p.begin()
+	var xs, marked, goal []float64
+	for _, c := range s.Cycles() {
+		x := a.heapXOf(s, c)
+		xs = append(xs, x)
+		marked = append(marked, float64(c.HeapMarked)/(1<<20))
+		goal = append(goal, float64(c.HeapGoal)/(1<<20))
+	}
+	if len(xs) == 0 || a.heapMB == 0 {
+		p.note("no heap sizes")
+	} else {
+		if goal[0] != 0 && s.Pacing() == gcstats.PacingHeap {
+			p.line(xs, goal, "#aaaaaa")
+		}
+		p.line(xs, marked, "#2ca02c")
+	}
+	xlabel, xhi := "cycle", fmt.Sprint(p.xhi)
+	if a.heapByTime {
+		xlabel, xhi = "time", fmt.Sprintf("%.3gs", p.xhi)
+	}
+	p.axes(xlabel, []float64{0, p.xhi}, []string{"0", xhi}, "0", mb(p.yhi*(1<<20)))
+}
+
+// galleryPanel is one small SVG plot in a gallery.
+type galleryPanel struct {
+	w             io.Writer
+	xlo, xhi, yhi float64
+}
+
+func (p *galleryPanel) px(x float64) float64 {
+	return galleryLeft + (x-p.xlo)/(p.xhi-p.xlo)*(galleryWidth-galleryLeft-galleryRight)
+}
+
+func (p *galleryPanel) py(y float64) float64 {
+	return galleryTop + (1-y/p.yhi)*(galleryHeight-galleryTop-galleryBottom)
+}
+
+func (p *galleryPanel) begin() {
+	fmt.Fprintf(p.w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="10">`+"\n", galleryWidth, galleryHeight)
+}
+
+func (p *galleryPanel) line(xs, ys []float64, color string) {
+	fmt.Fprintf(p.w, `<polyline fill="none" stroke="%s" stroke-width="1.5" points="`, color)
+	for i := range xs {
+		fmt.Fprintf(p.w, "%.1f,%.1f ", p.px(xs[i]), p.py(ys[i]))
+	}
+	fmt.Fprint(p.w, `"/>`+"\n")
+}
+
+func (p *galleryPanel) bar(x0, x1, y float64, color string) {
+	fmt.Fprintf(p.w, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"/>`+"\n", p.px(x0), p.py(y), p.px(x1)-p.px(x0), p.py(0)-p.py(y), color)
+}
+
+func (p *galleryPanel) note(text string) {
+	fmt.Fprintf(p.w, `<text x="%d" y="%d" text-anchor="middle" fill="#999">%s</text>`+"\n", (galleryWidth+galleryLeft-galleryRight)/2, galleryHeight/2, html.EscapeString(text))
+}
+
+// axes draws the frame, the X ticks at xticks labeled xlabels, the Y
+// labels at 0 and yhi, and the X axis label, and ends the plot.
+func (p *galleryPanel) axes(label string, xticks []float64, xlabels []string, ylo, yhi string) {
+	bottom := galleryHeight - galleryBottom
+	fmt.Fprintf(p.w, `<rect x="%d" y="%d" width="%d" height="%d" fill="none" stroke="black"/>`+"\n", galleryLeft, galleryTop, galleryWidth-galleryLeft-galleryRight, bottom-galleryTop)
+	for i, x := range xticks {
+		fmt.Fprintf(p.w, `<text x="%.1f" y="%d" text-anchor="middle">%s</text>`+"\n", p.px(x), bottom+12, html.EscapeString(xlabels[i]))
+	}
+	fmt.Fprintf(p.w, `<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n", galleryLeft-3, bottom, html.EscapeString(ylo))
+	fmt.Fprintf(p.w, `<text x="%d" y="%d" text-anchor="end">%s</text>`+"\n", galleryLeft-3, galleryTop+8, html.EscapeString(yhi))
+	fmt.Fprintf(p.w, `<text x="%d" y="%d" text-anchor="middle">%s</text>`+"\n", (galleryWidth+galleryLeft-galleryRight)/2, galleryHeight-5, html.EscapeString(label))
+	fmt.Fprint(p.w, "</svg>")
+}
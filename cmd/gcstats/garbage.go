@@ -0,0 +1,53 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// doGarbage prints how much of the heap was garbage in each cycle
+// and how this and the live heap trend over the trace.
+func doGarbage(s *gcstats.GcStats) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "GC\ttrigger\tlive\tlive/trigger\tgarbage\t\n")
+	var ns, ratios, lives []float64
+	for _, c := range s.Cycles() {
+		ratio := c.LiveRatio()
+		if math.IsNaN(ratio) {
+			fmt.Fprintf(w, "%d\t%d MB\t%d MB\t-\t-\t\n", c.N, c.HeapTrigger>>20, c.HeapLive>>20)
+			continue
+		}
+		fmt.Fprintf(w, "%d\t%d MB\t%d MB\t%.2f\t%s\t\n", c.N, c.HeapTrigger>>20, c.HeapLive>>20, ratio, pct(math.Max(0, 1-ratio)))
+		ns = append(ns, float64(c.N))
+		ratios = append(ratios, ratio)
+		lives = append(lives, float64(c.HeapLive>>20))
+	}
+	w.Flush()
+
+	if len(ratios) == 0 {
+		fmt.Println("\nNo cycles with known heap sizes.")
+		return
+	}
+	var mean float64
+	for _, r := range ratios {
+		mean += r
+	}
+	mean /= float64(len(ratios))
+	fmt.Println()
+	fmt.Printf("Mean garbage fraction: %s\n", pct(math.Max(0, 1-mean)))
+	if len(ratios) >= 2 {
+		// Report the change in the least-squares fit from the
+		// first to the last cycle.
+		span := ns[len(ns)-1] - ns[0]
+		fmt.Printf("Garbage fraction trend: %+.0f percentage points over %d GCs\n", -100*span*fitLine(ns, ratios).slope, int(span))
+		fmt.Printf("Live heap trend: %+.0f MB over %d GCs\n", span*fitLine(ns, lives).slope, int(span))
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/gcstatshttp"
+)
+
+// doGrafana serves the traces in paths as a Grafana datasource at
+// addr until killed. Each trace is named after its file and, since
+// GC traces record only times relative to program start, is placed
+// in wall-clock time so that it ends at the file's modification time.
+// The traces are then aligned by alignStarts according to align and
+// offsets.
+func doGrafana(addr string, paths []string, useMmap bool, align string, offsets []time.Duration) {
+	var traces []gcstatshttp.GrafanaTrace
+	var runs []*gcstats.GcStats
+	var starts []time.Time
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			log.Fatal(err)
+		}
+		s, err := parseInput(f, useMmap)
+		f.Close()
+		if err != nil {
+			log.Fatalf("%s: error parsing log: %s", path, err)
+		}
+		phases := s.Phases()
+		if len(phases) == 0 {
+			log.Fatalf("%s: no GC recorded", path)
+		}
+		last := phases[len(phases)-1]
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		start := fi.ModTime().Add(-time.Duration(last.Begin + last.Duration))
+		traces = append(traces, gcstatshttp.GrafanaTrace{Name: name, Stats: s})
+		runs, starts = append(runs, s), append(starts, start)
+	}
+	starts, err := alignStarts(runs, starts, align, offsets)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i := range traces {
+		traces[i].Start = starts[i]
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Serving Grafana datasource at http://%s/\n", ln.Addr())
+	log.Fatal(http.Serve(ln, gcstatshttp.NewGrafanaHandler(traces...)))
+}
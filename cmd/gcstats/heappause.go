@@ -0,0 +1,46 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// doHeapPause plots the heap sizes and the longest STW pause of each
+// cycle of s over execution time, with the pauses on a second Y axis,
+// to show whether pauses grow with the heap.
+func doHeapPause(s *gcstats.GcStats) {
+	recs := cycleRecords(s)
+	var xs, trigger, live, goal, pause []float64
+	haveGoal := false
+	for _, r := range recs {
+		if r.BeginNS == nil || r.HeapTrigger == 0 {
+			continue
+		}
+		xs = append(xs, xAxis.x(*r.BeginNS))
+		trigger = append(trigger, float64(r.HeapTrigger)/(1<<20))
+		live = append(live, float64(r.HeapLive)/(1<<20))
+		goal = append(goal, float64(r.HeapGoal)/(1<<20))
+		pause = append(pause, float64(r.MaxPauseNS)/1e9)
+		haveGoal = haveGoal || r.HeapGoal != 0
+	}
+	if len(xs) == 0 {
+		fmt.Fprintln(os.Stderr, "no cycles with known heap sizes")
+		os.Exit(1)
+	}
+	plot := newPlot(xAxis.label(), "heap MB", xs, append(xAxis.args(), "--style", "heappause", "--y2label", "longest STW pause")...)
+	plot.addColumn("heap at trigger", trigger)
+	plot.addColumn("live heap", live)
+	// The heap goal is meaningless if the cycles weren't paced.
+	if haveGoal && s.Pacing() == gcstats.PacingHeap {
+		plot.addColumn("heap goal", goal)
+	}
+	plot.addColumn("longest STW pause", pause)
+	addTimeMarkers(plot, s)
+	showPlot(plot)
+}
@@ -0,0 +1,31 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/gcstatshttp"
+)
+
+// doHTTP serves analyses of s at addr until killed.
+func doHTTP(s *gcstats.GcStats, addr string) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	url := "http://" + ln.Addr().String() + "/"
+	fmt.Printf("Serving GC analyses at %s\n", url)
+	if s.HaveProgTimes() {
+		fmt.Printf("Timeline at %stimeline\n", url)
+	}
+	h := gcstatshttp.NewHandler(s)
+	h.Labels = flagLabels.Map()
+	log.Fatal(http.Serve(ln, h))
+}
@@ -0,0 +1,73 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// labelFlags is the value of -label: name=value labels describing the
+// input, such as the Go version or GOGC setting of the run. They are
+// copied into JSON output, exported metrics, and plot legends so that
+// results from different configurations describe themselves.
+type labelFlags []label
+
+type label struct {
+	name, value string
+}
+
+// flagLabels is the -label flag shared by the main command and its
+// subcommands.
+var flagLabels labelFlags
+
+// labelNameRE matches valid label names. These are restricted to
+// names that are also valid Prometheus and OpenTelemetry attribute
+// names.
+var labelNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// addLabelFlag adds the -label flag to fs.
+func addLabelFlag(fs *flag.FlagSet) {
+	fs.Var(&flagLabels, "label", "Attach the label `name=value` to the input in JSON output, exported metrics, and plot legends; may be repeated")
+}
+
+func (l *labelFlags) String() string {
+	var parts []string
+	for _, lab := range *l {
+		parts = append(parts, lab.name+"="+lab.value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Set adds a name=value label, replacing any earlier label with the
+// same name.
+func (l *labelFlags) Set(v string) error {
+	name, value, ok := strings.Cut(v, "=")
+	if !ok || !labelNameRE.MatchString(name) {
+		return fmt.Errorf("want name=value, where name is a letter or underscore followed by letters, digits, and underscores")
+	}
+	for i := range *l {
+		if (*l)[i].name == name {
+			(*l)[i].value = value
+			return nil
+		}
+	}
+	*l = append(*l, label{name, value})
+	return nil
+}
+
+// Map returns the labels as a map, or nil if there are none.
+func (l labelFlags) Map() map[string]string {
+	if len(l) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(l))
+	for _, lab := range l {
+		m[lab.name] = lab.value
+	}
+	return m
+}
@@ -0,0 +1,45 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// phasesFlag is the value of -phases: files of phase layouts for GC
+// traces of runtimes not built into gcstats. Each file is loaded as
+// soon as the flag is parsed, so it applies to every trace read
+// afterwards.
+type phasesFlag []string
+
+// flagPhases is the -phases flag shared by the main command and its
+// subcommands.
+var flagPhases phasesFlag
+
+// addPhasesFlag adds the -phases flag to fs.
+func addPhasesFlag(fs *flag.FlagSet) {
+	fs.Var(&flagPhases, "phases", "Load GC trace phase layouts from the JSON `file`, for runtimes whose traces report phases differently; may be repeated")
+}
+
+func (p *phasesFlag) String() string {
+	return strings.Join(*p, ", ")
+}
+
+func (p *phasesFlag) Set(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := gcstats.LoadPhaseLayouts(f); err != nil {
+		return err
+	}
+	*p = append(*p, path)
+	return nil
+}
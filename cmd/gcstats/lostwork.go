@@ -0,0 +1,36 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// doLostWork prints, for each of the comma-separated windows, the
+// fraction of windows with mutator utilization below target and the
+// mutator work lost in those windows, both as a fraction of the
+// execution and as mutator time.
+func doLostWork(s *gcstats.GcStats, target float64, windows string) {
+	if target <= 0 || target > 1 {
+		fmt.Fprintf(os.Stderr, "-lost-work target must be in (0, 1]\n")
+		os.Exit(2)
+	}
+	ws := parseWindows("-mmu-at", windows)
+	phases := s.Phases()
+	dur := float64(phases[len(phases)-1].End() - phases[0].Begin)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "window\tbelow %s\tlost work\tlost time\t\n", pct(target))
+	for _, window := range ws {
+		mud := computeMUD(s, int(window))
+		lost := mud.LostWork(target)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t\n", window, pct(mud.CDF(target)), pct(lost), ns(lost*dur))
+	}
+	w.Flush()
+}
@@ -7,9 +7,10 @@
 // To collect a GC trace, run the program with
 //     $ env GODEBUG=gctrace=1 <program>
 //
-// gcstats supports both Go 1.4 and Go 1.5 traces; however, mutator
-// utilization analyses require the following patch to the Go 1.4
-// runtime to add program execution times to the trace:
+// gcstats supports traces from Go 1.4 and later, and -phases adds
+// layouts for runtimes that report phases differently. However,
+// mutator utilization analyses require the following patch to the Go
+// 1.4 runtime to add program execution times to the trace:
 //
 //     --- src/runtime/mgc0.c
 //     +++ src/runtime/mgc0.c
@@ -19,6 +20,9 @@
 //     @@ -1492 +1492 @@
 //     -			stats.nprocyield, stats.nosyield, stats.nsleep);
 //     +			stats.nprocyield, stats.nosyield, stats.nsleep, t0/1000);
+//
+// Without this patch, -estimate-times approximates execution times
+// from phase durations and the program's total run time.
 package main
 
 // TODO(austin): Explain analyses in doc comment.
@@ -29,7 +33,10 @@ import (
 	"io"
 	"log"
 	"math"
+	"math/rand"
 	"os"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/aclements/go-gcstats/gcstats"
@@ -39,30 +46,216 @@ import (
 
 const samples = 500
 
-var flagShow = flag.Bool("show", false, "Show plot in a window")
+var (
+	flagShow     = flag.Bool("show", false, "Show plot in a window")
+	flagPlot     = flag.String("plot", "", "Save plot to image `file` rather than showing it; the format is taken from the extension")
+	flagKeepData = flag.Bool("keep-data", false, "With -plot, also write the plotted table next to the image, with the extension .tsv")
+	flagApprox   = flag.Float64("approx", 0, "Approximate mutator utilization distributions to within `epsilon` utilization, at most 1 and at least 1e-6 (0 for exact)")
+	flagMutTime  = flag.Bool("mutator-time", false, "Measure the windows of -mmu, -mut, -mmu-at, -mucdf, -muccdf, and -mudmap in mutator time (mutator CPU time divided by GOMAXPROCS) rather than wall-clock time")
+	flagPerP     = flag.Bool("per-p", false, "Base -mmu, -mut, -mmu-at, -mucdf, -muccdf, -mudmap, and -mumap on the Ps fully available to the mutator during concurrent mark rather than the average CPU used by GC")
+	flagBlackout = flag.Float64("blackout", 0, "Report the longest stretch in which mutator utilization is at most `util` in the summary")
+
+	flagDigits   = flag.Int("digits", 0, "Print durations and percentages with `n` significant digits (default 3 for durations and 2 for percentages)")
+	flagDecimals = flag.Int("decimals", -1, "Print durations and percentages with `n` decimal places; overrides -digits")
+
+	flagTitle  = flag.String("title", "", "Title of plots")
+	flagWidth  = flag.Float64("width", 0, "Width of plots in `inches`")
+	flagHeight = flag.Float64("height", 0, "Height of plots in `inches`")
+	flagXRange = flag.String("xrange", "", "Limit the X axis of plots to `lo,hi` in axis units; either bound may be empty")
+	flagYRange = flag.String("yrange", "", "Limit the Y axis of plots to `lo,hi` in axis units; either bound may be empty")
+	flagLogX   = flag.Bool("logx", false, "Use a log scale for the X axis of plots")
+	flagLogY   = flag.Bool("logy", false, "Use a log scale for the Y axis of plots")
+
+	flagMarkers = flag.String("markers", "", "Shade `spans` in plots over execution time: gc (GC cycles), stw (STW pauses), or gc,stw")
+
+	flagOverlap    = flag.String("overlap", "shift", "Repair cycles that overlap the previous cycle by `policy`: shift, truncate (the start of the overlapping cycle), drop, or error")
+	flagOverlapTol = flag.Duration("overlap-tolerance", gcstats.DefaultOverlapTolerance, "Treat overlaps between cycles longer than `duration` as errors")
+)
+
+// overlapPolicy is the policy selected by -overlap.
+var overlapPolicy gcstats.OverlapPolicy
 
 func main() {
+	loadPlugins()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "ci":
+			os.Exit(doCI(os.Args[2:]))
+		case "cycles":
+			os.Exit(doCycles(os.Args[2:]))
+		case "aggregate":
+			os.Exit(doAggregate(os.Args[2:]))
+		case "dump":
+			os.Exit(doDump(os.Args[2:]))
+		case "compare":
+			os.Exit(doCompare(os.Args[2:]))
+		case "export":
+			os.Exit(doExport(os.Args[2:]))
+		case "daemon":
+			os.Exit(doDaemon(os.Args[2:]))
+		case "partial":
+			os.Exit(doPartial(os.Args[2:]))
+		case "merge":
+			os.Exit(doMerge(os.Args[2:]))
+		case "eval":
+			os.Exit(doEval(os.Args[2:]))
+		case "gallery":
+			os.Exit(doGallery(os.Args[2:]))
+		case "demo":
+			os.Exit(doDemo(os.Args[2:]))
+		case "badge":
+			os.Exit(doBadge(os.Args[2:]))
+		}
+	}
+
 	var (
-		flagSummary = flag.Bool("summary", false, "Compute summary statistics")
-		flagMMU     = flag.Bool("mmu", false, "Compute MMU graph")
-		flagMUT     = flag.Bool("mut", false, "Compute mutator utilization topology")
-		flagMUCDF   = flag.Duration("mucdf", 0, "Compute mutator utilization CDF for all windows of `duration`")
-		flagMUCCDF  = flag.Duration("muccdf", 0, "Compute mutator utilization complementary CDF for all windows of `duration`")
-		flagMUDMap  = flag.Bool("mudmap", false, "Compute MUD heat map")
-		flagStopKDE = flag.Bool("stopkde", false, "Compute KDE of stop times")
-		flagStopCDF = flag.Bool("stopcdf", false, "Compute CDF of KDE of stop times")
+		flagSummary    = flag.Bool("summary", false, "Compute summary statistics")
+		flagMMU        = flag.Bool("mmu", false, "Compute MMU graph")
+		flagMUT        = flag.Bool("mut", false, "Compute mutator utilization topology")
+		flagMMUAt      = flag.String("mmu-at", "", "Print the MMU and mutator utilization percentiles at each of the comma-separated `windows`, such as 1ms,10ms,100ms")
+		flagMUPhase    = flag.String("mu-phase", "", "Split the mutator utilization percentiles at the -mmu-at windows (default 1ms,10ms,100ms) by whether windows overlap a phase of one of the comma-separated `kinds`, such as mark, or gc for any phase but sweep")
+		flagLostWork   = flag.Float64("lost-work", 0, "Print the mutator work lost in -mmu-at windows (default 1ms,10ms,100ms) with utilization below `target`")
+		flagWhatIfSTW  = flag.Float64("what-if-stw", -1, "Compare pause percentiles, mean mutator utilization, and MMU at the -mmu-at windows (default 1ms,10ms,100ms) to their values with every STW phase scaled by `factor`, such as 0.5")
+		flagMUCDF      = flag.Duration("mucdf", 0, "Compute mutator utilization CDF for all windows of `duration`")
+		flagMUCCDF     = flag.Duration("muccdf", 0, "Compute mutator utilization complementary CDF for all windows of `duration`")
+		flagMUDMap     = flag.Bool("mudmap", false, "Compute MUD heat map")
+		flagMUMap      = flag.Bool("mumap", false, "Compute heat map of minimum mutator utilization over time and window size")
+		flagMapWindows = flag.String("mudmap-windows", "1ms,1s", "Range `lo,hi` of the window sizes of -mudmap and -mumap")
+		flagMapN       = flag.Int("mudmap-nwindows", 100, "Number of window sizes of -mudmap and -mumap, logarithmically spaced over -mudmap-windows")
+		flagMapUtils   = flag.Int("mudmap-utils", 100, "Number of mutator utilization levels from 0 to 1 of -mudmap")
+		flagStopKDE    = flag.Bool("stopkde", false, "Compute KDE of stop times")
+		flagStopCDF    = flag.Bool("stopcdf", false, "Compute CDF of KDE of stop times")
+		flagIdle       = flag.Bool("idle", false, "Report how much marking was done by assists, background workers, and idle Ps in each cycle")
+		flagFrac       = flag.Bool("fractional", false, "Estimate fractional mark worker CPU in each cycle and flag cycles over the GC CPU target")
+		flagDiag       = flag.Bool("diagnostics", false, "Print warnings about unparsed and adjusted lines of the trace to stderr")
+		flagBootstrap  = flag.Bool("bootstrap", false, "Show 95% bootstrap confidence intervals of summary percentiles and, with -mut, shade them around each curve by resampling GC cycles")
+		flagTail       = flag.Bool("tail", false, "Fit a generalized Pareto model to the tail of the STW pause distribution and extrapolate extreme percentiles")
+		flagModes      = flag.Bool("modes", false, "Detect multiple modes in the distribution of STW pause times and report their peaks and weights")
+		flagWarmup     = flag.Bool("warmup", false, "Detect the warm-up cycles at the beginning of the trace, before the heap goal and GC interval converge")
+		flagSkipWarmup = flag.Bool("skipwarmup", false, "Exclude the warm-up cycles at the beginning of the trace from analyses")
+		flagPauseTrend = flag.Bool("pausetrend", false, "Fit the longest STW pause of each cycle against cycle number, time, and live heap, with confidence intervals")
+		flagAllocRate  = flag.Bool("allocrate", false, "Correlate the interval between GC cycles with the allocation rate over the interval")
+		flagPauseProcs = flag.Bool("pauseprocs", false, "Relate STW pause durations to the fraction of GOMAXPROCS used by the garbage collector during them")
+		flagPauseHeap  = flag.String("pauseheap", "", "Regress the longest STW pause of each cycle against the heap size at that cycle; `heap` is live or goal")
+		flagHeapPause  = flag.Bool("heappause", false, "Plot the heap sizes and the longest STW pause of each cycle over execution time, with pauses on a second Y axis")
+		flagGarbage    = flag.Bool("garbage", false, "Report the fraction of the heap that was garbage in each cycle and its trend")
+		flagPausePct   = flag.Duration("pausepct", 0, "Compute 99th and 99.9th percentile STW pause times over sliding windows of `duration`")
+		flagDuty       = flag.Bool("duty", false, "Compute the fraction of wall-clock time a GC cycle was active, overall and over windows")
+		flagPhaseTime  = flag.Bool("phasetime", false, "Compute the duration of each phase of each cycle over execution time")
+		flagCumGC      = flag.Bool("cumgc", false, "Compute cumulative GC CPU time and STW time over execution time")
+		flagCPU        = flag.Bool("cpu", false, "Account for the CPU-seconds spent in STW phases, assists, dedicated, fractional, and idle mark workers, and the mutator")
+		flagCost       = flag.Float64("cost", 0, "With the summary, translate GC CPU time into core-hours and its cost at `rate` dollars per core-hour")
+		flagScvg       = flag.Bool("scvg", false, "Compare the heap memory the scavenger returned to the OS with the heap in use, from the scvg lines of the trace")
+		flagSched      = flag.Bool("sched", false, "Correlate runnable goroutines and threads from an interleaved GODEBUG=schedtrace trace with GC cycles and STW pauses")
+		flagBursts     = flag.Duration("bursts", 0, "Group STW pauses at most `gap` apart into bursts and report burst sizes and combined pause durations")
+		flagBucket     = flag.Duration("bucket", 0, "Print the GC count, pause 99th percentile, and GC CPU fraction of each `duration` interval of execution time")
+		flagSTWRate    = flag.Duration("stwrate", 0, "Compute STW pauses per second and STW time per second over execution time in intervals of `duration`")
+		flagFollow     = flag.Bool("follow", false, "Follow a growing trace, periodically printing a summary")
+		flagInterval   = flag.Duration("interval", 5*time.Second, "Print a summary every `interval` with -follow")
+		flagAlertPause = flag.Duration("alert-pause", 0, "With -follow, alert when an STW pause is longer than `duration`")
+		flagAlertMU    = flag.Float64("alert-mu", 0, "With -follow, alert when mutator utilization over the last -alert-window falls below `fraction`")
+		flagAlertWin   = flag.Duration("alert-window", time.Minute, "The `window` of execution time for -alert-mu")
+		flagAlertCmd   = flag.String("alert-cmd", "", "Run shell `command` for each alert, with the alert as JSON on stdin and in GCSTATS_* environment variables")
+		flagAlertHook  = flag.String("alert-webhook", "", "POST each alert as JSON to `url`")
+		flagMmap       = flag.Bool("mmap", false, "Memory-map the input file rather than reading it")
+		flagHTTP       = flag.String("http", "", "Serve analyses and an interactive timeline over HTTP at `addr`")
+		flagBench      = flag.Bool("bench", false, "Summarize GC activity per benchmark in the output of 'go test -bench' (stdout and stderr combined)")
+		flagPublish    = flag.String("publish", "", "Publish a JSON record for each GC cycle to `dest`, a nats://host[:port]/subject URL or - for stdout; with -follow, keep publishing as the trace grows")
+		flagGrafana    = flag.String("grafana", "", "Serve the input traces as a Grafana SimpleJSON datasource at `addr`")
+		flagAlign      = flag.String("align", "wall", "With -grafana, align the traces by `mode`: wall (each ends at its file's modification time), start (programs start together), or gc (first GC cycles begin together)")
+		flagOffsets    = flag.String("offsets", "", "With -grafana, shift each trace later by the corresponding duration in the comma-separated `list` after -align, such as 0,-1.5s")
+		flagTemplate   = flag.String("template", "", "Print a report by executing the text/template in `file` instead of the summary")
+		flagTimeAxis   = flag.String("timeaxis", "rel", "X axis of plots over execution time: `axis` is rel (seconds since start), wall (wall-clock time), or gc (GC cycle)")
+		flagStart      = flag.String("start", "", "With -timeaxis wall, the wall-clock `time` the program started in RFC 3339 format; by default, the trace ends at the input's modification time")
+		flagEstimate   = flag.Bool("estimate-times", false, "Estimate program execution times missing from a Go 1.4 trace from phase durations, so mutator utilization can be analyzed approximately")
+		flagRuntime    = flag.Duration("runtime", 0, "With -estimate-times, the total run `time` of the program (default assumes GC phases took 5% of the run time)")
+		flagCross      = flag.String("crosscheck", "", "Compare STW phases against the execution trace in `file` (binary or 'go tool trace -d=parsed' output)")
 	)
 
+	addLabelFlag(flag.CommandLine)
+	addPhasesFlag(flag.CommandLine)
+	flagAnalyses := addAnalysisFlags(flag.CommandLine)
+
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [flags] [input]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -grafana addr [-align wall|start|gc] [-offsets list] input...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s -mmu|-mut input... (mean and range across runs)\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s ci -baseline baseline.json [flags] [input]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s cycles [-format csv|json|parquet] [input]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s aggregate [-format text|json] input...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s dump [-gc n|lo-hi] [input]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s compare [-alpha a] old new\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s export -hdr [-interval d] [-mu windows] | -perfetto | -otlp [input]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s daemon -dir directory [-http addr]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s partial [-mud windows] [input] > part.json\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s merge [-format text|json] part.json...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s eval 'expr' [input]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s gallery [-o gallery.html] input...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s demo [name]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s badge [-metric name] [-threshold value] [-o badge.svg] [input]\n", os.Args[0])
 		flag.PrintDefaults()
 	}
 	flag.Parse()
+	if *flagPlot != "" {
+		*flagShow = true
+	}
+	for _, r := range []*string{flagXRange, flagYRange} {
+		if err := checkRange(*r); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+	if err := checkMarkers(*flagMarkers); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if !(*flagApprox >= 0 && *flagApprox <= 1) {
+		fmt.Fprintln(os.Stderr, "-approx must be between 0 and 1")
+		os.Exit(2)
+	}
+	var err error
+	if overlapPolicy, err = gcstats.ParseOverlapPolicy(*flagOverlap); err != nil {
+		fmt.Fprintf(os.Stderr, "bad -overlap: %s\n", err)
+		os.Exit(2)
+	}
+
+	if *flagGrafana != "" {
+		if flag.NArg() == 0 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		offsets, err := parseOffsets(*flagOffsets)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "bad -offsets: %s\n", err)
+			os.Exit(2)
+		}
+		doGrafana(*flagGrafana, flag.Args(), *flagMmap, *flagAlign, offsets)
+		return
+	}
 
-	if !(*flagMMU || *flagMUT || *flagMUCDF != 0 || *flagMUCCDF != 0 || *flagMUDMap || *flagStopKDE || *flagStopCDF) {
+	if !(*flagMMU || *flagMUT || *flagMMUAt != "" || *flagMUPhase != "" || *flagLostWork != 0 || *flagWhatIfSTW >= 0 || *flagMUCDF != 0 || *flagMUCCDF != 0 || *flagMUDMap || *flagMUMap || *flagStopKDE || *flagStopCDF || *flagSTWRate != 0 || *flagBucket != 0 || *flagBursts != 0 || *flagSched || *flagScvg || *flagCPU || *flagCumGC || *flagPhaseTime || *flagDuty || *flagPausePct != 0 || *flagGarbage || *flagHeapPause || *flagPauseHeap != "" || *flagPauseProcs || *flagAllocRate || *flagPauseTrend || *flagWarmup || *flagModes || *flagTail || *flagIdle || *flagFrac || *flagCross != "" || *flagHTTP != "" || *flagTemplate != "" || anyAnalysis(flagAnalyses)) {
 		*flagSummary = true
 	}
 
+	if flag.NArg() > 1 {
+		// Multiple runs of the same workload.
+		if !(*flagMMU || *flagMUT) {
+			flag.Usage()
+			os.Exit(1)
+		}
+		runs := parseRuns(flag.Args(), *flagMmap, *flagSkipWarmup)
+		for _, s := range runs {
+			requireProgTimes(s)
+		}
+		if *flagMMU {
+			doMMURuns(runs)
+		}
+		if *flagMUT {
+			doMUTRuns(runs)
+		}
+		return
+	}
+
 	var input io.Reader
 	if flag.NArg() == 0 {
 		input = os.Stdin
@@ -77,45 +270,222 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *flagBench {
+		doBench(input)
+		return
+	}
+
+	if *flagPublish != "" {
+		publish(input, *flagPublish, *flagFollow, *flagInterval)
+		return
+	}
+
+	alerts := &alerter{pause: *flagAlertPause, mu: *flagAlertMU, window: *flagAlertWin, cmd: *flagAlertCmd, webhook: *flagAlertHook}
+	if alerts.enabled() && (!*flagFollow || *flagAlertWin <= 0) {
+		fmt.Fprintln(os.Stderr, "-alert-pause and -alert-mu require -follow, and -alert-window must be positive")
+		os.Exit(2)
+	}
+
+	if *flagFollow {
+		follow(input, *flagInterval, alerts)
+		return
+	}
+
 	// Read input log
-	s, err := gcstats.NewFromLog(input)
+	s, err := parseInput(input, *flagMmap)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error parsing log: %s\n", err)
 		os.Exit(1)
 	}
+	if *flagEstimate && !s.HaveProgTimes() {
+		if s, err = s.EstimateBeginTimes(*flagRuntime); err != nil {
+			fmt.Fprintf(os.Stderr, "error estimating execution times: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stderr, "warning: program execution times are estimated; mutator utilization results are approximate")
+	}
+	if *flagDiag {
+		for _, d := range s.Diagnostics() {
+			fmt.Fprintln(os.Stderr, d)
+		}
+	}
 	if len(s.Phases()) == 0 {
 		fmt.Fprintf(os.Stderr, "no GC recorded; did you set GODEBUG=gctrace=1?")
 		os.Exit(1)
 	}
 
+	if s.HaveProgTimes() {
+		if err := setTimeAxis(s, input, *flagTimeAxis, *flagStart); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	if *flagWarmup && needPacing(s, "-warmup") {
+		doWarmup(s)
+	}
+	if *flagSkipWarmup {
+		s = s.SkipCycles(s.Warmup())
+	}
+
+	if *flagHTTP != "" {
+		doHTTP(s, *flagHTTP)
+		return
+	}
+
+	if *flagTemplate != "" {
+		doTemplate(s, *flagTemplate)
+	}
+
 	if *flagSummary {
-		doSummary(s)
+		doSummary(s, *flagBootstrap)
+		if *flagCost > 0 && s.HaveProgTimes() {
+			printCost(s, *flagCost)
+		}
+		if w := s.Warmup(); w > 0 && !*flagSkipWarmup {
+			fmt.Printf("\nFirst %d cycles are warm-up; use -skipwarmup to exclude them\n", w)
+		}
+		// Statistics are not comparable across different
+		// GOMAXPROCS, so also summarize each segment.
+		if segs := s.GomaxprocsSegments(); len(segs) > 1 {
+			for _, seg := range segs {
+				fmt.Printf("\n== GOMAXPROCS=%d, GCs %d-%d ==\n\n", seg.Gomaxprocs, seg.First, seg.Last)
+				doSummary(seg.Stats, *flagBootstrap)
+			}
+		}
 	}
 
-	if *flagMMU {
-		requireProgTimes(s)
+	if *flagMMU && needProgTimes(s, "-mmu") {
 		doMMU(s)
 	}
 
-	if *flagMUT {
+	if *flagMUT && needProgTimes(s, "-mut") {
 		// TOOD: Support custom percentiles
-		requireProgTimes(s)
-		doMUT(s)
+		doMUT(s, *flagBootstrap)
 	}
 
-	if *flagMUCDF != 0 {
-		requireProgTimes(s)
+	if *flagMMUAt != "" && needProgTimes(s, "-mmu-at") {
+		doMMUAt(s, *flagMMUAt)
+	}
+
+	if *flagMUPhase != "" && needProgTimes(s, "-mu-phase") {
+		doMUPhase(s, *flagMUPhase, muWindows(*flagMMUAt))
+	}
+
+	if *flagLostWork != 0 && needProgTimes(s, "-lost-work") {
+		doLostWork(s, *flagLostWork, muWindows(*flagMMUAt))
+	}
+
+	if *flagWhatIfSTW >= 0 {
+		doWhatIfSTW(s, *flagWhatIfSTW, muWindows(*flagMMUAt))
+	}
+
+	if *flagMUCDF != 0 && needProgTimes(s, "-mucdf") {
 		doMUCDF(s, *flagMUCDF, "cdf")
 	}
 
-	if *flagMUCCDF != 0 {
-		requireProgTimes(s)
+	if *flagMUCCDF != 0 && needProgTimes(s, "-muccdf") {
 		doMUCDF(s, *flagMUCCDF, "ccdf")
 	}
 
-	if *flagMUDMap {
-		requireProgTimes(s)
-		doMUDMap(s)
+	if *flagMUDMap && needProgTimes(s, "-mudmap") {
+		doMUDMap(s, mapWindows(*flagMapWindows, *flagMapN), *flagMapUtils)
+	}
+	if *flagMUMap && needProgTimes(s, "-mumap") {
+		doMUMap(s, mapWindows(*flagMapWindows, *flagMapN))
+	}
+
+	if *flagGarbage {
+		doGarbage(s)
+	}
+
+	if *flagHeapPause && needProgTimes(s, "-heappause") {
+		doHeapPause(s)
+	}
+
+	switch *flagPauseHeap {
+	case "":
+	case "live":
+		doPauseHeap(s, *flagPauseHeap)
+	case "goal":
+		if needPacing(s, "-pauseheap goal") {
+			doPauseHeap(s, *flagPauseHeap)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "-pauseheap must be live or goal\n")
+		os.Exit(2)
+	}
+
+	if *flagPauseProcs {
+		doPauseProcs(s)
+	}
+
+	if *flagTail {
+		doTail(s)
+	}
+
+	if *flagModes {
+		doModes(s)
+	}
+
+	if *flagPauseTrend {
+		doPauseTrend(s)
+	}
+
+	if *flagAllocRate && needProgTimes(s, "-allocrate") && needPacing(s, "-allocrate") {
+		doAllocRate(s)
+	}
+
+	if *flagIdle {
+		doIdle(s)
+	}
+
+	if *flagFrac {
+		doFractional(s)
+	}
+
+	if *flagPausePct != 0 && needProgTimes(s, "-pausepct") {
+		doPausePct(s, *flagPausePct)
+	}
+
+	if *flagDuty && needProgTimes(s, "-duty") {
+		doDuty(s)
+	}
+
+	if *flagCumGC && needProgTimes(s, "-cumgc") {
+		doCumGC(s)
+	}
+
+	if *flagPhaseTime && needProgTimes(s, "-phasetime") {
+		doPhaseTime(s)
+	}
+
+	if *flagSTWRate != 0 && needProgTimes(s, "-stwrate") {
+		doSTWRate(s, *flagSTWRate)
+	}
+
+	if *flagBucket != 0 && needProgTimes(s, "-bucket") {
+		doBuckets(s, *flagBucket)
+	}
+
+	if *flagBursts != 0 && needProgTimes(s, "-bursts") {
+		doBursts(s, *flagBursts)
+	}
+
+	if *flagCPU && needProgTimes(s, "-cpu") {
+		doCPU(s)
+	}
+
+	if *flagSched && needProgTimes(s, "-sched") {
+		doSched(s)
+	}
+
+	if *flagScvg {
+		doScvg(s)
+	}
+
+	if *flagCross != "" && needProgTimes(s, "-crosscheck") {
+		doCrossCheck(s, *flagCross)
 	}
 
 	if *flagStopKDE || *flagStopCDF {
@@ -128,6 +498,86 @@ func main() {
 			doStopCDF(s, kdes)
 		}
 	}
+
+	runAnalyses(s, flagAnalyses)
+
+	if len(skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "skipped %s: these analyses require program execution times, which are\n"+
+			"missing from this GC trace. Please see 'go doc gcstats' for how to enable\n"+
+			"these, or use -estimate-times to approximate them.\n", strings.Join(skipped, ", "))
+	}
+	if len(unpaced) > 0 {
+		fmt.Fprintf(os.Stderr, "skipped %s: these analyses assume GC cycles are paced by heap growth,\n"+
+			"but %s.\n", strings.Join(unpaced, ", "), pacingReason(s.Pacing()))
+	}
+	if len(skipped) > 0 || len(unpaced) > 0 {
+		os.Exit(exitPartial)
+	}
+}
+
+// parseInput parses the GC log in input, displaying progress if
+// stderr is a terminal. If useMmap is true and input is a file other
+// than stdin, it memory-maps input rather than reading it. If -cache
+// is set and input is a file other than stdin, parseInput loads the
+// parsed trace from the cache if possible.
+func parseInput(input io.Reader, useMmap bool) (*gcstats.GcStats, error) {
+	f, ok := input.(*os.File)
+	if ok && useMmap && f != os.Stdin {
+		return parseMmap(f)
+	}
+
+	var size int64
+	if ok {
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+			size = fi.Size()
+		}
+	}
+	prog := newProgress("reading log", size)
+	defer prog.done()
+	r := &progressReader{input, prog}
+
+	if ok && f != os.Stdin {
+		// Read the whole file so it can be parsed in parallel.
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		prog.done()
+		if *flagCache != "" {
+			return parseCached(data, func() (*gcstats.GcStats, error) {
+				return parseBytes(data)
+			})
+		}
+		return parseBytes(data)
+	}
+	return parseAll(gcstats.NewParser(r))
+}
+
+// parseBytes parses the whole log in data like parseAll, displaying
+// progress if stderr is a terminal.
+func parseBytes(data []byte) (*gcstats.GcStats, error) {
+	prog := newProgress("parsing log", int64(len(data)))
+	defer prog.done()
+	p := gcstats.NewParserBytes(data)
+	if prog != nil {
+		p.Progress = prog.add
+	}
+	return parseAll(p)
+}
+
+// newParser applies -overlap and -overlap-tolerance to p and returns
+// it.
+func newParser(p *gcstats.Parser) *gcstats.Parser {
+	p.OverlapPolicy, p.OverlapTolerance = overlapPolicy, *flagOverlapTol
+	return p
+}
+
+// parseAll parses the whole log read by p with the -overlap settings.
+func parseAll(p *gcstats.Parser) (*gcstats.GcStats, error) {
+	if err := newParser(p).ParseAll(); err != nil {
+		return nil, err
+	}
+	return p.Stats(), nil
 }
 
 func showPlot(p *plot) {
@@ -142,61 +592,65 @@ func showPlot(p *plot) {
 	}
 }
 
-func doSummary(s *gcstats.GcStats) {
-	// Pause time: Max, 99th %ile, 95th %ile, mean
-	// Phase time distributions
-	// Mutator utilization
-	// 50ms mutator utilization: Min, 1st %ile, 5th %ile
-	pauseTimes, _ := stopsToSamples(s)
-	pauseTimes.Sort()
-	fmt.Print("STW: max=", ns(pauseTimes.Percentile(1)), " 99%ile=", ns(pauseTimes.Percentile(.99)), " 95%ile=", ns(pauseTimes.Percentile(.95)), " mean=", ns(pauseTimes.Mean()), "\n")
+// perPOf and perPStats cache the per-P utilization model of the trace
+// most recently passed to muBasis, so analyses over several windows
+// of the same trace reuse its cached mutator utilization sums.
+var perPOf, perPStats *gcstats.GcStats
 
-	fmt.Println()
-	clockByKind := make(map[gcstats.PhaseKind]*stats.Sample)
-	for _, phase := range s.Phases() {
-		if phase.Duration == -1 {
-			continue
-		}
-		sample := clockByKind[phase.Kind]
-		if sample == nil {
-			sample = new(stats.Sample)
-			clockByKind[phase.Kind] = sample
-		}
-		sample.Xs = append(sample.Xs, float64(phase.Duration))
+// muBasis returns the trace to compute mutator utilization of s from:
+// s itself, or its per-P utilization model if requested by -per-p.
+func muBasis(s *gcstats.GcStats) *gcstats.GcStats {
+	if !*flagPerP {
+		return s
 	}
-	for kind := gcstats.PhaseSweepTerm; kind <= gcstats.PhaseMultiple; kind++ {
-		clock := clockByKind[kind]
-		if clock == nil {
-			continue
-		}
-		clock.Sort()
-		min, max := clock.Bounds()
-		if min == 0 && max == 0 {
-			continue
-		}
-		fmt.Printf("%-10s max=%s 99%%ile=%s 95%%ile=%s mean=%s stddev=%s\n", kind.String()[5:]+":", ns(clock.Percentile(1)), ns(clock.Percentile(.99)), ns(clock.Percentile(.95)), ns(clock.Mean()), ns(clock.StdDev()))
+	if s != perPOf {
+		perPOf, perPStats = s, s.PerPUtilization()
 	}
+	return perPStats
+}
 
-	if s.HaveProgTimes() {
-		fmt.Println()
-		fmt.Print("Mean mutator utilization: ", pct(s.MutatorUtilization()), "\n")
-		mud := s.MutatorUtilizationDistribution(10e6)
-		fmt.Print("10ms mutator utilization: min=", pct(mud.InvCDF(0)), " 1%ile=", pct(mud.InvCDF(0.01)), " 5%ile=", pct(mud.InvCDF(0.05)), "\n")
+// computeMMU returns the MMU of s for windows of size windowNS, in
+// mutator time if requested by -mutator-time and over the Ps fully
+// available to the mutator if requested by -per-p. It uses the -cache
+// directory if s was loaded through it.
+func computeMMU(s *gcstats.GcStats, windowNS int) float64 {
+	key := analysisCacheKey(s, "mmu", windowNS)
+	var mmu cachedFloat
+	if key != "" && cacheLoad(key, &mmu) {
+		return float64(mmu)
+	}
+	s = muBasis(s)
+	if *flagMutTime {
+		mmu = cachedFloat(s.MutatorTimeMMU(windowNS))
+	} else {
+		mmu = cachedFloat(s.MMU(windowNS))
 	}
+	if key != "" {
+		cacheStore(key, mmu)
+	}
+	return float64(mmu)
+}
+
+// granularityLabel returns the axis label of plots over window size.
+func granularityLabel() string {
+	if *flagMutTime {
+		return "granularity (mutator time)"
+	}
+	return "granularity"
 }
 
 func doMMU(s *gcstats.GcStats) {
 	// 1e9 ns = 1000 ms
 	windows := vec.Logspace(-3, 0, samples, 10)
-	plot := newPlot("granularity", "mutator utilization", windows, "--style", "mmu")
+	plot := newPlot(granularityLabel(), "mutator utilization", windows, "--style", "mmu")
 	plot.addSeries("MMU", func(window float64) float64 {
-		return s.MMU(int(window * 1e9))
+		return computeMMU(s, int(window*1e9))
 	})
 	showPlot(plot)
 }
 
 func doMUCDF(s *gcstats.GcStats, window time.Duration, typ string) {
-	mud := s.MutatorUtilizationDistribution(int(window))
+	mud := computeMUD(s, int(window))
 	utils := vec.Linspace(0, 1, 100)
 	ylabel := "cumulative probability"
 	if typ == "ccdf" {
@@ -213,19 +667,39 @@ func doMUCDF(s *gcstats.GcStats, window time.Duration, typ string) {
 	showPlot(plot)
 }
 
-func doMUDMap(s *gcstats.GcStats) {
-	windows := ints(vec.Logspace(6, 9, 100, 10))
+// mapWindows returns the n window sizes of -mudmap and -mumap,
+// logarithmically spaced over spec, a window range lo,hi. It exits if
+// the grid is invalid.
+func mapWindows(spec string, n int) []int {
+	ws := parseWindows("-mudmap-windows", spec)
+	if len(ws) != 2 || ws[0] > ws[1] || n < 2 {
+		fmt.Fprintln(os.Stderr, "-mudmap-windows must be lo,hi with lo <= hi, and -mudmap-nwindows must be at least 2")
+		os.Exit(2)
+	}
+	return ints(vec.Logspace(math.Log10(float64(ws[0])), math.Log10(float64(ws[1])), n, 10))
+}
+
+// doMUDMap prints the MUD of s at each of windows as a heat map of
+// CDFs at nutils utilization levels from 0 to 1.
+func doMUDMap(s *gcstats.GcStats, windows []int, nutils int) {
+	if nutils < 2 {
+		fmt.Fprintln(os.Stderr, "-mudmap-utils must be at least 2")
+		os.Exit(2)
+	}
 	muds := make([]*gcstats.MUD, len(windows))
+	prog := newProgress("computing MUDs", int64(len(windows)))
 	for i, windowNS := range windows {
-		muds[i] = s.MutatorUtilizationDistribution(windowNS)
+		muds[i] = computeMUD(s, windowNS)
+		prog.add(1)
 	}
+	prog.done()
 	// gnuplot "nonuniform matrix" format
 	fmt.Printf("%d ", len(windows)+1)
 	for _, windowNS := range windows {
 		fmt.Printf("%d ", windowNS)
 	}
 	fmt.Print("\n")
-	utils := vec.Linspace(0, 1, 100)
+	utils := vec.Linspace(0, 1, nutils)
 	for _, util := range utils {
 		fmt.Printf("%g ", util)
 		for _, mud := range muds {
@@ -235,24 +709,55 @@ func doMUDMap(s *gcstats.GcStats) {
 	}
 }
 
-func doMUT(s *gcstats.GcStats) {
+// doMUMap prints the minimum mutator utilization of the windows
+// beginning in each time bucket of s, for each of windows.
+func doMUMap(s *gcstats.GcStats, windows []int) {
+	m := muBasis(s).UtilizationMap(windows, 100)
+	// gnuplot "nonuniform matrix" format, with time in seconds
+	// along X and window size along Y.
+	fmt.Printf("%d ", len(m.MU[0])+1)
+	for j := range m.MU[0] {
+		fmt.Printf("%g ", float64(int64(j)*m.Width)/1e9)
+	}
+	fmt.Print("\n")
+	for i, windowNS := range windows {
+		fmt.Printf("%d ", windowNS)
+		for _, mu := range m.MU[i] {
+			fmt.Printf("%g ", mu)
+		}
+		fmt.Print("\n")
+	}
+}
+
+// mutPercentiles are the curves plotted by -mut. x is the fraction
+// of windows with lower mutator utilization.
+var mutPercentiles = []struct {
+	label string
+	x     float64
+}{
+	{"100%ile", 0},
+	{"99.9%ile", 0.001},
+	{"99%ile", 0.01},
+	{"90%ile", 0.1},
+}
+
+func doMUT(s *gcstats.GcStats, bootstrap bool) {
 	windows := vec.Logspace(-3, 0, samples, 10)
 	muds := make(map[float64]*gcstats.MUD)
+	prog := newProgress("computing MUDs", int64(len(windows)))
 	for _, window := range windows {
-		muds[window] = s.MutatorUtilizationDistribution(int(window * 1e9))
+		muds[window] = computeMUD(s, int(window*1e9))
+		prog.add(1)
 	}
+	prog.done()
 
-	plot := newPlot("granularity", "mutator utilization", windows, "--style", "mut")
-	type config struct {
-		label string
-		x     float64
+	if bootstrap {
+		doMUTBootstrap(s, windows, muds)
+		return
 	}
-	for _, c := range []config{
-		{"100%ile", 0},
-		{"99.9%ile", 0.001},
-		{"99%ile", 0.01},
-		{"90%ile", 0.1},
-	} {
+
+	plot := newPlot(granularityLabel(), "mutator utilization", windows, "--style", "mut")
+	for _, c := range mutPercentiles {
 		plot.addSeries(c.label, func(x float64) float64 {
 			return muds[x].InvCDF(c.x)
 		})
@@ -260,6 +765,240 @@ func doMUT(s *gcstats.GcStats) {
 	showPlot(plot)
 }
 
+// mutResamples is the number of resamples of GC cycles used to
+// compute bootstrap confidence bands for -mut. This is fewer than for
+// summary statistics because each resample requires computing a MUD
+// at every window.
+const mutResamples = 100
+
+// doMUTBootstrap plots the percentile curves of -mut with 95%
+// bootstrap confidence bands computed by resampling the GC cycles of
+// s. muds are the MUDs of s at each of windows.
+func doMUTBootstrap(s *gcstats.GcStats, windows []float64, muds map[float64]*gcstats.MUD) {
+	// boot[i][j] is the sample of percentile i at window j over
+	// the resamples.
+	boot := make([][]stats.Sample, len(mutPercentiles))
+	for i := range boot {
+		boot[i] = make([]stats.Sample, len(windows))
+	}
+	// Resampling is deterministic so plots are reproducible.
+	rnd := rand.New(rand.NewSource(1))
+	prog := newProgress("bootstrapping MUDs", int64(mutResamples))
+	for r := 0; r < mutResamples; r++ {
+		rs := s.ResampleCycles(rnd)
+		for j, window := range windows {
+			mud := computeMUD(rs, int(window*1e9))
+			for i, c := range mutPercentiles {
+				boot[i][j].Xs = append(boot[i][j].Xs, mud.InvCDF(c.x))
+			}
+		}
+		prog.add(1)
+	}
+	prog.done()
+
+	plot := newPlot(granularityLabel(), "mutator utilization", windows, "--style", "mut", "--bands")
+	for i, c := range mutPercentiles {
+		plot.addSeries(c.label, func(x float64) float64 {
+			return muds[x].InvCDF(c.x)
+		})
+		lo, hi := make([]float64, len(windows)), make([]float64, len(windows))
+		for j := range windows {
+			boot[i][j].Sort()
+			lo[j], hi[j] = boot[i][j].Percentile(0.025), boot[i][j].Percentile(0.975)
+		}
+		plot.addColumn(c.label+" CI low", lo)
+		plot.addColumn(c.label+" CI high", hi)
+	}
+	showPlot(plot)
+}
+
+// muWindows returns the windows given to -mmu-at, or 1ms, 10ms, and
+// 100ms if it was not set, for the analyses that report at those
+// windows.
+func muWindows(mmuAt string) string {
+	if mmuAt == "" {
+		return "1ms,10ms,100ms"
+	}
+	return mmuAt
+}
+
+// parseWindows parses windows, a comma-separated list of window
+// durations given to flag name, and exits if any is invalid.
+func parseWindows(name, windows string) []time.Duration {
+	var ws []time.Duration
+	for _, f := range strings.Split(windows, ",") {
+		w, err := time.ParseDuration(strings.TrimSpace(f))
+		if err != nil || w <= 0 {
+			fmt.Fprintf(os.Stderr, "bad %s window %q\n", name, f)
+			os.Exit(2)
+		}
+		ws = append(ws, w)
+	}
+	return ws
+}
+
+// doMMUAt prints a table of the MMU and the mutator utilization
+// percentiles of -mut at each of the comma-separated windows.
+func doMMUAt(s *gcstats.GcStats, windows string) {
+	ws := parseWindows("-mmu-at", windows)
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "window\tMMU\t")
+	for _, c := range mutPercentiles[1:] {
+		fmt.Fprintf(w, "%s\t", c.label)
+	}
+	fmt.Fprintf(w, "\n")
+	for _, window := range ws {
+		fmt.Fprintf(w, "%s\t%s\t", window, pct(computeMMU(s, int(window))))
+		mud := computeMUD(s, int(window))
+		for _, c := range mutPercentiles[1:] {
+			fmt.Fprintf(w, "%s\t", pct(mud.InvCDF(c.x)))
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	w.Flush()
+}
+
+func doPausePct(s *gcstats.GcStats, window time.Duration) {
+	// Slide windows by a quarter of their width.
+	pctiles := []float64{0.99, 0.999, 1}
+	windows := s.PausePercentiles(int64(window), int64(window)/4, pctiles)
+	xs := make([]float64, len(windows))
+	for i, w := range windows {
+		// Plot each window at its center.
+		xs[i] = xAxis.x(w.Begin + int64(window)/2)
+	}
+	plot := newPlot(xAxis.label(), fmt.Sprintf("pause time in %s window", window), xs, append(xAxis.args(), "--style", "pausepct")...)
+	for i, label := range []string{"99%ile", "99.9%ile", "max"} {
+		ys := make([]float64, len(windows))
+		for j, w := range windows {
+			ys[j] = math.NaN()
+			if w.Count != 0 {
+				ys[j] = float64(w.Percentiles[i]) / 1e9
+			}
+		}
+		plot.addColumn(label, ys)
+	}
+	addTimeMarkers(plot, s)
+	showPlot(plot)
+}
+
+func doDuty(s *gcstats.GcStats) {
+	fmt.Print("GC active: ", pct(s.DutyCycle()), " of wall-clock time (", pct(1-s.MutatorUtilization()), " of CPU time)\n")
+	windows := []time.Duration{time.Millisecond, 10 * time.Millisecond, 100 * time.Millisecond, time.Second, 10 * time.Second}
+	windowNS := make([]int64, len(windows))
+	for i, w := range windows {
+		windowNS[i] = int64(w)
+	}
+	fmt.Print("Max GC active:")
+	for i, duty := range s.MaxDutyCycles(windowNS) {
+		fmt.Print(" ", windows[i], "=", pct(duty))
+	}
+	fmt.Println()
+}
+
+func doCumGC(s *gcstats.GcStats) {
+	last := s.Phases()[len(s.Phases())-1]
+	xs := make([]float64, samples)
+	ts := make([]int64, samples)
+	for i, t := range vec.Linspace(0, float64(last.End()), samples) {
+		ts[i] = int64(t)
+		xs[i] = xAxis.x(ts[i])
+	}
+	cpu, stw := s.CumulativeGC(ts)
+	plot := newPlot(xAxis.label(), "cumulative time", xs, append(xAxis.args(), "--style", "cumgc")...)
+	plot.addColumn("GC CPU", vec.Map(func(x float64) float64 { return x / 1e9 }, cpu))
+	plot.addColumn("STW", vec.Map(func(x float64) float64 { return x / 1e9 }, stw))
+	addTimeMarkers(plot, s)
+	showPlot(plot)
+}
+
+func doPhaseTime(s *gcstats.GcStats) {
+	// Collect the duration of each phase kind by cycle begin time.
+	durs := make(map[gcstats.PhaseKind]map[float64]float64)
+	var xs []float64
+	for _, c := range s.Cycles() {
+		xs = append(xs, xAxis.x(c.Begin))
+	}
+	begins := make(map[int]float64)
+	for i, c := range s.Cycles() {
+		begins[c.N] = xs[i]
+	}
+	for _, p := range s.Phases() {
+		if p.Kind == gcstats.PhaseSweep || p.Duration < 0 {
+			continue
+		}
+		if durs[p.Kind] == nil {
+			durs[p.Kind] = make(map[float64]float64)
+		}
+		durs[p.Kind][begins[p.N]] += float64(p.Duration) / 1e9
+	}
+
+	plot := newPlot(xAxis.label(), "phase duration", xs, append(xAxis.args(), "--style", "phasetime")...)
+	for kind := gcstats.PhaseSweepTerm; kind < gcstats.PhaseSweep; kind++ {
+		if d := durs[kind]; d != nil {
+			plot.addSeries(kind.String()[len("Phase"):], func(x float64) float64 {
+				if dur, ok := d[x]; ok {
+					return dur
+				}
+				return math.NaN()
+			})
+		}
+	}
+	addTimeMarkers(plot, s)
+	showPlot(plot)
+}
+
+func doSTWRate(s *gcstats.GcStats, interval time.Duration) {
+	bins := s.STWRate(int64(interval))
+	perSec := 1e9 / float64(interval)
+	xs := make([]float64, len(bins))
+	counts := make([]float64, len(bins))
+	durs := make([]float64, len(bins))
+	for i, bin := range bins {
+		xs[i] = xAxis.x(bin.Begin)
+		counts[i] = float64(bin.Count) * perSec
+		durs[i] = float64(bin.Duration) / 1e6 * perSec
+	}
+	plot := newPlot(xAxis.label(), "pauses/sec", xs, append(xAxis.args(), "--style", "stwrate", "--y2label", "STW ms/sec")...)
+	plot.addColumn("pauses/sec", counts)
+	plot.addColumn("STW ms/sec", durs)
+	addTimeMarkers(plot, s)
+	showPlot(plot)
+}
+
+// computeMUD returns the mutator utilization distribution of s for
+// windows of size windowNS, in mutator time if requested by
+// -mutator-time and over the Ps fully available to the mutator if
+// requested by -per-p. It uses the -cache directory if s was loaded
+// through it.
+func computeMUD(s *gcstats.GcStats, windowNS int) *gcstats.MUD {
+	key := analysisCacheKey(s, "mud", windowNS)
+	mud := new(gcstats.MUD)
+	if key != "" && cacheLoad(key, mud) {
+		return mud
+	}
+	s = muBasis(s)
+	if *flagMutTime {
+		mud = s.MutatorTimeMUD(windowNS)
+	} else {
+		mud = computeWallMUD(s, windowNS)
+	}
+	if key != "" {
+		cacheStore(key, mud)
+	}
+	return mud
+}
+
+// computeWallMUD returns the mutator utilization distribution of s
+// for windows of windowNS of wall-clock time, approximated if
+// requested by -approx.
+func computeWallMUD(s *gcstats.GcStats, windowNS int) *gcstats.MUD {
+	if *flagApprox > 0 {
+		return s.ApproxMutatorUtilizationDistribution(windowNS, *flagApprox)
+	}
+	return s.MutatorUtilizationDistribution(windowNS)
+}
+
 func stopKDEs(s *gcstats.GcStats) map[gcstats.PhaseKind]*stats.KDE {
 	stops := s.Stops()
 	times := make(map[gcstats.PhaseKind]stats.Sample)
@@ -354,11 +1093,58 @@ func printTable(f func(float64) float64, xs []float64) {
 	}
 }
 
+// exitPartial is the exit status if some requested analyses were
+// skipped because the trace lacks program execution times or its GC
+// cycles weren't paced by heap growth.
+const exitPartial = 3
+
+// skipped lists the flags of analyses skipped because the trace lacks
+// program execution times.
+var skipped []string
+
+// needProgTimes reports whether s has program execution times, as
+// required by the analysis requested by flag name. If not, it records
+// that the analysis was skipped so the others can still run.
+func needProgTimes(s *gcstats.GcStats, name string) bool {
+	if !s.HaveProgTimes() {
+		skipped = append(skipped, name)
+		return false
+	}
+	return true
+}
+
+// unpaced lists the flags of analyses skipped because the trace's GC
+// cycles weren't paced by heap growth.
+var unpaced []string
+
+// needPacing reports whether the GC cycles of s were paced by heap
+// growth, as assumed by the analysis requested by flag name. If not,
+// it records that the analysis was skipped so the others can still
+// run.
+func needPacing(s *gcstats.GcStats, name string) bool {
+	if s.Pacing() != gcstats.PacingHeap {
+		unpaced = append(unpaced, name)
+		return false
+	}
+	return true
+}
+
+// pacingReason describes why a trace with pacing p can't be analyzed
+// as paced by heap growth.
+func pacingReason(p gcstats.Pacing) string {
+	if p == gcstats.PacingForced {
+		return "every cycle of this trace was forced, as with GOGC=off"
+	}
+	return "this trace records no heap sizes"
+}
+
+// requireProgTimes exits if s lacks program execution times.
 func requireProgTimes(s *gcstats.GcStats) {
 	if !s.HaveProgTimes() {
 		fmt.Fprintln(os.Stderr,
 			"This analysis requires program execution times, which are missing from\n"+
-				"this GC trace. Please see 'go doc gcstats' for how to enable these.")
+				"this GC trace. Please see 'go doc gcstats' for how to enable these,\n"+
+				"or use -estimate-times to approximate them.")
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,107 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// idleHeavy is the fraction of mark CPU time done by idle workers
+// above which doIdle warns that the GC relies on idle Ps.
+const idleHeavy = 0.5
+
+// doIdle prints how much of the concurrent mark work of each cycle
+// was done by mutator assists, background workers, and idle workers.
+func doIdle(s *gcstats.GcStats) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "GC\tassist\tbackground\tidle\tidle share\t\n")
+	var assist, background, idle int64
+	for _, c := range s.Cycles() {
+		total := c.AssistCPU + c.BackgroundCPU + c.IdleCPU
+		if total == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t\n", c.N, ns(float64(c.AssistCPU)), ns(float64(c.BackgroundCPU)), ns(float64(c.IdleCPU)), pct(float64(c.IdleCPU)/float64(total)))
+		assist += c.AssistCPU
+		background += c.BackgroundCPU
+		idle += c.IdleCPU
+	}
+	w.Flush()
+
+	total := float64(assist + background + idle)
+	if total == 0 {
+		fmt.Println("\nThis trace does not report mark CPU time by worker type (requires Go 1.5 or later).")
+		return
+	}
+	fmt.Println()
+	fmt.Printf("Mark CPU: assist=%s background=%s idle=%s\n", pct(float64(assist)/total), pct(float64(background)/total), pct(float64(idle)/total))
+	if float64(idle)/total > idleHeavy {
+		fmt.Println("Most marking was done on idle Ps. Under higher load, this work will")
+		fmt.Println("shift to background workers and assists, reducing mutator throughput.")
+	}
+}
+
+// doFractional prints an estimate of the CPU time used by the
+// fractional mark worker in each cycle and flags cycles where the
+// garbage collector used more than its CPU target during concurrent
+// mark.
+// The fractional worker's CPU time is estimated by
+// Cycle.SplitBackground.
+func doFractional(s *gcstats.GcStats) {
+	marks := make(map[int]gcstats.Phase)
+	for _, p := range s.Phases() {
+		if p.Kind == gcstats.PhaseMark {
+			marks[p.N] = p
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "GC\tP\tmark\tbackground\tfractional\tGC CPU\t\t\n")
+	var n, over, smallP int
+	var fractional, background int64
+	for _, c := range s.Cycles() {
+		mark, ok := marks[c.N]
+		if !ok || mark.Duration <= 0 || c.AssistCPU+c.BackgroundCPU+c.IdleCPU == 0 {
+			continue
+		}
+		procs := mark.Gomaxprocs
+		dedicated := int64(float64(procs) * gcstats.GCGoalUtilization)
+		_, frac := c.SplitBackground(mark)
+		// Idle marking uses CPU the mutator didn't want, so
+		// it doesn't count against the target.
+		util := float64(c.AssistCPU+c.BackgroundCPU) / float64(int64(procs)*mark.Duration)
+		flag := ""
+		if util > gcstats.GCGoalUtilization {
+			flag = "over target"
+			over++
+		}
+		if dedicated == 0 {
+			smallP++
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\t%s\t%s\t%s\t\n", c.N, procs, ns(float64(mark.Duration)), ns(float64(c.BackgroundCPU)), ns(float64(frac)), pct(util), flag)
+		n++
+		fractional += frac
+		background += c.BackgroundCPU
+	}
+	w.Flush()
+
+	if n == 0 {
+		fmt.Println("\nThis trace does not report mark CPU time by worker type (requires Go 1.5 or later).")
+		return
+	}
+	fmt.Println()
+	if background > 0 {
+		fmt.Printf("Fractional worker: %s of background mark CPU\n", pct(float64(fractional)/float64(background)))
+	}
+	fmt.Printf("Cycles over the %s CPU target: %d of %d\n", pct(gcstats.GCGoalUtilization), over, n)
+	if smallP > 0 {
+		fmt.Printf("In %d cycles, GOMAXPROCS < %d, so all background marking was done by\n", smallP, int(1/gcstats.GCGoalUtilization))
+		fmt.Println("the fractional worker.")
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats/report"
+)
+
+// doPartial implements the partial subcommand and returns the exit
+// status.
+func doPartial(args []string) int {
+	fs := flag.NewFlagSet("partial", flag.ExitOnError)
+	var (
+		flagMUD  = fs.String("mud", "1ms,10ms,100ms", "Include mutator utilization distributions for the comma-separated `windows`")
+		flagMmap = fs.Bool("mmap", false, "Memory-map the input file rather than reading it")
+	)
+	addPhasesFlag(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s partial [flags] [input]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nWrite a mergeable JSON analysis of a shard of a trace for the merge subcommand.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	var windows []time.Duration
+	if *flagMUD != "" {
+		for _, w := range strings.Split(*flagMUD, ",") {
+			d, err := time.ParseDuration(w)
+			if err != nil || d <= 0 {
+				fmt.Fprintf(os.Stderr, "bad -mud window %q\n", w)
+				return 2
+			}
+			windows = append(windows, d)
+		}
+	}
+
+	var input io.Reader = os.Stdin
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer f.Close()
+		input = f
+	}
+	s, err := parseInput(input, *flagMmap)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error parsing log: %s\n", err)
+		return 1
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(report.NewPartial(s, windows)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// doMerge implements the merge subcommand and returns the exit
+// status.
+func doMerge(args []string) int {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	flagFormat := fs.String("format", "text", "Output `format`: text, or json for a partial that can be merged further")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s merge [flags] part.json...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\nMerge the partial analyses of shards of a trace written by the partial subcommand.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 || *flagFormat != "text" && *flagFormat != "json" {
+		fs.Usage()
+		return 2
+	}
+
+	var parts []*report.Partial
+	for _, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		p := new(report.Partial)
+		if err := json.Unmarshal(data, p); err != nil || p.Pauses == nil {
+			fmt.Fprintf(os.Stderr, "%s: not a partial analysis: %v\n", path, err)
+			return 1
+		}
+		parts = append(parts, p)
+	}
+	merged, err := report.MergePartials(parts...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *flagFormat == "json" {
+		if err := json.NewEncoder(os.Stdout).Encode(merged); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Printf("Shards: %d  GCs: %d\n", merged.Shards, merged.Cycles)
+	if n := merged.Pauses.Count(); n > 0 {
+		q := merged.Pauses.Quantile
+		fmt.Printf("STW: count=%d max=%s 99%%ile≈%s 95%%ile≈%s 50%%ile≈%s mean=%s\n", n, ns(float64(merged.MaxPauseNS)), ns(q(0.99)), ns(q(0.95)), ns(q(0.5)), ns(float64(merged.PauseTotalNS)/float64(n)))
+	}
+	if !merged.ProgTimes {
+		return 0
+	}
+	fmt.Printf("Mean mutator utilization: %s\n", pct(merged.MutatorUtilization()))
+	var keys []string
+	for key := range merged.MUD {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return merged.MUD[keys[i]].WindowNS < merged.MUD[keys[j]].WindowNS })
+	for _, key := range keys {
+		f := merged.MUD[key]
+		fmt.Printf("%s mutator utilization: min=%s 1%%ile=%s 5%%ile=%s 50%%ile=%s\n", key, pct(f.MMU), pct(f.InvCDF(0.01)), pct(f.InvCDF(0.05)), pct(f.InvCDF(0.5)))
+	}
+	return 0
+}
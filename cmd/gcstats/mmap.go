@@ -0,0 +1,30 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"os"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// parseMmap parses the GC log in f by memory-mapping it. This lets
+// the OS manage paging for very large logs. If f can't be mapped,
+// parseMmap falls back to reading it. Like parseInput, it uses the
+// -cache directory if set and displays progress.
+func parseMmap(f *os.File) (*gcstats.GcStats, error) {
+	data, unmap, err := mmapFile(f)
+	if err != nil {
+		return parseInput(f, false)
+	}
+	defer unmap()
+	parse := func() (*gcstats.GcStats, error) {
+		return parseBytes(data)
+	}
+	if *flagCache != "" {
+		return parseCached(data, parse)
+	}
+	return parse()
+}
@@ -0,0 +1,49 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// doModes reports the modes of the STW pause distribution, overall
+// and for each kind of pause.
+func doModes(s *gcstats.GcStats) {
+	var all []int64
+	byKind := make(map[gcstats.PhaseKind][]int64)
+	for _, stop := range s.Stops() {
+		all = append(all, stop.Duration)
+		byKind[stop.Kind] = append(byKind[stop.Kind], stop.Duration)
+	}
+	var kinds []gcstats.PhaseKind
+	for kind := range byKind {
+		kinds = append(kinds, kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+
+	printModes := func(label string, durations []int64) {
+		modes := gcstats.Modes(durations)
+		switch len(modes) {
+		case 0:
+			return
+		case 1:
+			fmt.Printf("%s: unimodal, peak %s\n", label, ns(float64(modes[0].Peak)))
+			return
+		}
+		fmt.Printf("%s: %d modes\n", label, len(modes))
+		for _, m := range modes {
+			fmt.Printf("  peak %-8s range %s-%s  %4s (%d pauses)\n", ns(float64(m.Peak)), ns(float64(m.Low)), ns(float64(m.High)), pct(m.Weight), m.Count)
+		}
+	}
+	printModes("All STW", all)
+	if len(kinds) > 1 {
+		for _, kind := range kinds {
+			printModes(kind.String()[len("Phase"):], byKind[kind])
+		}
+	}
+}
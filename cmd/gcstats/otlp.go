@@ -0,0 +1,185 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// OTLP/JSON messages, as defined by the OpenTelemetry protocol's
+// JSON encoding of ExportTraceServiceRequest. IDs are hex strings and
+// 64-bit integers are decimal strings.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    string   `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+	}
+)
+
+// otlpSpanKindInternal is SPAN_KIND_INTERNAL.
+const otlpSpanKindInternal = 1
+
+func otlpString(key, v string) otlpKeyValue {
+	return otlpKeyValue{key, otlpValue{StringValue: &v}}
+}
+
+func otlpInt(key string, v int64) otlpKeyValue {
+	return otlpKeyValue{key, otlpValue{IntValue: strconv.FormatInt(v, 10)}}
+}
+
+func otlpDouble(key string, v float64) otlpKeyValue {
+	return otlpKeyValue{key, otlpValue{DoubleValue: &v}}
+}
+
+func otlpBool(key string, v bool) otlpKeyValue {
+	return otlpKeyValue{key, otlpValue{BoolValue: &v}}
+}
+
+// otlpSpans returns an OTLP request with a span for each GC cycle of
+// s, from the beginning of sweep termination to the end of mark
+// termination, each in its own trace. The STW phases of each cycle
+// are child spans. start is the wall-clock time the program started.
+func otlpSpans(s *gcstats.GcStats, start time.Time, service string) *otlpRequest {
+	id := func(n int) string {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = byte(rand.Uint32())
+		}
+		return hex.EncodeToString(b)
+	}
+	at := func(t int64) string {
+		return strconv.FormatInt(start.UnixNano()+t, 10)
+	}
+
+	cycles := make(map[int]gcstats.Cycle)
+	for _, c := range s.Cycles() {
+		cycles[c.N] = c
+	}
+	var spans []otlpSpan
+	phases := s.Phases()
+	for i := 0; i < len(phases); {
+		// Collect the cycle's phases up to concurrent sweep.
+		n, j := phases[i].N, i
+		for j < len(phases) && phases[j].N == n && phases[j].Kind != gcstats.PhaseSweep && phases[j].Duration >= 0 {
+			j++
+		}
+		if j == i {
+			i++
+			continue
+		}
+		root := otlpSpan{
+			TraceID:           id(16),
+			SpanID:            id(8),
+			Name:              fmt.Sprintf("GC %d", n),
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: at(phases[i].Begin),
+			EndTimeUnixNano:   at(phases[j-1].End()),
+			Attributes:        []otlpKeyValue{otlpInt("gc.number", int64(n)), otlpInt("gc.gomaxprocs", int64(phases[i].Gomaxprocs))},
+		}
+		if c, ok := cycles[n]; ok {
+			root.Attributes = append(root.Attributes, otlpString("gc.cause", c.Cause.String()), otlpBool("gc.forced", c.Forced))
+			if c.HeapTrigger != 0 {
+				root.Attributes = append(root.Attributes, otlpInt("gc.heap.trigger", c.HeapTrigger), otlpInt("gc.heap.marked", c.HeapMarked), otlpInt("gc.heap.live", c.HeapLive))
+			}
+			if c.HeapGoal != 0 {
+				root.Attributes = append(root.Attributes, otlpInt("gc.heap.goal", c.HeapGoal))
+			}
+		}
+		spans = append(spans, root)
+		for _, p := range phases[i:j] {
+			if !p.STW {
+				continue
+			}
+			spans = append(spans, otlpSpan{
+				TraceID:           root.TraceID,
+				SpanID:            id(8),
+				ParentSpanID:      root.SpanID,
+				Name:              p.Kind.String()[len("Phase"):],
+				Kind:              otlpSpanKindInternal,
+				StartTimeUnixNano: at(p.Begin),
+				EndTimeUnixNano:   at(p.End()),
+				Attributes:        []otlpKeyValue{otlpInt("gc.number", int64(n)), otlpDouble("gc.procs", p.GCProcs)},
+			})
+		}
+		// Skip the rest of the cycle.
+		for i = j; i < len(phases) && phases[i].N == n; {
+			i++
+		}
+	}
+	attrs := []otlpKeyValue{otlpString("service.name", service)}
+	for _, l := range flagLabels {
+		attrs = append(attrs, otlpString(l.name, l.value))
+	}
+	return &otlpRequest{[]otlpResourceSpans{{
+		Resource:   otlpResource{attrs},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{"gcstats"}, Spans: spans}},
+	}}}
+}
+
+// writeOTLP writes req as OTLP/JSON to w or, if endpoint is not "",
+// posts it to the OTLP/HTTP traces endpoint, such as
+// http://localhost:4318/v1/traces.
+func writeOTLP(w io.Writer, req *otlpRequest, endpoint string) error {
+	if endpoint == "" {
+		return json.NewEncoder(w).Encode(req)
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s: %s", endpoint, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
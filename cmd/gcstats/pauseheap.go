@@ -0,0 +1,54 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/internal/go-moremath/vec"
+)
+
+// doPauseHeap regresses the longest STW pause of each cycle against
+// the heap size of that cycle, where by is "live" or "goal".
+func doPauseHeap(s *gcstats.GcStats, by string) {
+	var xs, ys []float64
+	for _, r := range cycleRecords(s) {
+		heap := r.HeapLive
+		if by == "goal" {
+			heap = r.HeapGoal
+		}
+		if heap == 0 || r.MaxPauseNS == 0 {
+			continue
+		}
+		xs = append(xs, float64(heap)/(1<<20))
+		ys = append(ys, float64(r.MaxPauseNS))
+	}
+	if len(xs) < 3 {
+		fmt.Fprintf(os.Stderr, "need at least 3 cycles with a known heap %s\n", by)
+		os.Exit(1)
+	}
+	fit := fitLine(xs, ys)
+	if math.IsNaN(fit.r2) {
+		fmt.Fprintf(os.Stderr, "heap %s does not vary between cycles\n", by)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Max pause vs. heap %s over %d cycles:\n", by, fit.n)
+	fmt.Printf("  slope      %s/MB ± %s/MB (95%% CI)\n", signedNS(fit.slope), ns(fit.slopeCI))
+	fmt.Printf("  intercept  %s\n", signedNS(fit.intercept))
+	fmt.Printf("  R²         %.3f (%s of pause variance explained by heap %s)\n", fit.r2, pct(fit.r2), by)
+
+	if *flagShow {
+		plot := newPlot("heap "+by+" (MB)", "max STW pause (ms)", xs, "--style", "scatter", "--fit")
+		plot.addColumn("GC cycles", vec.Map(func(y float64) float64 { return y / 1e6 }, ys))
+		plot.addSeries("least-squares fit", func(x float64) float64 {
+			return (fit.slope*x + fit.intercept) / 1e6
+		})
+		showPlot(plot)
+	}
+}
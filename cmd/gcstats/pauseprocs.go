@@ -0,0 +1,100 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// doPauseProcs relates the duration of each STW phase to the
+// fraction of GOMAXPROCS the garbage collector kept busy during it.
+// If long pauses have low parallelism, termination work is being
+// serialized rather than simply being large.
+func doPauseProcs(s *gcstats.GcStats) {
+	type stw struct{ par, dur float64 }
+	var kinds []gcstats.PhaseKind
+	byKind := make(map[gcstats.PhaseKind][]stw)
+	for _, p := range s.Phases() {
+		if !p.STW || p.Duration <= 0 || p.Gomaxprocs == 0 {
+			continue
+		}
+		if byKind[p.Kind] == nil {
+			kinds = append(kinds, p.Kind)
+		}
+		byKind[p.Kind] = append(byKind[p.Kind], stw{p.GCProcs / float64(p.Gomaxprocs), float64(p.Duration)})
+	}
+	if len(kinds) == 0 {
+		fmt.Fprintf(os.Stderr, "no STW phases with known durations\n")
+		os.Exit(1)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "phase\tpauses\tparallelism\tlongest 10%%\trest\tcorrelation\t\n")
+	for _, kind := range kinds {
+		stws := byKind[kind]
+		sort.Slice(stws, func(i, j int) bool { return stws[i].dur > stws[j].dur })
+
+		// Compare the mean parallelism of the longest pauses
+		// against the remaining pauses.
+		nLong := (len(stws) + 9) / 10
+		var all, long float64
+		var xs, ys []float64
+		for i, st := range stws {
+			all += st.par
+			if i < nLong {
+				long += st.par
+			}
+			xs, ys = append(xs, st.par), append(ys, st.dur)
+		}
+		rest := "-"
+		if len(stws) > nLong {
+			rest = pct((all - long) / float64(len(stws)-nLong))
+		}
+
+		// The correlation coefficient between parallelism and
+		// duration. Negative values mean longer pauses were
+		// less parallel.
+		corr := "-"
+		if len(stws) >= 3 {
+			if fit := fitLine(xs, ys); !math.IsNaN(fit.r2) {
+				corr = fmt.Sprintf("%+.2f", math.Copysign(math.Sqrt(math.Max(0, fit.r2)), fit.slope))
+			}
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t\n", kind.String()[len("Phase"):], len(stws), pct(all/float64(len(stws))), pct(long/float64(nLong)), rest, corr)
+	}
+	w.Flush()
+
+	if *flagShow {
+		// Plot each kind as its own series. Each row of the
+		// plot table is one pause, so the other kinds are NaN.
+		var xs []float64
+		for _, kind := range kinds {
+			for _, st := range byKind[kind] {
+				xs = append(xs, st.par)
+			}
+		}
+		plot := newPlot("GC procs / GOMAXPROCS", "STW pause (ms)", xs, "--style", "scatter")
+		row := 0
+		for _, kind := range kinds {
+			ys := make([]float64, len(xs))
+			for i := range ys {
+				ys[i] = math.NaN()
+			}
+			for _, st := range byKind[kind] {
+				ys[row] = st.dur / 1e6
+				row++
+			}
+			plot.addColumn(kind.String()[len("Phase"):], ys)
+		}
+		showPlot(plot)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// doPauseTrend fits the longest STW pause of each cycle against the
+// cycle number, the cycle's begin time, and the live heap, and
+// reports whether pauses grew over the trace.
+func doPauseTrend(s *gcstats.GcStats) {
+	var gcs, times, pauses []float64
+	var lives, livePauses []float64
+	for _, r := range cycleRecords(s) {
+		if r.MaxPauseNS == 0 {
+			continue
+		}
+		gcs = append(gcs, float64(r.GC))
+		pauses = append(pauses, float64(r.MaxPauseNS))
+		if r.BeginNS != nil {
+			times = append(times, float64(*r.BeginNS)/1e9)
+		}
+		if r.HeapLive != 0 {
+			lives = append(lives, float64(r.HeapLive)/(1<<20))
+			livePauses = append(livePauses, float64(r.MaxPauseNS))
+		}
+	}
+	if len(pauses) < 3 {
+		fmt.Fprintf(os.Stderr, "need at least 3 cycles with known pause times\n")
+		os.Exit(1)
+	}
+
+	fmt.Printf("Max pause trend over %d cycles:\n", len(pauses))
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "against\tslope\t95%% CI\tR²\tsignificant\t\n")
+	row := func(against, unit string, xs, ys []float64) {
+		if len(xs) < 3 {
+			return
+		}
+		fit := fitLine(xs, ys)
+		if math.IsNaN(fit.r2) {
+			fmt.Fprintf(w, "%s\t-\t-\t-\t-\t\n", against)
+			return
+		}
+		sig := "no"
+		if math.Abs(fit.slope) > fit.slopeCI {
+			sig = "yes"
+		}
+		fmt.Fprintf(w, "%s\t%s/%s\t±%s\t%.3f\t%s\t\n", against, signedNS(fit.slope), unit, ns(fit.slopeCI), fit.r2, sig)
+	}
+	row("GC cycle", "GC", gcs, pauses)
+	if len(times) == len(pauses) {
+		row("time", "s", times, pauses)
+	}
+	row("live heap", "MB", lives, livePauses)
+	w.Flush()
+
+	// Summarize the change in max pause from the first to last
+	// cycle according to the fit against cycle number.
+	fit := fitLine(gcs, pauses)
+	span := gcs[len(gcs)-1] - gcs[0]
+	change, ci := fit.slope*span, fit.slopeCI*span
+	fmt.Println()
+	if math.Abs(change) <= ci {
+		fmt.Printf("No significant change in max pause over %d GCs (%s ± %s).\n", int(span), signedNS(change), ns(ci))
+	} else {
+		verb := "grew"
+		if change < 0 {
+			verb = "shrank"
+		}
+		fmt.Printf("Max pause %s by %s ± %s over %d GCs (from %s to %s by the fit).\n", verb, ns(math.Abs(change)), ns(ci), int(span), ns(fit.intercept+fit.slope*gcs[0]), ns(fit.intercept+fit.slope*gcs[len(gcs)-1]))
+	}
+}
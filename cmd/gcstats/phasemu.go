@@ -0,0 +1,76 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// parsePhaseKinds parses a comma-separated list of phase kinds for
+// -mu-phase. Kinds are named as for gcstats.ParsePhaseKind, and "gc"
+// stands for every phase but sweep.
+func parsePhaseKinds(list string) ([]gcstats.PhaseKind, error) {
+	var kinds []gcstats.PhaseKind
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if strings.EqualFold(name, "gc") {
+			for kind := gcstats.PhaseSweepTerm; kind < gcstats.PhaseSweep; kind++ {
+				kinds = append(kinds, kind)
+			}
+			continue
+		}
+		kind, err := gcstats.ParsePhaseKind(name)
+		if err != nil {
+			return nil, err
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+// doMUPhase prints, for each of the comma-separated windows, the
+// mutator utilization percentiles of the windows that overlap a phase
+// of one of the comma-separated kinds and of those that don't, and
+// the share of the utilization loss in the overlapping windows.
+func doMUPhase(s *gcstats.GcStats, kinds, windows string) {
+	ks, err := parsePhaseKinds(kinds)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bad -mu-phase: %s\n", err)
+		os.Exit(2)
+	}
+	ws := parseWindows("-mmu-at", windows)
+	s = muBasis(s)
+
+	pctiles := mutPercentiles[1:]
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "window\tin %s\t", kinds)
+	for _, c := range pctiles {
+		fmt.Fprintf(w, "in %s\t", c.label)
+	}
+	for _, c := range pctiles {
+		fmt.Fprintf(w, "out %s\t", c.label)
+	}
+	fmt.Fprintf(w, "loss in\t\n")
+	for _, window := range ws {
+		d := s.PhaseMutatorUtilizationDistribution(int(window), ks...)
+		fmt.Fprintf(w, "%s\t%s\t", window, pct(d.InFraction))
+		for _, mud := range []*gcstats.MUD{d.In, d.Out} {
+			for _, c := range pctiles {
+				if mud == nil {
+					fmt.Fprintf(w, "-\t")
+				} else {
+					fmt.Fprintf(w, "%s\t", pct(mud.InvCDF(c.x)))
+				}
+			}
+		}
+		fmt.Fprintf(w, "%s\t\n", pct(d.InLoss))
+	}
+	w.Flush()
+}
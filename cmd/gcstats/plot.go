@@ -10,6 +10,9 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/aclements/go-gcstats/internal/go-moremath/vec"
 )
@@ -17,14 +20,21 @@ import (
 //go:generate sh -c "(echo '// GENERATED. DO NOT EDIT.'; echo; echo package main; echo; echo -n 'var plotpy = `'; cat plot.py; echo '`') > bindata_plotpy.go"
 
 type plot struct {
-	hdrs []string
-	cols [][]float64
-	args []string
+	hdrs  []string
+	cols  [][]float64
+	args  []string
+	spans []plotSpan
+}
+
+// plotSpan is a range of X values to shade in a plot.
+type plotSpan struct {
+	label      string
+	begin, end float64
 }
 
 func newPlot(xlabel, ylabel string, xs []float64, args ...string) *plot {
 	args = append(args, "--ylabel", ylabel)
-	return &plot{[]string{xlabel}, [][]float64{xs}, args}
+	return &plot{hdrs: []string{xlabel}, cols: [][]float64{xs}, args: args}
 }
 
 func (p *plot) addSeries(label string, f func(float64) float64) {
@@ -32,6 +42,19 @@ func (p *plot) addSeries(label string, f func(float64) float64) {
 	p.cols = append(p.cols, vec.Map(f, p.cols[0]))
 }
 
+// addSpan shades the X values [begin, end] of the plot. Spans with
+// the same label share a color and legend entry.
+func (p *plot) addSpan(label string, begin, end float64) {
+	p.spans = append(p.spans, plotSpan{label, begin, end})
+}
+
+// addColumn adds a series with the values ys at each of the plot's
+// x values.
+func (p *plot) addColumn(label string, ys []float64) {
+	p.hdrs = append(p.hdrs, label)
+	p.cols = append(p.cols, ys)
+}
+
 func (p *plot) show() error {
 	f, err := ioutil.TempFile("", "gcstats")
 	if err != nil {
@@ -48,7 +71,30 @@ func (p *plot) show() error {
 	}
 	f.Close()
 
-	cmd := exec.Command(f.Name(), p.args...)
+	args := append(p.args, plotFlagArgs()...)
+	if len(p.spans) > 0 {
+		sf, err := ioutil.TempFile("", "gcstats-spans")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(sf.Name())
+		for _, span := range p.spans {
+			fmt.Fprintf(sf, "%s\t%v\t%v\n", span.label, span.begin, span.end)
+		}
+		if err := sf.Close(); err != nil {
+			return err
+		}
+		args = append(args, "--spans", sf.Name())
+	}
+	if *flagPlot != "" {
+		args = append(args, "--output", *flagPlot)
+		if *flagKeepData {
+			if err := p.writeData(*flagPlot); err != nil {
+				return err
+			}
+		}
+	}
+	cmd := exec.Command(f.Name(), args...)
 	stdin, err := cmd.StdinPipe()
 	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
 	if err != nil {
@@ -69,6 +115,72 @@ func (p *plot) show() error {
 	return nil
 }
 
+// writeData writes the plot's table next to the image file image.
+func (p *plot) writeData(image string) error {
+	path := strings.TrimSuffix(image, filepath.Ext(image)) + ".tsv"
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := p.writeTable(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// plotFlagArgs returns the plot.py arguments for the plot
+// customization flags.
+func plotFlagArgs() []string {
+	var args []string
+	if *flagTitle != "" {
+		args = append(args, "--title", *flagTitle)
+	}
+	if len(flagLabels) > 0 {
+		args = append(args, "--legend-title", flagLabels.String())
+	}
+	if *flagWidth > 0 {
+		args = append(args, "--width", fmt.Sprint(*flagWidth))
+	}
+	if *flagHeight > 0 {
+		args = append(args, "--height", fmt.Sprint(*flagHeight))
+	}
+	if *flagXRange != "" {
+		args = append(args, "--xrange", *flagXRange)
+	}
+	if *flagYRange != "" {
+		args = append(args, "--yrange", *flagYRange)
+	}
+	if *flagLogX {
+		args = append(args, "--logx")
+	}
+	if *flagLogY {
+		args = append(args, "--logy")
+	}
+	return args
+}
+
+// checkRange checks that r is empty or an axis range of the form
+// "lo,hi", where either bound may be empty.
+func checkRange(r string) error {
+	if r == "" {
+		return nil
+	}
+	lo, hi, ok := strings.Cut(r, ",")
+	if !ok {
+		return fmt.Errorf("bad range %q: want lo,hi", r)
+	}
+	for _, b := range []string{lo, hi} {
+		if b == "" {
+			continue
+		}
+		if _, err := strconv.ParseFloat(b, 64); err != nil {
+			return fmt.Errorf("bad range %q: %s", r, err)
+		}
+	}
+	return nil
+}
+
 func (p *plot) writeTable(w io.Writer) error {
 	for i, hdr := range p.hdrs {
 		if i != 0 {
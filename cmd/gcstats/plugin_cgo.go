@@ -0,0 +1,15 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build (linux || darwin || freebsd) && cgo
+
+package main
+
+import "plugin"
+
+// openPlugin opens the Go plugin at path, running its init functions.
+func openPlugin(path string) error {
+	_, err := plugin.Open(path)
+	return err
+}
@@ -0,0 +1,14 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !((linux || darwin || freebsd) && cgo)
+
+package main
+
+import "errors"
+
+// openPlugin reports that Go plugins are not supported.
+func openPlugin(path string) error {
+	return errors.New("plugins are not supported on this platform")
+}
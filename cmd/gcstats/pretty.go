@@ -4,7 +4,10 @@
 
 package main
 
-import "fmt"
+import (
+	"strconv"
+	"strings"
+)
 
 func ns(ns float64) string {
 	for _, d := range []struct {
@@ -13,11 +16,7 @@ func ns(ns float64) string {
 	}{{"ns", 1000}, {"µs", 1000}, {"ms", 1000}, {"sec", 60}, {"min", 60}, {"hour", 0}} {
 		if ns < d.div || d.div == 0 {
 			// Keep at least three digits.
-			if ns <= 999 {
-				return fmt.Sprintf("%1.3g%s", ns, d.unit)
-			} else {
-				return fmt.Sprintf("%d%s", int64(ns+0.5), d.unit)
-			}
+			return formatNum(ns, 3) + d.unit
 		}
 		ns /= d.div
 	}
@@ -25,5 +24,36 @@ func ns(ns float64) string {
 }
 
 func pct(x float64) string {
-	return fmt.Sprintf("%.2g%%", 100*x)
+	return formatNum(100*x, 2) + "%"
+}
+
+// mb formats a number of bytes in megabytes.
+func mb(bytes float64) string {
+	return formatNum(bytes/(1<<20), 3) + "MB"
+}
+
+// dollars formats an amount of money in dollars to the cent.
+func dollars(x float64) string {
+	if x > 0 && x < 0.005 {
+		return "<$0.01"
+	}
+	return "$" + strconv.FormatFloat(x, 'f', 2, 64)
+}
+
+// formatNum formats x with the precision given by -decimals or
+// -digits, or with def significant digits if neither is set.
+func formatNum(x float64, def int) string {
+	if *flagDecimals >= 0 {
+		return strconv.FormatFloat(x, 'f', *flagDecimals, 64)
+	}
+	digits := def
+	if *flagDigits > 0 {
+		digits = *flagDigits
+	}
+	s := strconv.FormatFloat(x, 'g', digits, 64)
+	if x >= 1 && strings.Contains(s, "e") {
+		// Avoid printing, for example, 100% as "1e+02%".
+		s = strconv.FormatFloat(x, 'f', 0, 64)
+	}
+	return s
 }
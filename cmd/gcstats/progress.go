@@ -0,0 +1,86 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// progress displays the progress of a long operation on stderr. A
+// nil *progress displays nothing, so operations can report progress
+// unconditionally.
+type progress struct {
+	label string
+	total int64 // 0 if unknown
+	n     int64
+	last  time.Time
+}
+
+// progressWidth is the width of the progress bar in characters.
+const progressWidth = 40
+
+// newProgress returns a progress indicator for an operation with
+// total units of work, or 0 if the total is unknown. It returns nil
+// if stderr isn't a terminal.
+func newProgress(label string, total int64) *progress {
+	fi, err := os.Stderr.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	return &progress{label: label, total: total}
+}
+
+// add records n more units of completed work.
+func (p *progress) add(n int64) {
+	if p == nil {
+		return
+	}
+	p.n += n
+	// Limit the update rate so displaying progress doesn't slow
+	// down the operation.
+	if now := time.Now(); now.Sub(p.last) >= 100*time.Millisecond {
+		p.last = now
+		p.print()
+	}
+}
+
+func (p *progress) print() {
+	if p.total <= 0 {
+		fmt.Fprintf(os.Stderr, "\r%s: %d MB", p.label, p.n>>20)
+		return
+	}
+	frac := float64(p.n) / float64(p.total)
+	if frac > 1 {
+		frac = 1
+	}
+	bar := int(frac * progressWidth)
+	fmt.Fprintf(os.Stderr, "\r%s: [%s%s] %3.0f%%", p.label, strings.Repeat("=", bar), strings.Repeat(" ", progressWidth-bar), 100*frac)
+}
+
+// done erases the progress indicator.
+func (p *progress) done() {
+	if p == nil || p.last.IsZero() {
+		return
+	}
+	fmt.Fprint(os.Stderr, "\r\x1b[K")
+	p.last = time.Time{}
+}
+
+// progressReader is an io.Reader that reports the number of bytes
+// read from r to p.
+type progressReader struct {
+	r io.Reader
+	p *progress
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.p.add(int64(n))
+	return n, err
+}
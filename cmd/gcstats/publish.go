@@ -0,0 +1,60 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/gcstatsbus"
+)
+
+// publish parses input and publishes a record for each GC cycle to
+// dest, which is either a nats:// URL or "-" for JSON lines on
+// stdout. If follow is true and input is a regular file, it polls
+// for new cycles every interval until killed.
+func publish(input io.Reader, dest string, follow bool, interval time.Duration) {
+	var sink gcstatsbus.Sink
+	if dest == "-" {
+		sink = gcstatsbus.NewJSONSink(os.Stdout)
+	} else {
+		var err error
+		if sink, err = gcstatsbus.DialNATS(dest); err != nil {
+			fmt.Fprintf(os.Stderr, "error connecting to %s: %s\n", dest, err)
+			os.Exit(1)
+		}
+	}
+
+	p := newParser(gcstats.NewParser(input))
+	st := gcstatsbus.NewStreamer(p, sink)
+	st.Labels = flagLabels.Map()
+	if f, ok := input.(*os.File); ok && f != os.Stdin {
+		st.Source = filepath.Base(f.Name())
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+			p.Follow = follow
+		}
+	}
+
+	for {
+		for st.Next() {
+		}
+		if err := st.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "error publishing log: %s\n", err)
+			os.Exit(1)
+		}
+		if !p.Follow {
+			break
+		}
+		time.Sleep(interval)
+	}
+	if err := sink.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "error publishing log: %s\n", err)
+		os.Exit(1)
+	}
+}
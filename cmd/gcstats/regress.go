@@ -0,0 +1,54 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"math"
+
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+)
+
+// linearFit is a least-squares fit of y = slope*x + intercept.
+type linearFit struct {
+	n                int
+	slope, intercept float64
+
+	// r2 is the coefficient of determination: the fraction of the
+	// variance of y explained by the fit.
+	r2 float64
+
+	// slopeCI is the half-width of the 95% confidence interval of
+	// the slope. It is NaN if there are fewer than 3 points.
+	slopeCI float64
+}
+
+// fitLine returns the least-squares line through the points
+// (xs[i], ys[i]).
+func fitLine(xs, ys []float64) linearFit {
+	n := len(xs)
+	var mx, my float64
+	for i := range xs {
+		mx += xs[i]
+		my += ys[i]
+	}
+	mx, my = mx/float64(n), my/float64(n)
+	var sxx, sxy, syy float64
+	for i := range xs {
+		dx, dy := xs[i]-mx, ys[i]-my
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	fit := linearFit{n: n, slope: sxy / sxx}
+	fit.intercept = my - fit.slope*mx
+	sse := syy - fit.slope*sxy
+	fit.r2 = 1 - sse/syy
+	fit.slopeCI = math.NaN()
+	if n > 2 {
+		se := math.Sqrt(math.Max(0, sse) / float64(n-2) / sxx)
+		fit.slopeCI = stats.InvCDF(stats.TDist{V: float64(n - 2)})(0.975) * se
+	}
+	return fit
+}
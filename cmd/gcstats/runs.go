@@ -0,0 +1,99 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/internal/go-moremath/vec"
+)
+
+// parseRuns parses each of the GC traces at paths, which are
+// typically repeated runs of the same workload. It exits on error.
+func parseRuns(paths []string, useMmap, skipWarmup bool) []*gcstats.GcStats {
+	var runs []*gcstats.GcStats
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		s, err := parseInput(f, useMmap)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: error parsing log: %s\n", path, err)
+			os.Exit(1)
+		}
+		if len(s.Phases()) == 0 {
+			fmt.Fprintf(os.Stderr, "%s: no GC recorded\n", path)
+			os.Exit(1)
+		}
+		if skipWarmup {
+			s = s.SkipCycles(s.Warmup())
+		}
+		runs = append(runs, s)
+	}
+	return runs
+}
+
+// addBand adds series for the mean, minimum, and maximum across runs
+// of f(run, x) at each x value of p. These are plotted as a line and
+// a band by the --bands option.
+func (p *plot) addBand(label string, runs int, f func(run int, x float64) float64) {
+	vals := make([][]float64, runs)
+	for run := range vals {
+		vals[run] = vec.Map(func(x float64) float64 { return f(run, x) }, p.cols[0])
+	}
+	mean, lo, hi := make([]float64, len(p.cols[0])), make([]float64, len(p.cols[0])), make([]float64, len(p.cols[0]))
+	for i := range mean {
+		lo[i], hi[i] = math.Inf(1), math.Inf(-1)
+		for run := range vals {
+			v := vals[run][i]
+			mean[i] += v / float64(runs)
+			lo[i], hi[i] = math.Min(lo[i], v), math.Max(hi[i], v)
+		}
+	}
+	p.addColumn(label, mean)
+	p.addColumn(label+" min", lo)
+	p.addColumn(label+" max", hi)
+}
+
+// doMMURuns plots the mean MMU across runs with a band from the
+// minimum to the maximum MMU of any run.
+func doMMURuns(runs []*gcstats.GcStats) {
+	windows := vec.Logspace(-3, 0, samples, 10)
+	plot := newPlot(granularityLabel(), "mutator utilization", windows, "--style", "mmu", "--bands")
+	plot.addBand("MMU", len(runs), func(run int, window float64) float64 {
+		return computeMMU(runs[run], int(window*1e9))
+	})
+	showPlot(plot)
+}
+
+// doMUTRuns is like doMUT, but plots the mean of each percentile
+// curve across runs with a band from the minimum to the maximum.
+func doMUTRuns(runs []*gcstats.GcStats) {
+	windows := vec.Logspace(-3, 0, samples, 10)
+	muds := make([]map[float64]*gcstats.MUD, len(runs))
+	prog := newProgress("computing MUDs", int64(len(windows)*len(runs)))
+	for i, s := range runs {
+		muds[i] = make(map[float64]*gcstats.MUD)
+		for _, window := range windows {
+			muds[i][window] = computeMUD(s, int(window*1e9))
+			prog.add(1)
+		}
+	}
+	prog.done()
+
+	plot := newPlot(granularityLabel(), "mutator utilization", windows, "--style", "mut", "--bands")
+	for _, c := range mutPercentiles {
+		plot.addBand(c.label, len(runs), func(run int, x float64) float64 {
+			return muds[run][x].InvCDF(c.x)
+		})
+	}
+	showPlot(plot)
+}
@@ -0,0 +1,105 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+)
+
+// doSched correlates the scheduler trace samples interleaved with the
+// GC trace of s with GC activity. It compares the runnable goroutines
+// and threads in samples taken during GC cycles against those taken
+// between cycles, and reports whether the sampling intervals that
+// contain STW pauses also have run queue spikes, which suggests that
+// the pauses delayed runnable goroutines.
+func doSched(s *gcstats.GcStats) {
+	samples := s.Sched()
+	if len(samples) < 2 {
+		fmt.Fprintln(os.Stderr, "need at least 2 scheduler trace samples; run the program with GODEBUG=gctrace=1,schedtrace=<ms>")
+		os.Exit(1)
+	}
+
+	// Classify each sample by whether it was taken during a GC
+	// cycle, before concurrent sweep.
+	phases := s.Phases()
+	inGC := func(t int64) bool {
+		i := sort.Search(len(phases), func(i int) bool { return phases[i].Begin > t }) - 1
+		return i >= 0 && phases[i].Kind != gcstats.PhaseSweep && t < phases[i].Begin+max(0, phases[i].Duration)
+	}
+	var gcRun, otherRun, gcThreads, otherThreads stats.Sample
+	var all stats.Sample
+	for _, ss := range samples {
+		run, threads := float64(ss.Runnable()), float64(ss.Threads)
+		all.Xs = append(all.Xs, run)
+		if inGC(ss.Time) {
+			gcRun.Xs, gcThreads.Xs = append(gcRun.Xs, run), append(gcThreads.Xs, threads)
+		} else {
+			otherRun.Xs, otherThreads.Xs = append(otherRun.Xs, run), append(otherThreads.Xs, threads)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "\tsamples\trunnable mean\tmax\tthreads mean\tmax\t\n")
+	row := func(label string, run, threads stats.Sample) {
+		if len(run.Xs) == 0 {
+			fmt.Fprintf(w, "%s\t0\t-\t-\t-\t-\t\n", label)
+			return
+		}
+		_, runMax := run.Bounds()
+		_, threadMax := threads.Bounds()
+		fmt.Fprintf(w, "%s\t%d\t%.1f\t%d\t%.1f\t%d\t\n", label, len(run.Xs), run.Mean(), int(runMax), threads.Mean(), int(threadMax))
+	}
+	row("during GC", gcRun, gcThreads)
+	row("between GCs", otherRun, otherThreads)
+	w.Flush()
+
+	// Attribute each pause to the sampling interval that ends
+	// with the first sample at or after it begins.
+	stwByInterval := make([]float64, len(samples))
+	for stop := range s.StopsSeq() {
+		i := sort.Search(len(samples), func(i int) bool { return samples[i].Time >= stop.Begin })
+		if i > 0 && i < len(samples) {
+			stwByInterval[i] += float64(stop.Duration)
+		}
+	}
+	// A spike is a sample in the top 5% of runnable goroutines.
+	all.Sort()
+	threshold := math.Max(1, all.Percentile(0.95))
+	var paused, pausedSpikes, spikes int
+	var stws, runs []float64
+	for i := 1; i < len(samples); i++ {
+		run := float64(samples[i].Runnable())
+		spike := run >= threshold
+		if spike {
+			spikes++
+		}
+		if stwByInterval[i] > 0 {
+			paused++
+			if spike {
+				pausedSpikes++
+			}
+		}
+		stws, runs = append(stws, stwByInterval[i]), append(runs, run)
+	}
+	intervals := len(samples) - 1
+	fmt.Println()
+	fmt.Printf("Run queue spikes (at least %d runnable goroutines): %d of %d intervals (%s)\n", int(threshold), spikes, intervals, pct(float64(spikes)/float64(intervals)))
+	if paused == 0 {
+		fmt.Println("No sampling intervals contain STW pauses")
+		return
+	}
+	fmt.Printf("Intervals with STW pauses that end in a spike: %d of %d (%s)\n", pausedSpikes, paused, pct(float64(pausedSpikes)/float64(paused)))
+	if fit := fitLine(stws, runs); !math.IsNaN(fit.r2) {
+		r := math.Copysign(math.Sqrt(fit.r2), fit.slope)
+		fmt.Printf("Correlation of STW time and runnable goroutines per interval: r=%.2f\n", r)
+	}
+}
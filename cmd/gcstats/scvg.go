@@ -0,0 +1,81 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// doScvg reports the memory the scavenger returned to the OS over the
+// run of s against the heap in use, to explain why the heap can shrink
+// without the process's RSS shrinking with it.
+func doScvg(s *gcstats.GcStats) {
+	samples := s.Scavenges()
+	if len(samples) == 0 {
+		fmt.Fprintln(os.Stderr, "no scavenger samples; the trace has no scvg lines with heap sizes")
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "after GC\ttime\tin use\tidle\treleased\tretained\t\n")
+	for _, ss := range samples {
+		t := "-"
+		if s.HaveProgTimes() {
+			t = ns(float64(ss.Time))
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t\n", ss.GC, t, mb(float64(ss.InUse)), mb(float64(ss.Idle)), mb(float64(ss.Released)), mb(float64(ss.Consumed)))
+	}
+	w.Flush()
+
+	// Compare the drop in heap in use from its peak with the
+	// drop in memory retained from the OS.
+	first, last := samples[0], samples[len(samples)-1]
+	var peak gcstats.ScvgSample
+	for _, ss := range samples {
+		if ss.InUse >= peak.InUse {
+			peak = ss
+		}
+	}
+	fmt.Println()
+	fmt.Printf("Heap in use: %s -> %s (peak %s after GC %d)\n", mb(float64(first.InUse)), mb(float64(last.InUse)), mb(float64(peak.InUse)), peak.GC)
+	fmt.Printf("Retained from the OS: %s -> %s\n", mb(float64(first.Consumed)), mb(float64(last.Consumed)))
+	fmt.Printf("Released to the OS: %s of %s obtained\n", mb(float64(last.Released)), mb(float64(last.Sys)))
+	shrank := peak.InUse - last.InUse
+	returned := peak.Consumed - last.Consumed
+	if shrank > 0 {
+		fmt.Printf("Since the peak, the heap in use shrank by %s and retained memory by %s.\n", mb(float64(shrank)), mb(float64(returned)))
+		if returned < shrank/2 {
+			fmt.Printf("%s of the heap is idle but not yet returned to the OS. The scavenger\n", mb(float64(last.Idle-last.Released)))
+			fmt.Printf("releases idle heap memory only after it has gone unused for several\n")
+			fmt.Printf("minutes, so RSS lags behind the heap in use.\n")
+		}
+	}
+
+	if !*flagShow {
+		return
+	}
+	if !s.HaveProgTimes() {
+		fmt.Fprintln(os.Stderr, "plotting scavenger samples requires program execution times")
+		return
+	}
+	xs := make([]float64, len(samples))
+	var inUse, retained, released []float64
+	for i, ss := range samples {
+		xs[i] = xAxis.x(ss.Time)
+		inUse = append(inUse, float64(ss.InUse)/(1<<20))
+		retained = append(retained, float64(ss.Consumed)/(1<<20))
+		released = append(released, float64(ss.Released)/(1<<20))
+	}
+	plot := newPlot(xAxis.label(), "MB", xs, append(xAxis.args(), "--style", "memory")...)
+	plot.addColumn("in use", inUse)
+	plot.addColumn("retained", retained)
+	plot.addColumn("released", released)
+	addTimeMarkers(plot, s)
+	showPlot(plot)
+}
@@ -0,0 +1,216 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+)
+
+// summary accumulates summary statistics of a GC trace. It is
+// updated incrementally as phases are added to the trace.
+type summary struct {
+	// nphases is the number of phases incorporated into the
+	// summary.
+	nphases int
+
+	// pauses are the STW pauses and clockByKind are samples of
+	// phase durations, which print sorts.
+	pauses      gcstats.Pauses
+	clockByKind map[gcstats.PhaseKind]*stats.Sample
+
+	// ci indicates that percentiles should be printed with
+	// bootstrap confidence intervals.
+	ci bool
+}
+
+func doSummary(s *gcstats.GcStats, ci bool) {
+	sum := summary{ci: ci}
+	sum.update(s)
+	sum.print(s)
+}
+
+// update incorporates phases added to s since the last call to
+// update.
+func (sum *summary) update(s *gcstats.GcStats) {
+	phases := s.Phases()[sum.nphases:]
+	sum.nphases += len(phases)
+	sum.pauses.Add(phases)
+
+	if sum.clockByKind == nil {
+		sum.clockByKind = make(map[gcstats.PhaseKind]*stats.Sample)
+	}
+	for _, phase := range phases {
+		if phase.Duration == -1 {
+			continue
+		}
+		sample := sum.clockByKind[phase.Kind]
+		if sample == nil {
+			sample = new(stats.Sample)
+			sum.clockByKind[phase.Kind] = sample
+		}
+		sample.Xs = append(sample.Xs, float64(phase.Duration))
+		sample.Sorted = false
+	}
+}
+
+func (sum *summary) print(s *gcstats.GcStats) {
+	// Pause time: Max, 99th %ile, 95th %ile, mean
+	// Phase time distributions
+	// Mutator utilization
+	// 50ms mutator utilization: Min, 1st %ile, 5th %ile
+	if env := s.Environment(); env != (gcstats.Environment{}) {
+		fmt.Print("Environment: ", env, "\n\n")
+	}
+	pauseTimes := stats.Sample{Xs: sum.pauses.Durations(), Sorted: true}
+	fmt.Print("STW: max=", ns(pauseTimes.Percentile(1)), " ", sum.pctiles(pauseTimes, 1, ns, .99, .95), " mean=", ns(pauseTimes.Mean()), "\n")
+
+	fmt.Println()
+	for kind := gcstats.PhaseSweepTerm; kind <= gcstats.PhaseMultiple; kind++ {
+		clock := sum.clockByKind[kind]
+		if clock == nil {
+			continue
+		}
+		if !clock.Sorted {
+			slices.Sort(clock.Xs)
+			clock.Sorted = true
+		}
+		min, max := clock.Bounds()
+		if min == 0 && max == 0 {
+			continue
+		}
+		fmt.Printf("%-10s max=%s %s mean=%s stddev=%s\n", kind.String()[5:]+":", ns(clock.Percentile(1)), sum.pctiles(*clock, 1, ns, .99, .95), ns(clock.Mean()), ns(clock.StdDev()))
+	}
+
+	printHeapSummary(s)
+	printAssistSummary(s)
+
+	if s.HaveProgTimes() {
+		fmt.Println()
+		fmt.Print("Mean mutator utilization: ", pct(s.MutatorUtilization()), "\n")
+		mud := computeWallMUD(s, 10e6)
+		fmt.Print("10ms mutator utilization: min=", pct(mud.InvCDF(0)), " 1%ile=", pct(mud.InvCDF(0.01)))
+		if sum.ci {
+			// Sample windows at a quarter-window step and
+			// resample runs of overlapping windows.
+			lo, hi := gcstats.PercentileCI(s.WindowedMutatorUtilization(10e6, 2.5e6), []float64{0.01, 0.05}, 4)
+			fmt.Print(ciSuffix(lo[0], hi[0], pct))
+			fmt.Print(" 5%ile=", pct(mud.InvCDF(0.05)), ciSuffix(lo[1], hi[1], pct), "\n")
+		} else {
+			fmt.Print(" 5%ile=", pct(mud.InvCDF(0.05)), "\n")
+		}
+		if begin, dur := s.LongestBlackout(*flagBlackout); dur > 0 {
+			fmt.Print("Longest blackout")
+			if *flagBlackout > 0 {
+				fmt.Print(" (utilization <= ", pct(*flagBlackout), ")")
+			}
+			fmt.Print(": ", ns(float64(dur)), " at ", ns(float64(begin)), "\n")
+		}
+	}
+}
+
+// printHeapSummary prints statistics of the heap sizes of s, if the
+// trace records them. If its cycles weren't paced by heap growth, it
+// says so and omits the heap goal, which is then meaningless.
+func printHeapSummary(s *gcstats.GcStats) {
+	var live, goal, garbage stats.Sample
+	var alloc float64
+	cycles := s.Cycles()
+	pacing := s.Pacing()
+	if pacing != gcstats.PacingHeap && len(cycles) > 0 {
+		fmt.Println()
+		fmt.Print("GC pacing: ", pacing, "; ", pacingReason(pacing), "\n")
+	}
+	for i, c := range cycles {
+		if c.HeapTrigger == 0 {
+			continue
+		}
+		live.Xs = append(live.Xs, float64(c.HeapLive))
+		if c.HeapGoal != 0 && pacing == gcstats.PacingHeap {
+			goal.Xs = append(goal.Xs, float64(c.HeapGoal))
+		}
+		garbage.Xs = append(garbage.Xs, math.Max(0, 1-c.LiveRatio()))
+		// The heap grows from the live heap of this cycle to
+		// the trigger of the next.
+		if i+1 < len(cycles) && cycles[i+1].HeapTrigger >= c.HeapLive {
+			alloc += float64(cycles[i+1].HeapTrigger - c.HeapLive)
+		}
+	}
+	if len(live.Xs) == 0 {
+		return
+	}
+
+	fmt.Println()
+	minMeanMax := func(xs stats.Sample) string {
+		min, max := xs.Bounds()
+		return "min=" + mb(min) + " mean=" + mb(xs.Mean()) + " max=" + mb(max)
+	}
+	fmt.Print("Live heap: ", minMeanMax(live), "\n")
+	if len(goal.Xs) > 0 {
+		fmt.Print("Heap goal: ", minMeanMax(goal), "\n")
+	}
+	fmt.Print("Allocated between GCs: total=", mb(alloc), " mean=", mb(alloc/float64(max(1, len(cycles)-1))), "\n")
+	fmt.Print("Mean garbage fraction: ", pct(garbage.Mean()), "\n")
+}
+
+// printAssistSummary prints the mutator CPU time lost to mark assists,
+// if the trace records it. Assists don't stop the world, so they
+// don't appear in pause statistics, but they delay the goroutines
+// that perform them.
+func printAssistSummary(s *gcstats.GcStats) {
+	var total, worst int64
+	var worstN, n int
+	for _, c := range s.Cycles() {
+		if c.AssistCPU+c.BackgroundCPU+c.IdleCPU == 0 {
+			// The trace doesn't report mark CPU.
+			continue
+		}
+		n++
+		total += c.AssistCPU
+		if c.AssistCPU > worst {
+			worst, worstN = c.AssistCPU, c.N
+		}
+	}
+	if n == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Print("Mutator time lost to assists: total=", ns(float64(total)), " mean=", ns(float64(total)/float64(n)), "/cycle")
+	if worst > 0 {
+		fmt.Printf(" worst=%s (GC %d)", ns(float64(worst)), worstN)
+	}
+	fmt.Println()
+}
+
+// pctiles formats the percentiles pctiles of sorted sample xs as
+// "99%ile=x 95%ile=y ...", with bootstrap confidence intervals if sum.ci
+// is set. block is passed to PercentileCI.
+func (sum *summary) pctiles(xs stats.Sample, block int, format func(float64) string, pctiles ...float64) string {
+	var lo, hi []float64
+	if sum.ci {
+		lo, hi = gcstats.PercentileCI(xs.Xs, pctiles, block)
+	}
+	var out string
+	for i, p := range pctiles {
+		if i > 0 {
+			out += " "
+		}
+		out += fmt.Sprintf("%g%%ile=", p*100) + format(xs.Percentile(p))
+		if sum.ci {
+			out += ciSuffix(lo[i], hi[i], format)
+		}
+	}
+	return out
+}
+
+// ciSuffix formats the confidence interval [lo, hi].
+func ciSuffix(lo, hi float64, format func(float64) string) string {
+	return "[" + format(lo) + "," + format(hi) + "]"
+}
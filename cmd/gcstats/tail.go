@@ -0,0 +1,58 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// doTail fits a model to the tail of the STW pause distribution and
+// extrapolates extreme percentiles from it.
+func doTail(s *gcstats.GcStats) {
+	var pauses []int64
+	for _, stop := range s.Stops() {
+		pauses = append(pauses, stop.Duration)
+	}
+	m, qs, err := gcstats.EstimateTail(pauses, []float64{0.99, 0.999, 0.9999, 1})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cannot fit pause tail: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Generalized Pareto fit to the %d longest of %d pauses (over %s):\n", m.Exceedances, m.N, ns(m.Threshold))
+	fmt.Printf("  shape=%.3f scale=%s\n", m.Shape, ns(m.Scale))
+	fmt.Printf("  observed max=%s\n", ns(float64(s.MaxPause())))
+	for _, q := range qs {
+		label := fmt.Sprintf("%g%%ile", q.P*100)
+		if q.P == 1 {
+			label = "max"
+			if math.IsInf(q.Value, 1) {
+				fmt.Printf("  %-9s unbounded (heavy tail)\n", label)
+				continue
+			}
+		}
+		ci := "-"
+		if !math.IsNaN(q.Low) {
+			ci = ns(q.Low) + "-" + bound(q.High)
+		}
+		note := ""
+		if q.P > 1-1/float64(m.N) {
+			note = " (extrapolated)"
+		}
+		fmt.Printf("  %-9s %-8s 95%% CI %s%s\n", label, ns(q.Value), ci, note)
+	}
+}
+
+// bound formats an upper confidence bound, which may be infinite.
+func bound(x float64) string {
+	if math.IsInf(x, 1) {
+		return "∞"
+	}
+	return ns(x)
+}
@@ -0,0 +1,153 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/gcstats/report"
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+)
+
+// templateData is the data passed to a -template report.
+type templateData struct {
+	// Summary is the summary served as summary.json by -http.
+	Summary *report.Summary
+
+	// Cycles has a record for each GC cycle, as printed by the
+	// cycles subcommand.
+	Cycles []*cycleRecord
+
+	// Metrics are the metrics checked by the ci subcommand.
+	Metrics map[string]float64
+
+	// Warmup is the number of warm-up cycles at the beginning of
+	// the trace.
+	Warmup int
+
+	// Modes are the modes of the STW pause distribution.
+	Modes []gcstats.Mode
+
+	// Tail is a model of the tail of the STW pause distribution,
+	// or nil if there are too few pauses to fit one.
+	Tail *gcstats.TailModel
+
+	s      *gcstats.GcStats
+	pauses stats.Sample
+}
+
+// PausePercentile returns the p'th percentile STW pause time in
+// nanoseconds, where 0 <= p <= 1.
+func (d *templateData) PausePercentile(p float64) float64 {
+	return d.pauses.Percentile(p)
+}
+
+// MMU returns the minimum mutator utilization over windows of the
+// given duration, such as "10ms".
+func (d *templateData) MMU(window string) (float64, error) {
+	w, err := d.window(window)
+	if err != nil {
+		return 0, err
+	}
+	return d.s.MMU(w), nil
+}
+
+// MU returns the p'th percentile mutator utilization over windows of
+// the given duration, where 0 <= p <= 1.
+func (d *templateData) MU(window string, p float64) (float64, error) {
+	w, err := d.window(window)
+	if err != nil {
+		return 0, err
+	}
+	return computeWallMUD(d.s, w).InvCDF(p), nil
+}
+
+func (d *templateData) window(window string) (int, error) {
+	if !d.s.HaveProgTimes() {
+		return 0, fmt.Errorf("mutator utilization requires program execution times")
+	}
+	w, err := time.ParseDuration(window)
+	if err != nil {
+		return 0, err
+	}
+	if w <= 0 {
+		return 0, fmt.Errorf("window must be positive")
+	}
+	return int(w), nil
+}
+
+// templateFuncs are the functions available to a -template report in
+// addition to the standard text/template functions.
+var templateFuncs = template.FuncMap{
+	// ns formats a number of nanoseconds.
+	"ns": func(x interface{}) (string, error) {
+		f, err := toFloat(x)
+		return ns(f), err
+	},
+	// pct formats a fraction as a percentage.
+	"pct": func(x interface{}) (string, error) {
+		f, err := toFloat(x)
+		return pct(f), err
+	},
+	// mb formats a number of bytes in megabytes.
+	"mb": func(x interface{}) (string, error) {
+		f, err := toFloat(x)
+		return mb(f), err
+	},
+}
+
+func toFloat(x interface{}) (float64, error) {
+	switch x := x.(type) {
+	case int:
+		return float64(x), nil
+	case int64:
+		return float64(x), nil
+	case float64:
+		return x, nil
+	case *int64:
+		if x != nil {
+			return float64(*x), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot format %v as a number", x)
+}
+
+// doTemplate executes the text/template in path against s and writes
+// the result to stdout.
+func doTemplate(s *gcstats.GcStats, path string) {
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).ParseFiles(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	d := &templateData{
+		Summary: report.NewSummary(s),
+		Cycles:  cycleRecords(s),
+		Metrics: ciMetricValues(s),
+		Warmup:  s.Warmup(),
+		s:       s,
+	}
+	var durs []int64
+	for _, stop := range s.Stops() {
+		durs = append(durs, stop.Duration)
+		d.pauses.Xs = append(d.pauses.Xs, float64(stop.Duration))
+	}
+	d.pauses.Sort()
+	d.Modes = gcstats.Modes(durs)
+	if tail, err := gcstats.FitTail(durs); err == nil {
+		d.Tail = &tail
+	}
+
+	if err := tmpl.Execute(os.Stdout, d); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// timeAxis maps execution times to the X values of plots over
+// execution time, according to -timeaxis.
+type timeAxis struct {
+	// kind is "rel" for seconds since program start, "wall" for
+	// wall-clock time in Unix seconds, or "gc" for GC cycle
+	// number.
+	kind string
+
+	// start is the wall-clock time the program started, for
+	// "wall".
+	start time.Time
+
+	// begins and ns are the begin times and numbers of each GC
+	// cycle, for "gc".
+	begins []int64
+	ns     []int
+}
+
+// xAxis is the time axis of plots over execution time.
+var xAxis = &timeAxis{kind: "rel"}
+
+// setTimeAxis sets xAxis to an axis of the given kind for trace s
+// read from input. Wall-clock times are anchored at start, in RFC 3339
+// format, or, if start is "", so that the trace ends at the
+// modification time of input.
+func setTimeAxis(s *gcstats.GcStats, input io.Reader, kind, start string) error {
+	a := &timeAxis{kind: kind}
+	switch a.kind {
+	case "rel":
+	case "wall":
+		var err error
+		if a.start, err = traceStart(s, input, start); err != nil {
+			return err
+		}
+	case "gc":
+		phases := s.Phases()
+		for i, p := range phases {
+			if i == 0 || p.N != phases[i-1].N {
+				a.begins = append(a.begins, p.Begin)
+				a.ns = append(a.ns, p.N)
+			}
+		}
+	default:
+		return fmt.Errorf("-timeaxis must be rel, wall, or gc")
+	}
+	xAxis = a
+	return nil
+}
+
+// traceStart returns the wall-clock time the program that produced
+// trace s started: start, in RFC 3339 format, or, if start is "", the
+// time that makes the trace end at the modification time of input.
+func traceStart(s *gcstats.GcStats, input io.Reader, start string) (time.Time, error) {
+	if start != "" {
+		t, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("bad -start: %s", err)
+		}
+		return t, nil
+	}
+	f, ok := input.(*os.File)
+	if !ok || f == os.Stdin {
+		return time.Time{}, fmt.Errorf("wall-clock times require -start when reading stdin")
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return time.Time{}, err
+	}
+	last := s.Phases()[len(s.Phases())-1]
+	return fi.ModTime().Add(-time.Duration(last.Begin + last.Duration)), nil
+}
+
+// label returns the label of the axis.
+func (a *timeAxis) label() string {
+	switch a.kind {
+	case "wall":
+		return "wall-clock time"
+	case "gc":
+		return "GC cycle"
+	}
+	return "execution time"
+}
+
+// x returns the X value of execution time t in nanoseconds.
+func (a *timeAxis) x(t int64) float64 {
+	switch a.kind {
+	case "wall":
+		return float64(a.start.UnixNano()+t) / 1e9
+	case "gc":
+		// Interpolate between the beginnings of cycles.
+		i := sort.Search(len(a.begins), func(i int) bool { return a.begins[i] > t }) - 1
+		if i < 0 {
+			return float64(a.ns[0])
+		}
+		x := float64(a.ns[i])
+		if i+1 < len(a.begins) {
+			x += float64(t-a.begins[i]) / float64(a.begins[i+1]-a.begins[i])
+		}
+		return x
+	}
+	return float64(t) / 1e9
+}
+
+// args returns the plot.py arguments for the axis.
+func (a *timeAxis) args() []string {
+	return []string{"--xaxis", a.kind}
+}
+
+// addTimeMarkers shades the GC cycles and STW pauses of s in plot p
+// over execution time, as selected by -markers.
+func addTimeMarkers(p *plot, s *gcstats.GcStats) {
+	for _, m := range strings.Split(*flagMarkers, ",") {
+		switch m {
+		case "gc":
+			// Mark each cycle up to the end of mark
+			// termination, excluding concurrent sweep.
+			// Cycles not paced by the heap trigger are
+			// shaded by their cause.
+			labels := make(map[int]string)
+			for _, c := range s.Cycles() {
+				if c.Cause != gcstats.CausePaced {
+					labels[c.N] = "GC (" + c.Cause.String() + ")"
+				}
+			}
+			var begin, end int64
+			phases := s.Phases()
+			for i, ph := range phases {
+				if i == 0 || ph.N != phases[i-1].N {
+					begin = ph.Begin
+				}
+				if ph.Kind == gcstats.PhaseSweep || ph.Duration < 0 {
+					continue
+				}
+				end = ph.End()
+				if i+1 == len(phases) || phases[i+1].N != ph.N || phases[i+1].Kind == gcstats.PhaseSweep {
+					label := labels[ph.N]
+					if label == "" {
+						label = "GC"
+					}
+					p.addSpan(label, xAxis.x(begin), xAxis.x(end))
+				}
+			}
+		case "stw":
+			for _, stop := range s.Stops() {
+				p.addSpan("STW", xAxis.x(stop.Begin), xAxis.x(stop.End()))
+			}
+		}
+	}
+}
+
+// checkMarkers checks that markers is a valid value of -markers.
+func checkMarkers(markers string) error {
+	for _, m := range strings.Split(markers, ",") {
+		if m != "" && m != "gc" && m != "stw" {
+			return fmt.Errorf("bad -markers %q: want a comma-separated list of gc and stw", markers)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// doWarmup reports the warm-up prefix of s and how the heap goal and
+// GC interval compare before and after it.
+func doWarmup(s *gcstats.GcStats) {
+	cycles := s.Cycles()
+	w := s.Warmup()
+	if w == 0 {
+		fmt.Printf("No warm-up detected in %d cycles.\n", len(cycles))
+		return
+	}
+	fmt.Printf("Warm-up: first %d of %d cycles", w, len(cycles))
+	if s.HaveProgTimes() {
+		fmt.Printf(" (until %s)", ns(float64(cycles[w].Begin)))
+	}
+	fmt.Println()
+
+	// mean returns the mean heap goal of cycles [lo, hi) and the
+	// mean interval from each to the next cycle.
+	mean := func(lo, hi int) (goal, interval float64) {
+		var nIntervals int
+		for i := lo; i < hi; i++ {
+			g := cycles[i].HeapGoal
+			if g == 0 {
+				g = cycles[i].HeapTrigger
+			}
+			goal += float64(g)
+			if i+1 < len(cycles) {
+				interval += float64(cycles[i+1].Begin - cycles[i].Begin)
+				nIntervals++
+			}
+		}
+		return goal / float64(hi-lo), interval / float64(nIntervals)
+	}
+	wGoal, wInterval := mean(0, w)
+	sGoal, sInterval := mean(w, len(cycles))
+	fmt.Printf("  heap goal:  %d MB during warm-up, %d MB after\n", int64(wGoal)>>20, int64(sGoal)>>20)
+	if s.HaveProgTimes() && len(cycles)-w >= 2 {
+		fmt.Printf("  interval:   %s during warm-up, %s after\n", ns(wInterval), ns(sInterval))
+	}
+	fmt.Println("Use -skipwarmup to exclude the warm-up cycles from analyses.")
+}
@@ -0,0 +1,55 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+)
+
+// doWhatIfSTW prints the pause percentiles of s and, if it has
+// program execution times, its mean mutator utilization and MMU at
+// each of the comma-separated windows, next to the same metrics with
+// every STW phase scaled by factor.
+func doWhatIfSTW(s *gcstats.GcStats, factor float64, windows string) {
+	scaled, err := s.ScaleSTW(factor)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "bad -what-if-stw: %s\n", err)
+		os.Exit(2)
+	}
+	ws := parseWindows("-mmu-at", windows)
+
+	pauses := func(s *gcstats.GcStats) stats.Sample {
+		var xs stats.Sample
+		for _, stop := range s.Stops() {
+			xs.Xs = append(xs.Xs, float64(stop.Duration))
+		}
+		xs.Sort()
+		return xs
+	}
+	p1, p2 := pauses(s), pauses(scaled)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "metric\tactual\tSTW x%v\t\n", factor)
+	if len(p1.Xs) > 0 {
+		for _, p := range []struct {
+			label  string
+			pctile float64
+		}{{"max pause", 1}, {"99%ile pause", .99}, {"95%ile pause", .95}, {"50%ile pause", .5}} {
+			fmt.Fprintf(w, "%s\t%s\t%s\t\n", p.label, ns(p1.Percentile(p.pctile)), ns(p2.Percentile(p.pctile)))
+		}
+	}
+	if s.HaveProgTimes() {
+		fmt.Fprintf(w, "mean utilization\t%s\t%s\t\n", pct(s.MutatorUtilization()), pct(scaled.MutatorUtilization()))
+		for _, window := range ws {
+			fmt.Fprintf(w, "%s MMU\t%s\t%s\t\n", window, pct(computeMMU(s, int(window))), pct(computeMMU(scaled, int(window))))
+		}
+	}
+	w.Flush()
+}
@@ -0,0 +1,161 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package analysis is a registry of analyses of GC traces that the
+// gcstats command runs in addition to its own.
+//
+// A package registers its analyses from an init function:
+//
+//	func init() {
+//		analysis.Register(analysis.Analysis{
+//			Name:  "long-marks",
+//			Usage: "Count mark phases longer than 10ms",
+//			Kind:  analysis.Metric,
+//			Compute: func(s *gcstats.GcStats) (interface{}, error) {
+//				n := 0
+//				for _, p := range s.Phases() {
+//					if p.Kind == gcstats.PhaseMark && p.Duration > 10e6 {
+//						n++
+//					}
+//				}
+//				return float64(n), nil
+//			},
+//		})
+//	}
+//
+// The gcstats command adds a flag for each registered analysis, named
+// after it, and loads packages built with -buildmode=plugin from the
+// files listed in the GCSTATS_PLUGINS environment variable, so
+// organizations can add analyses without changing gcstats. Metric
+// analyses are also checked by gcstats ci.
+package analysis
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// Kind is the kind of result an analysis produces.
+type Kind int
+
+const (
+	// Text analyses return a string or a value formatted with
+	// fmt's %v verb, which is printed as is.
+	Text Kind = iota
+
+	// Metric analyses return a float64, which is printed as
+	// "name: value" and compared against the baseline by
+	// gcstats ci. Larger values are regressions unless
+	// LowerIsWorse is set.
+	Metric
+
+	// JSON analyses return a value that is printed as indented
+	// JSON.
+	JSON
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Text:
+		return "text"
+	case Metric:
+		return "metric"
+	case JSON:
+		return "json"
+	}
+	return fmt.Sprintf("Kind(%d)", int(k))
+}
+
+// An Analysis is a named computation over a GC trace.
+type Analysis struct {
+	// Name identifies the analysis. It is also the name of the
+	// gcstats flag that runs it and, for metrics, of the ci
+	// metric, so it must consist of lower-case letters, digits,
+	// '-', and '_', starting with a letter.
+	Name string
+
+	// Usage describes the analysis in the flag's usage message.
+	Usage string
+
+	// Kind is the kind of result returned by Compute.
+	Kind Kind
+
+	// LowerIsWorse indicates that a decrease in a Metric is a
+	// regression.
+	LowerIsWorse bool
+
+	// NeedProgTimes indicates that the analysis requires program
+	// execution times. gcstats skips it for traces without them.
+	NeedProgTimes bool
+
+	// Compute computes the analysis of s.
+	Compute func(s *gcstats.GcStats) (interface{}, error)
+}
+
+var (
+	registryLock sync.Mutex
+	registry     = make(map[string]Analysis)
+)
+
+var nameRE = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// Register adds a to the registry. It panics if a is invalid or an
+// analysis with the same name is already registered.
+func Register(a Analysis) {
+	if !nameRE.MatchString(a.Name) {
+		panic(fmt.Sprintf("analysis: bad name %q", a.Name))
+	}
+	if a.Compute == nil {
+		panic("analysis: " + a.Name + " has no Compute function")
+	}
+	if a.Kind < Text || a.Kind > JSON {
+		panic(fmt.Sprintf("analysis: %s has bad kind %v", a.Name, a.Kind))
+	}
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, dup := registry[a.Name]; dup {
+		panic("analysis: Register called twice for " + a.Name)
+	}
+	registry[a.Name] = a
+}
+
+// All returns the registered analyses sorted by name.
+func All() []Analysis {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	all := make([]Analysis, 0, len(registry))
+	for _, a := range registry {
+		all = append(all, a)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+// Lookup returns the registered analysis named name.
+func Lookup(name string) (Analysis, bool) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	a, ok := registry[name]
+	return a, ok
+}
+
+// Value computes a Metric analysis of s and returns its value.
+func (a Analysis) Value(s *gcstats.GcStats) (float64, error) {
+	if a.Kind != Metric {
+		return 0, fmt.Errorf("analysis %s is not a metric", a.Name)
+	}
+	v, err := a.Compute(s)
+	if err != nil {
+		return 0, err
+	}
+	f, ok := v.(float64)
+	if !ok {
+		return 0, fmt.Errorf("metric %s returned %T, not float64", a.Name, v)
+	}
+	return f, nil
+}
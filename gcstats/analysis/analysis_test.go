@@ -0,0 +1,56 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package analysis
+
+import (
+	"testing"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+func TestRegister(t *testing.T) {
+	s := gcstats.NewFromPhases([]gcstats.Phase{
+		{Begin: 0, Duration: 10, Kind: gcstats.PhaseSweepTerm, Gomaxprocs: 1, GCProcs: 1, STW: true},
+		{Begin: 10, Duration: 90, Kind: gcstats.PhaseSweep, Gomaxprocs: 1},
+	}, 1)
+	Register(Analysis{Name: "phases", Kind: Metric, Compute: func(s *gcstats.GcStats) (interface{}, error) {
+		return float64(len(s.Phases())), nil
+	}})
+	Register(Analysis{Name: "a-text", Compute: func(s *gcstats.GcStats) (interface{}, error) {
+		return "hello", nil
+	}})
+
+	all := All()
+	if len(all) != 2 || all[0].Name != "a-text" || all[1].Name != "phases" {
+		t.Fatalf("want a-text and phases, got %+v", all)
+	}
+	a, ok := Lookup("phases")
+	if !ok {
+		t.Fatalf("phases not found")
+	}
+	if v, err := a.Value(s); err != nil || v != 2 {
+		t.Errorf("want 2, got %v, %v", v, err)
+	}
+	a, _ = Lookup("a-text")
+	if _, err := a.Value(s); err == nil {
+		t.Errorf("want error for Value of text analysis")
+	}
+
+	for _, bad := range []Analysis{
+		{Name: "phases", Compute: a.Compute},
+		{Name: "Bad Name", Compute: a.Compute},
+		{Name: "nocompute"},
+		{Name: "badkind", Kind: JSON + 1, Compute: a.Compute},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("want panic registering %q", bad.Name)
+				}
+			}()
+			Register(bad)
+		}()
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Benchmark is the GC activity during one benchmark in the output of
+// "go test -bench" run with GODEBUG=gctrace=1.
+type Benchmark struct {
+	// Name is the full name of the benchmark as printed by go
+	// test, such as "BenchmarkFoo/bar-8".
+	Name string
+
+	// Result is the benchmark's result, such as
+	// "1000	1234 ns/op", or "" if the benchmark didn't finish.
+	Result string
+
+	// Stats is the GC cycles that began during the benchmark.
+	Stats *GcStats
+}
+
+// ParseBenchLog parses the combined standard output and standard
+// error of "go test -bench" run with GODEBUG=gctrace=1 and attributes
+// each GC cycle to the benchmark that was running when it began.
+//
+// A benchmark is considered to be running from when go test prints
+// its name until it prints its result. The testing package runs each
+// benchmark once before printing its name, so GC cycles during that
+// first run are not attributed to any benchmark.
+//
+// GC traces from other processes, such as the go command itself, may
+// be interleaved with the test's trace. Cycles that don't follow the
+// cycle number sequence are ignored, except that cycle 1 starts a new
+// sequence, such as when go test starts the next test binary. For the
+// most reliable results, run the test binary directly.
+func ParseBenchLog(r io.Reader) ([]*Benchmark, error) {
+	var benchmarks []*Benchmark
+	var cur *Benchmark
+	var p *Parser
+	lastN := 0
+	finish := func(result string) {
+		if cur != nil {
+			cur.Result = result
+			p.stats.setComplete()
+			cur, p = nil, nil
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+
+		// go test prints the benchmark name followed by a tab
+		// before running it, so GC trace lines may follow the
+		// name on the same line.
+		if name, rest, ok := benchName(line); ok {
+			finish("")
+			p = &Parser{stats: &GcStats{progTimes: true}}
+			cur = &Benchmark{Name: name, Stats: p.stats}
+			benchmarks = append(benchmarks, cur)
+			line = rest
+		}
+		if n, ok := gcNumber(line); ok {
+			if n != lastN+1 && n != 1 && lastN != 0 {
+				continue
+			}
+			lastN = n
+		}
+		if cur == nil {
+			continue
+		}
+		if isBenchResult(line) {
+			finish(strings.TrimSpace(line))
+			continue
+		}
+		if strings.HasPrefix(line, "--- FAIL") || line == "PASS" || line == "FAIL" {
+			finish("")
+			continue
+		}
+		if _, err := p.parseLine(line); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	finish("")
+	return benchmarks, nil
+}
+
+// benchName splits a line that begins with a benchmark name into the
+// name and the remainder of the line after the name's tab.
+func benchName(line string) (name, rest string, ok bool) {
+	if !strings.HasPrefix(line, "Benchmark") {
+		return "", "", false
+	}
+	name, rest, ok = strings.Cut(line, "\t")
+	name = strings.TrimRight(name, " ")
+	if !ok || strings.ContainsAny(name, " :") {
+		return "", "", false
+	}
+	return name, rest, true
+}
+
+// isBenchResult reports whether line is the result of a benchmark,
+// such as "   1000	      1234 ns/op".
+func isBenchResult(line string) bool {
+	l := lineScanner{strings.TrimLeft(line, " ")}
+	if _, ok := l.integer(); !ok || !l.literal("\t") {
+		return false
+	}
+	return strings.Contains(l.s, " ns/op")
+}
+
+// gcNumber returns the cycle number of a GC trace line in any
+// supported format.
+func gcNumber(line string) (int, bool) {
+	l := lineScanner{line}
+	if !l.literal("gc") {
+		return 0, false
+	}
+	l.literal(" ")
+	n, ok := l.integer()
+	return int(n), ok
+}
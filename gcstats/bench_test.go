@@ -0,0 +1,56 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseBenchLog(t *testing.T) {
+	const log = `gc 1 @0.001s 1%: 0.01+0.1+0.1+0.1+0.01 ms clock, 0.01+0.1+0+0.1/0.1/0.1+0.01 ms cpu, 4->4->1 MB, 4 MB goal, 4 P
+goos: linux
+goarch: amd64
+BenchmarkAlloc-4   	gc 2 @0.019s 5%: 0.11+1.1+2.2+1.4+0.59 ms clock, 0.22+1.1+0+1.5/1.0/2.1+1.1 ms cpu, 4->4->1 MB, 4 MB goal, 4 P
+gc 3 @0.037s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 12 @0.040s 1%: 0.01+0.1+0.1+0.1+0.01 ms clock, 0.01+0.1+0+0.1/0.1/0.1+0.01 ms cpu, 4->4->1 MB, 4 MB goal, 4 P
+gc 4 @0.052s 7%: 0.038+0.53+0.006+4.9+0.59 ms clock, 0.15+0.53+0+0.83/4.2/3.3+2.3 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+   12265	     10910 ns/op
+gc 5 @0.065s 9%: 0.033+0.51+0.006+4.6+1.4 ms clock, 0.13+0.51+0+0.21/4.0/3.7+5.9 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+BenchmarkNoAlloc-4 	184145521	         0.7296 ns/op
+BenchmarkSub/big-4 	gc 6 @0.080s 9%: 0.033+0.51+0.006+4.6+1.4 ms clock, 0.13+0.51+0+0.21/4.0/3.7+5.9 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+--- FAIL: BenchmarkSub/big-4
+FAIL
+`
+	bs, err := ParseBenchLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name, result string
+		gcs          []int
+	}{
+		{"BenchmarkAlloc-4", "12265	     10910 ns/op", []int{2, 3, 4}},
+		{"BenchmarkNoAlloc-4", "184145521	         0.7296 ns/op", nil},
+		{"BenchmarkSub/big-4", "", []int{6}},
+	}
+	if len(bs) != len(want) {
+		t.Fatalf("want %d benchmarks, got %d", len(want), len(bs))
+	}
+	for i, w := range want {
+		b := bs[i]
+		if b.Name != w.name || b.Result != w.result {
+			t.Errorf("benchmark %d: want %q %q, got %q %q", i, w.name, w.result, b.Name, b.Result)
+		}
+		var gcs []int
+		for _, c := range b.Stats.Cycles() {
+			gcs = append(gcs, c.N)
+		}
+		if !slices.Equal(gcs, w.gcs) {
+			t.Errorf("%s: want cycles %v, got %v", b.Name, w.gcs, gcs)
+		}
+	}
+}
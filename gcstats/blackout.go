@@ -0,0 +1,37 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+// LongestBlackout returns the longest contiguous interval in which
+// the mutator utilization never exceeds threshold, as its beginning
+// and duration in nanoseconds. With a threshold of 0, this is the
+// longest stretch in which the mutator could not run at all, which
+// is the worst delay GC imposed on any request, regardless of window
+// size. Consecutive STW phases are part of the same stretch, as are
+// concurrent phases in which GC left the mutator no more than
+// threshold utilization. If no phase is at or below threshold,
+// LongestBlackout returns a duration of 0.
+//
+// This will panic if the trace does not have program execution times.
+func (s *GcStats) LongestBlackout(threshold float64) (begin, duration int64) {
+	s.requireProgTimes()
+	var cur, curBegin int64
+	in := false
+	for p := range s.AllPhases() {
+		util := 1 - gcProcs(p)/float64(p.Gomaxprocs)
+		if util > threshold {
+			in = false
+			continue
+		}
+		if !in {
+			in, cur, curBegin = true, 0, p.Begin
+		}
+		cur += p.Duration
+		if cur > duration {
+			begin, duration = curBegin, cur
+		}
+	}
+	return
+}
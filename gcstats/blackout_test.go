@@ -0,0 +1,41 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import "testing"
+
+func TestLongestBlackout(t *testing.T) {
+	s := NewFromPhases([]Phase{
+		{Begin: 0, Duration: 10, Kind: PhaseSweep, Gomaxprocs: 2},
+		{Begin: 10, Duration: 3, Kind: PhaseSweepTerm, Gomaxprocs: 2, GCProcs: 1, STW: true},
+		{Begin: 13, Duration: 20, Kind: PhaseMark, Gomaxprocs: 2, GCProcs: 1},
+		{Begin: 33, Duration: 2, Kind: PhaseMarkTerm, Gomaxprocs: 2, GCProcs: 2, STW: true},
+		{Begin: 35, Duration: 5, Kind: PhaseSweep, Gomaxprocs: 2},
+		{Begin: 40, Duration: 4, Kind: PhaseSweepTerm, Gomaxprocs: 2, GCProcs: 2, STW: true},
+		{Begin: 44, Duration: 1, Kind: PhaseMark, Gomaxprocs: 2, GCProcs: 2},
+		{Begin: 45, Duration: 5, Kind: PhaseSweep, Gomaxprocs: 2},
+	}, 2)
+	for _, test := range []struct {
+		threshold  float64
+		begin, dur int64
+	}{
+		// The second cycle's sweep termination runs into a
+		// mark phase that uses every proc.
+		{0, 40, 5},
+		// Half utilization joins the first cycle's phases.
+		{0.5, 10, 25},
+		{1, 0, 50},
+	} {
+		begin, dur := s.LongestBlackout(test.threshold)
+		if begin != test.begin || dur != test.dur {
+			t.Errorf("LongestBlackout(%v): want %d+%d, got %d+%d", test.threshold, test.begin, test.dur, begin, dur)
+		}
+	}
+
+	s = NewFromPhases([]Phase{{Begin: 0, Duration: 10, Kind: PhaseSweep, Gomaxprocs: 1}}, 1)
+	if _, dur := s.LongestBlackout(0); dur != 0 {
+		t.Errorf("want no blackout, got %d", dur)
+	}
+}
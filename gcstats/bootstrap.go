@@ -0,0 +1,113 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+)
+
+// bootstrapResamples is the number of resamples used to compute
+// bootstrap confidence intervals.
+const bootstrapResamples = 500
+
+// PercentileCI returns 95% confidence intervals for the percentiles
+// pctiles of xs, computed by bootstrap resampling. If block is
+// greater than 1, it uses a moving block bootstrap, which resamples
+// runs of block consecutive values and hence accounts for
+// correlation between nearby values of xs. The intervals are NaN if
+// xs is empty.
+func PercentileCI(xs []float64, pctiles []float64, block int) (lo, hi []float64) {
+	lo, hi = make([]float64, len(pctiles)), make([]float64, len(pctiles))
+	if len(xs) == 0 {
+		for i := range pctiles {
+			lo[i], hi[i] = math.NaN(), math.NaN()
+		}
+		return
+	}
+	block = max(1, min(block, len(xs)))
+
+	// Resampling is deterministic so reports are reproducible.
+	rnd := rand.New(rand.NewSource(1))
+	boot := make([]stats.Sample, len(pctiles))
+	resample := stats.Sample{Xs: make([]float64, len(xs))}
+	for r := 0; r < bootstrapResamples; r++ {
+		for i := 0; i < len(xs); i += block {
+			start := rnd.Intn(len(xs) - block + 1)
+			copy(resample.Xs[i:], xs[start:start+block])
+		}
+		resample.Sorted = false
+		resample.Sort()
+		for i, p := range pctiles {
+			boot[i].Xs = append(boot[i].Xs, resample.Percentile(p))
+		}
+	}
+	for i := range pctiles {
+		boot[i].Sort()
+		lo[i], hi[i] = boot[i].Percentile(0.025), boot[i].Percentile(0.975)
+	}
+	return
+}
+
+// WindowedMutatorUtilization returns the mean mutator utilization of
+// windows of windowNS nanoseconds beginning every stepNS nanoseconds
+// from the beginning of the log. This samples the distribution
+// returned by MutatorUtilizationDistribution.
+//
+// This will panic if the trace does not have program execution times.
+func (s *GcStats) WindowedMutatorUtilization(windowNS, stepNS int64) []float64 {
+	s.requireProgTimes()
+	log := s.Phases()
+	if len(log) == 0 {
+		return nil
+	}
+	var mus []float64
+	begin, end := log[0].Begin, log[len(log)-1].End()
+	for t := begin; t+windowNS <= end; t += stepNS {
+		mus = append(mus, s.MutatorUtilizationBetween(t, t+windowNS))
+	}
+	return mus
+}
+
+// ResampleCycles returns a bootstrap resample of s that consists of
+// as many GC cycles as s, drawn with replacement from the cycles of s
+// using rnd. Each cycle includes the phases that follow it until the
+// next cycle, so the mutator time between cycles is resampled with
+// the cycle. The resampled cycles are laid end to end, so the result
+// has program execution times, but no cycle records.
+//
+// Analyses of many resamples estimate the uncertainty of analyses of
+// s that comes from observing only a few GC cycles.
+//
+// This will panic if the trace does not have program execution times.
+func (s *GcStats) ResampleCycles(rnd *rand.Rand) *GcStats {
+	s.requireProgTimes()
+	log := s.Phases()
+	var starts []int
+	for i, p := range log {
+		if i == 0 || p.N != log[i-1].N {
+			starts = append(starts, i)
+		}
+	}
+	starts = append(starts, len(log))
+
+	ncycles := len(starts) - 1
+	out := make([]Phase, 0, len(log))
+	var t int64
+	if len(log) > 0 {
+		t = log[0].Begin
+	}
+	for i := 0; i < ncycles; i++ {
+		c := rnd.Intn(ncycles)
+		for _, p := range log[starts[c]:starts[c+1]] {
+			p.Begin = t
+			t += p.Duration
+			out = append(out, p)
+		}
+	}
+	return NewFromPhases(out, ncycles)
+}
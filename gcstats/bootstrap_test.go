@@ -0,0 +1,89 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestPercentileCI(t *testing.T) {
+	xs := make([]float64, 1000)
+	for i := range xs {
+		xs[i] = float64(i)
+	}
+	for _, block := range []int{1, 10} {
+		lo, hi := PercentileCI(xs, []float64{0.5, 0.99}, block)
+		for i, want := range []float64{500, 990} {
+			if !(lo[i] < want && want < hi[i]) || hi[i]-lo[i] > 200 {
+				t.Errorf("block %d: want CI around %v, got %v-%v", block, want, lo[i], hi[i])
+			}
+		}
+	}
+
+	// Blocks longer than the sample resample the whole sample.
+	lo, hi := PercentileCI(xs[:5], []float64{0.5}, 10)
+	if lo[0] != 2 || hi[0] != 2 {
+		t.Errorf("one block: want CI 2-2, got %v-%v", lo[0], hi[0])
+	}
+
+	lo, hi = PercentileCI(nil, []float64{0.5}, 1)
+	if !math.IsNaN(lo[0]) || !math.IsNaN(hi[0]) {
+		t.Errorf("empty: want NaN CI, got %v-%v", lo[0], hi[0])
+	}
+}
+
+func TestWindowedMutatorUtilization(t *testing.T) {
+	// GC runs for 100ns out of every 400ns.
+	var log []Phase
+	for i := 0; i < 4; i++ {
+		log = append(log,
+			Phase{Begin: int64(i) * 400, Duration: 100, Kind: PhaseMarkTerm, N: i + 1, Gomaxprocs: 1, GCProcs: 1, STW: true},
+			Phase{Begin: int64(i)*400 + 100, Duration: 300, Kind: PhaseSweep, N: i + 1, Gomaxprocs: 1})
+	}
+	s := NewFromPhases(log, 4)
+	mus := s.WindowedMutatorUtilization(400, 100)
+	if len(mus) != 13 {
+		t.Fatalf("want 13 windows, got %d", len(mus))
+	}
+	for i, mu := range mus {
+		if mu != 0.75 {
+			t.Errorf("window %d: want 0.75, got %v", i, mu)
+		}
+	}
+	if mus := s.WindowedMutatorUtilization(100, 100); mus[0] != 0 || mus[1] != 1 {
+		t.Errorf("100ns windows: want 0, 1, ..., got %v", mus)
+	}
+}
+
+func TestResampleCycles(t *testing.T) {
+	s := &GcStats{progTimes: true, n: 3, log: []Phase{
+		{Begin: 0, Duration: 1, Kind: PhaseMarkTerm, N: 1, STW: true},
+		{Begin: 1, Duration: 9, Kind: PhaseSweep, N: 1},
+		{Begin: 10, Duration: 2, Kind: PhaseMarkTerm, N: 2, STW: true},
+		{Begin: 12, Duration: 18, Kind: PhaseSweep, N: 2},
+		{Begin: 30, Duration: 3, Kind: PhaseMarkTerm, N: 3, STW: true},
+	}}
+	r := s.ResampleCycles(rand.New(rand.NewSource(1)))
+	if r.Count() != 3 {
+		t.Errorf("want 3 cycles, got %d", r.Count())
+	}
+	phases := r.Phases()
+	for i := 1; i < len(phases); i++ {
+		if phases[i-1].End() != phases[i].Begin {
+			t.Fatalf("phase %d ends at %d, but phase %d begins at %d", i-1, phases[i-1].End(), i, phases[i].Begin)
+		}
+	}
+	// Each cycle's phases must be resampled together.
+	for i, p := range phases {
+		if p.Kind == PhaseSweep && (i == 0 || phases[i-1].N != p.N) {
+			t.Errorf("sweep phase %d separated from its cycle", i)
+		}
+		if p.Duration != int64(p.N) && p.Duration != int64(9*p.N) {
+			t.Errorf("phase %d of cycle %d has unexpected duration %d", i, p.N, p.Duration)
+		}
+	}
+}
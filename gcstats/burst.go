@@ -0,0 +1,44 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+// Burst is a cluster of stop-the-world pauses that occurred close
+// together. Several short pauses in quick succession can hurt latency
+// as much as one long pause, even though each looks fine on its own.
+type Burst struct {
+	// Begin and End are the beginning of the first pause and the
+	// end of the last pause in nanoseconds.
+	Begin, End int64
+
+	// Pauses is the number of pauses in the burst.
+	Pauses int
+
+	// Duration is the combined duration of the pauses in
+	// nanoseconds.
+	Duration int64
+}
+
+// Bursts groups the stop-the-world pauses of s into bursts in which
+// each pause begins at most gap nanoseconds after the previous pause
+// ends. Consecutive STW phases count as a single pause, as in Stops.
+// Every pause belongs to exactly one burst, so isolated pauses are
+// bursts of one pause.
+//
+// This will panic if the trace does not have program execution times.
+func (s *GcStats) Bursts(gap int64) []Burst {
+	s.requireProgTimes()
+	var bursts []Burst
+	for stop := range s.StopsSeq() {
+		if n := len(bursts); n > 0 && stop.Begin-bursts[n-1].End <= gap {
+			b := &bursts[n-1]
+			b.End = stop.End()
+			b.Pauses++
+			b.Duration += stop.Duration
+			continue
+		}
+		bursts = append(bursts, Burst{stop.Begin, stop.End(), 1, stop.Duration})
+	}
+	return bursts
+}
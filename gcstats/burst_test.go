@@ -0,0 +1,35 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBursts(t *testing.T) {
+	s := &GcStats{progTimes: true, log: []Phase{
+		{Begin: 0, Duration: 2, Kind: PhaseSweepTerm, N: 1, STW: true},
+		{Begin: 2, Duration: 5, Kind: PhaseMark, N: 1},
+		{Begin: 7, Duration: 2, Kind: PhaseMarkTerm, N: 1, STW: true},
+		{Begin: 9, Duration: 100, Kind: PhaseSweep, N: 1},
+		{Begin: 109, Duration: 3, Kind: PhaseSweepTerm, N: 2, STW: true},
+		{Begin: 112, Duration: 1, Kind: PhaseScan, N: 2, STW: true},
+		{Begin: 113, Duration: 10, Kind: PhaseMark, N: 2},
+	}}
+	want := []Burst{
+		{Begin: 0, End: 9, Pauses: 2, Duration: 4},
+		{Begin: 109, End: 113, Pauses: 1, Duration: 4},
+	}
+	if got := s.Bursts(5); !reflect.DeepEqual(want, got) {
+		t.Errorf("want bursts %v, got %v", want, got)
+	}
+	if got := s.Bursts(4); len(got) != 3 {
+		t.Errorf("with gap 4, want 3 bursts, got %v", got)
+	}
+	if got := s.Bursts(100); len(got) != 1 || got[0].Pauses != 3 || got[0].Duration != 8 {
+		t.Errorf("with gap 100, want 1 burst of 3 pauses, got %v", got)
+	}
+}
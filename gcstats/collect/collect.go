@@ -0,0 +1,194 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package collect runs Go programs and collects their GC traces.
+package collect
+
+import (
+	"debug/buildinfo"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// Cmd is a command that runs with GODEBUG=gctrace=1 and whose GC
+// trace is parsed as it runs.
+//
+// The command's stderr is passed through to Cmd.Stderr, or os.Stderr
+// if that is nil, and also parsed by Parser. Since the command's
+// stderr is buffered until it is parsed, the command never blocks on
+// the Parser.
+//
+// The parsed trace's Environment records the command's GOGC,
+// GOMEMLIMIT, GOMAXPROCS, and GODEBUG settings, and the Go version
+// of its binary if it can be read.
+type Cmd struct {
+	*exec.Cmd
+
+	buf    *pipeBuffer
+	parser *gcstats.Parser
+}
+
+// Command returns a Cmd to execute the named program with the given
+// arguments. See exec.Command.
+func Command(name string, arg ...string) *Cmd {
+	return &Cmd{Cmd: exec.Command(name, arg...)}
+}
+
+// Start starts the command. See exec.Cmd.Start.
+func (c *Cmd) Start() error {
+	env := c.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	c.Env = withGCTrace(env)
+
+	passthrough := c.Stderr
+	if passthrough == nil {
+		passthrough = os.Stderr
+	}
+	c.buf = newPipeBuffer()
+	c.Stderr = &teeWriter{passthrough, c.buf}
+	c.parser = gcstats.NewParser(c.buf)
+	io.WriteString(c.buf, c.environment().Header()+"\n")
+	return c.Cmd.Start()
+}
+
+// Parser returns the Parser for the command's GC trace. This is only
+// valid after Start. Parser.Next blocks until the command prints the
+// next GC cycle and returns false once the command has exited and
+// its entire trace has been parsed.
+func (c *Cmd) Parser() *gcstats.Parser {
+	return c.parser
+}
+
+// Wait waits for the command to exit. See exec.Cmd.Wait. Any GC
+// cycles printed by the command remain available to Parser.
+func (c *Cmd) Wait() error {
+	err := c.Cmd.Wait()
+	c.buf.close()
+	return err
+}
+
+// Run starts the command, waits for it to exit, and returns its
+// parsed GC trace. If the command exits with an error, Run returns
+// both the trace and the error.
+func (c *Cmd) Run() (*gcstats.GcStats, error) {
+	if err := c.Start(); err != nil {
+		return nil, err
+	}
+	waitErr := make(chan error, 1)
+	go func() {
+		waitErr <- c.Wait()
+	}()
+
+	p := c.Parser()
+	for p.Next() {
+	}
+	err := <-waitErr
+	if perr := p.Err(); perr != nil {
+		return nil, perr
+	}
+	return p.Stats(), err
+}
+
+// environment returns the runtime settings of the command.
+func (c *Cmd) environment() gcstats.Environment {
+	env := gcstats.EnvironmentFromVars(c.Env)
+	if info, err := buildinfo.ReadFile(c.Path); err == nil {
+		env.GoVersion = info.GoVersion
+	}
+	return env
+}
+
+// withGCTrace returns env with gctrace=1 added to GODEBUG.
+func withGCTrace(env []string) []string {
+	out := make([]string, 0, len(env)+1)
+	debug := ""
+	for _, kv := range env {
+		if val, ok := strings.CutPrefix(kv, "GODEBUG="); ok {
+			// Like exec, use the last GODEBUG.
+			debug = val
+			continue
+		}
+		out = append(out, kv)
+	}
+	// Later settings take precedence, so this overrides any
+	// existing gctrace setting.
+	if debug != "" {
+		debug += ","
+	}
+	return append(out, "GODEBUG="+debug+"gctrace=1")
+}
+
+// teeWriter writes to w and buf. Errors writing to buf are ignored,
+// since they only occur if the trace isn't being parsed.
+type teeWriter struct {
+	w   io.Writer
+	buf *pipeBuffer
+}
+
+func (t *teeWriter) Write(b []byte) (int, error) {
+	t.buf.Write(b)
+	return t.w.Write(b)
+}
+
+// pipeBuffer is an unbounded in-memory pipe. Unlike io.Pipe, writes
+// never block.
+type pipeBuffer struct {
+	mu     sync.Mutex
+	cond   sync.Cond
+	data   []byte
+	closed bool
+}
+
+func newPipeBuffer() *pipeBuffer {
+	b := &pipeBuffer{}
+	b.cond.L = &b.mu
+	return b
+}
+
+func (b *pipeBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return 0, io.ErrClosedPipe
+	}
+	b.data = append(b.data, p...)
+	b.cond.Broadcast()
+	return len(p), nil
+}
+
+// Read reads from the buffer, blocking until data is available or
+// the buffer is closed.
+func (b *pipeBuffer) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for len(b.data) == 0 && !b.closed {
+		b.cond.Wait()
+	}
+	if len(b.data) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, b.data)
+	b.data = b.data[n:]
+	if len(b.data) == 0 {
+		// Release the consumed data.
+		b.data = nil
+	}
+	return n, nil
+}
+
+// close causes reads to return io.EOF once the buffered data has been
+// read.
+func (b *pipeBuffer) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	b.cond.Broadcast()
+}
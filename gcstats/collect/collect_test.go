@@ -0,0 +1,64 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package collect
+
+import (
+	"bytes"
+	"os/exec"
+	"reflect"
+	"testing"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+const trace = `gc 1 @0.010s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+app output
+gc 2 @0.050s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+`
+
+func TestRun(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+
+	var stderr bytes.Buffer
+	c := Command("sh", "-c", `printf '%s' "$TRACE" >&2; echo "$GODEBUG"`)
+	c.Env = []string{"TRACE=" + trace, "GODEBUG=madvdontneed=1", "GOGC=200"}
+	c.Stderr = &stderr
+	var stdout bytes.Buffer
+	c.Stdout = &stdout
+	s, err := c.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := stdout.String(), "madvdontneed=1,gctrace=1\n"; got != want {
+		t.Errorf("want GODEBUG %q, got %q", want, got)
+	}
+	if got := stderr.String(); got != trace {
+		t.Errorf("want stderr %q, got %q", trace, got)
+	}
+	if s.Count() != 2 {
+		t.Errorf("want 2 GCs, got %d", s.Count())
+	}
+	// sh isn't a Go binary, so its Go version is unknown.
+	if got, want := s.Environment(), (gcstats.Environment{GOGC: "200", GODEBUG: "madvdontneed=1,gctrace=1"}); got != want {
+		t.Errorf("want environment %+v, got %+v", want, got)
+	}
+}
+
+func TestWithGCTrace(t *testing.T) {
+	for _, test := range []struct {
+		env, want []string
+	}{
+		{nil, []string{"GODEBUG=gctrace=1"}},
+		{[]string{"A=1", "GODEBUG="}, []string{"A=1", "GODEBUG=gctrace=1"}},
+		{[]string{"GODEBUG=gctrace=2", "B=2"}, []string{"B=2", "GODEBUG=gctrace=2,gctrace=1"}},
+	} {
+		if got := withGCTrace(test.env); !reflect.DeepEqual(test.want, got) {
+			t.Errorf("withGCTrace(%q): want %q, got %q", test.env, test.want, got)
+		}
+	}
+}
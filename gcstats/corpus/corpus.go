@@ -0,0 +1,96 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package corpus embeds a set of GC traces recorded from real
+// programs, so users can try gcstats without collecting a trace and
+// parsers can be tested against the formats real runtimes print.
+//
+// Tests of other packages can parse every trace:
+//
+//	for _, t := range corpus.All() {
+//		s, err := gcstats.NewFromLog(t.Open())
+//		...
+//	}
+//
+// (A directory named testdata can't hold an importable package, hence
+// the name.)
+package corpus
+
+import (
+	"bytes"
+	"embed"
+	"io"
+)
+
+//go:embed traces/*.log
+var traces embed.FS
+
+// A Trace is one trace in the corpus.
+type Trace struct {
+	// Name identifies the trace.
+	Name string
+
+	// GoVersion is the version of Go that printed the trace.
+	GoVersion string
+
+	// Description describes the program and its settings.
+	Description string
+
+	// Sched indicates that the trace includes scheduler trace
+	// (GODEBUG=schedtrace) lines.
+	Sched bool
+
+	// Forced indicates that every GC cycle in the trace was forced
+	// by runtime.GC, so the trace has no pacing.
+	Forced bool
+}
+
+var all = []Trace{
+	{
+		Name:        "runtime-compile",
+		GoVersion:   "go1.5",
+		Description: "Compiling the Go 1.5 runtime package",
+	},
+	{
+		Name:        "binarytrees",
+		GoVersion:   "go1.27",
+		Description: "A binary-trees allocation benchmark with GOMAXPROCS=4",
+		Sched:       true,
+	},
+	{
+		Name:        "binarytrees-gogc-off",
+		GoVersion:   "go1.27",
+		Description: "The binary-trees benchmark with GOGC=off, calling runtime.GC between rounds",
+		Forced:      true,
+	},
+}
+
+// All returns the traces in the corpus.
+func All() []Trace {
+	return append([]Trace(nil), all...)
+}
+
+// Lookup returns the trace named name.
+func Lookup(name string) (Trace, bool) {
+	for _, t := range all {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Trace{}, false
+}
+
+// Bytes returns the text of t.
+func (t Trace) Bytes() []byte {
+	data, err := traces.ReadFile("traces/" + t.Name + ".log")
+	if err != nil {
+		panic("corpus: missing trace " + t.Name)
+	}
+	return data
+}
+
+// Open returns a reader of the text of t.
+func (t Trace) Open() io.Reader {
+	return bytes.NewReader(t.Bytes())
+}
@@ -0,0 +1,37 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package corpus
+
+import (
+	"testing"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+func TestParse(t *testing.T) {
+	for _, tr := range All() {
+		s, err := gcstats.NewFromLog(tr.Open())
+		if err != nil {
+			t.Errorf("%s: %v", tr.Name, err)
+			continue
+		}
+		if s.Count() == 0 || !s.HaveProgTimes() {
+			t.Errorf("%s: want cycles with program times, got %d cycles", tr.Name, s.Count())
+		}
+		if got := len(s.Sched()) > 0; got != tr.Sched {
+			t.Errorf("%s: want scheduler samples %v, got %v", tr.Name, tr.Sched, got)
+		}
+		forced := true
+		for _, c := range s.Cycles() {
+			forced = forced && c.Forced
+		}
+		if forced != tr.Forced {
+			t.Errorf("%s: want all cycles forced %v, got %v", tr.Name, tr.Forced, forced)
+		}
+	}
+	if _, ok := Lookup("nonexistent"); ok {
+		t.Errorf("Lookup of nonexistent trace succeeded")
+	}
+}
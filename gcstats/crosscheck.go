@@ -0,0 +1,246 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TraceSTW is a stop-the-world pause recorded by the execution
+// tracer (runtime/trace).
+type TraceSTW struct {
+	// Begin and Duration are in nanoseconds on the tracer's
+	// clock, which has an arbitrary origin.
+	Begin, Duration int64
+
+	// Reason is the reason for the pause, such as "GC sweep
+	// termination".
+	Reason string
+}
+
+// Kind returns the GC phase of a pause, and false if the pause was
+// not caused by the garbage collector.
+func (p TraceSTW) Kind() (PhaseKind, bool) {
+	switch p.Reason {
+	case "GC sweep termination":
+		return PhaseSweepTerm, true
+	case "GC mark termination":
+		return PhaseMarkTerm, true
+	}
+	return 0, false
+}
+
+// End returns the end time of p.
+func (p TraceSTW) End() int64 {
+	return p.Begin + p.Duration
+}
+
+// ParseTraceSTWs returns the stop-the-world pauses in the textual
+// dump of an execution trace printed by "go tool trace -d=parsed".
+func ParseTraceSTWs(r io.Reader) ([]TraceSTW, error) {
+	// Event lines look like
+	// M=1 P=0 G=9 RangeBegin Time=<ns> Name="stop-the-world (<reason>)" Scope=...
+	const stwPrefix = `Name="stop-the-world (`
+	var stws []TraceSTW
+	begins := make(map[string]int64)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := scanner.Text()
+		var begin bool
+		if strings.Contains(line, " RangeBegin Time=") {
+			begin = true
+		} else if !strings.Contains(line, " RangeEnd Time=") {
+			continue
+		}
+		i := strings.Index(line, stwPrefix)
+		if i < 0 {
+			continue
+		}
+		reason := line[i+len(stwPrefix):]
+		if j := strings.Index(reason, `)"`); j >= 0 {
+			reason = reason[:j]
+		}
+		ts := line[strings.Index(line, " Time=")+len(" Time="):]
+		if j := strings.IndexByte(ts, ' '); j >= 0 {
+			ts = ts[:j]
+		}
+		t, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad time: %s", lineno, line)
+		}
+
+		if begin {
+			begins[reason] = t
+		} else if b, ok := begins[reason]; ok {
+			stws = append(stws, TraceSTW{b, t - b, reason})
+			delete(begins, reason)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(stws, func(i, j int) bool { return stws[i].Begin < stws[j].Begin })
+	return stws, nil
+}
+
+// CrossCheck compares the STW phases of a GC trace against the pauses
+// recorded by an execution trace of the same run.
+type CrossCheck struct {
+	// Offset is the GC trace time minus the execution trace time
+	// that best aligns the two traces.
+	Offset int64
+
+	// Matched is the pairs of GC STW phases and the execution
+	// trace pauses they correspond to.
+	Matched []STWMatch
+
+	// Missed is GC pauses in the execution trace that have no
+	// corresponding phase in the GC trace.
+	Missed []TraceSTW
+
+	// Unmatched is STW phases in the GC trace during the execution
+	// trace that have no corresponding pause in the execution
+	// trace.
+	Unmatched []Phase
+
+	// Other is non-GC pauses in the execution trace. These are
+	// never reported by GC traces.
+	Other []TraceSTW
+}
+
+// STWMatch is a STW phase from a GC trace and the corresponding pause
+// from an execution trace.
+type STWMatch struct {
+	Phase Phase
+	Trace TraceSTW
+}
+
+// BeginDrift returns the begin time of m's execution trace pause
+// (aligned to the GC trace) minus the begin time of its GC trace
+// phase.
+func (m STWMatch) BeginDrift(offset int64) int64 {
+	return m.Trace.Begin + offset - m.Phase.Begin
+}
+
+// Underestimate returns how much the GC trace underestimates the
+// duration of the pause. This is negative if the GC trace
+// overestimates it.
+func (m STWMatch) Underestimate() int64 {
+	return m.Trace.Duration - m.Phase.Duration
+}
+
+// crossCheckSlop is the maximum difference in begin times between
+// a GC trace phase and an execution trace pause that are considered
+// the same pause. Go 1.5 traces report begin times to the
+// millisecond.
+const crossCheckSlop = 2e6
+
+// CrossCheckTrace aligns the STW phases of s with the pauses in stws,
+// which must be sorted by begin time, and reports the discrepancies.
+// This panics if s does not have program execution times.
+func (s *GcStats) CrossCheckTrace(stws []TraceSTW) *CrossCheck {
+	s.requireProgTimes()
+
+	cc := new(CrossCheck)
+	var gcSTWs []TraceSTW
+	for _, stw := range stws {
+		if _, ok := stw.Kind(); ok {
+			gcSTWs = append(gcSTWs, stw)
+		} else {
+			cc.Other = append(cc.Other, stw)
+		}
+	}
+	var phases []Phase
+	for _, phase := range s.Phases() {
+		if phase.STW {
+			phases = append(phases, phase)
+		}
+	}
+	if len(gcSTWs) == 0 || len(phases) == 0 {
+		cc.Missed = gcSTWs
+		return cc
+	}
+
+	cc.Offset = alignOffset(phases, gcSTWs)
+	cc.Matched = alignSTWs(phases, gcSTWs, cc.Offset, cc)
+	return cc
+}
+
+// alignOffset returns the offset to add to the begin times of stws
+// that best aligns them with phases. The traces have different time
+// origins, so this votes on the offsets that would align each of the
+// first few pauses in stws with each phase of the same kind, and then
+// picks the most popular offset that aligns the most pauses.
+func alignOffset(phases []Phase, stws []TraceSTW) int64 {
+	const voters = 16
+	votes := make(map[int64]int)
+	var offsets []int64
+	for _, stw := range stws[:min(voters, len(stws))] {
+		kind, _ := stw.Kind()
+		for _, phase := range phases {
+			if phase.Kind == kind {
+				offset := phase.Begin - stw.Begin
+				votes[offset/crossCheckSlop]++
+				offsets = append(offsets, offset)
+			}
+		}
+	}
+	bestVotes, bestBucket := -1, int64(0)
+	for bucket := range votes {
+		v := votes[bucket-1] + votes[bucket] + votes[bucket+1]
+		if v > bestVotes || v == bestVotes && bucket < bestBucket {
+			bestVotes, bestBucket = v, bucket
+		}
+	}
+
+	bestOffset, bestMatches := int64(0), -1
+	for _, offset := range offsets {
+		if b := offset / crossCheckSlop; b < bestBucket-1 || b > bestBucket+1 {
+			continue
+		}
+		if m := len(alignSTWs(phases, stws, offset, nil)); m > bestMatches || m == bestMatches && offset < bestOffset {
+			bestOffset, bestMatches = offset, m
+		}
+	}
+	return bestOffset
+}
+
+// alignSTWs matches phases with stws after adding offset to the stw
+// begin times. If cc is non-nil, it also records unmatched pauses in
+// cc.
+func alignSTWs(phases []Phase, stws []TraceSTW, offset int64, cc *CrossCheck) []STWMatch {
+	var matched []STWMatch
+	first, last := stws[0].Begin+offset-crossCheckSlop, stws[len(stws)-1].End()+offset+crossCheckSlop
+	i := 0
+	for _, stw := range stws {
+		kind, _ := stw.Kind()
+		begin := stw.Begin + offset
+		// Skip phases that come too early to match.
+		for i < len(phases) && phases[i].Begin < begin-crossCheckSlop {
+			if cc != nil && phases[i].Begin >= first {
+				cc.Unmatched = append(cc.Unmatched, phases[i])
+			}
+			i++
+		}
+		if i < len(phases) && phases[i].Begin <= begin+crossCheckSlop && phases[i].Kind == kind {
+			matched = append(matched, STWMatch{phases[i], stw})
+			i++
+		} else if cc != nil {
+			cc.Missed = append(cc.Missed, stw)
+		}
+	}
+	if cc != nil {
+		for ; i < len(phases) && phases[i].Begin <= last; i++ {
+			cc.Unmatched = append(cc.Unmatched, phases[i])
+		}
+	}
+	return matched
+}
@@ -0,0 +1,74 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCrossCheckTrace(t *testing.T) {
+	var log, dump strings.Builder
+	stw := func(reason string, begin, dur int64) {
+		// The execution trace clock is offset by 1 second.
+		begin += 1e9
+		fmt.Fprintf(&dump, "M=1 P=0 G=9 RangeBegin Time=%d Name=\"stop-the-world (%s)\" Scope=Goroutine(9)\n", begin, reason)
+		fmt.Fprintf(&dump, "M=1 P=0 G=9 StateTransition Time=%d GoID=4 Waiting->Runnable Reason=\"\"\n", begin+1)
+		fmt.Fprintf(&dump, "M=1 P=0 G=9 RangeEnd Time=%d Name=\"stop-the-world (%s)\" Scope=Goroutine(9) Attributes=[]\n", begin+dur, reason)
+	}
+	stw("start trace", 1e6, 5000)
+	for i := int64(0); i < 3; i++ {
+		begin := 10e6 + i*50e6
+		fmt.Fprintf(&log, "gc %d @%.3fs 5%%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P\n", i+1, float64(begin)/1e9)
+		stw("GC sweep termination", begin+100e3, 39e3)
+		markTerm := int64(620e3)
+		if i == 1 {
+			markTerm = 1e6
+		}
+		stw("GC mark termination", begin+4939e3, markTerm)
+	}
+	// A GC pause missing from the GC trace.
+	stw("GC sweep termination", 135e6, 50e3)
+
+	s, err := NewFromLog(strings.NewReader(log.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stws, err := ParseTraceSTWs(strings.NewReader(dump.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stws) != 8 {
+		t.Fatalf("want 8 pauses, got %d: %v", len(stws), stws)
+	}
+
+	cc := s.CrossCheckTrace(stws)
+	if cc.Offset != -1e9-100e3 {
+		t.Errorf("want offset %d, got %d", int64(-1e9-100e3), cc.Offset)
+	}
+	if len(cc.Matched) != 6 {
+		t.Errorf("want 6 matched pauses, got %d", len(cc.Matched))
+	}
+	for _, m := range cc.Matched {
+		want := int64(0)
+		if m.Phase.N == 2 && m.Phase.Kind == PhaseMarkTerm {
+			want = 380e3
+		}
+		if got := m.Underestimate(); got != want {
+			t.Errorf("gc %d %s: want underestimate %d, got %d", m.Phase.N, m.Phase.Kind, want, got)
+		}
+	}
+	if want := []TraceSTW{{1135e6, 50e3, "GC sweep termination"}}; !reflect.DeepEqual(want, cc.Missed) {
+		t.Errorf("want missed %v, got %v", want, cc.Missed)
+	}
+	if len(cc.Unmatched) != 0 {
+		t.Errorf("want no unmatched phases, got %v", cc.Unmatched)
+	}
+	if want := []TraceSTW{{1001e6, 5000, "start trace"}}; !reflect.DeepEqual(want, cc.Other) {
+		t.Errorf("want other %v, got %v", want, cc.Other)
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+// CumulativeGC returns the GC CPU time and the stop-the-world time in
+// nanoseconds from the beginning of the log until each time in ts,
+// which must be sorted. GC CPU time counts only the procs the garbage
+// collector used, even during stop-the-world phases.
+//
+// This will panic if the trace does not have program execution times.
+func (s *GcStats) CumulativeGC(ts []int64) (cpu, stw []float64) {
+	s.requireProgTimes()
+	cpu, stw = make([]float64, len(ts)), make([]float64, len(ts))
+
+	// cpuSum and stwSum are the totals up to log[i].Begin.
+	var cpuSum, stwSum float64
+	i := 0
+	for j, t := range ts {
+		for i < len(s.log) && s.log[i].End() <= t {
+			phase := s.log[i]
+			cpuSum += phase.GCProcs * float64(phase.Duration)
+			if phase.STW {
+				stwSum += float64(phase.Duration)
+			}
+			i++
+		}
+		cpu[j], stw[j] = cpuSum, stwSum
+		if i < len(s.log) && s.log[i].Begin < t {
+			// Include the part of the phase before t.
+			phase := s.log[i]
+			d := float64(t - phase.Begin)
+			cpu[j] += phase.GCProcs * d
+			if phase.STW {
+				stw[j] += d
+			}
+		}
+	}
+	return
+}
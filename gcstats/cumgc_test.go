@@ -0,0 +1,28 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCumulativeGC(t *testing.T) {
+	s := NewFromPhases([]Phase{
+		{1000, 100, PhaseSweepTerm, 1, 4, 2, true},
+		{1100, 400, PhaseMark, 1, 4, 1, false},
+		{1500, 100, PhaseMarkTerm, 1, 4, 4, true},
+		{1600, 1000, PhaseSweep, 1, 4, 0, false},
+	}, 1)
+	cpu, stw := s.CumulativeGC([]int64{0, 1000, 1050, 1300, 1550, 2000, 5000})
+	wantCPU := []float64{0, 0, 100, 200 + 200, 600 + 200, 1000, 1000}
+	wantSTW := []float64{0, 0, 50, 100, 150, 200, 200}
+	if !reflect.DeepEqual(cpu, wantCPU) {
+		t.Errorf("want GC CPU %v, got %v", wantCPU, cpu)
+	}
+	if !reflect.DeepEqual(stw, wantSTW) {
+		t.Errorf("want STW %v, got %v", wantSTW, stw)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"fmt"
+	"math"
+)
+
+// Cycle summarizes a single GC cycle.
+type Cycle struct {
+	// N is the GC cycle number.
+	N int
+
+	// Begin is the time the cycle began in nanoseconds, or 0 if
+	// the trace doesn't have program execution times.
+	Begin int64
+
+	// HeapTrigger is the heap size in bytes when the cycle
+	// started. HeapMarked is the heap size when marking finished.
+	// HeapLive is the size of the heap marked live by the cycle.
+	// HeapGoal is the heap size the cycle was aiming to finish
+	// at, or 0 if it is unknown.
+	//
+	// GC traces report these in whole megabytes.
+	HeapTrigger, HeapMarked, HeapLive, HeapGoal int64
+
+	// AssistCPU, BackgroundCPU, and IdleCPU are the CPU time in
+	// nanoseconds spent in concurrent marking by mutator assists,
+	// by dedicated and fractional background workers, and by
+	// workers running on otherwise idle Ps. These are 0 for
+	// traces that don't report them (before Go 1.5).
+	AssistCPU, BackgroundCPU, IdleCPU int64
+
+	// Forced indicates that the cycle was forced by a call to
+	// runtime.GC. Go 1.5 runs forced cycles with the world
+	// stopped and they are omitted from the trace, so this is
+	// only set for later trace formats.
+	Forced bool
+
+	// Cause is what triggered the cycle.
+	Cause Cause
+}
+
+// Cause is what triggered a GC cycle.
+type Cause int
+
+const (
+	// CausePaced means the heap reached the trigger set by the
+	// GC pacer.
+	CausePaced Cause = iota
+
+	// CauseForced means the cycle was forced by runtime.GC or by
+	// the runtime's periodic GC.
+	CauseForced
+
+	// CauseLimit means the heap goal was lowered below what the
+	// pacer would otherwise have chosen, such as by a memory
+	// limit. Traces don't record this, so it is inferred from a
+	// drop in the ratio of the heap goal to the previous cycle's
+	// live heap.
+	CauseLimit
+)
+
+var causeNames = []string{"paced", "forced", "limit"}
+
+func (c Cause) String() string {
+	if c >= 0 && int(c) < len(causeNames) {
+		return causeNames[c]
+	}
+	return fmt.Sprintf("Cause(%d)", int(c))
+}
+
+// LiveRatio returns the ratio of the live heap to the heap size when
+// the cycle was triggered, HeapLive/HeapTrigger. One minus this
+// approximates the fraction of the heap that was garbage. Since the
+// heap can grow during a concurrent cycle, this may exceed 1. It
+// returns NaN if the heap size at the trigger is unknown, which
+// includes heaps that round to 0 MB in the trace.
+func (c Cycle) LiveRatio() float64 {
+	if c.HeapTrigger == 0 {
+		return math.NaN()
+	}
+	return float64(c.HeapLive) / float64(c.HeapTrigger)
+}
+
+// Cycles returns a slice of the recorded garbage collection cycles.
+func (s *GcStats) Cycles() []Cycle {
+	return s.cycles
+}
@@ -0,0 +1,48 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestLiveRatio(t *testing.T) {
+	for _, test := range []struct {
+		c    Cycle
+		want float64
+	}{
+		{Cycle{HeapTrigger: 4 << 20, HeapMarked: 5 << 20, HeapLive: 1 << 20}, 0.25},
+		{Cycle{HeapTrigger: 4 << 20, HeapMarked: 6 << 20, HeapLive: 5 << 20}, 1.25},
+		{Cycle{HeapTrigger: 0, HeapLive: 0}, math.NaN()},
+	} {
+		got := test.c.LiveRatio()
+		if got != test.want && !(math.IsNaN(got) && math.IsNaN(test.want)) {
+			t.Errorf("%+v: want %v, got %v", test.c, test.want, got)
+		}
+	}
+}
+
+func TestCause(t *testing.T) {
+	// GOGC=100 doubles the live heap until cycle 4, whose goal is
+	// limited. Cycle 2's goal is raised to the minimum heap.
+	const log = `gc 1 @0.010s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 2 @0.020s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->10 MB, 4 MB goal, 4 P
+gc 3 @0.030s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 20->20->10 MB, 20 MB goal, 4 P
+gc 4 @0.040s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 12->12->10 MB, 12 MB goal, 4 P
+gc 5 @0.050s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 20->20->10 MB, 20 MB goal, 4 P
+`
+	s, err := NewFromLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Cause{CausePaced, CausePaced, CausePaced, CauseLimit, CausePaced}
+	for i, c := range s.Cycles() {
+		if c.Cause != want[i] {
+			t.Errorf("cycle %d: want cause %v, got %v", c.N, want[i], c.Cause)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Diagnostic is a data quality issue found while parsing a GC
+// trace.
+type Diagnostic struct {
+	// Line is the 1-based line number of the trace the issue was
+	// found on, or 0 if it doesn't pertain to a particular line.
+	Line int
+
+	// Text is the offending line, if any.
+	Text string
+
+	// Error indicates that the issue stopped parsing. Otherwise,
+	// the issue is a warning and parsing continued.
+	Error bool
+
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if d.Line != 0 {
+		fmt.Fprintf(&b, "line %d: ", d.Line)
+	}
+	if d.Error {
+		b.WriteString("error: ")
+	}
+	b.WriteString(d.Message)
+	if d.Text != "" {
+		fmt.Fprintf(&b, ": %s", d.Text)
+	}
+	return b.String()
+}
+
+// A ParseError is a line of a GC trace that looks like a trace line
+// in a known format but could not be parsed.
+type ParseError struct {
+	// Line is the 1-based line number of the line in the trace.
+	Line int
+
+	// Text is the line.
+	Text string
+
+	// Format is the trace format the line was recognized as, such
+	// as "Go 1.5 gctrace".
+	Format string
+
+	// Field is the field of the line that failed to parse, such
+	// as "ms clock", or "" if the line as a whole is malformed.
+	Field string
+
+	Message string
+}
+
+// Trace formats recognized by the parser.
+const (
+	formatGo14 = "Go 1.4 gctrace"
+	formatGo15 = "Go 1.5 gctrace"
+)
+
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	if e.Line != 0 {
+		fmt.Fprintf(&b, "line %d: ", e.Line)
+	}
+	b.WriteString(e.describe())
+	if e.Text != "" {
+		fmt.Fprintf(&b, ": %s", e.Text)
+	}
+	return b.String()
+}
+
+// setText sets the text of the line of e and, if it's not already
+// known, the format the line was recognized as.
+func (e *ParseError) setText(text string) {
+	e.Text = text
+	if e.Format == "" {
+		e.Format = formatGo14
+		if strings.HasPrefix(text, "gc ") {
+			e.Format = formatGo15
+		}
+	}
+}
+
+// describe returns the error without its line number and text, as
+// the message of its Diagnostic.
+func (e *ParseError) describe() string {
+	var b strings.Builder
+	b.WriteString(e.Format)
+	if e.Field != "" {
+		fmt.Fprintf(&b, ": field %q", e.Field)
+	}
+	fmt.Fprintf(&b, ": %s", e.Message)
+	return b.String()
+}
+
+// Diagnostics returns the warnings and errors found while parsing
+// the trace of s, in the order they were found. These include lines
+// that look like GC trace lines but could not be parsed, cycles that
+// overlapped their predecessor and were repaired, the final phase of
+// the trace, which is dropped because its duration is unknown, and
+// estimated execution times.
+func (s *GcStats) Diagnostics() []Diagnostic {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	return s.diags[:len(s.diags):len(s.diags)]
+}
+
+func (s *GcStats) addDiagnostic(d Diagnostic) {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	s.diags = append(s.diags, d)
+}
+
+// skippedLineDiagnostic returns a diagnostic message for line, which
+// was not parsed as a GC cycle, if it appears to be a GC trace line.
+func skippedLineDiagnostic(line string) (string, bool) {
+	rest, ok := strings.CutPrefix(line, "gc")
+	if !ok || rest == "" || rest[0] != ' ' && digits(rest) == 0 {
+		return "", false
+	}
+	if strings.Contains(line, "(forced)") {
+		return "ignored forced GC, which runs with the world stopped", true
+	}
+	return "unrecognized GC trace line", true
+}
@@ -0,0 +1,216 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import "fmt"
+
+// downsamplePhases is the number of phases in each merged cycle
+// produced by Downsample.
+const downsamplePhases = 4
+
+// Downsample returns a GcStats with at most maxPhases phases that
+// approximates s for plotting. It merges runs of adjacent cycles into
+// single cycles consisting of the longest pause of the run, its
+// concurrent GC phases, the rest of its STW phases, and its sweep
+// phases, each combined into one phase. Hence, the merged cycles span
+// the same time as the original cycles and have the same total STW
+// time and longest pauses. A merged cycle has the GOMAXPROCS that
+// lasted longest in its run, and otherwise the same GC CPU time. If
+// maxPhases is too small to keep the longest pauses, the whole trace
+// is merged into one cycle with a single STW phase. Cycle records
+// are merged by taking the largest heap sizes and the total mark CPU
+// times. The result is the same for a given s and maxPhases.
+//
+// Downsample returns s if it already has at most maxPhases phases
+// or lacks program execution times. It panics if maxPhases is less
+// than 2.
+func (s *GcStats) Downsample(maxPhases int) *GcStats {
+	if maxPhases < 2 {
+		panic("Downsample maxPhases must be at least 2")
+	}
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	if len(s.log) <= maxPhases || !s.progTimes {
+		return s
+	}
+
+	// Find the first phase of each cycle.
+	var starts []int
+	for i, p := range s.log {
+		if i == 0 || p.N != s.log[i-1].N {
+			starts = append(starts, i)
+		}
+	}
+	runs := max(maxPhases/downsamplePhases, 1)
+	per := (len(starts) + runs - 1) / runs
+	compact := maxPhases < downsamplePhases
+
+	out := &GcStats{
+		progTimes: true,
+		estimated: s.estimated,
+		complete:  true,
+		sched:     s.sched,
+		scvg:      s.scvg,
+		diags:     s.diags[:len(s.diags):len(s.diags)],
+		env:       s.env,
+	}
+	nc := 0
+	for i := 0; i < len(starts); {
+		first := s.log[starts[i]]
+		j := min(i+per, len(starts))
+		end := len(s.log)
+		if j < len(starts) {
+			end = starts[j]
+		}
+		out.log = append(out.log, mergePhases(s.log[starts[i]:end], compact)...)
+		out.n++
+
+		// Merge the records of the cycles in the run.
+		last := s.log[end-1].N
+		for nc < len(s.cycles) && s.cycles[nc].N < first.N {
+			nc++
+		}
+		if nc < len(s.cycles) && s.cycles[nc].N <= last {
+			c := s.cycles[nc]
+			for nc++; nc < len(s.cycles) && s.cycles[nc].N <= last; nc++ {
+				c = mergeCycles(c, s.cycles[nc])
+			}
+			out.cycles = append(out.cycles, c)
+		}
+		i = j
+	}
+	out.diags = append(out.diags, Diagnostic{Message: fmt.Sprintf("downsampled %d cycles to %d", len(starts), out.n)})
+	return out
+}
+
+// mergePhases returns the phases of a merged cycle spanning phases,
+// which must be the consecutive phases of one or more cycles. If
+// compact is set, it returns at most two phases by merging all of the
+// pauses into one STW phase and the rest into one non-STW phase.
+func mergePhases(phases []Phase, compact bool) []Phase {
+	// Sum each run of consecutive STW phases, which is one pause,
+	// and keep the longest separate from the others.
+	var longest, stw, run, conc, sweep phaseSum
+	endRun := func() {
+		if run.n == 0 {
+			return
+		}
+		if run.dur > longest.dur || longest.n == 0 {
+			longest, run = run, longest
+		}
+		stw.addSum(run)
+		run = phaseSum{}
+	}
+	for _, p := range phases {
+		switch {
+		case p.STW:
+			run.add(p)
+			continue
+		case p.Kind == PhaseSweep:
+			sweep.add(p)
+		default:
+			conc.add(p)
+		}
+		endRun()
+	}
+	endRun()
+	if compact {
+		stw.addSum(longest)
+		longest = phaseSum{}
+		conc.addSum(sweep)
+		sweep = conc
+		conc = phaseSum{}
+	}
+
+	// Use the GOMAXPROCS that lasted longest.
+	procsDur := make(map[int]int64)
+	gomaxprocs := phases[0].Gomaxprocs
+	for _, p := range phases {
+		procsDur[p.Gomaxprocs] += p.Duration
+		if procsDur[p.Gomaxprocs] > procsDur[gomaxprocs] {
+			gomaxprocs = p.Gomaxprocs
+		}
+	}
+
+	// Each STW phase is followed by a non-STW phase, even if it's
+	// empty, so the merged STW phases remain separate pauses.
+	first := phases[0]
+	out := make([]Phase, 0, downsamplePhases)
+	now := first.Begin
+	emit := func(sum phaseSum, stw bool) {
+		procs := min(sum.procs(), float64(gomaxprocs))
+		out = append(out, Phase{now, sum.dur, sum.kind, first.N, gomaxprocs, procs, stw})
+		now += sum.dur
+	}
+	if longest.n > 0 {
+		emit(longest, true)
+	}
+	if conc.n == 0 {
+		conc.kind = PhaseMultiple
+	}
+	if conc.n > 0 || longest.n > 0 && stw.n > 0 {
+		emit(conc, false)
+	}
+	if stw.n > 0 {
+		emit(stw, true)
+	}
+	if sweep.n == 0 || !compact {
+		sweep.kind = PhaseSweep
+	}
+	emit(sweep, false)
+	return out
+}
+
+// phaseSum accumulates phases to be merged into one.
+type phaseSum struct {
+	n       int
+	kind    PhaseKind
+	dur     int64
+	gcProcs float64 // GCProcs-weighted duration
+}
+
+func (s *phaseSum) add(p Phase) {
+	s.addSum(phaseSum{1, p.Kind, p.Duration, p.GCProcs * float64(p.Duration)})
+}
+
+func (s *phaseSum) addSum(o phaseSum) {
+	if o.n == 0 {
+		return
+	}
+	if s.n == 0 {
+		s.kind = o.kind
+	} else if s.kind != o.kind {
+		s.kind = PhaseMultiple
+	}
+	s.n += o.n
+	s.dur += o.dur
+	s.gcProcs += o.gcProcs
+}
+
+// procs returns the mean GCProcs of the accumulated phases.
+func (s *phaseSum) procs() float64 {
+	if s.dur == 0 {
+		return 0
+	}
+	return s.gcProcs / float64(s.dur)
+}
+
+// mergeCycles returns the record of a merged cycle consisting of a
+// followed by b.
+func mergeCycles(a, b Cycle) Cycle {
+	a.HeapTrigger = max(a.HeapTrigger, b.HeapTrigger)
+	a.HeapMarked = max(a.HeapMarked, b.HeapMarked)
+	a.HeapLive = max(a.HeapLive, b.HeapLive)
+	a.HeapGoal = max(a.HeapGoal, b.HeapGoal)
+	a.AssistCPU += b.AssistCPU
+	a.BackgroundCPU += b.BackgroundCPU
+	a.IdleCPU += b.IdleCPU
+	a.Forced = a.Forced || b.Forced
+	if a.Cause == CausePaced {
+		// Keep rarer causes visible.
+		a.Cause = b.Cause
+	}
+	return a
+}
@@ -0,0 +1,100 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDownsample(t *testing.T) {
+	var log strings.Builder
+	log.WriteString(benchLog(100))
+	// One cycle has a long pause.
+	log.WriteString("gc 101 @5.000s 5%: 9.5+0.80+2.4+1.7+0.62 ms clock, 9.5+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->40->2 MB, 4 MB goal, 4 P\n")
+	s, err := NewFromLog(strings.NewReader(log.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := s.Downsample(40)
+	if n := len(d.Phases()); n > 40 {
+		t.Fatalf("want at most 40 phases, got %d", n)
+	}
+	if d.Count() >= s.Count() || len(d.Cycles()) != d.Count() {
+		t.Errorf("want fewer than %d cycles with records, got %d cycles and %d records", s.Count(), d.Count(), len(d.Cycles()))
+	}
+
+	total := func(s *GcStats) (span, stw int64, gcCPU float64) {
+		phases := s.Phases()
+		for i, p := range phases {
+			if i > 0 && phases[i-1].End() != p.Begin {
+				t.Fatalf("phase %d ends at %d, but phase %d begins at %d", i-1, phases[i-1].End(), i, p.Begin)
+			}
+			if p.STW {
+				stw += p.Duration
+			}
+			gcCPU += p.GCProcs * float64(p.Duration)
+		}
+		return phases[len(phases)-1].End() - phases[0].Begin, stw, gcCPU
+	}
+	span1, stw1, cpu1 := total(s)
+	span2, stw2, cpu2 := total(d)
+	if span1 != span2 || stw1 != stw2 || math.Abs(cpu1-cpu2) > 1e-6*cpu1 {
+		t.Errorf("want span %d, STW %d, GC CPU %v; got %d, %d, %v", span1, stw1, cpu1, span2, stw2, cpu2)
+	}
+	if got, want := d.MaxPause(), s.MaxPause(); got != want {
+		t.Errorf("want max pause %d, got %d", want, got)
+	}
+	var maxMarked int64
+	for _, c := range d.Cycles() {
+		maxMarked = max(maxMarked, c.HeapMarked)
+	}
+	if maxMarked != 40<<20 {
+		t.Errorf("want max marked heap 40MB, got %d", maxMarked)
+	}
+
+	if s.Downsample(len(s.Phases())) != s {
+		t.Errorf("want s when it has few enough phases")
+	}
+}
+
+func TestDownsampleGomaxprocs(t *testing.T) {
+	// GOMAXPROCS alternates between 4 and 8, so no two adjacent
+	// cycles have the same GOMAXPROCS.
+	var log strings.Builder
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&log, "gc %d @%.3fs 5%%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, %d P\n", i+1, float64(i)*0.05, 4+4*(i%2))
+	}
+	s, err := NewFromLog(strings.NewReader(log.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, maxPhases := range []int{2, 3, 4, 8, 20, 100} {
+		d := s.Downsample(maxPhases)
+		phases := d.Phases()
+		if len(phases) > maxPhases {
+			t.Errorf("Downsample(%d): got %d phases", maxPhases, len(phases))
+		}
+		var stw1, stw2 int64
+		for _, p := range s.Phases() {
+			if p.STW {
+				stw1 += p.Duration
+			}
+		}
+		for _, p := range phases {
+			if p.STW {
+				stw2 += p.Duration
+			}
+			if p.GCProcs > float64(p.Gomaxprocs) {
+				t.Errorf("Downsample(%d): phase %+v uses more than GOMAXPROCS", maxPhases, p)
+			}
+		}
+		if stw1 != stw2 {
+			t.Errorf("Downsample(%d): want STW time %d, got %d", maxPhases, stw1, stw2)
+		}
+	}
+}
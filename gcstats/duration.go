@@ -0,0 +1,65 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import "time"
+
+// This file provides time.Duration forms of the methods that take
+// or return int64 nanoseconds.
+
+// Dur returns the duration of p, or -1ns if it is unknown.
+func (p Phase) Dur() time.Duration {
+	return time.Duration(p.Duration)
+}
+
+// BeginDur returns the time p began relative to the beginning of
+// the program, or 0 if the trace doesn't have program execution
+// times.
+func (p Phase) BeginDur() time.Duration {
+	return time.Duration(p.Begin)
+}
+
+// BeginTime returns the wall-clock time p began, given the
+// wall-clock time start at which the program began.
+func (p Phase) BeginTime(start time.Time) time.Time {
+	return start.Add(p.BeginDur())
+}
+
+// BeginDur returns the time c began relative to the beginning of
+// the program, or 0 if the trace doesn't have program execution
+// times.
+func (c Cycle) BeginDur() time.Duration {
+	return time.Duration(c.Begin)
+}
+
+// BeginTime returns the wall-clock time c began, given the
+// wall-clock time start at which the program began.
+func (c Cycle) BeginTime(start time.Time) time.Time {
+	return start.Add(c.BeginDur())
+}
+
+// MaxPauseDur is like MaxPause, but returns a time.Duration.
+func (s *GcStats) MaxPauseDur() time.Duration {
+	return time.Duration(s.MaxPause())
+}
+
+// MMUDur is like MMU, but takes the window size as a time.Duration.
+func (s *GcStats) MMUDur(window time.Duration) float64 {
+	return s.MMU(int(window))
+}
+
+// MutatorUtilizationBetweenDur is like MutatorUtilizationBetween,
+// but takes times relative to the beginning of the program as
+// time.Durations.
+func (s *GcStats) MutatorUtilizationBetweenDur(begin, end time.Duration) float64 {
+	return s.MutatorUtilizationBetween(int64(begin), int64(end))
+}
+
+// MutatorUtilizationDistributionDur is like
+// MutatorUtilizationDistribution, but takes the window size as a
+// time.Duration.
+func (s *GcStats) MutatorUtilizationDistributionDur(window time.Duration) *MUD {
+	return s.MutatorUtilizationDistribution(int(window))
+}
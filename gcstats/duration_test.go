@@ -0,0 +1,38 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDurationAPI(t *testing.T) {
+	log := []Phase{
+		{0, 2e6, PhaseMarkTerm, 1, 1, 1, true},
+		{2e6, 8e6, PhaseSweep, 1, 1, 0, false},
+	}
+	s := NewFromPhases(log, 1)
+	p := s.Phases()[1]
+	if p.Dur() != 8*time.Millisecond || p.BeginDur() != 2*time.Millisecond {
+		t.Errorf("want phase at 2ms for 8ms, got %v for %v", p.BeginDur(), p.Dur())
+	}
+	start := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	if got := p.BeginTime(start); !got.Equal(start.Add(2 * time.Millisecond)) {
+		t.Errorf("want begin time %v, got %v", start.Add(2*time.Millisecond), got)
+	}
+	if s.MaxPauseDur() != 2*time.Millisecond {
+		t.Errorf("want max pause 2ms, got %v", s.MaxPauseDur())
+	}
+	if got, want := s.MMUDur(4*time.Millisecond), s.MMU(4e6); got != want {
+		t.Errorf("MMUDur: want %v, got %v", want, got)
+	}
+	if got := s.MutatorUtilizationBetweenDur(0, 4*time.Millisecond); got != 0.5 {
+		t.Errorf("MutatorUtilizationBetweenDur: want 0.5, got %v", got)
+	}
+	if got := s.MutatorUtilizationDistributionDur(4 * time.Millisecond).InvCDF(0); got != s.MMU(4e6) {
+		t.Errorf("MutatorUtilizationDistributionDur: want min %v, got %v", s.MMU(4e6), got)
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"math"
+	"sort"
+)
+
+// dutySums records the times during which a GC cycle was active and
+// the cumulative active time, for computing duty cycles over
+// arbitrary windows.
+type dutySums struct {
+	// begin[i] and end[i] delimit the i'th active interval.
+	begin, end []int64
+	// sum[i] is the total active time before begin[i].
+	sum []int64
+	// logBegin and logEnd are the span of the log.
+	logBegin, logEnd int64
+}
+
+func newDutySums(log []Phase) *dutySums {
+	ds := &dutySums{logBegin: log[0].Begin, logEnd: log[len(log)-1].End()}
+	var total int64
+	for _, phase := range log {
+		if phase.Kind == PhaseSweep {
+			continue
+		}
+		if n := len(ds.end); n > 0 && ds.end[n-1] == phase.Begin {
+			// Extend the current interval.
+			ds.end[n-1] = phase.End()
+		} else {
+			if n > 0 {
+				total += ds.end[n-1] - ds.begin[n-1]
+			}
+			ds.begin = append(ds.begin, phase.Begin)
+			ds.end = append(ds.end, phase.End())
+			ds.sum = append(ds.sum, total)
+		}
+	}
+	return ds
+}
+
+// at returns the total active time before t.
+func (ds *dutySums) at(t int64) int64 {
+	// Find the last interval beginning at or before t.
+	i := sort.Search(len(ds.begin), func(i int) bool { return ds.begin[i] > t }) - 1
+	if i < 0 {
+		return 0
+	}
+	return ds.sum[i] + int64Min(t, ds.end[i]) - ds.begin[i]
+}
+
+// DutyCycle returns the fraction of wall-clock time between the
+// first and last logged GC during which a GC cycle was active, from
+// the beginning of sweep termination to the end of mark termination.
+// Unlike mutator utilization, this doesn't depend on how much CPU
+// the garbage collector used.
+//
+// This will panic if the trace does not have program execution times.
+func (s *GcStats) DutyCycle() float64 {
+	s.requireProgTimes()
+	if len(s.log) == 0 {
+		return math.NaN()
+	}
+	ds := newDutySums(s.log)
+	return ds.between(ds.logBegin, ds.logEnd)
+}
+
+// between returns the duty cycle in [begin, end).
+func (ds *dutySums) between(begin, end int64) float64 {
+	return float64(ds.at(end)-ds.at(begin)) / float64(end-begin)
+}
+
+// MaxDutyCycles returns the maximum GC duty cycle over all windows of
+// each size given in windowNS. For windows longer than the log, this
+// is the duty cycle of the whole log.
+//
+// This will panic if the trace does not have program execution times.
+func (s *GcStats) MaxDutyCycles(windowNS []int64) []float64 {
+	s.requireProgTimes()
+	out := make([]float64, len(windowNS))
+	if len(s.log) == 0 {
+		for i := range out {
+			out[i] = math.NaN()
+		}
+		return out
+	}
+	ds := newDutySums(s.log)
+	for i, window := range windowNS {
+		if window <= 0 {
+			out[i] = math.NaN()
+			continue
+		}
+		if window >= ds.logEnd-ds.logBegin {
+			out[i] = ds.between(ds.logBegin, ds.logEnd)
+			continue
+		}
+		// The active time in a window is piecewise linear in
+		// the window's position, so its maximum occurs when
+		// either edge of the window is at an interval edge.
+		var max float64
+		try := func(begin int64) {
+			begin = int64Max(ds.logBegin, int64Min(begin, ds.logEnd-window))
+			max = math.Max(max, ds.between(begin, begin+window))
+		}
+		for j := range ds.begin {
+			try(ds.begin[j])
+			try(ds.end[j] - window)
+		}
+		out[i] = max
+	}
+	return out
+}
@@ -0,0 +1,30 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDutyCycle(t *testing.T) {
+	s := NewFromPhases([]Phase{
+		{0, 100, PhaseSweepTerm, 1, 4, 4, true},
+		{100, 300, PhaseMark, 1, 4, 1, false},
+		{400, 100, PhaseMarkTerm, 1, 4, 4, true},
+		{500, 500, PhaseSweep, 1, 4, 0, false},
+		{1000, 100, PhaseSweepTerm, 2, 4, 4, true},
+		{1100, 100, PhaseMarkTerm, 2, 4, 4, true},
+		{1200, 800, PhaseSweep, 2, 4, 0, false},
+	}, 2)
+	if got, want := s.DutyCycle(), 700.0/2000; got != want {
+		t.Errorf("want duty cycle %v, got %v", want, got)
+	}
+	got := s.MaxDutyCycles([]int64{100, 500, 1000, 1500, 5000})
+	want := []float64{1, 1, 0.5, 700.0 / 1500, 700.0 / 2000}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want max duty cycles %v, got %v", want, got)
+	}
+}
@@ -4,7 +4,11 @@
 
 package gcstats
 
-import "sort"
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
 
 type edge struct {
 	// At x, the function steps to value y until the next edge.
@@ -13,19 +17,10 @@ type edge struct {
 	dirac float64
 }
 
-type edges []edge
-
-func (e edges) Len() int {
-	return len(e)
-}
-
-func (e edges) Less(i, j int) bool {
-	return e[i].x < e[j].x
-}
-
-func (e edges) Swap(i, j int) {
-	e[i], e[j] = e[j], e[i]
-}
+// edgePool holds scratch buffers for uniformSumToEdges, which would
+// otherwise allocate a buffer proportional to the number of addends
+// for every MUD.
+var edgePool = sync.Pool{New: func() interface{} { return new([]edge) }}
 
 // uniformSumToEdges converts a sum of scaled uniform distributions
 // representing a step function to a sorted list of the edges of that
@@ -37,7 +32,9 @@ func uniformSumToEdges(us []uniform) []edge {
 
 	// Create initial edges. Here we use y as a height change,
 	// rather than an absolute height.
-	deltas := []edge{}
+	buf := edgePool.Get().(*[]edge)
+	defer edgePool.Put(buf)
+	deltas := (*buf)[:0]
 	for _, u := range us {
 		if u.l == u.r {
 			deltas = append(deltas, edge{u.l, 0, u.area})
@@ -46,9 +43,12 @@ func uniformSumToEdges(us []uniform) []edge {
 			deltas = append(deltas, edge{u.l, h, 0}, edge{u.r, -h, 0})
 		}
 	}
+	*buf = deltas // Retain any growth for reuse
 
 	// Sort edges
-	sort.Sort(edges(deltas))
+	slices.SortFunc(deltas, func(a, b edge) int {
+		return cmp.Compare(a.x, b.x)
+	})
 
 	// Merge edges with identical x's and eliminate edges that
 	// don't contribute anything
@@ -0,0 +1,102 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+// encodingVersion is the version of the binary encodings of GcStats
+// and MUD. It must be incremented whenever the encoded types change
+// so stale encodings, such as those in an on-disk cache, are
+// rejected rather than misread.
+const encodingVersion = 2
+
+// encodedStats is the binary encoding of a GcStats.
+type encodedStats struct {
+	Version   int
+	Log       []Phase
+	N         int
+	Cycles    []Cycle
+	Diags     []Diagnostic
+	Sched     []SchedSample
+	Scvg      []ScvgSample
+	ProgTimes bool
+	Estimated bool
+	Env       Environment
+}
+
+// MarshalBinary encodes the phases, cycles, diagnostics, samples, and
+// environment of s, so a parsed trace can be saved and loaded again without
+// reparsing it. Cached analyses are not encoded.
+func (s *GcStats) MarshalBinary() ([]byte, error) {
+	s.cacheLock.Lock()
+	e := encodedStats{encodingVersion, s.log, s.n, s.cycles, s.diags, s.sched, s.scvg, s.progTimes, s.estimated, s.env}
+	s.cacheLock.Unlock()
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&e); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary sets s, which must be a new GcStats, to the trace
+// encoded by MarshalBinary in data. The result is complete, so no
+// more phases will be added to it.
+func (s *GcStats) UnmarshalBinary(data []byte) error {
+	var e encodedStats
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&e); err != nil {
+		return err
+	}
+	if e.Version != encodingVersion {
+		return fmt.Errorf("encoded GC trace has version %d; want %d", e.Version, encodingVersion)
+	}
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	s.log, s.n, s.cycles, s.diags, s.sched, s.scvg = e.Log, e.N, e.Cycles, e.Diags, e.Sched, e.Scvg
+	s.progTimes, s.estimated, s.complete, s.env = e.ProgTimes, e.Estimated, true, e.Env
+	return nil
+}
+
+// encodedMUD is the binary encoding of a MUD.
+type encodedMUD struct {
+	Version  int
+	WindowNS int
+	// Edges holds the x, y, and dirac of each edge.
+	Edges [][3]float64
+	CSums []float64
+}
+
+// MarshalBinary encodes m, so it can be saved and loaded again
+// without recomputing it.
+func (m *MUD) MarshalBinary() ([]byte, error) {
+	e := encodedMUD{Version: encodingVersion, WindowNS: m.WindowNS, Edges: make([][3]float64, len(m.edges)), CSums: m.csums}
+	for i, ed := range m.edges {
+		e.Edges[i] = [3]float64{ed.x, ed.y, ed.dirac}
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&e); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary sets m to the MUD encoded by MarshalBinary in data.
+func (m *MUD) UnmarshalBinary(data []byte) error {
+	var e encodedMUD
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&e); err != nil {
+		return err
+	}
+	if e.Version != encodingVersion {
+		return fmt.Errorf("encoded MUD has version %d; want %d", e.Version, encodingVersion)
+	}
+	m.WindowNS, m.edges, m.csums = e.WindowNS, make([]edge, len(e.Edges)), e.CSums
+	for i, v := range e.Edges {
+		m.edges[i] = edge{v[0], v[1], v[2]}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	s, err := NewFromLog(strings.NewReader(benchLog(20) + "garbage\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s2 GcStats
+	if err := s2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(s.Phases(), s2.Phases()) || !reflect.DeepEqual(s.Cycles(), s2.Cycles()) || !reflect.DeepEqual(s.Diagnostics(), s2.Diagnostics()) {
+		t.Errorf("decoded trace differs from original")
+	}
+	if s.Count() != s2.Count() || !s2.HaveProgTimes() || s.MMU(10e6) != s2.MMU(10e6) {
+		t.Errorf("decoded trace analyzes differently")
+	}
+
+	mud := s.MutatorUtilizationDistribution(10e6)
+	if data, err = mud.MarshalBinary(); err != nil {
+		t.Fatal(err)
+	}
+	var mud2 MUD
+	if err := mud2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []float64{0, 0.01, 0.5, 1} {
+		if got, want := mud2.InvCDF(q), mud.InvCDF(q); got != want {
+			t.Errorf("decoded MUD InvCDF(%v) = %v, want %v", q, got, want)
+		}
+	}
+
+	if err := new(GcStats).UnmarshalBinary([]byte("junk")); err == nil {
+		t.Errorf("want error decoding junk")
+	}
+}
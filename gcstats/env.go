@@ -0,0 +1,121 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import "strings"
+
+// Environment records the runtime settings of the program that
+// produced a trace, so results can be interpreted later. Each field is
+// in the syntax of the corresponding environment variable and is
+// empty if the setting is unknown or was left at its default.
+type Environment struct {
+	// GoVersion is the Go version of the program, such as
+	// "go1.22.1". It is recorded as GOVERSION.
+	GoVersion string
+
+	GOGC, GOMEMLIMIT, GOMAXPROCS, GODEBUG string
+}
+
+// envHeader prefixes the environment header line of a GC trace. The
+// line is a comment, so other tools ignore it.
+const envHeader = "# gcstats env:"
+
+// vars returns the variable names of the fields of e and pointers to
+// them, in the order they are printed.
+func (e *Environment) vars() ([]string, []*string) {
+	return []string{"GOVERSION", "GOGC", "GOMEMLIMIT", "GOMAXPROCS", "GODEBUG"},
+		[]*string{&e.GoVersion, &e.GOGC, &e.GOMEMLIMIT, &e.GOMAXPROCS, &e.GODEBUG}
+}
+
+// EnvironmentFromVars returns the Environment recorded by vars, a
+// list of NAME=value environment variables such as os.Environ
+// returns. As in the environment, later variables take precedence.
+// Other variables are ignored.
+func EnvironmentFromVars(vars []string) Environment {
+	var e Environment
+	names, fields := e.vars()
+	for _, kv := range vars {
+		name, val, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		for i := range names {
+			if names[i] == name {
+				*fields[i] = val
+			}
+		}
+	}
+	return e
+}
+
+// Vars returns the known settings of e as NAME=value strings.
+func (e Environment) Vars() []string {
+	var out []string
+	names, fields := e.vars()
+	for i, f := range fields {
+		if *f != "" {
+			out = append(out, names[i]+"="+*f)
+		}
+	}
+	return out
+}
+
+// String returns the known settings of e separated by spaces, such
+// as "GOVERSION=go1.22.1 GOGC=200".
+func (e Environment) String() string {
+	return strings.Join(e.Vars(), " ")
+}
+
+// Header returns a line that records e in a GC trace. The parser
+// reads the line back into the trace's Environment, so a collector
+// can write it ahead of a trace to make the saved trace describe the
+// run that produced it. Values must not contain spaces.
+func (e Environment) Header() string {
+	return envHeader + " " + e.String()
+}
+
+// parseEnvLine parses an environment header line written by
+// Environment.Header.
+func parseEnvLine(line string) (Environment, bool) {
+	rest, ok := strings.CutPrefix(line, envHeader)
+	if !ok {
+		return Environment{}, false
+	}
+	return EnvironmentFromVars(strings.Fields(rest)), true
+}
+
+// update sets the fields of e to the known settings of o.
+func (e *Environment) update(o Environment) {
+	_, fields := e.vars()
+	_, ofields := o.vars()
+	for i, f := range ofields {
+		if *f != "" {
+			*fields[i] = *f
+		}
+	}
+}
+
+// updateEnv sets the settings of s.env known by env.
+func (s *GcStats) updateEnv(env Environment) {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	s.env.update(env)
+}
+
+// Environment returns the runtime settings recorded for s, either by
+// environment header lines in its trace or by SetEnvironment.
+func (s *GcStats) Environment() Environment {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	return s.env
+}
+
+// SetEnvironment records env as the runtime settings of the program
+// that produced s, such as by a collector that knows them.
+func (s *GcStats) SetEnvironment(env Environment) {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	s.env = env
+}
@@ -0,0 +1,48 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEnvironment(t *testing.T) {
+	env := EnvironmentFromVars([]string{"HOME=/root", "GOGC=100", "GOMAXPROCS=4", "GOGC=off", "GODEBUG=madvdontneed=1,gctrace=1"})
+	want := Environment{GOGC: "off", GOMAXPROCS: "4", GODEBUG: "madvdontneed=1,gctrace=1"}
+	if env != want {
+		t.Fatalf("want %+v, got %+v", want, env)
+	}
+	env.GoVersion = "go1.27.1"
+	if got, want := env.String(), "GOVERSION=go1.27.1 GOGC=off GOMAXPROCS=4 GODEBUG=madvdontneed=1,gctrace=1"; got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+
+	// The header is read back from a trace, and later header
+	// lines add to earlier ones.
+	cycles := strings.SplitAfter(benchLog(3), "\n")
+	log := env.Header() + "\n" + cycles[0] + cycles[1] + Environment{GOMEMLIMIT: "1GiB"}.Header() + "\n" + cycles[2]
+	want = env
+	want.GOMEMLIMIT = "1GiB"
+	s, err := NewFromLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Environment(); got != want {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+	for _, n := range []int{1, 2, 4} {
+		p := NewParserBytes(nil)
+		if err := p.parseChunks(splitLines([]byte(log), n, 1)); err != nil {
+			t.Fatal(err)
+		}
+		if got := p.Stats().Environment(); got != want {
+			t.Errorf("in %d chunks: want %+v, got %+v", n, want, got)
+		}
+	}
+	if got := s.SkipCycles(1).Environment(); got != want {
+		t.Errorf("SkipCycles lost the environment: got %+v", got)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"fmt"
+	"time"
+)
+
+// AssumedSTWFraction is the fraction of the program's run time that
+// EstimateBeginTimes assumes was spent in GC phases if the total run
+// time is not known.
+const AssumedSTWFraction = 0.05
+
+// EstimateBeginTimes returns a copy of s with approximate program
+// execution times reconstructed from the durations of its phases, for
+// Go 1.4 traces that lack begin times. This makes analyses of
+// mutator utilization possible, but their results are only as good
+// as the estimate.
+//
+// The time between cycles is the total run time less the time spent
+// in GC phases. It is divided between the gaps in proportion to the
+// heap allocated in each gap or, if the trace lacks heap sizes,
+// evenly. If total is 0, EstimateBeginTimes assumes GC phases took
+// AssumedSTWFraction of the run time.
+//
+// The result's EstimatedTimes method returns true and its diagnostics
+// record the estimate. If s already has program execution times,
+// EstimateBeginTimes returns s.
+func (s *GcStats) EstimateBeginTimes(total time.Duration) (*GcStats, error) {
+	if s.progTimes {
+		return s, nil
+	}
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+
+	// Weight each gap between cycles by the heap allocated in it.
+	// Gaps are the sweep phases, whose durations are unknown.
+	cycleIdx := make(map[int]int, len(s.cycles))
+	for i, c := range s.cycles {
+		cycleIdx[c.N] = i
+	}
+	var busy int64
+	var weights []float64
+	byHeap := true
+	for _, p := range s.log {
+		if p.Duration >= 0 {
+			busy += p.Duration
+			continue
+		}
+		w := 0.0
+		if i, ok := cycleIdx[p.N]; ok && i+1 < len(s.cycles) {
+			w = float64(s.cycles[i+1].HeapTrigger - s.cycles[i].HeapLive)
+		}
+		if w <= 0 {
+			byHeap = false
+		}
+		weights = append(weights, w)
+	}
+	var sum float64
+	for i := range weights {
+		if !byHeap {
+			weights[i] = 1
+		}
+		sum += weights[i]
+	}
+
+	how := "a total run time of " + total.String()
+	if total == 0 {
+		total = time.Duration(float64(busy) / AssumedSTWFraction)
+		how = fmt.Sprintf("an assumed %.0f%% of run time in GC", 100*AssumedSTWFraction)
+	}
+	gap := int64(total) - busy
+	if gap < 0 {
+		return nil, fmt.Errorf("total run time %s is less than the %s spent in GC phases", total, time.Duration(busy))
+	}
+
+	out := &GcStats{
+		log:       make([]Phase, len(s.log)),
+		cycles:    make([]Cycle, len(s.cycles)),
+		n:         s.n,
+		progTimes: true,
+		estimated: true,
+		complete:  true,
+		diags:     s.diags[:len(s.diags):len(s.diags)],
+		env:       s.env,
+	}
+	copy(out.cycles, s.cycles)
+	var t, assigned int64
+	var w float64
+	gaps := 0
+	for i, p := range s.log {
+		p.Begin = t
+		if p.Duration < 0 {
+			// Compute each end from the cumulative weight so
+			// rounding errors don't accumulate.
+			w += weights[gaps]
+			gaps++
+			end := int64(float64(gap) * w / sum)
+			if gaps == len(weights) {
+				end = gap
+			}
+			p.Duration = end - assigned
+			assigned = end
+		}
+		t += p.Duration
+		out.log[i] = p
+		if j, ok := cycleIdx[p.N]; ok && (i == 0 || s.log[i-1].N != p.N) {
+			out.cycles[j].Begin = p.Begin
+		}
+	}
+	// Time the scavenger samples by the end of their cycles.
+	ends := make(map[int]int64, len(out.cycles))
+	for _, p := range out.log {
+		ends[p.N] = p.Begin
+	}
+	out.scvg = make([]ScvgSample, len(s.scvg))
+	for i, sample := range s.scvg {
+		sample.Time = ends[sample.GC]
+		out.scvg[i] = sample
+	}
+	gapsBy := "evenly"
+	if byHeap {
+		gapsBy = "by heap allocated"
+	}
+	out.diags = append(out.diags, Diagnostic{Message: fmt.Sprintf("program execution times are estimated from phase durations and %s, with time between cycles divided %s", how, gapsBy)})
+	return out, nil
+}
+
+// EstimatedTimes returns true if the program execution times of s
+// were estimated by EstimateBeginTimes rather than recorded in the
+// trace.
+func (s *GcStats) EstimatedTimes() bool {
+	return s.estimated
+}
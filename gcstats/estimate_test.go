@@ -0,0 +1,69 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEstimateBeginTimes(t *testing.T) {
+	// 1 MB is allocated between cycles 1 and 2 and 3 MB between
+	// cycles 2 and 3.
+	const log = `gc1(1): 100+0+0+0 us, 4 -> 2 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields
+gc2(1): 100+0+0+0 us, 3 -> 2 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields
+gc3(1): 100+0+0+0 us, 5 -> 2 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields
+`
+	s, err := NewFromLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.HaveProgTimes() {
+		t.Fatal("log unexpectedly has program times")
+	}
+
+	est, err := s.EstimateBeginTimes(100300 * time.Microsecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !est.HaveProgTimes() || !est.EstimatedTimes() || s.EstimatedTimes() {
+		t.Fatalf("want estimated program times")
+	}
+	// The 100ms between cycles is split 1:3.
+	wantBegins := []int64{0, 25100e3, 100200e3}
+	for i, c := range est.Cycles() {
+		if c.Begin != wantBegins[i] {
+			t.Errorf("cycle %d: want begin %d, got %d", c.N, wantBegins[i], c.Begin)
+		}
+	}
+	phases := est.Phases()
+	for i := 1; i < len(phases); i++ {
+		if phases[i-1].End() != phases[i].Begin {
+			t.Errorf("phase %d ends at %d, but phase %d begins at %d", i-1, phases[i-1].End(), i, phases[i].Begin)
+		}
+	}
+	if last := phases[len(phases)-1]; last.End() != int64(100300*time.Microsecond) {
+		t.Errorf("want trace to end at 100.3ms, got %d", last.End())
+	}
+	if d := est.Diagnostics(); len(d) == 0 || !strings.HasPrefix(d[len(d)-1].Message, "program execution times are estimated") {
+		t.Errorf("want estimate diagnostic, got %v", d)
+	}
+
+	// Without a total, GC phases are assumed to take a fixed
+	// fraction of the run time.
+	est, err = s.EstimateBeginTimes(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	phases = est.Phases()
+	if got, want := phases[len(phases)-1].End(), int64(300e3/AssumedSTWFraction); got != want {
+		t.Errorf("want assumed run time %d, got %d", want, got)
+	}
+
+	if _, err := s.EstimateBeginTimes(200 * time.Microsecond); err == nil {
+		t.Errorf("want error for total shorter than GC phases")
+	}
+}
@@ -4,6 +4,11 @@
 
 package gcstats
 
+import (
+	"slices"
+	"sync"
+)
+
 // Phase represents the times for a single phase of a garbage
 // collection cycle.
 type Phase struct {
@@ -57,15 +62,96 @@ const (
 type GcStats struct {
 	// Log of phases in order. These are assumed to span every
 	// moment of program execution, though the exact duration of
-	// phases spanning GC cycles may not be known.
+	// phases spanning GC cycles may not be known. Phases are only
+	// ever appended, so consumers may incorporate the phases
+	// added since they last looked.
 	log []Phase
 	n   int // # of GCs
 
+	// cycles records each GC cycle, if known.
+	cycles []Cycle
+
+	// diags records issues found while parsing the log.
+	diags []Diagnostic
+
+	// sched records scheduler trace samples interleaved with the
+	// log.
+	sched []SchedSample
+
+	// scvg records scavenger samples interleaved with the log.
+	scvg []ScvgSample
+
+	// env records the runtime settings of the traced program.
+	env Environment
+
 	// progTimes indicates that phases have begin times that
 	// indicate when they happened during program execution.
 	//
 	// If true, log[i].Begin+log[i].Duration == log[i+1].Begin.
 	progTimes bool
+
+	// estimated indicates that the begin times were estimated by
+	// EstimateBeginTimes rather than recorded in the trace.
+	estimated bool
+
+	// complete indicates that no more phases will be appended to
+	// log, so cached analyses don't need to retain the state
+	// necessary to update them incrementally.
+	complete bool
+
+	// cacheLock protects log and the caches below, which are
+	// computed lazily from log. Phases may be appended to log, in
+	// which case the caches are updated incrementally, but any
+	// other change to log must invalidate the caches.
+	cacheLock sync.Mutex
+
+	// sums caches cumulative utilization sums over log for
+	// computing windowed mutator utilization.
+	sums *utilSums
+
+	// meanMU caches running totals for MutatorUtilization.
+	meanMU meanMU
+
+	// muds caches builders for mutator utilization
+	// distributions.
+	muds map[mudKey]*mudBuilder
+}
+
+// NewFromPhases constructs GcStats from a log of phases recorded by
+// some means other than a GC trace. phases must be in order and have
+// program times, so each phase begins when the previous phase ends.
+// ncycles is the number of GC cycles in phases. The returned GcStats
+// retains phases, so the caller must not modify it.
+func NewFromPhases(phases []Phase, ncycles int) *GcStats {
+	return &GcStats{log: phases, n: ncycles, progTimes: true, complete: true}
+}
+
+// appendCycle appends cycle and phases, which must be the newly
+// complete phases, to s. Cached analyses incorporate the new phases
+// the next time they are used.
+func (s *GcStats) appendCycle(phases []Phase, cycle Cycle) {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	s.log = append(s.log, phases...)
+	s.cycles = append(s.cycles, cycle)
+	s.n++
+}
+
+// setComplete indicates that no more phases will be appended to s.
+func (s *GcStats) setComplete() {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	s.complete = true
+}
+
+// invalidate discards all analysis results cached on s. This must be
+// called whenever s.log changes other than by appendCycle.
+func (s *GcStats) invalidate() {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	s.sums = nil
+	s.meanMU = meanMU{}
+	s.muds = nil
 }
 
 // HaveProgTimes returns true if the log has begin times and hence
@@ -92,30 +178,15 @@ func (s *GcStats) Phases() []Phase {
 // averages their CPU utilization. If the joined phases have multiple
 // phase kinds, the joined phase will have kind PhaseMultiple.
 func (s *GcStats) Stops() []Phase {
+	return JoinStops(s.log)
+}
+
+// JoinStops is like GcStats.Stops, but returns the stop-the-world
+// phases of phases, which must be consecutive phases of a log.
+func JoinStops(phases []Phase) []Phase {
 	stw := []Phase{}
-	join := false
-	for _, phase := range s.log {
-		if !phase.STW {
-			join = false
-			continue
-		}
-		if join {
-			// Join with previous STW
-			prev := stw[len(stw)-1]
-			dur1 := prev.Duration
-			dur2 := phase.Duration
-			f := float64(dur1) / float64(dur1+dur2)
-			prev.GCProcs = prev.GCProcs*f + phase.GCProcs*(1-f)
-
-			prev.Duration += dur2
-			if prev.Kind != phase.Kind {
-				prev.Kind = PhaseMultiple
-			}
-			stw[len(stw)-1] = prev
-			continue
-		}
+	for phase := range joinStops(slices.Values(phases)) {
 		stw = append(stw, phase)
-		join = true
 	}
 	return stw
 }
@@ -123,7 +194,7 @@ func (s *GcStats) Stops() []Phase {
 // MaxPause returns the maximum pause time in nanoseconds.
 func (s *GcStats) MaxPause() int64 {
 	maxpause := int64(0)
-	for _, phase := range s.Stops() {
+	for phase := range s.StopsSeq() {
 		if phase.Duration > maxpause {
 			maxpause = phase.Duration
 		}
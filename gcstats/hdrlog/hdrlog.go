@@ -0,0 +1,239 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package hdrlog writes HdrHistogram interval logs, so distributions
+// computed by gcstats can be merged, plotted, and compared with the
+// HdrHistogram tools, such as HistogramLogProcessor.
+//
+// The log format is described at
+// https://github.com/HdrHistogram/HdrHistogram/blob/master/src/main/java/org/HdrHistogram/HistogramLogWriter.java
+// Histograms are written in the compressed V2 encoding.
+package hdrlog
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"time"
+)
+
+// Version is the version of the log format written by Writer.
+const Version = "1.3"
+
+const (
+	encodingCookie           = 0x1c849303 | 0x10
+	compressedEncodingCookie = 0x1c849304 | 0x10
+)
+
+// A Histogram counts integer values with a fixed number of
+// significant decimal digits, laid out like HdrHistogram's
+// histograms so it can be encoded for them.
+type Histogram struct {
+	// Ratio is the value of one integer unit, for histograms of
+	// non-integer values. The zero value is treated as 1.
+	Ratio float64
+
+	lowest, highest int64
+	digits          int
+	counts          []int64
+	max             int64
+
+	unitMagnitude               uint
+	subBucketHalfCountMagnitude uint
+	subBucketHalfCount          int
+	subBucketMask               int64
+}
+
+// New returns an empty histogram of values from lowest to highest,
+// which records each value to the given number of significant decimal
+// digits, between 0 and 5.
+func New(lowest, highest int64, digits int) *Histogram {
+	if lowest < 1 || highest < 2*lowest || digits < 0 || digits > 5 {
+		panic(fmt.Sprintf("bad histogram range %d-%d with %d digits", lowest, highest, digits))
+	}
+	h := &Histogram{lowest: lowest, highest: highest, digits: digits}
+	single := 2 * int64(math.Pow10(digits))
+	subBucketCountMagnitude := uint(math.Ceil(math.Log2(float64(single))))
+	h.subBucketHalfCountMagnitude = max(subBucketCountMagnitude, 1) - 1
+	subBucketCount := int64(1) << (h.subBucketHalfCountMagnitude + 1)
+	h.subBucketHalfCount = int(subBucketCount / 2)
+	h.unitMagnitude = uint(bits.Len64(uint64(lowest)) - 1)
+	h.subBucketMask = (subBucketCount - 1) << h.unitMagnitude
+
+	// Find the number of buckets needed to cover highest.
+	buckets := 1
+	for untrackable := subBucketCount << h.unitMagnitude; untrackable <= highest; untrackable <<= 1 {
+		if untrackable > math.MaxInt64/2 {
+			buckets++
+			break
+		}
+		buckets++
+	}
+	h.counts = make([]int64, (buckets+1)*h.subBucketHalfCount)
+	return h
+}
+
+// Record adds value v to h, clamping it to h's range.
+func (h *Histogram) Record(v int64) {
+	v = min(max(v, 0), h.highest)
+	h.counts[h.index(v)]++
+	h.max = max(h.max, v)
+}
+
+// Max returns the largest value recorded in h.
+func (h *Histogram) Max() int64 {
+	return h.max
+}
+
+// index returns the index in h.counts of the bucket containing v.
+func (h *Histogram) index(v int64) int {
+	base := 64 - int(h.unitMagnitude) - int(h.subBucketHalfCountMagnitude) - 1
+	bucket := base - bits.LeadingZeros64(uint64(v|h.subBucketMask))
+	sub := int(v >> (uint(bucket) + h.unitMagnitude))
+	return (bucket+1)<<h.subBucketHalfCountMagnitude + sub - h.subBucketHalfCount
+}
+
+// Encode returns h in the compressed V2 encoding.
+func (h *Histogram) Encode() ([]byte, error) {
+	// Encode the counts up to the last nonzero count as ZigZag
+	// LEB128 varints, with runs of zeros as their negated length.
+	last := len(h.counts) - 1
+	for last >= 0 && h.counts[last] == 0 {
+		last--
+	}
+	var payload []byte
+	for i := 0; i <= last; {
+		if h.counts[i] != 0 {
+			payload = appendZigZag(payload, h.counts[i])
+			i++
+			continue
+		}
+		zeros := 0
+		for ; i <= last && h.counts[i] == 0; i++ {
+			zeros++
+		}
+		if zeros == 1 {
+			payload = appendZigZag(payload, 0)
+		} else {
+			payload = appendZigZag(payload, -int64(zeros))
+		}
+	}
+
+	ratio := h.Ratio
+	if ratio == 0 {
+		ratio = 1
+	}
+	var raw bytes.Buffer
+	binary.Write(&raw, binary.BigEndian, struct {
+		Cookie, PayloadLen, NormalizingOffset, Digits int32
+		Lowest, Highest                               int64
+		Ratio                                         float64
+	}{encodingCookie, int32(len(payload)), 0, int32(h.digits), h.lowest, h.highest, ratio})
+	raw.Write(payload)
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	if _, err := zw.Write(raw.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	out := binary.BigEndian.AppendUint32(nil, compressedEncodingCookie)
+	out = binary.BigEndian.AppendUint32(out, uint32(compressed.Len()))
+	return append(out, compressed.Bytes()...), nil
+}
+
+// appendZigZag appends x to buf as a ZigZag-encoded LEB128 varint of
+// at most 9 bytes, as HdrHistogram encodes counts.
+func appendZigZag(buf []byte, x int64) []byte {
+	u := uint64(x<<1) ^ uint64(x>>63)
+	for i := 0; i < 8; i++ {
+		if u < 0x80 {
+			return append(buf, byte(u))
+		}
+		buf = append(buf, byte(u)|0x80)
+		u >>= 7
+	}
+	// The ninth byte holds the remaining 8 bits.
+	return append(buf, byte(u))
+}
+
+// An Interval is a histogram of the values observed over a period of
+// time.
+type Interval struct {
+	// Tag distinguishes histograms of different quantities in the
+	// same log. It may be empty.
+	Tag string
+
+	// Start is the start of the interval relative to the log's
+	// start time, and Length is its length.
+	Start, Length time.Duration
+
+	// Max is the largest value in the interval, in the units the
+	// log reports, such as milliseconds for histograms of
+	// nanoseconds.
+	Max float64
+
+	Hist *Histogram
+}
+
+// A Writer writes an HdrHistogram interval log.
+type Writer struct {
+	w           io.Writer
+	wroteHeader bool
+
+	// Start is the wall-clock time the log begins. If it is zero,
+	// the log has no start time.
+	Start time.Time
+
+	// Comment, if not empty, is written as a comment at the top of
+	// the log.
+	Comment string
+}
+
+// NewWriter returns a Writer that writes a log to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes interval iv to the log, preceded by the log's header
+// if this is the first interval.
+func (w *Writer) Write(iv Interval) error {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if w.Comment != "" {
+			if _, err := fmt.Fprintf(w.w, "#%s\n", w.Comment); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w.w, "#[Histogram log format version %s]\n", Version); err != nil {
+			return err
+		}
+		if !w.Start.IsZero() {
+			secs := float64(w.Start.UnixNano()) / 1e9
+			if _, err := fmt.Fprintf(w.w, "#[StartTime: %.3f (seconds since epoch), %s]\n#[BaseTime: %.3f (seconds since epoch)]\n", secs, w.Start.UTC().Format(time.RFC1123), secs); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w.w, `"StartTimestamp","Interval_Length","Interval_Max","Interval_Compressed_Histogram"`); err != nil {
+			return err
+		}
+	}
+	enc, err := iv.Hist.Encode()
+	if err != nil {
+		return err
+	}
+	tag := ""
+	if iv.Tag != "" {
+		tag = "Tag=" + iv.Tag + ","
+	}
+	_, err = fmt.Fprintf(w.w, "%s%.3f,%.3f,%.3f,%s\n", tag, iv.Start.Seconds(), iv.Length.Seconds(), iv.Max, base64.StdEncoding.EncodeToString(enc))
+	return err
+}
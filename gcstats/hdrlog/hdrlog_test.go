@@ -0,0 +1,148 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package hdrlog
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIndex(t *testing.T) {
+	h := New(1, 3600e9, 3)
+	for _, test := range []struct {
+		v     int64
+		index int
+	}{
+		{0, 0}, {1, 1}, {2047, 2047}, {2048, 2048}, {2049, 2048}, {4095, 3071}, {4096, 3072},
+	} {
+		if got := h.index(test.v); got != test.index {
+			t.Errorf("index(%d): want %d, got %d", test.v, test.index, got)
+		}
+	}
+}
+
+// decode decodes a compressed V2 histogram and returns its header and
+// counts.
+func decode(t *testing.T, enc []byte) (digits int32, lowest, highest int64, ratio float64, counts []int64) {
+	if binary.BigEndian.Uint32(enc) != compressedEncodingCookie {
+		t.Fatalf("bad compressed cookie %#x", binary.BigEndian.Uint32(enc))
+	}
+	if n := binary.BigEndian.Uint32(enc[4:]); int(n) != len(enc)-8 {
+		t.Fatalf("compressed length %d, want %d", n, len(enc)-8)
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(enc[8:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hdr struct {
+		Cookie, PayloadLen, NormalizingOffset, Digits int32
+		Lowest, Highest                               int64
+		Ratio                                         float64
+	}
+	r := bytes.NewReader(raw)
+	if err := binary.Read(r, binary.BigEndian, &hdr); err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Cookie != encodingCookie || int(hdr.PayloadLen) != r.Len() {
+		t.Fatalf("bad header %+v with %d payload bytes", hdr, r.Len())
+	}
+	for r.Len() > 0 {
+		u, err := binary.ReadUvarint(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		x := int64(u>>1) ^ -int64(u&1)
+		if x < 0 {
+			counts = append(counts, make([]int64, -x)...)
+		} else {
+			counts = append(counts, x)
+		}
+	}
+	return hdr.Digits, hdr.Lowest, hdr.Highest, hdr.Ratio, counts
+}
+
+func TestEncode(t *testing.T) {
+	h := New(1, 3600e9, 3)
+	h.Ratio = 1e-3
+	for _, v := range []int64{1, 1, 5, 2048, 2049, 1e6} {
+		h.Record(v)
+	}
+	if h.Max() != 1e6 {
+		t.Errorf("want max 1e6, got %d", h.Max())
+	}
+	enc, err := h.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	digits, lowest, highest, ratio, counts := decode(t, enc)
+	if digits != 3 || lowest != 1 || highest != 3600e9 || ratio != 1e-3 {
+		t.Errorf("want 3 digits, range 1-3600e9, ratio 1e-3; got %d, %d-%d, %v", digits, lowest, highest, ratio)
+	}
+	want := make([]int64, h.index(1e6)+1)
+	want[1], want[5], want[2048], want[h.index(1e6)] = 2, 1, 2, 1
+	if len(counts) != len(want) {
+		t.Fatalf("want %d counts, got %d", len(want), len(counts))
+	}
+	for i := range want {
+		if counts[i] != want[i] {
+			t.Errorf("count %d: want %d, got %d", i, want[i], counts[i])
+		}
+	}
+}
+
+func TestAppendZigZag(t *testing.T) {
+	for _, x := range []int64{0, 1, -1, 63, -64, 64, 1 << 40, -(1 << 40)} {
+		buf := appendZigZag(nil, x)
+		u, n := binary.Uvarint(buf)
+		if n != len(buf) || int64(u>>1)^-int64(u&1) != x {
+			t.Errorf("%d: encoded as %x", x, buf)
+		}
+	}
+	// Values that need 9 bytes use all 8 bits of the last byte.
+	if buf := appendZigZag(nil, math.MinInt64); len(buf) != 9 || buf[8] != 0xff {
+		t.Errorf("MinInt64: encoded as %x", buf)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Start = time.Unix(1441812123, 250e6)
+	h := New(1, 1000, 2)
+	h.Record(10)
+	for i, tag := range []string{"", "stw"} {
+		if err := w.Write(Interval{Tag: tag, Start: time.Duration(i) * time.Second, Length: time.Second, Max: 0.01, Hist: h}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("want 6 lines, got:\n%s", buf.String())
+	}
+	if lines[0] != "#[Histogram log format version 1.3]" || !strings.HasPrefix(lines[1], "#[StartTime: 1441812123.250 ") || lines[2] != "#[BaseTime: 1441812123.250 (seconds since epoch)]" {
+		t.Errorf("bad header:\n%s", buf.String())
+	}
+	if !strings.HasPrefix(lines[4], "0.000,1.000,0.010,HISTF") || !strings.HasPrefix(lines[5], "Tag=stw,1.000,1.000,0.010,HISTF") {
+		t.Errorf("bad intervals:\n%s", buf.String())
+	}
+	enc, err := base64.StdEncoding.DecodeString(lines[5][strings.LastIndex(lines[5], ",")+1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, _, counts := decode(t, enc); counts[10] != 1 {
+		t.Errorf("want one 10, got counts %v", counts)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package inprocess collects GC statistics for the running program,
+// so it can analyze its own pauses and mutator utilization without
+// running under GODEBUG=gctrace=1.
+//
+// A Collector periodically samples runtime/metrics and the runtime's
+// record of recent GC pauses and models the program's execution as a
+// log of phases. Each GC pause becomes a STW phase. The runtime
+// doesn't report when concurrent GC work happens, so the concurrent
+// GC CPU time in each sampling interval is spread uniformly over the
+// interval. Hence, analyses over windows shorter than the sampling
+// interval overestimate the utilization of windows during concurrent
+// marking.
+package inprocess
+
+import (
+	"math"
+	"os"
+	"runtime"
+	"runtime/metrics"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// DefaultInterval is the default sampling interval of a Collector.
+const DefaultInterval = time.Second
+
+// Sampled runtime/metrics.
+const (
+	metricCycles     = "/gc/cycles/total:gc-cycles"
+	metricGCCPU      = "/cpu/classes/gc/total:cpu-seconds"
+	metricPauseCPU   = "/cpu/classes/gc/pause:cpu-seconds"
+	metricGomaxprocs = "/sched/gomaxprocs:threads"
+	metricGOGC       = "/gc/gogc:percent"
+	metricMemLimit   = "/gc/gomemlimit:bytes"
+)
+
+// A Collector samples the GC behavior of the running program.
+type Collector struct {
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+
+	// mu protects the fields below.
+	mu sync.Mutex
+
+	// log is the modeled phases since the collector started.
+	log []gcstats.Phase
+
+	// start is the wall-clock time at which the collector started.
+	// Phase times are relative to start. startCycles is the number
+	// of GC cycles completed before the collector started.
+	start       int64
+	startCycles uint64
+
+	// last is the previous sample.
+	last sample
+
+	metrics  []metrics.Sample
+	memStats runtime.MemStats
+}
+
+type sample struct {
+	now    int64  // Wall-clock time in Unix nanoseconds
+	cycles uint64 // Completed GC cycles
+	gcCPU  float64
+	stwCPU float64
+}
+
+// Start starts a Collector that samples the program's GC behavior
+// every interval. If interval is 0, it uses DefaultInterval.
+func Start(interval time.Duration) *Collector {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	c := &Collector{
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		metrics: []metrics.Sample{
+			{Name: metricCycles},
+			{Name: metricGCCPU},
+			{Name: metricPauseCPU},
+			{Name: metricGomaxprocs},
+		},
+	}
+	c.last, _ = c.read()
+	c.start, c.startCycles = c.last.now, c.last.cycles
+	go c.loop()
+	return c
+}
+
+func (c *Collector) loop() {
+	defer close(c.done)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.Sample()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// Stop takes a final sample and stops the collector.
+func (c *Collector) Stop() {
+	close(c.stop)
+	<-c.done
+	c.Sample()
+}
+
+// Stats returns the GC statistics as of the most recent sample. The
+// returned GcStats is not affected by later samples. Its Environment
+// records the program's current GC settings.
+func (c *Collector) Stats() *gcstats.GcStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ncycles := int(c.last.cycles - c.startCycles)
+	// Limit the capacity so later samples don't modify the
+	// returned log.
+	s := gcstats.NewFromPhases(c.log[:len(c.log):len(c.log)], ncycles)
+	s.SetEnvironment(environment())
+	return s
+}
+
+// environment returns the program's current GC settings, which may
+// differ from its environment variables if it changed them with
+// runtime/debug.
+func environment() gcstats.Environment {
+	env := gcstats.Environment{
+		GoVersion:  runtime.Version(),
+		GOMAXPROCS: strconv.Itoa(runtime.GOMAXPROCS(0)),
+		GODEBUG:    os.Getenv("GODEBUG"),
+	}
+	settings := []metrics.Sample{{Name: metricGOGC}, {Name: metricMemLimit}}
+	metrics.Read(settings)
+	if settings[0].Value.Kind() == metrics.KindUint64 {
+		if gogc := settings[0].Value.Uint64(); gogc == math.MaxUint64 {
+			env.GOGC = "off"
+		} else {
+			env.GOGC = strconv.FormatUint(gogc, 10)
+		}
+	}
+	if settings[1].Value.Kind() == metrics.KindUint64 {
+		if limit := settings[1].Value.Uint64(); limit != math.MaxInt64 {
+			env.GOMEMLIMIT = strconv.FormatUint(limit, 10)
+		}
+	}
+	return env
+}
+
+// read samples the runtime's GC state. It returns the sample and the
+// current GOMAXPROCS. It must be called with c.mu held or before c is
+// shared.
+func (c *Collector) read() (sample, int) {
+	metrics.Read(c.metrics)
+	s := sample{now: time.Now().UnixNano()}
+	gomaxprocs := runtime.GOMAXPROCS(0)
+	for _, m := range c.metrics {
+		if m.Value.Kind() == metrics.KindBad {
+			// Not supported by this runtime.
+			continue
+		}
+		switch m.Name {
+		case metricCycles:
+			s.cycles = m.Value.Uint64()
+		case metricGCCPU:
+			s.gcCPU = m.Value.Float64()
+		case metricPauseCPU:
+			s.stwCPU = m.Value.Float64()
+		case metricGomaxprocs:
+			gomaxprocs = int(m.Value.Uint64())
+		}
+	}
+	return s, gomaxprocs
+}
+
+// pause is a GC pause in Unix nanoseconds.
+type pause struct {
+	begin, end int64
+	n          int // GC cycle of this pause
+}
+
+// Sample samples the program's GC behavior now, rather than waiting
+// for the next sampling interval.
+func (c *Collector) Sample() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cur, gomaxprocs := c.read()
+	if cur.now <= c.last.now {
+		return
+	}
+
+	// Collect the pauses since the last sample. The runtime only
+	// records the times of the most recent len(PauseEnd) cycles,
+	// so earlier pauses in this interval are lost.
+	var pauses []pause
+	if cur.cycles != c.last.cycles {
+		runtime.ReadMemStats(&c.memStats)
+		ms := &c.memStats
+		nrec := uint64(len(ms.PauseEnd))
+		for n := uint64(ms.NumGC); n > c.last.cycles && n+nrec > uint64(ms.NumGC); n-- {
+			i := (n + nrec - 1) % nrec
+			end := int64(ms.PauseEnd[i])
+			p := pause{end - int64(ms.PauseNs[i]), end, int(n)}
+			if p.end <= c.last.now || p.begin >= cur.now {
+				continue
+			}
+			p.begin = max(p.begin, c.last.now)
+			p.end = min(p.end, cur.now)
+			pauses = append(pauses, p)
+		}
+		sort.Slice(pauses, func(i, j int) bool { return pauses[i].begin < pauses[j].begin })
+		cur.cycles = uint64(ms.NumGC)
+	}
+
+	// Spread the concurrent GC CPU time over the time the world
+	// was running.
+	var stwNS int64
+	for _, p := range pauses {
+		stwNS += p.end - p.begin
+	}
+	runNS := cur.now - c.last.now - stwNS
+	concCPU := (cur.gcCPU - c.last.gcCPU) - (cur.stwCPU - c.last.stwCPU)
+	var gcProcs float64
+	if runNS > 0 && concCPU > 0 {
+		gcProcs = min(concCPU*1e9/float64(runNS), float64(gomaxprocs))
+	}
+
+	// Append the phases for this interval.
+	t, n := c.last.now, int(c.last.cycles)
+	run := func(end int64) {
+		if end <= t {
+			return
+		}
+		kind := gcstats.PhaseSweep
+		if gcProcs > 0 {
+			kind = gcstats.PhaseMark
+		}
+		c.log = append(c.log, gcstats.Phase{
+			Begin: t - c.start, Duration: end - t, Kind: kind, N: n,
+			Gomaxprocs: gomaxprocs, GCProcs: gcProcs,
+		})
+		t = end
+	}
+	for _, p := range pauses {
+		if p.end <= t {
+			// Overlaps the previous pause.
+			continue
+		}
+		run(p.begin)
+		n = p.n
+		c.log = append(c.log, gcstats.Phase{
+			Begin: t - c.start, Duration: p.end - t, Kind: gcstats.PhaseMarkTerm, N: n,
+			Gomaxprocs: gomaxprocs, GCProcs: float64(gomaxprocs), STW: true,
+		})
+		t = p.end
+	}
+	run(cur.now)
+	c.last = cur
+}
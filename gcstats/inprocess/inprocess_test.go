@@ -0,0 +1,47 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inprocess
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCollector(t *testing.T) {
+	c := Start(time.Hour)
+	for i := 0; i < 3; i++ {
+		time.Sleep(time.Millisecond)
+		runtime.GC()
+		c.Sample()
+	}
+	c.Stop()
+
+	s := c.Stats()
+	if s.Count() < 3 {
+		t.Errorf("want at least 3 GCs, got %d", s.Count())
+	}
+	phases := s.Phases()
+	if len(phases) == 0 {
+		t.Fatal("no phases recorded")
+	}
+	if phases[0].Begin != 0 {
+		t.Errorf("first phase begins at %d, want 0", phases[0].Begin)
+	}
+	for i := 1; i < len(phases); i++ {
+		if phases[i-1].End() != phases[i].Begin {
+			t.Fatalf("phase %d ends at %d, but phase %d begins at %d", i-1, phases[i-1].End(), i, phases[i].Begin)
+		}
+	}
+	if len(s.Stops()) < 3 {
+		t.Errorf("want at least 3 pauses, got %d", len(s.Stops()))
+	}
+	if env := s.Environment(); env.GoVersion != runtime.Version() || env.GOGC == "" {
+		t.Errorf("want environment of this program, got %+v", env)
+	}
+	if mmu := s.MMU(int(time.Millisecond)); mmu < 0 || mmu > 1 {
+		t.Errorf("MMU out of range: %v", mmu)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import "iter"
+
+// AllPhases returns an iterator over the recorded garbage collection
+// phases. It is equivalent to ranging over Phases, but doesn't
+// require the phases to be materialized as a slice.
+func (s *GcStats) AllPhases() iter.Seq[Phase] {
+	return func(yield func(Phase) bool) {
+		for _, p := range s.Phases() {
+			if !yield(p) {
+				return
+			}
+		}
+	}
+}
+
+// AllCycles returns an iterator over the recorded garbage collection
+// cycles. It is equivalent to ranging over Cycles.
+func (s *GcStats) AllCycles() iter.Seq[Cycle] {
+	return func(yield func(Cycle) bool) {
+		for _, c := range s.Cycles() {
+			if !yield(c) {
+				return
+			}
+		}
+	}
+}
+
+// StopsSeq returns an iterator over the stop-the-world phases, joined
+// as described by Stops. Unlike Stops, it joins phases as it goes
+// rather than building a slice of all stops.
+func (s *GcStats) StopsSeq() iter.Seq[Phase] {
+	return joinStops(s.AllPhases())
+}
+
+// joinStops returns an iterator over the STW phases of phases,
+// joining runs of consecutive STW phases.
+func joinStops(phases iter.Seq[Phase]) iter.Seq[Phase] {
+	return func(yield func(Phase) bool) {
+		var stw Phase
+		have := false
+		for phase := range phases {
+			if !phase.STW {
+				if have && !yield(stw) {
+					return
+				}
+				have = false
+				continue
+			}
+			if have {
+				// Join with previous STW
+				dur1 := stw.Duration
+				dur2 := phase.Duration
+				f := float64(dur1) / float64(dur1+dur2)
+				stw.GCProcs = stw.GCProcs*f + phase.GCProcs*(1-f)
+
+				stw.Duration += dur2
+				if stw.Kind != phase.Kind {
+					stw.Kind = PhaseMultiple
+				}
+				continue
+			}
+			stw, have = phase, true
+		}
+		if have {
+			yield(stw)
+		}
+	}
+}
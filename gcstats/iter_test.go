@@ -0,0 +1,62 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"io/ioutil"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestIterators(t *testing.T) {
+	data, err := ioutil.ReadFile("../media/runtime-compile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewFromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := slices.Collect(s.AllPhases()); !reflect.DeepEqual(got, s.Phases()) {
+		t.Errorf("AllPhases differs from Phases")
+	}
+	if got := slices.Collect(s.AllCycles()); !reflect.DeepEqual(got, s.Cycles()) {
+		t.Errorf("AllCycles differs from Cycles")
+	}
+	if got := slices.Collect(s.StopsSeq()); !reflect.DeepEqual(got, s.Stops()) {
+		t.Errorf("StopsSeq differs from Stops")
+	}
+
+	// Stopping early.
+	n := 0
+	for range s.StopsSeq() {
+		if n++; n == 3 {
+			break
+		}
+	}
+	if n != 3 {
+		t.Errorf("want 3 stops before break, got %d", n)
+	}
+}
+
+func TestJoinStops(t *testing.T) {
+	phases := []Phase{
+		{0, 10, PhaseSweepTerm, 1, 4, 4, true},
+		{10, 30, PhaseMarkTerm, 1, 4, 2, true},
+		{40, 100, PhaseSweep, 1, 4, 0, false},
+		{140, 20, PhaseSweepTerm, 2, 4, 4, true},
+	}
+	want := []Phase{
+		{0, 40, PhaseMultiple, 1, 4, 2.5, true},
+		{140, 20, PhaseSweepTerm, 2, 4, 4, true},
+	}
+	if got := JoinStops(phases); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	if got := JoinStops(nil); got == nil || len(got) != 0 {
+		t.Errorf("want empty non-nil slice, got %#v", got)
+	}
+}
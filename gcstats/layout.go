@@ -0,0 +1,184 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// A PhaseLayout describes the phases of a GC cycle as reported by a
+// version of the runtime in "gc <n> @<time>s ..." trace lines (Go 1.5
+// and later). Each line reports the '+'-separated clock and CPU times
+// of the phases in the order of Phases, followed by the concurrent
+// sweep, which is not reported.
+//
+// The parser picks the layout by the number of clock times in a line,
+// so layouts for new runtimes can be added with RegisterPhaseLayout
+// or LoadPhaseLayouts without changing the parser.
+type PhaseLayout struct {
+	// Name identifies the layout, such as "go1.5".
+	Name string
+
+	// Phases are the reported phases in order.
+	Phases []PhaseSpec
+
+	// Forced indicates that cycles forced by runtime.GC, which
+	// are marked "(forced)", have the same phases as other
+	// cycles. Otherwise, forced cycles are ignored, as in Go 1.5,
+	// which runs them with the world stopped.
+	Forced bool
+}
+
+// A PhaseSpec describes one phase of a PhaseLayout.
+type PhaseSpec struct {
+	Kind PhaseKind
+
+	// STW indicates that the phase stops the world.
+	STW bool
+
+	// MarkCPU indicates that the CPU time of the phase is split
+	// into '/'-separated assist, background, and idle time, which
+	// are recorded in Cycle.
+	MarkCPU bool
+}
+
+// maxLayoutPhases is the largest number of phases in a PhaseLayout.
+const maxLayoutPhases = 16
+
+// phaseLayouts is the registered layouts. It is replaced, never
+// modified, so parsers can read it without locking.
+var phaseLayouts atomic.Pointer[[]PhaseLayout]
+
+// phaseLayoutsLock serializes registrations.
+var phaseLayoutsLock sync.Mutex
+
+func init() {
+	phaseLayouts.Store(&[]PhaseLayout{
+		{Name: "go1.5", Phases: []PhaseSpec{
+			{Kind: PhaseSweepTerm, STW: true},
+			{Kind: PhaseScan},
+			{Kind: PhaseInstallWB},
+			{Kind: PhaseMark, MarkCPU: true},
+			{Kind: PhaseMarkTerm, STW: true},
+		}},
+		{Name: "go1.6", Forced: true, Phases: []PhaseSpec{
+			{Kind: PhaseSweepTerm, STW: true},
+			{Kind: PhaseMark, MarkCPU: true},
+			{Kind: PhaseMarkTerm, STW: true},
+		}},
+	})
+}
+
+// PhaseLayouts returns the registered phase layouts, starting with
+// the built-in layouts for Go 1.5 and Go 1.6 and later.
+func PhaseLayouts() []PhaseLayout {
+	return append([]PhaseLayout(nil), *phaseLayouts.Load()...)
+}
+
+// RegisterPhaseLayout adds l to the layouts used to parse GC traces.
+// It replaces any registered layout with the same name or the same
+// number of phases, so it can also redefine the built-in layouts. It
+// affects parsers created afterwards and lines parsed afterwards by
+// existing parsers.
+func RegisterPhaseLayout(l PhaseLayout) error {
+	if err := l.check(); err != nil {
+		return err
+	}
+	l.Phases = append([]PhaseSpec(nil), l.Phases...)
+
+	phaseLayoutsLock.Lock()
+	defer phaseLayoutsLock.Unlock()
+	var layouts []PhaseLayout
+	for _, old := range *phaseLayouts.Load() {
+		if old.Name != l.Name && len(old.Phases) != len(l.Phases) {
+			layouts = append(layouts, old)
+		}
+	}
+	layouts = append(layouts, l)
+	phaseLayouts.Store(&layouts)
+	return nil
+}
+
+func (l *PhaseLayout) check() error {
+	if l.Name == "" {
+		return fmt.Errorf("phase layout has no name")
+	}
+	if len(l.Phases) == 0 || len(l.Phases) > maxLayoutPhases {
+		return fmt.Errorf("phase layout %s must have 1 to %d phases", l.Name, maxLayoutPhases)
+	}
+	for _, p := range l.Phases {
+		if p.Kind < PhaseSweepTerm || p.Kind >= PhaseSweep {
+			return fmt.Errorf("phase layout %s has bad phase kind %v", l.Name, p.Kind)
+		}
+	}
+	return nil
+}
+
+// layoutFor returns the registered layout with n phases.
+func layoutFor(n int) (*PhaseLayout, bool) {
+	layouts := *phaseLayouts.Load()
+	for i := range layouts {
+		if len(layouts[i].Phases) == n {
+			return &layouts[i], true
+		}
+	}
+	return nil, false
+}
+
+// ParsePhaseKind returns the phase kind named name, as returned by
+// PhaseKind.String with or without the "Phase" prefix, in any case.
+func ParsePhaseKind(name string) (PhaseKind, error) {
+	for kind := PhaseSweepTerm; kind <= PhaseSweep; kind++ {
+		full := kind.String()
+		if strings.EqualFold(name, full) || strings.EqualFold(name, full[len("Phase"):]) {
+			return kind, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown phase kind %q", name)
+}
+
+// LoadPhaseLayouts reads a JSON array of phase layouts from r and
+// registers each with RegisterPhaseLayout. For example, the built-in
+// layout for Go 1.6 is
+//
+//	[{"name": "go1.6", "forced": true, "phases": [
+//		{"kind": "SweepTerm", "stw": true},
+//		{"kind": "Mark", "markCPU": true},
+//		{"kind": "MarkTerm", "stw": true}]}]
+func LoadPhaseLayouts(r io.Reader) error {
+	var raw []struct {
+		Name   string `json:"name"`
+		Forced bool   `json:"forced"`
+		Phases []struct {
+			Kind    string `json:"kind"`
+			STW     bool   `json:"stw"`
+			MarkCPU bool   `json:"markCPU"`
+		} `json:"phases"`
+	}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return fmt.Errorf("reading phase layouts: %w", err)
+	}
+	for _, rl := range raw {
+		l := PhaseLayout{Name: rl.Name, Forced: rl.Forced}
+		for _, rp := range rl.Phases {
+			kind, err := ParsePhaseKind(rp.Kind)
+			if err != nil {
+				return fmt.Errorf("phase layout %s: %w", rl.Name, err)
+			}
+			l.Phases = append(l.Phases, PhaseSpec{kind, rp.STW, rp.MarkCPU})
+		}
+		if err := RegisterPhaseLayout(l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGo16Layout(t *testing.T) {
+	const log = `gc 1 @0.010s 2%: 0.026+0.39+0.10 ms clock, 0.21+0.88/0.52/0+0.84 ms cpu, 4->4->0 MB, 5 MB goal, 8 P
+gc 2 @0.020s 2%: 0.030+0.40+0.12 ms clock, 0.24+0.1/0.8/0.3+0.96 ms cpu, 4->4->1 MB, 5 MB goal, 8 P (forced)
+`
+	s, err := NewFromLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []PhaseKind
+	for _, p := range s.Phases() {
+		if p.N == 1 {
+			kinds = append(kinds, p.Kind)
+		}
+	}
+	if want := []PhaseKind{PhaseSweepTerm, PhaseMark, PhaseMarkTerm, PhaseSweep}; !slices.Equal(kinds, want) {
+		t.Errorf("want phases %v, got %v", want, kinds)
+	}
+	cycles := s.Cycles()
+	if len(cycles) != 2 {
+		t.Fatalf("want 2 cycles, got %d", len(cycles))
+	}
+	if c := cycles[0]; c.AssistCPU != 880e3 || c.BackgroundCPU != 520e3 || c.Forced {
+		t.Errorf("cycle 1: want assist 880µs, background 520µs, not forced; got %+v", c)
+	}
+	if c := cycles[1]; !c.Forced || c.Cause != CauseForced {
+		t.Errorf("cycle 2: want forced, got %+v", c)
+	}
+}
+
+func TestLoadPhaseLayouts(t *testing.T) {
+	defer phaseLayouts.Store(phaseLayouts.Load())
+
+	const line = "gc 1 @0.010s 2%: 0.1+0.2+0.3+0.4 ms clock, 0.1+0.2+0.1/0.3/0+0.4 ms cpu, 4->4->0 MB, 5 MB goal, 4 P\n"
+	if _, err := NewFromLog(strings.NewReader(line)); err == nil {
+		t.Fatalf("want error for four clock times before registering a layout")
+	}
+
+	const layouts = `[{"name": "experiment", "phases": [
+		{"kind": "SweepTerm", "stw": true},
+		{"kind": "Scan"},
+		{"kind": "PhaseMark", "markCPU": true},
+		{"kind": "markterm", "stw": true}]}]`
+	if err := LoadPhaseLayouts(strings.NewReader(layouts)); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewFromLog(strings.NewReader(line))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []PhaseKind
+	for _, p := range s.Phases() {
+		kinds = append(kinds, p.Kind)
+	}
+	if want := []PhaseKind{PhaseSweepTerm, PhaseScan, PhaseMark, PhaseMarkTerm}; !slices.Equal(kinds[:len(want)], want) {
+		t.Errorf("want phases %v, got %v", want, kinds)
+	}
+	if c := s.Cycles()[0]; c.BackgroundCPU != 300e3 {
+		t.Errorf("want background 300µs, got %d", c.BackgroundCPU)
+	}
+	if n := len(PhaseLayouts()); n != 3 {
+		t.Errorf("want 3 layouts, got %d", n)
+	}
+
+	for _, bad := range []string{
+		`[{"name": "x", "phases": [{"kind": "Nope"}]}]`,
+		`[{"name": "x", "phases": [{"kind": "Sweep"}]}]`,
+		`[{"name": "", "phases": [{"kind": "Mark"}]}]`,
+		`[{"name": "x", "phases": []}]`,
+		`[{"name": "x", "phasez": []}]`,
+	} {
+		if err := LoadPhaseLayouts(strings.NewReader(bad)); err == nil {
+			t.Errorf("want error loading %s", bad)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import "math"
+
+// LostWork returns the mutator utilization lost in windows whose
+// utilization is below target, averaged over all windows: that is,
+// the integral of 1-util over the part of the distribution below
+// target. Windows at or above target count as losing nothing, so
+// this measures how much mutator work was denied during bad periods
+// rather than the overall GC overhead. Multiplied by the duration of
+// the execution, it is the mutator time lost during those periods.
+//
+// LostWork(1) is the mean utilization loss over all windows and
+// LostWork(0) is 0.
+func (d *MUD) LostWork(target float64) float64 {
+	var lost float64
+	for i, e := range d.edges {
+		if e.x >= target {
+			break
+		}
+		lost += e.dirac * (1 - e.x)
+		if i+1 == len(d.edges) || e.y == 0 {
+			continue
+		}
+		// Integrate (1-u) * y over [e.x, b).
+		b := math.Min(d.edges[i+1].x, target)
+		lost += e.y * ((b - e.x) - (b*b-e.x*e.x)/2)
+	}
+	return lost
+}
@@ -0,0 +1,31 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLostWork(t *testing.T) {
+	for _, test := range []struct {
+		window       int
+		target, lost float64
+	}{
+		// Half of the instants have no utilization.
+		{0, 0.5, 0.5},
+		{0, 1, 0.5},
+		{0, 0, 0},
+		// Two thirds of the windows are uniform over [0, 1]
+		// and the rest have full utilization.
+		{25, 1, 1.0 / 3},
+		{25, 0.5, 2.0 / 3 * (0.5 - 0.125)},
+	} {
+		mud := statsQuarters.MutatorUtilizationDistribution(test.window)
+		if got := mud.LostWork(test.target); math.Abs(got-test.lost) > 1e-12 {
+			t.Errorf("window %d: LostWork(%v): want %v, got %v", test.window, test.target, test.lost, got)
+		}
+	}
+}
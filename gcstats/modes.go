@@ -0,0 +1,158 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"math"
+
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+)
+
+// A Mode is one population of a multi-modal distribution of
+// durations.
+type Mode struct {
+	// Peak is the most likely duration in this mode in
+	// nanoseconds.
+	Peak int64
+
+	// Low and High bound the durations assigned to this mode.
+	Low, High int64
+
+	// Weight is the fraction of durations in this mode.
+	Weight float64
+
+	// Count is the number of durations in this mode.
+	Count int
+}
+
+// minModeWeight and minModeCount are the smallest fraction and
+// number of durations that Modes reports as a separate mode. Smaller
+// bumps are usually noise in the density estimate or lone outliers.
+const (
+	minModeWeight = 0.05
+	minModeCount  = 3
+)
+
+// Modes returns the modes of the distribution of durations, in order
+// of increasing duration. A single mode indicates a unimodal
+// distribution.
+//
+// Modes are the peaks of a kernel density estimate of the logs of
+// durations, since pause times often span orders of magnitude.
+// Durations are divided between modes at the minima of the density
+// between peaks. Peaks with less than 5% of the durations or fewer
+// than 3 durations are merged into their neighbors. Modes uses
+// Silverman's rule to choose the bandwidth, which oversmooths
+// multi-modal distributions, so reported modes are well separated.
+func Modes(durations []int64) []Mode {
+	var logs stats.Sample
+	lo, hi := int64(math.MaxInt64), int64(0)
+	for _, d := range durations {
+		if d <= 0 {
+			continue
+		}
+		logs.Xs = append(logs.Xs, math.Log(float64(d)))
+		lo, hi = min(lo, d), max(hi, d)
+	}
+	if len(logs.Xs) == 0 {
+		return nil
+	}
+	// With few durations or no variation, report a single mode
+	// peaking at the geometric mean.
+	one := []Mode{{Peak: int64(math.Exp(logs.Mean())), Low: lo, High: hi, Weight: 1, Count: len(logs.Xs)}}
+	bw := stats.BandwidthSilverman(logs)
+	if len(logs.Xs) < 10 || bw == 0 {
+		return one
+	}
+	kde := &stats.KDE{Sample: logs, Kernel: stats.GaussianKernel, Bandwidth: bw}
+
+	// Sample the density and find its local extrema.
+	const steps = 512
+	x0, x1 := math.Log(float64(lo))-2*bw, math.Log(float64(hi))+2*bw
+	xs := make([]float64, steps)
+	ys := make([]float64, steps)
+	for i := range xs {
+		xs[i] = x0 + (x1-x0)*float64(i)/(steps-1)
+		ys[i] = kde.PDF(xs[i])
+	}
+	// Each mode is a peak and the valley that bounds it above.
+	type peak struct{ peak, valley int }
+	var peaks []peak
+	for i := 1; i < steps-1; i++ {
+		if ys[i] > ys[i-1] && ys[i] >= ys[i+1] {
+			peaks = append(peaks, peak{i, steps - 1})
+		} else if ys[i] < ys[i-1] && ys[i] <= ys[i+1] && len(peaks) > 0 {
+			peaks[len(peaks)-1].valley = i
+		}
+	}
+
+	if len(peaks) == 0 {
+		return one
+	}
+
+	weight := func(i int) float64 {
+		from := 0.0
+		if i > 0 {
+			from = kde.CDF(xs[peaks[i-1].valley])
+		}
+		return kde.CDF(xs[peaks[i].valley]) - from
+	}
+	// Merge small modes into the neighbor separated from it by
+	// the shallower valley.
+	for len(peaks) > 1 {
+		small, smallW := -1, max(minModeWeight, minModeCount/float64(len(logs.Xs)))
+		for i := range peaks {
+			if w := weight(i); w < smallW {
+				small, smallW = i, w
+			}
+		}
+		if small < 0 {
+			break
+		}
+		// Merge with the left neighbor by removing the valley
+		// between them, or the right by removing its own.
+		left := small > 0 && (small == len(peaks)-1 || ys[peaks[small-1].valley] > ys[peaks[small].valley])
+		var keep, drop int
+		if left {
+			keep, drop = small-1, small
+		} else {
+			keep, drop = small+1, small
+		}
+		if ys[peaks[drop].peak] > ys[peaks[keep].peak] {
+			peaks[keep].peak = peaks[drop].peak
+		}
+		if left {
+			peaks[keep].valley = peaks[drop].valley
+		}
+		peaks = append(peaks[:drop], peaks[drop+1:]...)
+	}
+
+	// Assign durations to modes.
+	modes := make([]Mode, len(peaks))
+	for i, p := range peaks {
+		modes[i] = Mode{Peak: int64(math.Exp(xs[p.peak])), Low: math.MaxInt64}
+	}
+	for _, d := range durations {
+		if d <= 0 {
+			continue
+		}
+		l := math.Log(float64(d))
+		i := 0
+		for i < len(peaks)-1 && l > xs[peaks[i].valley] {
+			i++
+		}
+		m := &modes[i]
+		m.Count++
+		m.Low, m.High = min(m.Low, d), max(m.High, d)
+	}
+	out := modes[:0]
+	for _, m := range modes {
+		if m.Count > 0 {
+			m.Weight = float64(m.Count) / float64(len(logs.Xs))
+			out = append(out, m)
+		}
+	}
+	return out
+}
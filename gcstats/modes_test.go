@@ -0,0 +1,47 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import "testing"
+
+func TestModes(t *testing.T) {
+	// spread returns n durations evenly spread ±10% around
+	// center.
+	spread := func(n int, center int64) []int64 {
+		var ds []int64
+		for i := 0; i < n; i++ {
+			ds = append(ds, center*9/10+center*int64(i)/5/int64(n))
+		}
+		return ds
+	}
+
+	modes := Modes(spread(100, 100000))
+	if len(modes) != 1 || modes[0].Count != 100 || modes[0].Weight != 1 {
+		t.Errorf("unimodal: want 1 mode of 100, got %+v", modes)
+	}
+
+	modes = Modes(append(spread(80, 100000), spread(20, 5000000)...))
+	if len(modes) != 2 {
+		t.Fatalf("bimodal: want 2 modes, got %+v", modes)
+	}
+	if modes[0].Count != 80 || modes[1].Count != 20 || modes[1].Weight != 0.2 {
+		t.Errorf("bimodal: want modes of 80 and 20, got %+v", modes)
+	}
+	for i, center := range []int64{100000, 5000000} {
+		if m := modes[i]; m.Peak < center*8/10 || m.Peak > center*12/10 || m.Low > center || m.High < center {
+			t.Errorf("mode %d: want peak and range around %d, got %+v", i, center, m)
+		}
+	}
+
+	// A few outliers are not a separate mode.
+	modes = Modes(append(spread(98, 100000), 5000000, 5100000))
+	if len(modes) != 1 {
+		t.Errorf("outliers: want 1 mode, got %+v", modes)
+	}
+
+	if modes := Modes(nil); modes != nil {
+		t.Errorf("empty: want no modes, got %+v", modes)
+	}
+}
@@ -6,43 +6,103 @@ package gcstats
 
 import (
 	"math"
+	"slices"
 	"sort"
+	"sync"
 )
 
-// muInWindow returns the mutator utilization in the time window
-// [begin, end). The utilization will be in the range [0, 1].
-func muInWindow(begin, end int64, log []Phase) float64 {
+// gcProcs returns the number of procs unavailable to the mutator
+// during phase.
+func gcProcs(phase Phase) float64 {
+	if phase.STW {
+		// GC may not use all of the procs, but the mutator
+		// doesn't get any.
+		return float64(phase.Gomaxprocs)
+	}
+	return phase.GCProcs
+}
+
+// utilSums records the cumulative GC and total CPU time at each
+// phase boundary of a log. Given the phases containing the edges of a
+// window, this computes the mutator utilization of that window in
+// constant time, so the work of scanning the log is shared across
+// all windows and window sizes.
+type utilSums struct {
+	log []Phase
+
+	// gc[i] and total[i] are the GC CPU time and total CPU time
+	// in nanoseconds from the beginning of the log to
+	// log[i].Begin. Each has len(log)+1 elements.
+	gc, total []float64
+}
+
+func newUtilSums(log []Phase) *utilSums {
+	us := &utilSums{gc: []float64{0}, total: []float64{0}}
+	us.extend(log)
+	return us
+}
+
+// extend updates us for phases appended to its log. us.log must be a
+// prefix of log.
+func (us *utilSums) extend(log []Phase) {
+	for i := len(us.log); i < len(log); i++ {
+		phase := log[i]
+		us.gc = append(us.gc, us.gc[i]+gcProcs(phase)*float64(phase.Duration))
+		us.total = append(us.total, us.total[i]+float64(int64(phase.Gomaxprocs)*phase.Duration))
+	}
+	us.log = log
+}
+
+// find returns the index of the phase containing time t, searching
+// forward from phase i. If t falls on the boundary between two
+// phases, this returns the later phase. If t is past the end of the
+// log, this returns the last phase.
+func (us *utilSums) find(t int64, i int) int {
+	for i < len(us.log)-1 && us.log[i].End() <= t {
+		i++
+	}
+	return i
+}
+
+// at returns the cumulative GC and total CPU time at time t, which
+// must fall in phase i.
+func (us *utilSums) at(t int64, i int) (gc, total float64) {
+	phase := us.log[i]
+	d := float64(t - phase.Begin)
+	return us.gc[i] + gcProcs(phase)*d, us.total[i] + float64(phase.Gomaxprocs)*d
+}
+
+// mu returns the mutator utilization in the time window [begin,
+// end), where phase bi contains begin and phase ei contains end. The
+// utilization will be in the range [0, 1].
+func (us *utilSums) mu(begin int64, bi int, end int64, ei int) float64 {
 	// If begin==end, compute instantaneous utilization.
 	if begin == end {
 		end++
+		ei = us.find(end, ei)
 	}
 
-	// Compute the total time and GC time in [begin, end)
-	totalNS := 0.0
-	gcNS := 0.0
-	for _, phase := range log {
-		if phase.End() < begin {
-			continue
-		} else if phase.Begin >= end {
-			break
-		}
+	gc0, total0 := us.at(begin, bi)
+	gc1, total1 := us.at(end, ei)
+	gcNS, totalNS := gc1-gc0, total1-total0
+	return (totalNS - gcNS) / totalNS
+}
 
-		// Section of this phase that overlaps the window
-		pbegin := int64Max(begin, phase.Begin)
-		pend := int64Min(end, phase.End())
-		pdur := pend - pbegin
+// utilSums returns the utilization sums for s.log, computing or
+// extending them if necessary.
+func (s *GcStats) utilSums() *utilSums {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	return s.utilSumsLocked()
+}
 
-		gcprocs := phase.GCProcs
-		if phase.STW {
-			// GC may not use all of the procs, but the
-			// mutator doesn't get any.
-			gcprocs = float64(phase.Gomaxprocs)
-		}
-		gcNS += gcprocs * float64(pdur)
-		totalNS += float64(int64(phase.Gomaxprocs) * pdur)
+func (s *GcStats) utilSumsLocked() *utilSums {
+	if s.sums == nil {
+		s.sums = newUtilSums(s.log)
+	} else if len(s.sums.log) != len(s.log) {
+		s.sums.extend(s.log)
 	}
-
-	return (totalNS - gcNS) / totalNS
+	return s.sums
 }
 
 func (s *GcStats) requireProgTimes() {
@@ -57,14 +117,46 @@ func (s *GcStats) requireProgTimes() {
 // This will panic if the trace does not have program execution times.
 func (s *GcStats) MutatorUtilization() float64 {
 	s.requireProgTimes()
-	gcNS := float64(0)
-	totalNS := int64(0)
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+
+	// Incorporate any phases added since the last call.
+	m := &s.meanMU
+	for _, phase := range s.log[m.nlog:] {
+		m.gcNS += phase.GCProcs * float64(phase.Duration)
+		m.totalNS += int64(phase.Gomaxprocs) * phase.Duration
+	}
+	m.nlog = len(s.log)
+	return (float64(m.totalNS) - m.gcNS) / float64(m.totalNS)
+}
 
-	for _, phase := range s.log {
-		gcNS += phase.GCProcs * float64(phase.Duration)
-		totalNS += int64(phase.Gomaxprocs) * phase.Duration
+// MutatorUtilizationBetween returns the mean mutator utilization in
+// the time window [begin, end) in nanoseconds, clamped to the span of
+// the log. It returns NaN if the window doesn't overlap the log.
+//
+// This will panic if the trace does not have program execution times.
+func (s *GcStats) MutatorUtilizationBetween(begin, end int64) float64 {
+	s.requireProgTimes()
+	us := s.utilSums()
+	if len(us.log) == 0 {
+		return math.NaN()
+	}
+	begin = int64Max(begin, us.log[0].Begin)
+	end = int64Min(end, us.log[len(us.log)-1].End())
+	if begin >= end {
+		return math.NaN()
 	}
-	return (float64(totalNS) - gcNS) / float64(totalNS)
+	bi := sort.Search(len(us.log)-1, func(i int) bool { return us.log[i].End() > begin })
+	ei := us.find(end, bi)
+	return us.mu(begin, bi, end, ei)
+}
+
+// meanMU records the running totals for the mean mutator
+// utilization.
+type meanMU struct {
+	nlog    int // # of phases incorporated into the totals
+	gcNS    float64
+	totalNS int64
 }
 
 // MMUs returns the minimum mutator utilization for each window size
@@ -100,6 +192,9 @@ func (s *GcStats) MMU(windowNS int) (mmu float64) {
 	}
 
 	mmu = 1.0
+	if len(s.log) == 0 {
+		return
+	}
 
 	// We can think of the mutator utilization as a function of
 	// the start time of the window. This function is continuous
@@ -110,28 +205,28 @@ func (s *GcStats) MMU(windowNS int) (mmu float64) {
 	// occur when one of the edges of the window aligns with one
 	// of the edges of a phase, so these are the only points we
 	// need to consider.
-	leftIdx := 0
+	us := s.utilSums()
+	first, last := s.log[0].Begin, s.log[len(s.log)-1].End()
+	leftIdx, rightIdx := 0, 0
 	for i, phase := range s.log {
 		// Consider the window starting at phase.Begin
 		begin, end := phase.Begin, phase.Begin+int64(windowNS)
-		if end <= s.log[len(s.log)-1].End() {
-			// phase contains begin, so we can consider
-			// the log starting at phase.
-			util := muInWindow(begin, end, s.log[i:])
+		if end <= last {
+			// The end of the window moves monotonically
+			// forward, so we can search from where we
+			// were last.
+			rightIdx = us.find(end, rightIdx)
+			util := us.mu(begin, i, end, rightIdx)
 			mmu = math.Min(mmu, util)
 		}
 
 		// Consider the window ending at phase.End()
 		begin, end = phase.End()-int64(windowNS), phase.End()
-		if begin >= s.log[0].Begin {
-			// This is a little trickier. We need to
-			// consider the log starting at the phase
-			// containing begin. Since it's monotonic, we
-			// can search from where we were last.
-			for s.log[leftIdx].End() < begin {
-				leftIdx++
-			}
-			util := muInWindow(begin, end, s.log[leftIdx:])
+		if begin >= first {
+			// Likewise, the beginning of the window
+			// moves monotonically forward.
+			leftIdx = us.find(begin, leftIdx)
+			util := us.mu(begin, leftIdx, end, i)
 			mmu = math.Min(mmu, util)
 		}
 	}
@@ -167,39 +262,229 @@ type MUD struct {
 }
 
 // MutatorUtilizationDistribution returns the mutator utilization
-// distribution (MUD) for windows of size windowNS.
+// distribution (MUD) for windows of size windowNS. MUDs are cached on
+// s, so repeated calls with the same windowNS are cheap, and are
+// updated incrementally as phases are added to s.
 //
 // This will panic if the trace does not have program execution times.
 func (s *GcStats) MutatorUtilizationDistribution(windowNS int) *MUD {
 	s.requireProgTimes()
-	if len(s.log) == 0 {
-		return &MUD{edges: []edge{{0, 0, 1}}, csums: []float64{0}}
+	return s.cachedMUD(mudKey{windowNS, 0}, func() mudAccumulator {
+		return new(uniformSum)
+	})
+}
+
+// mudKey identifies a MUD in the MUD cache. epsilon is 0 for exact
+// MUDs.
+type mudKey struct {
+	windowNS int
+	epsilon  float64
+}
+
+// cachedMUD returns the MUD for key, using or creating its builder in
+// s's cache. If the builder must be created, newAcc returns the
+// accumulator for its addends. The MUD is computed without holding
+// s.cacheLock, so a slow MUD doesn't hold up other analyses of s or
+// phases being appended to it.
+func (s *GcStats) cachedMUD(key mudKey, newAcc func() mudAccumulator) *MUD {
+	s.cacheLock.Lock()
+	b := s.muds[key]
+	if b == nil {
+		if s.muds == nil {
+			s.muds = make(map[mudKey]*mudBuilder)
+		}
+		b = &mudBuilder{windowNS: key.windowNS, acc: newAcc(), capped: -1}
+		s.muds[key] = b
 	}
+	// Appending phases extends s.log and the sums in place, so
+	// copies of their headers remain valid.
+	log, us, complete := s.log, *s.utilSumsLocked(), s.complete
+	s.cacheLock.Unlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.compute(log, &us, complete)
+}
+
+// mudAccumulator accumulates the scaled uniform distributions that
+// sum to a MUD.
+type mudAccumulator interface {
+	// reset discards all addends and releases any memory held
+	// for them.
+	reset()
+
+	// reserve prepares to add approximately n addends.
+	reserve(n int)
+
+	add(u uniform)
+
+	// edges returns the step function of the sum of the
+	// addends, scaled by 1/total.
+	edges(total float64) []edge
+}
 
-	// The distribution is the sum of many scaled uniform
-	// distributions (some of which may have zero width). Compute
-	// these.
-	addends := []uniform{}
+// uniformSum is a mudAccumulator that records addends exactly.
+type uniformSum []uniform
+
+// uniformPool holds released uniformSum buffers.
+var uniformPool sync.Pool
+
+func (us *uniformSum) reset() {
+	if cap(*us) > 0 {
+		buf := (*us)[:0]
+		uniformPool.Put(&buf)
+	}
+	*us = nil
+}
+
+func (us *uniformSum) reserve(n int) {
+	if *us == nil {
+		if buf, ok := uniformPool.Get().(*[]uniform); ok {
+			*us = *buf
+		}
+	}
+	*us = slices.Grow(*us, n)
+}
+
+func (us *uniformSum) add(u uniform) {
+	*us = append(*us, u)
+}
+
+func (us *uniformSum) edges(total float64) []edge {
+	// Turn the collection of uniform addends into a sorted list
+	// of edges of the resulting step function.
+	edges := uniformSumToEdges(*us)
+	for i := range edges {
+		edges[i].y /= total
+		edges[i].dirac /= total
+	}
+	return edges
+}
+
+// mudBuilder incrementally computes a MUD as phases are appended to a
+// log. Appending phases only adds windows, so the builder only
+// computes the addends for windows that end in the new phases.
+type mudBuilder struct {
+	// mu serializes compute.
+	mu sync.Mutex
+
+	windowNS int
+	acc      mudAccumulator
+
+	// capped is windowNS capped at the duration of the log, or -1
+	// if no addends have been computed. If the log grows, capped
+	// may change, which changes every window.
+	capped int
+
+	// begin is the beginning of the next window to consider.
+	// beginPhase and endPhase are the indexes of the phases
+	// containing the beginning and the end of that window.
+	begin                int64
+	beginPhase, endPhase int
+
+	// mud is the MUD computed from the first nlog phases of the
+	// log.
+	nlog int
+	mud  *MUD
+
+	// filter, if non-nil, selects the windows included in the
+	// MUD by the indexes of the phases containing their beginning
+	// and end. If no windows are selected, the MUD is nil.
+	filter func(beginPhase, endPhase int) bool
+
+	// weight is the total duration over which the included
+	// windows slid, and loss is the integral of 1-utilization
+	// over that duration.
+	weight, loss float64
+}
+
+// compute returns the MUD of log, which must be a prefix of or have
+// as a prefix the log of any previous call. If log is shorter than a
+// previous log, as when a caller's snapshot of a growing log is
+// stale, compute returns the MUD of that longer log. If complete is
+// true, log will not grow, so compute releases the state for updating
+// the MUD.
+func (b *mudBuilder) compute(log []Phase, us *utilSums, complete bool) *MUD {
+	if len(log) < b.nlog || (b.mud != nil && len(log) == b.nlog) {
+		return b.mud
+	}
+	b.nlog = len(log)
+
+	if len(log) == 0 {
+		b.mud = &MUD{edges: []edge{{0, 0, 1}}, csums: []float64{0}}
+		return b.mud
+	}
 
 	// Compute first and last absolute time
-	first, last := s.log[0].Begin, s.log[len(s.log)-1].End()
+	first, last := log[0].Begin, log[len(log)-1].End()
 
 	// Cap the window at the duration of the log
-	windowNS = int(int64Min(int64(windowNS), last-first))
+	capped := int(int64Min(int64(b.windowNS), last-first))
+	if capped != b.capped {
+		// Start over.
+		b.acc.reset()
+		b.capped = capped
+		b.begin, b.beginPhase, b.endPhase = first, 0, 0
+		b.weight, b.loss = 0, 0
+	}
+
+	lastBegin := last - int64(capped)
+	if first == lastBegin {
+		// The window spans the whole log, so there's nowhere
+		// to slide it and the distribution is a single delta
+		// function.
+		util := us.mu(first, 0, last, len(log)-1)
+		b.mud = nil
+		if b.filter == nil || b.filter(0, len(log)-1) {
+			b.weight, b.loss = 1, 1-util
+			b.mud = newMUD(capped, []edge{{util, 0, 1}})
+		}
+		return b.mud
+	}
+
+	// Each phase produces about two addends.
+	b.acc.reserve(2 * (len(log) - b.beginPhase))
+	b.slide(log, us, lastBegin)
+
+	// The addends are weighted by duration, so normalize by the
+	// total duration over which we slid the window.
+	total := float64(lastBegin - first)
+	if b.filter != nil {
+		total = b.weight
+	}
+	b.mud = nil
+	if total > 0 {
+		b.mud = newMUD(capped, b.acc.edges(total))
+	}
+
+	if complete {
+		// We'll never need to extend this MUD, so release the
+		// addends.
+		b.acc.reset()
+		b.capped = -1
+	}
+	return b.mud
+}
+
+// slide computes the addends of the distribution for windows
+// beginning in [b.begin, lastBegin) and adds them to b.acc. Each
+// addend is weighted by the duration over which the window slid to
+// produce it.
+func (b *mudBuilder) slide(log []Phase, us *utilSums, lastBegin int64) {
+	windowNS := b.capped
 
 	// [begin, end) is the current window. Slide it from
-	// begin==first to begin==lastBegin.
-	begin := first
-	lastBegin := last - int64(windowNS)
-	beginPhase, endPhase := 0, 0
+	// begin==b.begin to begin==lastBegin.
+	begin := b.begin
+	beginPhase, endPhase := b.beginPhase, b.endPhase
 	for begin < lastBegin {
 		end := begin + int64(windowNS)
 
 		// Find phases containing begin and end
-		for s.log[beginPhase].End() <= begin {
+		for log[beginPhase].End() <= begin {
 			beginPhase++
 		}
-		for s.log[endPhase].End() <= end {
+		for log[endPhase].End() <= end {
 			endPhase++
 		}
 
@@ -208,12 +493,11 @@ func (s *GcStats) MutatorUtilizationDistribution(windowNS int) *MUD {
 		// slide the window as long as both endpoints remain
 		// in their same respective phase because the "height"
 		// of the uniform addend will be constant for this.
-		duration := int64Min(s.log[beginPhase].End()-begin, s.log[endPhase].End()-end)
-		//fmt.Println(begin, end, duration, first, last, beginPhase, s.log[beginPhase], endPhase, s.log[endPhase])
+		duration := int64Min(log[beginPhase].End()-begin, log[endPhase].End()-end)
 
 		// Compute utilization at left edge of sliding window.
 		// This is one edge of the uniform distribution.
-		lutil := muInWindow(begin, end, s.log[beginPhase:])
+		lutil := us.mu(begin, beginPhase, end, endPhase)
 
 		// Compute utilization at right edge of sliding
 		// window. This is the other edge of the uniform
@@ -224,7 +508,8 @@ func (s *GcStats) MutatorUtilizationDistribution(windowNS int) *MUD {
 		// mutator utilization is a continuous function of
 		// window position. We don't bother modeling this
 		// because these infinitesimals don't matter for CDFs.
-		rutil := muInWindow(begin+duration, end+duration, s.log[beginPhase:])
+		rbegin, rend := begin+duration, end+duration
+		rutil := us.mu(rbegin, us.find(rbegin, beginPhase), rend, us.find(rend, endPhase))
 
 		// If the window size is 0, our continuity assumption
 		// above is violated, but it's easy to fix: the
@@ -239,29 +524,31 @@ func (s *GcStats) MutatorUtilizationDistribution(windowNS int) *MUD {
 			lutil, rutil = rutil, lutil
 		}
 
-		// Finally, the area of this addend is the fraction we
-		// just considered of the overall sliding window
-		// interval.
-		area := float64(duration) / float64(lastBegin-first)
+		// The utilization sums are subject to round-off
+		// error, so a window whose utilization is constant
+		// as it slides may have slightly different
+		// utilizations at either end. Treat this as a delta
+		// function rather than an extremely narrow (and
+		// hence extremely tall) uniform distribution.
+		if rutil-lutil < 1e-12 {
+			rutil = lutil
+		}
 
 		// Add it to the distribution
-		addends = append(addends, uniform{lutil, rutil, area})
+		if b.filter == nil || b.filter(beginPhase, endPhase) {
+			b.acc.add(uniform{lutil, rutil, float64(duration)})
+			b.weight += float64(duration)
+			b.loss += float64(duration) * (1 - (lutil+rutil)/2)
+		}
 
 		begin += duration
 	}
+	b.begin, b.beginPhase, b.endPhase = begin, beginPhase, endPhase
+}
 
-	// If lastBegin-first==0, the above logic has nowhere to slide
-	// the window, so it doesn't produce any addends. Handle this
-	// case here.
-	if first == lastBegin {
-		util := muInWindow(first, last, s.log)
-		addends = append(addends, uniform{util, util, 1})
-	}
-
-	// Turn the collection of uniform addends into a sorted list
-	// of edges of the resulting step function.
-	edges := uniformSumToEdges(addends)
-
+// newMUD returns a MUD for windows of size windowNS with the step
+// function described by edges.
+func newMUD(windowNS int, edges []edge) *MUD {
 	// Compute cumulative sums. csums[i] is the sum up to, but
 	// not including edges[i].
 	csums := make([]float64, len(edges))
@@ -4,7 +4,11 @@
 
 package gcstats
 
-import "testing"
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
 
 //           ━━━━━━━━━━━━━━━━━━━━           1
 //           ▏                  ▕           0.75
@@ -12,11 +16,11 @@ import "testing"
 //           ▏                  ▕           0.25
 // ━━━━━━━━━━--------------------━━━━━━━━━━ 0
 // 0        25        50        75       100 time
-var statsQuarters = GcStats{[]Phase{
+var statsQuarters = GcStats{log: []Phase{
 	{Begin: 0, Duration: 25, Gomaxprocs: 4, GCProcs: 4},
 	{Begin: 25, Duration: 50, Gomaxprocs: 4, GCProcs: 0},
 	{Begin: 75, Duration: 25, Gomaxprocs: 4, GCProcs: 4},
-}, 1}
+}, n: 1, progTimes: true}
 
 func testMUDCDF(t *testing.T, mud *MUD, x, cdf float64) {
 	got := mud.CDF(x)
@@ -99,3 +103,150 @@ func TestQuartersMUD100(t *testing.T) {
 }
 
 // TODO: Test delta in the middle of a non-zero region.
+
+// muInWindowSlow directly computes the mutator utilization in the
+// time window [begin, end) of log.
+func muInWindowSlow(begin, end int64, log []Phase) float64 {
+	if begin == end {
+		end++
+	}
+	totalNS, gcNS := 0.0, 0.0
+	for _, phase := range log {
+		if phase.End() < begin {
+			continue
+		} else if phase.Begin >= end {
+			break
+		}
+		pdur := int64Min(end, phase.End()) - int64Max(begin, phase.Begin)
+		gcNS += gcProcs(phase) * float64(pdur)
+		totalNS += float64(int64(phase.Gomaxprocs) * pdur)
+	}
+	return (totalNS - gcNS) / totalNS
+}
+
+// randomLog returns a log of n phases with random durations and GC
+// utilization.
+func randomLog(r *rand.Rand, n int) []Phase {
+	log := make([]Phase, n)
+	begin := int64(0)
+	for i := range log {
+		dur := r.Int63n(100)
+		stw := r.Intn(4) == 0
+		log[i] = Phase{Begin: begin, Duration: dur, Gomaxprocs: 4, GCProcs: 4 * r.Float64(), STW: stw}
+		begin += dur
+	}
+	return log
+}
+
+func TestUtilSums(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	log := randomLog(r, 100)
+	us := newUtilSums(log)
+	last := log[len(log)-1].End()
+	for i := 0; i < 1000; i++ {
+		begin := r.Int63n(last)
+		end := begin + r.Int63n(last-begin)
+		got := us.mu(begin, us.find(begin, 0), end, us.find(end, 0))
+		want := muInWindowSlow(begin, end, log)
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("mu(%d, %d): want %v, got %v", begin, end, want, got)
+		}
+	}
+}
+
+func TestMutatorUtilizationBetween(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	s := &GcStats{log: randomLog(r, 100), n: 1, progTimes: true}
+	first, last := s.log[0].Begin, s.log[len(s.log)-1].End()
+	for i := 0; i < 1000; i++ {
+		begin := first + r.Int63n(last-first)
+		end := begin + 1 + r.Int63n(last-begin)
+		got := s.MutatorUtilizationBetween(begin, end)
+		want := muInWindowSlow(begin, end, s.log)
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("MutatorUtilizationBetween(%d, %d): want %v, got %v", begin, end, want, got)
+		}
+	}
+	// The window is clamped to the log.
+	if got, want := s.MutatorUtilizationBetween(first-100, last+100), muInWindowSlow(first, last, s.log); math.Abs(got-want) > 1e-9 {
+		t.Errorf("MutatorUtilizationBetween over whole log: want %v, got %v", want, got)
+	}
+	if got := s.MutatorUtilizationBetween(last, last+100); !math.IsNaN(got) {
+		t.Errorf("MutatorUtilizationBetween past end of log: want NaN, got %v", got)
+	}
+}
+
+func TestApproxMUD(t *testing.T) {
+	const epsilon = 0.01
+	r := rand.New(rand.NewSource(1))
+	s := &GcStats{log: randomLog(r, 100), n: 1, progTimes: true}
+	for _, windowNS := range []int{0, 10, 100, 1000} {
+		exact := s.MutatorUtilizationDistribution(windowNS)
+		approx := s.ApproxMutatorUtilizationDistribution(windowNS, epsilon)
+		for util := 0.0; util <= 1; util += epsilon / 3 {
+			lo, hi := exact.CDF(util-epsilon), exact.CDF(util+epsilon)
+			if got := approx.CDF(util); got < lo-1e-9 || got > hi+1e-9 {
+				t.Errorf("window %d: approx CDF(%v)=%v not in exact range [%v, %v]", windowNS, util, got, lo, hi)
+			}
+		}
+		for _, pctile := range []float64{0, 0.01, 0.1, 0.5, 0.9, 1} {
+			want, got := exact.InvCDF(pctile), approx.InvCDF(pctile)
+			if math.Abs(want-got) > epsilon+1e-9 {
+				t.Errorf("window %d: approx InvCDF(%v)=%v, exact %v", windowNS, pctile, got, want)
+			}
+		}
+	}
+}
+
+func TestApproxMUDEpsilon(t *testing.T) {
+	s := &GcStats{log: statsQuarters.log, n: 1, progTimes: true}
+	for _, epsilon := range []float64{0, -0.1, 1.5, math.NaN(), math.Inf(1)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("want panic for epsilon %v", epsilon)
+				}
+			}()
+			s.ApproxMutatorUtilizationDistribution(25, epsilon)
+		}()
+	}
+	// Tiny epsilons are limited, so the grid stays small.
+	exact := s.MutatorUtilizationDistribution(25)
+	approx := s.ApproxMutatorUtilizationDistribution(25, 1e-300)
+	for _, pctile := range []float64{0, 0.5, 1} {
+		if want, got := exact.InvCDF(pctile), approx.InvCDF(pctile); math.Abs(want-got) > minApproxEpsilon+1e-9 {
+			t.Errorf("approx InvCDF(%v)=%v, exact %v", pctile, got, want)
+		}
+	}
+}
+
+func TestMUDBuilderStale(t *testing.T) {
+	// A caller with an older snapshot of a growing log may
+	// compute after a caller with a newer one.
+	r := rand.New(rand.NewSource(1))
+	s := &GcStats{log: randomLog(r, 100), n: 1, progTimes: true}
+	us := s.utilSumsLocked()
+	b := &mudBuilder{windowNS: 100, acc: new(uniformSum), capped: -1}
+	newer := b.compute(s.log, us, false)
+	if older := b.compute(s.log[:50], us, false); older != newer {
+		t.Errorf("stale log: want the MUD of the newer log")
+	}
+	if want, got := s.MutatorUtilizationDistribution(100).InvCDF(0.5), newer.InvCDF(0.5); want != got {
+		t.Errorf("want median %v, got %v", want, got)
+	}
+}
+
+func TestMUDCache(t *testing.T) {
+	s := &GcStats{log: statsQuarters.log, n: 1, progTimes: true}
+	mud := s.MutatorUtilizationDistribution(25)
+	if s.MutatorUtilizationDistribution(25) != mud {
+		t.Errorf("MUD not cached")
+	}
+	if s.ApproxMutatorUtilizationDistribution(25, 0.01) == mud {
+		t.Errorf("approximate MUD shares cache entry with exact MUD")
+	}
+	s.invalidate()
+	if s.MutatorUtilizationDistribution(25) == mud {
+		t.Errorf("MUD cache not invalidated")
+	}
+}
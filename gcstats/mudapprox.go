@@ -0,0 +1,133 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import "math"
+
+// ApproxMutatorUtilizationDistribution is like
+// MutatorUtilizationDistribution, but returns an approximate MUD that
+// can be computed in time linear in the length of the trace and in
+// space proportional to 1/epsilon, rather than sorting every addend
+// of the exact distribution.
+//
+// The approximation error is bounded along the utilization axis: for
+// any util, the approximate CDF(util) lies between the exact
+// CDF(util-epsilon) and CDF(util+epsilon). Equivalently, every
+// InvCDF of the approximate MUD is within epsilon of the exact
+// InvCDF.
+//
+// epsilon must be greater than 0 and at most 1. Since the space used
+// is proportional to 1/epsilon, an epsilon less than 1e-6 is treated
+// as 1e-6.
+//
+// This will panic if the trace does not have program execution times.
+func (s *GcStats) ApproxMutatorUtilizationDistribution(windowNS int, epsilon float64) *MUD {
+	if !(epsilon > 0 && epsilon <= 1) {
+		panic("ApproxMutatorUtilizationDistribution epsilon must be in (0, 1]")
+	}
+	epsilon = max(epsilon, minApproxEpsilon)
+	s.requireProgTimes()
+	return s.cachedMUD(mudKey{windowNS, epsilon}, func() mudAccumulator {
+		return newMUDGrid(epsilon)
+	})
+}
+
+// minApproxEpsilon is the smallest epsilon of an approximate MUD. Its
+// grid has a million cells.
+const minApproxEpsilon = 1e-6
+
+// mudGrid is a mudAccumulator that coalesces scaled uniform
+// distributions over [0, 1] into a fixed grid of cells, treating the
+// mass within each cell as uniform.
+type mudGrid struct {
+	width float64
+
+	// ddensity[i] is the change in density at the beginning of
+	// cell i due to addends that completely cover cell i.
+	ddensity []float64
+
+	// mass[i] is the mass of addends that partially cover cell
+	// i.
+	mass []float64
+}
+
+func newMUDGrid(epsilon float64) *mudGrid {
+	n := int(math.Ceil(1 / epsilon))
+	return &mudGrid{1 / float64(n), make([]float64, n+1), make([]float64, n)}
+}
+
+func (g *mudGrid) reset() {
+	for i := range g.ddensity {
+		g.ddensity[i] = 0
+	}
+	for i := range g.mass {
+		g.mass[i] = 0
+	}
+}
+
+func (g *mudGrid) reserve(n int) {}
+
+// cell returns the index of the cell containing x.
+func (g *mudGrid) cell(x float64) int {
+	i := int(x / g.width)
+	if i < 0 {
+		return 0
+	} else if i >= len(g.mass) {
+		return len(g.mass) - 1
+	}
+	return i
+}
+
+// add adds u to the grid in constant time.
+func (g *mudGrid) add(u uniform) {
+	li, ri := g.cell(u.l), g.cell(u.r)
+	if li == ri {
+		g.mass[li] += u.area
+		return
+	}
+
+	// Add the pieces of u that partially cover the first and
+	// last cells. Cells in between are completely covered.
+	h := u.area / (u.r - u.l)
+	g.mass[li] += h * (float64(li+1)*g.width - u.l)
+	g.mass[ri] += h * (u.r - float64(ri)*g.width)
+	g.ddensity[li+1] += h
+	g.ddensity[ri] -= h
+}
+
+// edges returns the step function of the grid's distribution, scaled
+// by 1/total. Empty cells at either end of the grid are trimmed so the
+// support of the step function is within one cell of the support of
+// the exact distribution.
+func (g *mudGrid) edges(total float64) []edge {
+	const tiny = 1e-15
+
+	edges := make([]edge, len(g.mass)+1)
+	density := 0.0
+	for i, mass := range g.mass {
+		density += g.ddensity[i]
+		mass = (mass + density*g.width) / total
+		if mass < tiny {
+			// Round off error from cancelling densities.
+			mass = 0
+		}
+		edges[i] = edge{float64(i) * g.width, mass / g.width, 0}
+	}
+	edges[len(g.mass)] = edge{1, 0, 0}
+
+	lo, hi := 0, len(g.mass)
+	for lo < hi && edges[lo].y == 0 {
+		lo++
+	}
+	for hi > lo && edges[hi-1].y == 0 {
+		hi--
+	}
+	if lo == hi {
+		return []edge{{0, 0, 0}}
+	}
+	edges = edges[lo : hi+1]
+	edges[len(edges)-1].y = 0
+	return edges
+}
@@ -0,0 +1,96 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"math"
+	"sort"
+)
+
+// A UtilizationMap records when during a run the windows of each of
+// several sizes had low mutator utilization. It divides the run into
+// equal time buckets and records, for each window size, the minimum
+// utilization of the windows that begin in each bucket. Unlike a MUD,
+// which aggregates windows over the whole run, this shows when the
+// bad windows happened.
+type UtilizationMap struct {
+	// WindowNS are the window sizes in nanoseconds.
+	WindowNS []int
+
+	// Begin is the beginning of the first bucket and Width is
+	// the width of each bucket in nanoseconds, so bucket j spans
+	// [Begin+j*Width, Begin+(j+1)*Width).
+	Begin, Width int64
+
+	// MU[i][j] is the minimum mutator utilization of the windows
+	// of size WindowNS[i] that begin in bucket j, or NaN if no
+	// window of that size beginning in bucket j fits in the run.
+	MU [][]float64
+}
+
+// UtilizationMap computes the utilization map of s for the given
+// window sizes and number of time buckets. The map's MMU for each
+// window size, the minimum across its buckets, equals s.MMU.
+//
+// This will panic if the trace does not have program execution times.
+func (s *GcStats) UtilizationMap(windowNS []int, buckets int) *UtilizationMap {
+	s.requireProgTimes()
+	m := &UtilizationMap{WindowNS: windowNS, MU: make([][]float64, len(windowNS))}
+	us := s.utilSums()
+	log := us.log
+	for i := range m.MU {
+		m.MU[i] = make([]float64, buckets)
+		for j := range m.MU[i] {
+			m.MU[i][j] = math.NaN()
+		}
+	}
+	if len(log) == 0 || buckets <= 0 {
+		return m
+	}
+	first, last := log[0].Begin, log[len(log)-1].End()
+	m.Begin = first
+	m.Width = max((last-first+int64(buckets)-1)/int64(buckets), 1)
+
+	// util returns the utilization of the window [t, t+w).
+	util := func(t, w int64) float64 {
+		bi := sort.Search(len(log)-1, func(i int) bool { return log[i].End() > t })
+		ei := us.find(t+w, bi)
+		return us.mu(t, bi, t+w, ei)
+	}
+	for i, window := range windowNS {
+		w := int64(window)
+		row := m.MU[i]
+		if w <= 0 || first > last-w {
+			continue
+		}
+		// As for MMU, the utilization is piecewise linear in
+		// the window's beginning, so the minimum in a bucket
+		// occurs at an edge of the bucket or where an edge of
+		// the window aligns with an edge of a phase.
+		consider := func(t int64) {
+			if t < first || t > last-w {
+				return
+			}
+			j := min(int((t-first)/m.Width), buckets-1)
+			if u := util(t, w); !(u >= row[j]) {
+				row[j] = u
+			}
+		}
+		for j := range row {
+			lo := first + int64(j)*m.Width
+			hi := min(lo+m.Width, last-w+1) - 1
+			if lo > last-w {
+				break
+			}
+			consider(lo)
+			consider(hi)
+		}
+		for _, p := range log {
+			consider(p.Begin)
+			consider(p.End() - w)
+		}
+	}
+	return m
+}
@@ -0,0 +1,48 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestUtilizationMap(t *testing.T) {
+	var log strings.Builder
+	log.WriteString(benchLog(100))
+	// A long pause near the end of the run.
+	log.WriteString("gc 101 @5.000s 5%: 20+0.80+2.4+1.7+0.62 ms clock, 20+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P\n")
+	log.WriteString("gc 102 @5.050s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P\n")
+	s, err := NewFromLog(strings.NewReader(log.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	windows := []int{1e6, 10e6, 100e6}
+	m := s.UtilizationMap(windows, 50)
+	for i, w := range windows {
+		lo, worst := math.Inf(1), -1
+		for j, u := range m.MU[i] {
+			if u < lo {
+				lo, worst = u, j
+			}
+		}
+		if want := s.MMU(w); math.Abs(lo-want) > 1e-9 {
+			t.Errorf("window %d: want minimum %v, got %v", w, want, lo)
+		}
+		// The worst windows are around the long pause.
+		if begin := m.Begin + int64(worst)*m.Width; begin > 5.02e9 || begin+m.Width+int64(w) < 5e9 {
+			t.Errorf("window %d: want worst bucket near 5s, got bucket %d at %d", w, worst, begin)
+		}
+	}
+	// Windows longer than the run don't fit anywhere.
+	m = s.UtilizationMap([]int{1e12}, 10)
+	for _, u := range m.MU[0] {
+		if !math.IsNaN(u) {
+			t.Errorf("want NaN for window longer than the run, got %v", u)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"math"
+	"sort"
+)
+
+// mutatorTime maps between wall-clock time and mutator time over a
+// log. Mutator time advances at the mutator utilization of each
+// phase: it is the mutator CPU time divided by GOMAXPROCS, so it
+// equals wall-clock time when no GC is running and stands still
+// during STW phases.
+//
+// Windows of a fixed mutator time contain a fixed amount of mutator
+// work, so they measure how much GC stretched that work rather than
+// how much of a fixed wall-clock interval GC took. For throughput
+// workloads this is the fairer measure.
+type mutatorTime struct {
+	us *utilSums
+
+	// tau[i] is the mutator time in nanoseconds from the
+	// beginning of the log to log[i].Begin. It has len(log)+1
+	// elements.
+	tau []float64
+}
+
+func newMutatorTime(us *utilSums) *mutatorTime {
+	tau := make([]float64, len(us.log)+1)
+	for i, phase := range us.log {
+		util := 1 - gcProcs(phase)/float64(phase.Gomaxprocs)
+		tau[i+1] = tau[i] + util*float64(phase.Duration)
+	}
+	return &mutatorTime{us, tau}
+}
+
+// total returns the mutator time of the whole log.
+func (m *mutatorTime) total() float64 {
+	return m.tau[len(m.tau)-1]
+}
+
+// wall returns the wall-clock time at which mutator time reaches tau
+// and the index of the phase containing it. If mutator time stands
+// still at tau, wall returns the latest such time if late is true and
+// the earliest otherwise.
+func (m *mutatorTime) wall(tau float64, late bool) (float64, int) {
+	log := m.us.log
+	var j int
+	if late {
+		j = sort.Search(len(m.tau), func(j int) bool { return m.tau[j] > tau })
+	} else {
+		j = sort.Search(len(m.tau), func(j int) bool { return m.tau[j] >= tau })
+		if j < len(m.tau) && m.tau[j] == tau {
+			// tau falls on a phase boundary.
+			if j == len(log) {
+				return float64(log[j-1].End()), j - 1
+			}
+			return float64(log[j].Begin), j
+		}
+	}
+	if j == 0 {
+		return float64(log[0].Begin), 0
+	} else if j == len(m.tau) {
+		return float64(log[len(log)-1].End()), len(log) - 1
+	}
+	// tau falls inside phase j-1, which therefore has non-zero
+	// utilization.
+	i := j - 1
+	frac := (tau - m.tau[i]) / (m.tau[j] - m.tau[i])
+	return float64(log[i].Begin) + frac*float64(log[i].Duration), i
+}
+
+// mu returns the mutator utilization in the wall-clock window [begin,
+// end], where phase bi contains begin and phase ei contains end.
+func (m *mutatorTime) mu(begin float64, bi int, end float64, ei int) float64 {
+	at := func(t float64, i int) (gc, total float64) {
+		phase := m.us.log[i]
+		d := t - float64(phase.Begin)
+		return m.us.gc[i] + gcProcs(phase)*d, m.us.total[i] + float64(phase.Gomaxprocs)*d
+	}
+	gc0, total0 := at(begin, bi)
+	gc1, total1 := at(end, ei)
+	if total1 <= total0 {
+		return 0
+	}
+	return math.Max(0, math.Min(1, 1-(gc1-gc0)/(total1-total0)))
+}
+
+// window returns the mutator utilization of the window of mutator time
+// from tau to tau+w. If late is true, the window excludes any STW
+// phase at tau and includes any at tau+w; otherwise the reverse.
+func (m *mutatorTime) window(tau, w float64, late bool) float64 {
+	begin, bi := m.wall(tau, late)
+	end, ei := m.wall(tau+w, late)
+	return m.mu(begin, bi, end, ei)
+}
+
+// breakpoints returns the starts of the windows of mutator time w at
+// which either edge of the window crosses a phase boundary, in
+// increasing order. Between breakpoints, a window contains the same
+// phases and its utilization changes monotonically.
+func (m *mutatorTime) breakpoints(w float64) []float64 {
+	last := m.total() - w
+	pts := []float64{0, last}
+	for _, tau := range m.tau {
+		for _, pt := range []float64{tau, tau - w} {
+			if pt > 0 && pt < last {
+				pts = append(pts, pt)
+			}
+		}
+	}
+	sort.Float64s(pts)
+	out := pts[:1]
+	for _, pt := range pts[1:] {
+		if pt != out[len(out)-1] {
+			out = append(out, pt)
+		}
+	}
+	return out
+}
+
+// MutatorTimeMMU is like MMU, but each window is windowNS nanoseconds
+// of mutator time rather than wall-clock time. Mutator time is mutator
+// CPU time divided by GOMAXPROCS: it advances with wall-clock time
+// while no GC is running, more slowly while GC uses some of the procs,
+// and not at all during STW phases. Hence, a window stretches to
+// include the GC work that delayed a fixed amount of mutator work.
+// Windows longer than the log's mutator time are capped.
+//
+// This will panic if the trace does not have program execution times.
+func (s *GcStats) MutatorTimeMMU(windowNS int) float64 {
+	s.requireProgTimes()
+	if windowNS <= 0 {
+		return 0
+	}
+	us := s.utilSums()
+	if len(us.log) == 0 {
+		return 1
+	}
+	m := newMutatorTime(us)
+	w := math.Min(float64(windowNS), m.total())
+
+	// Utilization is monotonic between breakpoints, so the
+	// minimum is at a breakpoint, approached from either side.
+	mmu := 1.0
+	for _, tau := range m.breakpoints(w) {
+		mmu = math.Min(mmu, m.window(tau, w, false))
+		mmu = math.Min(mmu, m.window(tau, w, true))
+	}
+	return mmu
+}
+
+// mutatorTimeMUDError is the largest error in utilization of the
+// addends of a MutatorTimeMUD. Between breakpoints, utilization is
+// not linear in the start of the window, so each segment is divided
+// into addends that approximate it as linear to within this error.
+const mutatorTimeMUDError = 0.001
+
+// addSegment adds the utilizations of the windows of mutator time w
+// starting in (lo, hi), which are between breakpoints, to acc. lutil
+// and rutil are the utilizations at lo and hi. If the utilization at
+// the midpoint differs from the linear interpolation by more than
+// mutatorTimeMUDError, addSegment splits the segment.
+func (m *mutatorTime) addSegment(acc *uniformSum, w, lo, lutil, hi, rutil float64, depth int) {
+	mid := (lo + hi) / 2
+	if depth < 20 {
+		mutil := m.window(mid, w, true)
+		if math.Abs(mutil-(lutil+rutil)/2) > mutatorTimeMUDError {
+			m.addSegment(acc, w, lo, lutil, mid, mutil, depth+1)
+			m.addSegment(acc, w, mid, mutil, hi, rutil, depth+1)
+			return
+		}
+	}
+	l, r := min(lutil, rutil), max(lutil, rutil)
+	if r-l < 1e-12 {
+		r = l
+	}
+	acc.add(uniform{l, r, hi - lo})
+}
+
+// MutatorTimeMUD is like MutatorUtilizationDistribution, but each
+// window is windowNS nanoseconds of mutator time, as for
+// MutatorTimeMMU, and windows are weighted uniformly in mutator time.
+// The distribution is approximate: percentiles are within about
+// mutatorTimeMUDError utilization of the exact distribution.
+//
+// This will panic if the trace does not have program execution times.
+func (s *GcStats) MutatorTimeMUD(windowNS int) *MUD {
+	s.requireProgTimes()
+	us := s.utilSums()
+	if len(us.log) == 0 {
+		return &MUD{edges: []edge{{0, 0, 1}}, csums: []float64{0}}
+	}
+	m := newMutatorTime(us)
+	w := math.Min(float64(windowNS), m.total())
+	last := m.total() - w
+	if last <= 0 {
+		// The window spans the whole log.
+		util := m.mu(float64(us.log[0].Begin), 0, float64(us.log[len(us.log)-1].End()), len(us.log)-1)
+		return newMUD(int(w), []edge{{util, 0, 1}})
+	}
+
+	var acc uniformSum
+	pts := m.breakpoints(w)
+	for i := 0; i+1 < len(pts); i++ {
+		// Windows strictly between breakpoints exclude STW
+		// phases at their beginning and include those at their
+		// end, like the late window at the left breakpoint and
+		// the early window at the right breakpoint.
+		lo, hi := pts[i], pts[i+1]
+		m.addSegment(&acc, w, lo, m.window(lo, w, true), hi, m.window(hi, w, false), 0)
+	}
+	mud := newMUD(int(w), acc.edges(last))
+	acc.reset()
+	return mud
+}
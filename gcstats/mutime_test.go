@@ -0,0 +1,79 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestMutatorTimeMMU(t *testing.T) {
+	// 10ns of mutator, a 2ns STW pause, and 10ns of mutator.
+	s := NewFromPhases([]Phase{
+		{Begin: 0, Duration: 10, Gomaxprocs: 1},
+		{Begin: 10, Duration: 2, Gomaxprocs: 1, GCProcs: 1, STW: true},
+		{Begin: 12, Duration: 10, Gomaxprocs: 1},
+	}, 1)
+	for _, test := range []struct {
+		window int
+		want   float64
+	}{
+		// 5ns of mutator time is stretched by the whole pause,
+		// where 5ns of wall-clock time contains 3ns of mutator.
+		{5, 5.0 / 7},
+		{1, 1.0 / 3},
+		// Windows are capped at the 20ns of mutator time.
+		{100, 20.0 / 22},
+	} {
+		if got := s.MutatorTimeMMU(test.window); math.Abs(got-test.want) > 1e-12 {
+			t.Errorf("MutatorTimeMMU(%d): want %v, got %v", test.window, test.want, got)
+		}
+	}
+	if got, want := s.MMU(5), 3.0/5; math.Abs(got-want) > 1e-12 {
+		t.Errorf("MMU(5): want %v, got %v", want, got)
+	}
+
+	// A third of the windows of 5ns contain the pause.
+	mud := s.MutatorTimeMUD(5)
+	if got, want := mud.InvCDF(0), 5.0/7; math.Abs(got-want) > 1e-9 {
+		t.Errorf("MUD InvCDF(0): want %v, got %v", want, got)
+	}
+	if got, want := mud.CDF(0.9), 1.0/3; math.Abs(got-want) > 1e-9 {
+		t.Errorf("MUD CDF(0.9): want %v, got %v", want, got)
+	}
+
+	// With a quarter of the procs always in GC, every window has
+	// the same utilization.
+	s = NewFromPhases([]Phase{
+		{Begin: 0, Duration: 100, Gomaxprocs: 4, GCProcs: 1},
+		{Begin: 100, Duration: 100, Gomaxprocs: 4, GCProcs: 1},
+	}, 1)
+	if got := s.MutatorTimeMMU(30); math.Abs(got-0.75) > 1e-12 {
+		t.Errorf("constant utilization: want MMU 0.75, got %v", got)
+	}
+}
+
+func TestMutatorTimeRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	s := &GcStats{log: randomLog(r, 100), n: 1, progTimes: true}
+	m := newMutatorTime(s.utilSums())
+	for _, w := range []int{10, 100, 1000} {
+		mmu := s.MutatorTimeMMU(w)
+		// Every sampled window has at least the MMU.
+		last := m.total() - float64(w)
+		for i := 0; i <= 1000; i++ {
+			tau := last * float64(i) / 1000
+			for _, late := range []bool{false, true} {
+				if util := m.window(tau, float64(w), late); util < mmu-1e-9 {
+					t.Errorf("window %d at %v has utilization %v below MMU %v", w, tau, util, mmu)
+				}
+			}
+		}
+		if got := s.MutatorTimeMUD(w).InvCDF(0); math.Abs(got-mmu) > 2*mutatorTimeMUDError {
+			t.Errorf("window %d: MUD minimum %v differs from MMU %v", w, got, mmu)
+		}
+	}
+}
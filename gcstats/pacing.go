@@ -0,0 +1,54 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import "fmt"
+
+// Pacing is how the garbage collector decided when to start the
+// cycles of a trace. Analyses of the heap goal and of how GC
+// frequency follows allocation assume the cycles were paced by heap
+// growth, and produce misleading results otherwise.
+type Pacing int
+
+const (
+	// PacingHeap means at least some cycles were triggered by
+	// heap growth, either by the GC pacer or the memory limit.
+	PacingHeap Pacing = iota
+
+	// PacingForced means every cycle was forced, such as when a
+	// program runs with GOGC=off and calls runtime.GC. The heap
+	// goal reported by such traces is meaningless.
+	PacingForced
+
+	// PacingUnknown means the trace records no heap sizes, so the
+	// pacing of its cycles can't be determined.
+	PacingUnknown
+)
+
+var pacingNames = []string{"heap", "forced", "unknown"}
+
+func (p Pacing) String() string {
+	if p >= 0 && int(p) < len(pacingNames) {
+		return pacingNames[p]
+	}
+	return fmt.Sprintf("Pacing(%d)", int(p))
+}
+
+// Pacing returns how the cycles of s were paced.
+func (s *GcStats) Pacing() Pacing {
+	cycles := s.Cycles()
+	forced, sized := true, false
+	for _, c := range cycles {
+		forced = forced && c.Forced
+		sized = sized || c.HeapTrigger != 0 || c.HeapGoal != 0
+	}
+	switch {
+	case len(cycles) > 0 && forced:
+		return PacingForced
+	case !sized:
+		return PacingUnknown
+	}
+	return PacingHeap
+}
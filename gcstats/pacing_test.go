@@ -0,0 +1,64 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// growingLog returns a log of n cycles whose heap goal grows for the
+// first 10 cycles, optionally all forced.
+func growingLog(n int, forced bool) string {
+	var buf strings.Builder
+	for i := 0; i < n; i++ {
+		goal := 4 * min(i+1, 10)
+		fmt.Fprintf(&buf, "gc %d @%.3fs 5%%: 0.039+0.80+0.62 ms clock, 0.11+0.80/1.3/7.3+1.8 ms cpu, %d->%d->2 MB, %d MB goal, 4 P", i+1, float64(i)*0.05, goal, goal, goal)
+		if forced {
+			buf.WriteString(" (forced)")
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+func TestPacing(t *testing.T) {
+	const forced = "gc 1 @0.287s 1%: 0.32+14+0.013 ms clock, 0.32+0/12/25+0.013 ms cpu, 136->136->8 MB, 8532210231528 MB goal, 0 MB stacks, 0 MB globals, 4 P (forced)\n"
+	const paced = "gc 2 @0.581s 0%: 0.25+14+0.018 ms clock, 0.25+0/10/24+0.018 ms cpu, 16->17->8 MB, 16 MB goal, 0 MB stacks, 0 MB globals, 4 P\n"
+	for _, test := range []struct {
+		log  string
+		want Pacing
+	}{
+		{benchLog(20), PacingHeap},
+		{forced, PacingForced},
+		{growingLog(20, true), PacingForced},
+		{forced + paced, PacingHeap},
+		{"gc1(1): 0+12+0+3 us, 0 -> 0 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields @12345\n", PacingUnknown},
+		{"", PacingUnknown},
+	} {
+		s, err := NewFromLog(strings.NewReader(test.log))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.Pacing(); got != test.want {
+			t.Errorf("want pacing %v, got %v for log:\n%s", test.want, got, test.log)
+		}
+	}
+}
+
+func TestWarmupForced(t *testing.T) {
+	// Warm-up is detected from the heap goal of paced cycles,
+	// but not if every cycle was forced.
+	for _, forced := range []bool{false, true} {
+		s, err := NewFromLog(strings.NewReader(growingLog(40, forced)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w := s.Warmup(); (w == 0) != forced {
+			t.Errorf("forced=%v: got warm-up of %d cycles", forced, w)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"bytes"
+	"runtime"
+	"sync"
+)
+
+// minChunkSize is the minimum number of bytes of log parsed by each
+// goroutine in NewFromBytes. Logs smaller than twice this are parsed
+// serially.
+const minChunkSize = 4 << 20
+
+// NewFromBytes constructs GcStats by parsing a GC log in data, such
+// as a memory-mapped file. Large logs are split at line boundaries
+// and the pieces are parsed concurrently. The result is the same as
+// parsing data with NewFromLog.
+func NewFromBytes(data []byte) (*GcStats, error) {
+	p := NewParserBytes(data)
+	if err := p.ParseAll(); err != nil {
+		return nil, err
+	}
+	return p.Stats(), nil
+}
+
+// ParseAll parses the rest of the input and adds it to Stats,
+// treating the end of the input as the end of the log regardless of
+// Follow. It returns the first error encountered, if any. If p was
+// returned by NewParserBytes and nothing has been parsed yet, large
+// logs are parsed concurrently, like NewFromBytes.
+func (p *Parser) ParseAll() error {
+	if p.r == nil && p.line == 0 && p.err == nil {
+		data := p.data
+		p.data = nil
+		p.err = p.parseChunks(splitLines(data, runtime.GOMAXPROCS(0), minChunkSize))
+		return p.err
+	}
+	p.Follow = false
+	for p.Next() {
+	}
+	return p.err
+}
+
+// parseChunks concurrently parses consecutive chunks of a GC log and
+// adds them to p.stats.
+func (p *Parser) parseChunks(chunks [][]byte) error {
+	// Parse each chunk into independent cycles. The fixups
+	// between adjacent cycles depend on all earlier cycles, so
+	// these are applied below as the chunks are stitched
+	// together.
+	parsed := make([]parsedChunk, len(chunks))
+	progress := p.Progress
+	if progress != nil {
+		var mu sync.Mutex
+		progress = func(n int64) {
+			mu.Lock()
+			defer mu.Unlock()
+			p.Progress(n)
+		}
+	}
+	var wg sync.WaitGroup
+	for i := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			parsed[i].parse(chunks[i], progress)
+		}()
+	}
+	wg.Wait()
+
+	for i := range parsed {
+		c := &parsed[i]
+		start, diag, sched, scvg, env := 0, 0, 0, 0, 0
+		// addDiags adds the chunk's diagnostics, scheduler and
+		// scavenger samples, and environment lines before
+		// line, interleaving them with those from addCycle.
+		addDiags := func(line int) {
+			for ; diag < len(c.diags) && c.diags[diag].Line < line; diag++ {
+				d := c.diags[diag]
+				d.Line += p.line
+				p.stats.addDiagnostic(d)
+			}
+			end := sched
+			for end < len(c.sched) && c.schedLines[end] < line {
+				end++
+			}
+			if end > sched {
+				p.stats.addSched(c.sched[sched:end]...)
+				sched = end
+			}
+			for ; scvg < len(c.scvg) && c.scvg[scvg].line < line; scvg++ {
+				p.addScvg(c.scvg[scvg].sample)
+			}
+			for ; env < len(c.env) && c.env[env].line < line; env++ {
+				p.stats.updateEnv(c.env[env].env)
+			}
+		}
+		for _, cycle := range c.cycles {
+			addDiags(cycle.line)
+			p.stats.progTimes = p.stats.progTimes && cycle.progTimes
+			base := p.line
+			p.line += cycle.line
+			_, err := p.addCycle(c.phases[start:cycle.end], cycle.cycle)
+			p.line = base
+			if err != nil {
+				pe := err.(*ParseError)
+				pe.setText(lineText(chunks[i], cycle.line))
+				p.stats.addDiagnostic(errorDiagnostic(pe.Line, pe.Text, pe))
+				return pe
+			}
+			start = cycle.end
+		}
+		if c.err != nil {
+			// Like Next, record everything before the error
+			// and then the error.
+			pe := c.err.(*ParseError)
+			addDiags(pe.Line)
+			pe.Line += p.line
+			p.stats.addDiagnostic(errorDiagnostic(pe.Line, pe.Text, pe))
+			return pe
+		}
+		addDiags(c.lines + 1)
+		p.line += c.lines
+		// Release the chunk's phases, which have been copied
+		// into p.stats.
+		*c = parsedChunk{}
+	}
+	p.finish()
+	return nil
+}
+
+// lineText returns line n of data, counting from 1.
+func lineText(data []byte, n int) string {
+	lines := Parser{data: data}
+	for {
+		line, ok := lines.readLine()
+		if !ok || lines.line >= n {
+			return line
+		}
+	}
+}
+
+// splitLines splits data into at most n chunks of at least min bytes
+// each. Each chunk except possibly the last ends with a newline.
+func splitLines(data []byte, n, min int) [][]byte {
+	size := len(data) / n
+	if size < min {
+		size = min
+	}
+	var chunks [][]byte
+	for len(data) > 0 {
+		end := len(data)
+		if size < end {
+			if i := bytes.IndexByte(data[size:], '\n'); i >= 0 {
+				end = size + i + 1
+			}
+		}
+		chunks = append(chunks, data[:end])
+		data = data[end:]
+	}
+	return chunks
+}
+
+// parsedChunk is the unstitched result of parsing part of a GC log.
+type parsedChunk struct {
+	// phases contains the phases of each parsed cycle, exactly as
+	// returned by phasesFromLine.
+	phases []Phase
+
+	// cycles records the extent of each cycle in phases.
+	cycles []chunkCycle
+
+	// err is the *ParseError that stopped parsing, if any. It
+	// follows the last cycle in cycles, and its line number is
+	// relative to the chunk.
+	err error
+
+	// lines is the number of lines in the chunk and diags records
+	// the skipped lines. Line numbers are relative to the chunk.
+	lines int
+	diags []Diagnostic
+
+	// sched records the scheduler trace samples in the chunk and
+	// schedLines their line numbers in the chunk.
+	sched      []SchedSample
+	schedLines []int
+
+	// scvg records the scavenger samples in the chunk. These are
+	// timed by the preceding cycle, so they must be interleaved
+	// with the cycles when the chunks are stitched together.
+	scvg []chunkScvg
+
+	// env records the environment header lines of the chunk.
+	env []chunkEnv
+}
+
+type chunkScvg struct {
+	// line is the line number of the sample in the chunk.
+	line   int
+	sample ScvgSample
+}
+
+type chunkEnv struct {
+	// line is the line number of the header line in the chunk.
+	line int
+	env  Environment
+}
+
+type chunkCycle struct {
+	// end is the index in phases following this cycle.
+	end int
+
+	// line is the line number of the cycle in the chunk.
+	line int
+
+	// progTimes indicates that the cycle has program execution
+	// times.
+	progTimes bool
+
+	// cycle is the cycle as returned by phasesFromLine.
+	cycle Cycle
+}
+
+// parse parses the chunk in data, reporting the bytes consumed to
+// progress if it's non-nil.
+func (c *parsedChunk) parse(data []byte, progress func(int64)) {
+	lines := Parser{data: data, Progress: progress}
+	defer lines.flushProgress()
+	for {
+		line, ok := lines.readLine()
+		if !ok {
+			return
+		}
+		c.lines = lines.line
+		n := len(c.phases)
+		var cycle Cycle
+		phases, progTimes, err := phasesFromLine(c.phases, &cycle, line)
+		if err != nil {
+			err.(*ParseError).Line = lines.line
+			c.err = err
+			return
+		}
+		c.phases = phases
+		if len(phases) == n {
+			// Not a GC cycle.
+			if sample, ok := parseSchedLine(line); ok {
+				c.sched = append(c.sched, sample)
+				c.schedLines = append(c.schedLines, lines.line)
+			} else if sample, ok := parseScvgLine(line); ok {
+				c.scvg = append(c.scvg, chunkScvg{lines.line, sample})
+			} else if env, ok := parseEnvLine(line); ok {
+				c.env = append(c.env, chunkEnv{lines.line, env})
+			} else if msg, ok := skippedLineDiagnostic(line); ok {
+				c.diags = append(c.diags, Diagnostic{Line: lines.line, Text: line, Message: msg})
+			}
+			continue
+		}
+		c.cycles = append(c.cycles, chunkCycle{len(phases), lines.line, progTimes, cycle})
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package parquet writes tables of flat columns as Apache Parquet
+// files, so GC telemetry can be loaded into tools such as DuckDB and
+// pandas.
+//
+// It supports only what gcstats needs: a single row group of
+// uncompressed, PLAIN-encoded INT64, DOUBLE, BOOLEAN, and UTF-8 string
+// columns, which may be nullable. The format is described at
+// https://github.com/apache/parquet-format.
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+const magic = "PAR1"
+
+// A Column is a named column of a table. Exactly one of its value
+// slices must be set.
+type Column struct {
+	Name string
+
+	Int64  []int64
+	Double []float64
+	Bool   []bool
+	String []string
+
+	// Valid, if not nil, makes the column nullable and reports
+	// whether each row's value is present. Values in rows that
+	// are not valid are ignored.
+	Valid []bool
+}
+
+// Physical types, encodings, and other enums of the format.
+const (
+	typeBoolean   = 0
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+
+	repRequired = 0
+	repOptional = 1
+
+	encPlain = 0
+	encRLE   = 3
+
+	convertedUTF8 = 0
+
+	pageData = 0
+)
+
+func (c *Column) rows() (int, int32) {
+	switch {
+	case c.Int64 != nil:
+		return len(c.Int64), typeInt64
+	case c.Double != nil:
+		return len(c.Double), typeDouble
+	case c.Bool != nil:
+		return len(c.Bool), typeBoolean
+	case c.String != nil:
+		return len(c.String), typeByteArray
+	}
+	return 0, -1
+}
+
+// Write writes a Parquet file to w with a row for each value of the
+// columns, which must all be the same length. createdBy names the
+// program that wrote the file.
+func Write(w io.Writer, cols []Column, createdBy string) error {
+	if len(cols) == 0 {
+		return fmt.Errorf("parquet: no columns")
+	}
+	rows, _ := cols[0].rows()
+	for _, c := range cols {
+		n, typ := c.rows()
+		if typ < 0 && rows != 0 {
+			return fmt.Errorf("parquet: column %s has no values", c.Name)
+		}
+		if n != rows || c.Valid != nil && len(c.Valid) != rows {
+			return fmt.Errorf("parquet: column %s has %d rows, want %d", c.Name, n, rows)
+		}
+	}
+
+	var file bytes.Buffer
+	file.WriteString(magic)
+	schema := []thriftStruct{{
+		{4, "schema"},
+		{5, int32(len(cols))},
+	}}
+	var chunks []thriftStruct
+	var total int64
+	for _, c := range cols {
+		_, typ := c.rows()
+		if typ < 0 {
+			// An empty table can't tell which slice is
+			// set; any type will do.
+			typ = typeInt64
+		}
+		rep := int32(repRequired)
+		if c.Valid != nil {
+			rep = repOptional
+		}
+		elem := thriftStruct{{1, typ}, {3, rep}, {4, c.Name}}
+		if typ == typeByteArray {
+			// Annotate the bytes as UTF-8 both the old and
+			// the new way.
+			elem = append(elem, thriftField{6, int32(convertedUTF8)}, thriftField{10, thriftStruct{{1, thriftStruct{}}}})
+		}
+		schema = append(schema, elem)
+
+		data := c.encode()
+		header := thriftStruct{
+			{1, int32(pageData)},
+			{2, int32(len(data))},
+			{3, int32(len(data))},
+			{5, thriftStruct{
+				{1, int32(rows)},
+				{2, int32(encPlain)},
+				{3, int32(encRLE)},
+				{4, int32(encRLE)},
+			}},
+		}
+		offset := int64(file.Len())
+		header.write(&file)
+		file.Write(data)
+		size := int64(file.Len()) - offset
+		total += size
+		chunks = append(chunks, thriftStruct{
+			{2, offset},
+			{3, thriftStruct{
+				{1, typ},
+				{2, []int32{encPlain, encRLE}},
+				{3, []string{c.Name}},
+				{4, int32(0)}, // UNCOMPRESSED
+				{5, int64(rows)},
+				{6, size},
+				{7, size},
+				{9, offset},
+			}},
+		})
+	}
+
+	meta := thriftStruct{
+		{1, int32(1)},
+		{2, schema},
+		{3, int64(rows)},
+		{4, []thriftStruct{{
+			{1, chunks},
+			{2, total},
+			{3, int64(rows)},
+		}}},
+		{6, createdBy},
+	}
+	start := file.Len()
+	meta.write(&file)
+	binary.Write(&file, binary.LittleEndian, uint32(file.Len()-start))
+	file.WriteString(magic)
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// encode returns the data of a page holding all of c's values: the
+// definition levels of a nullable column followed by the PLAIN
+// encoding of its present values.
+func (c *Column) encode() []byte {
+	var buf []byte
+	present := func(i int) bool { return c.Valid == nil || c.Valid[i] }
+	if c.Valid != nil {
+		// Definition levels are 1 bit wide, encoded as RLE
+		// runs and prefixed by their length.
+		var levels []byte
+		for i := 0; i < len(c.Valid); {
+			j := i
+			for j < len(c.Valid) && c.Valid[j] == c.Valid[i] {
+				j++
+			}
+			levels = binary.AppendUvarint(levels, uint64(j-i)<<1)
+			if c.Valid[i] {
+				levels = append(levels, 1)
+			} else {
+				levels = append(levels, 0)
+			}
+			i = j
+		}
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(levels)))
+		buf = append(buf, levels...)
+	}
+	switch {
+	case c.Int64 != nil:
+		for i, x := range c.Int64 {
+			if present(i) {
+				buf = binary.LittleEndian.AppendUint64(buf, uint64(x))
+			}
+		}
+	case c.Double != nil:
+		for i, x := range c.Double {
+			if present(i) {
+				buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(x))
+			}
+		}
+	case c.Bool != nil:
+		// Booleans are bit-packed, least significant bit
+		// first.
+		n := 0
+		for i, x := range c.Bool {
+			if !present(i) {
+				continue
+			}
+			if n%8 == 0 {
+				buf = append(buf, 0)
+			}
+			if x {
+				buf[len(buf)-1] |= 1 << (n % 8)
+			}
+			n++
+		}
+	case c.String != nil:
+		for i, x := range c.String {
+			if present(i) {
+				buf = binary.LittleEndian.AppendUint32(buf, uint32(len(x)))
+				buf = append(buf, x...)
+			}
+		}
+	}
+	return buf
+}
@@ -0,0 +1,154 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// thriftReader decodes the compact protocol into maps from field IDs
+// to int64, string, bool, map, or []interface{} values.
+type thriftReader struct {
+	t *testing.T
+	r *bytes.Reader
+}
+
+func (tr *thriftReader) varint() int64 {
+	u, err := binary.ReadUvarint(tr.r)
+	if err != nil {
+		tr.t.Fatal(err)
+	}
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (tr *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case ctTrue:
+		return true
+	case ctFalse:
+		return false
+	case ctI32, ctI64:
+		return tr.varint()
+	case ctBinary:
+		n, _ := binary.ReadUvarint(tr.r)
+		b := make([]byte, n)
+		tr.r.Read(b)
+		return string(b)
+	case ctList:
+		h, _ := tr.r.ReadByte()
+		n := uint64(h >> 4)
+		if n == 15 {
+			n, _ = binary.ReadUvarint(tr.r)
+		}
+		var l []interface{}
+		for i := uint64(0); i < n; i++ {
+			l = append(l, tr.value(h&0xf))
+		}
+		return l
+	case ctStruct:
+		m := make(map[int16]interface{})
+		last := int16(0)
+		for {
+			h, err := tr.r.ReadByte()
+			if err != nil {
+				tr.t.Fatal(err)
+			}
+			if h == 0 {
+				return m
+			}
+			id := last + int16(h>>4)
+			if h>>4 == 0 {
+				id = int16(tr.varint())
+			}
+			m[id] = tr.value(h & 0xf)
+			last = id
+		}
+	}
+	tr.t.Fatalf("bad Thrift type %d", typ)
+	return nil
+}
+
+func TestThrift(t *testing.T) {
+	var buf bytes.Buffer
+	thriftStruct{{1, int32(-2)}, {2, true}, {20, "ab"}, {21, []int32{1, 2}}, {22, thriftStruct{}}}.write(&buf)
+	want := []byte{0x15, 0x03, 0x11, 0x08, 0x28, 0x02, 'a', 'b', 0x19, 0x25, 0x02, 0x04, 0x1c, 0x00, 0x00}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("want % x, got % x", want, buf.Bytes())
+	}
+}
+
+func TestWrite(t *testing.T) {
+	cols := []Column{
+		{Name: "n", Int64: []int64{1, -2, 3}},
+		{Name: "begin", Int64: []int64{10, 0, 30}, Valid: []bool{true, false, true}},
+		{Name: "x", Double: []float64{0.5, 1, math.Inf(1)}},
+		{Name: "forced", Bool: []bool{false, true, true}},
+		{Name: "cause", String: []string{"paced", "forced", ""}},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, cols, "test"); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if string(b[:4]) != magic || string(b[len(b)-4:]) != magic {
+		t.Fatalf("bad magic")
+	}
+	n := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	footer := b[len(b)-8-n : len(b)-8]
+	tr := &thriftReader{t, bytes.NewReader(footer)}
+	meta := tr.value(ctStruct).(map[int16]interface{})
+	if tr.r.Len() != 0 {
+		t.Errorf("%d bytes left after metadata", tr.r.Len())
+	}
+	if meta[3] != int64(3) || meta[6] != "test" {
+		t.Errorf("want 3 rows by test, got %v", meta)
+	}
+	schema := meta[2].([]interface{})
+	if len(schema) != 6 || schema[0].(map[int16]interface{})[5] != int64(5) {
+		t.Fatalf("bad schema %v", schema)
+	}
+	wantTypes := []int64{typeInt64, typeInt64, typeDouble, typeBoolean, typeByteArray}
+	wantPages := [][]byte{
+		{1, 0, 0, 0, 0, 0, 0, 0, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 3, 0, 0, 0, 0, 0, 0, 0},
+		{6, 0, 0, 0, 2, 1, 2, 0, 2, 1, 10, 0, 0, 0, 0, 0, 0, 0, 30, 0, 0, 0, 0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0, 0xe0, 0x3f, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f, 0, 0, 0, 0, 0, 0, 0xf0, 0x7f},
+		{6},
+		{5, 0, 0, 0, 'p', 'a', 'c', 'e', 'd', 6, 0, 0, 0, 'f', 'o', 'r', 'c', 'e', 'd', 0, 0, 0, 0},
+	}
+	chunks := meta[4].([]interface{})[0].(map[int16]interface{})[1].([]interface{})
+	for i, c := range cols {
+		elem := schema[i+1].(map[int16]interface{})
+		wantRep := int64(repRequired)
+		if c.Valid != nil {
+			wantRep = repOptional
+		}
+		if elem[1] != wantTypes[i] || elem[3] != wantRep || elem[4] != c.Name {
+			t.Errorf("column %s: bad schema element %v", c.Name, elem)
+		}
+		cm := chunks[i].(map[int16]interface{})[3].(map[int16]interface{})
+		if !reflect.DeepEqual(cm[3], []interface{}{c.Name}) || cm[5] != int64(3) {
+			t.Errorf("column %s: bad column metadata %v", c.Name, cm)
+		}
+		off := cm[9].(int64)
+		tr := &thriftReader{t, bytes.NewReader(b[off:])}
+		header := tr.value(ctStruct).(map[int16]interface{})
+		start := off + int64(len(b[off:])-tr.r.Len())
+		size := header[3].(int64)
+		if start+size-off != cm[7] {
+			t.Errorf("column %s: page ends at %d, chunk size says %d", c.Name, start+size-off, cm[7])
+		}
+		if page := b[start : start+size]; !bytes.Equal(page, wantPages[i]) {
+			t.Errorf("column %s: want page % x, got % x", c.Name, wantPages[i], page)
+		}
+	}
+
+	if err := Write(&buf, []Column{{Name: "a", Int64: []int64{1}}, {Name: "b", Int64: []int64{}}}, ""); err == nil {
+		t.Errorf("want error for columns of different lengths")
+	}
+}
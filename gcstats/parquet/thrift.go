@@ -0,0 +1,122 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Parquet's metadata is serialized with Thrift's compact protocol.
+// Rather than generate code from parquet.thrift, the metadata is
+// built as generic structs of fields numbered as in parquet.thrift.
+
+// A thriftStruct is a Thrift struct. Its fields must be in increasing
+// order of ID.
+type thriftStruct []thriftField
+
+// A thriftField is a field of a Thrift struct. Its value must be an
+// int32, int64, string, bool, thriftStruct, or a slice of int32,
+// string, or thriftStruct.
+type thriftField struct {
+	id    int16
+	value interface{}
+}
+
+// Compact protocol type codes.
+const (
+	ctTrue   = 1
+	ctFalse  = 2
+	ctI32    = 5
+	ctI64    = 6
+	ctBinary = 8
+	ctList   = 9
+	ctStruct = 12
+)
+
+func (s thriftStruct) write(buf *bytes.Buffer) {
+	last := int16(0)
+	for _, f := range s {
+		var typ byte
+		switch v := f.value.(type) {
+		case bool:
+			typ = ctFalse
+			if v {
+				typ = ctTrue
+			}
+		case int32:
+			typ = ctI32
+		case int64:
+			typ = ctI64
+		case string:
+			typ = ctBinary
+		case thriftStruct:
+			typ = ctStruct
+		case []int32, []string, []thriftStruct:
+			typ = ctList
+		default:
+			panic(fmt.Sprintf("unsupported Thrift value %T", f.value))
+		}
+		if delta := f.id - last; delta > 0 && delta <= 15 {
+			buf.WriteByte(byte(delta)<<4 | typ)
+		} else {
+			buf.WriteByte(typ)
+			writeVarint(buf, int64(f.id))
+		}
+		last = f.id
+		writeValue(buf, f.value)
+	}
+	buf.WriteByte(0)
+}
+
+func writeValue(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case bool:
+		// Field headers encode booleans.
+	case int32:
+		writeVarint(buf, int64(v))
+	case int64:
+		writeVarint(buf, v)
+	case string:
+		writeUvarint(buf, uint64(len(v)))
+		buf.WriteString(v)
+	case thriftStruct:
+		v.write(buf)
+	case []int32:
+		writeListHeader(buf, len(v), ctI32)
+		for _, x := range v {
+			writeVarint(buf, int64(x))
+		}
+	case []string:
+		writeListHeader(buf, len(v), ctBinary)
+		for _, x := range v {
+			writeValue(buf, x)
+		}
+	case []thriftStruct:
+		writeListHeader(buf, len(v), ctStruct)
+		for _, x := range v {
+			x.write(buf)
+		}
+	}
+}
+
+func writeListHeader(buf *bytes.Buffer, n int, elem byte) {
+	if n < 15 {
+		buf.WriteByte(byte(n)<<4 | elem)
+		return
+	}
+	buf.WriteByte(0xf0 | elem)
+	writeUvarint(buf, uint64(n))
+}
+
+// writeVarint writes x as a ZigZag-encoded varint.
+func writeVarint(buf *bytes.Buffer, x int64) {
+	writeUvarint(buf, uint64(x<<1)^uint64(x>>63))
+}
+
+func writeUvarint(buf *bytes.Buffer, x uint64) {
+	buf.Write(binary.AppendUvarint(nil, x))
+}
@@ -6,214 +6,846 @@ package gcstats
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
-	"regexp"
+	"math"
 	"strconv"
 	"strings"
 	"time"
 )
 
-var (
-	// Go 1.4 GODEBUG=gctrace=1 format, with optional start time
-	gc14Log = regexp.MustCompile(`^gc(\d+)\(\d+\): (\d+)\+(\d+)\+(\d+)\+(\d+) us,.* (@\d+)?`)
-
-	// Go 1.5 GODEBUG=gctrace=1 format
-	gc15Head   = regexp.MustCompile(`^gc #?(\d+) @([\d.]+)s.*:`)
-	gc15Clocks = regexp.MustCompile(`^((?:\d+(?:\.\d+)?\+)*\d+(?:\.\d+)?) ms clock`)
-	gc15CPUs   = regexp.MustCompile(`^((?:\d+(?:\.\d+)?[+/])*\d+(?:\.\d+)?) ms cpu`)
-	gc15Ps     = regexp.MustCompile(`^(\d+) P`)
-)
-
 // NewFromLog constructs GcStats by parsing a GC log produced by
 // GODEBUG=gctrace=1.
 func NewFromLog(r io.Reader) (*GcStats, error) {
-	log := []Phase{}
-	n := 0
-	haveBegin := true
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		var phases []Phase
-		if gc14Log.MatchString(line) {
-			var haveBegin1 bool
-			phases, haveBegin1 = phasesFromLog14(scanner)
-			if len(phases) != 0 {
-				haveBegin = haveBegin && haveBegin1
-			}
-		} else if gc15Head.MatchString(line) {
-			var err error
-			phases, err = phasesFromLog15(scanner)
-			if err != nil {
-				return nil, err
+	p := NewParser(r)
+	for p.Next() {
+	}
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
+	return p.Stats(), nil
+}
+
+// Parser incrementally parses a GC log produced by GODEBUG=gctrace=1.
+//
+// Parsed cycles are appended to the GcStats returned by Stats, whose
+// analyses incorporate the new cycles incrementally. Hence, a Parser
+// can be used to follow a log that is still being written.
+type Parser struct {
+	// Follow indicates that the input may still be growing. If
+	// Follow is false, Next treats the end of the input as the
+	// end of the log. If Follow is true, a partial line at the end
+	// of the input is retained and Next may be called again once
+	// more input is available.
+	Follow bool
+
+	// OverlapPolicy specifies how to repair a cycle that begins
+	// before the previous cycle ends by at most OverlapTolerance.
+	// This usually happens because of rounding in the trace.
+	OverlapPolicy OverlapPolicy
+
+	// OverlapTolerance is the largest overlap between cycles that
+	// is repaired according to OverlapPolicy. Larger overlaps are
+	// errors. If 0, DefaultOverlapTolerance is used.
+	OverlapTolerance time.Duration
+
+	// Progress, if non-nil, is called periodically with the number
+	// of bytes of input consumed since the last call, so the
+	// progress of parsing a large log can be displayed. Calls are
+	// serialized, but may come from other goroutines when the log
+	// is parsed concurrently.
+	Progress func(n int64)
+
+	// The input is read from r or, if r is nil, from data.
+	r       *bufio.Reader
+	data    []byte
+	partial string
+	stats   *GcStats
+	err     error
+
+	// line is the number of lines read.
+	line int
+
+	// unreported is the number of bytes consumed but not yet
+	// reported to Progress.
+	unreported int64
+
+	// pending is the final phase of the most recent cycle, which
+	// is unterminated until the next cycle begins. havePending is
+	// false if no cycles have been parsed.
+	pending     Phase
+	havePending bool
+
+	// goalRatio is the largest ratio of a cycle's heap goal to
+	// the previous cycle's live heap seen so far. This estimates
+	// the ratio set by GOGC, which is used to infer cycles whose
+	// heap goal was limited.
+	goalRatio float64
+
+	// recent records the most recently added cycles, which are
+	// compared against each new cycle to drop duplicate records,
+	// such as those produced when several collectors capture the
+	// same log. dups is the number of duplicates dropped and
+	// firstDup is the line of the first.
+	recent         [dupWindow]cycleKey
+	nrecent        int
+	dups, firstDup int
+
+	// cycleBuf and addBuf are scratch buffers for parsing a
+	// cycle and adding it to stats.
+	cycleBuf, addBuf []Phase
+}
+
+// NewParser returns a Parser that reads a GC log from r.
+func NewParser(r io.Reader) *Parser {
+	return &Parser{r: bufio.NewReader(r), stats: &GcStats{progTimes: true}}
+}
+
+// NewParserBytes returns a Parser that reads a GC log from data, such
+// as a memory-mapped file. Unlike NewParser, this doesn't copy the
+// log through an intermediate buffer. The Parser does not retain
+// references to data once it has been parsed.
+func NewParserBytes(data []byte) *Parser {
+	return &Parser{data: data, stats: &GcStats{progTimes: true}}
+}
+
+// DefaultOverlapTolerance is the default OverlapTolerance of a
+// Parser.
+const DefaultOverlapTolerance = 5 * time.Millisecond
+
+// OverlapPolicy is a way of repairing overlapping GC cycles.
+type OverlapPolicy int
+
+const (
+	// OverlapShift shifts the later cycle so it begins when the
+	// earlier cycle ends. This is the default.
+	OverlapShift OverlapPolicy = iota
+
+	// OverlapTruncate truncates the beginning of the later cycle
+	// so it begins when the earlier cycle ends. Like the other
+	// policies, it never modifies phases already added to the
+	// GcStats.
+	OverlapTruncate
+
+	// OverlapDrop drops the later cycle.
+	OverlapDrop
+
+	// OverlapError treats any overlap as an error.
+	OverlapError
+)
+
+var overlapPolicyNames = []string{"shift", "truncate", "drop", "error"}
+
+func (o OverlapPolicy) String() string {
+	if o >= 0 && int(o) < len(overlapPolicyNames) {
+		return overlapPolicyNames[o]
+	}
+	return fmt.Sprintf("OverlapPolicy(%d)", int(o))
+}
+
+// ParseOverlapPolicy returns the OverlapPolicy named name, which is
+// one of "shift", "truncate", "drop", or "error".
+func ParseOverlapPolicy(name string) (OverlapPolicy, error) {
+	for i, n := range overlapPolicyNames {
+		if n == name {
+			return OverlapPolicy(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown overlap policy %q", name)
+}
+
+// Stats returns the GcStats for the cycles parsed so far. Next
+// updates the returned GcStats, so it must not be used concurrently
+// with Next.
+func (p *Parser) Stats() *GcStats {
+	return p.stats
+}
+
+// Err returns the first error encountered by Next, if any. A line
+// that could not be parsed is reported as a *ParseError.
+func (p *Parser) Err() error {
+	return p.err
+}
+
+// Next parses the next GC cycle from the input and adds it to Stats.
+// It returns false when it reaches the end of the input or
+// encounters an error, which can be retrieved with Err.
+func (p *Parser) Next() bool {
+	if p.err != nil {
+		return false
+	}
+
+	for {
+		line, ok := p.readLine()
+		if !ok {
+			p.flushProgress()
+			if p.err == nil && !p.Follow {
+				p.finish()
 			}
+			return false
 		}
 
-		if len(phases) == 0 {
-			continue
+		added, err := p.parseLine(line)
+		if err != nil {
+			p.stats.addDiagnostic(errorDiagnostic(p.line, line, err))
+			p.err = err
+			return false
+		}
+		if added {
+			return true
 		}
-		if haveBegin && len(log) > 0 && log[len(log)-1].Duration == -1 {
+	}
+}
+
+// errorDiagnostic returns the diagnostic of err, which stopped
+// parsing at line n with the given text.
+func errorDiagnostic(n int, text string, err error) Diagnostic {
+	msg := err.Error()
+	if pe, ok := err.(*ParseError); ok {
+		msg = pe.describe()
+	}
+	return Diagnostic{Line: n, Text: text, Error: true, Message: msg}
+}
+
+// parseLine parses a single line of the log and adds its GC cycle,
+// if any, to p.stats. It reports whether it added a cycle.
+func (p *Parser) parseLine(line string) (bool, error) {
+	var cycle Cycle
+	phases, progTimes, err := phasesFromLine(p.cycleBuf[:0], &cycle, line)
+	if err != nil {
+		err.(*ParseError).Line = p.line
+		return false, err
+	}
+	p.stats.progTimes = p.stats.progTimes && progTimes
+
+	p.cycleBuf = phases
+	if len(phases) == 0 {
+		if sample, ok := parseSchedLine(line); ok {
+			p.stats.addSched(sample)
+		} else if sample, ok := parseScvgLine(line); ok {
+			p.addScvg(sample)
+		} else if env, ok := parseEnvLine(line); ok {
+			p.stats.updateEnv(env)
+		} else if msg, ok := skippedLineDiagnostic(line); ok {
+			p.stats.addDiagnostic(Diagnostic{Line: p.line, Text: line, Message: msg})
+		}
+		return false, nil
+	}
+	added, err := p.addCycle(phases, cycle)
+	if err != nil {
+		err.(*ParseError).setText(line)
+	}
+	return added, err
+}
+
+// finish marks p.stats complete at the end of the log.
+func (p *Parser) finish() {
+	if p.havePending {
+		p.stats.addDiagnostic(Diagnostic{Message: fmt.Sprintf("dropped final %s phase of cycle %d because its duration is unknown", p.pending.Kind.String()[len("Phase"):], p.pending.N)})
+	}
+	if p.dups > 0 {
+		records := "records"
+		if p.dups == 1 {
+			records = "record"
+		}
+		p.stats.addDiagnostic(Diagnostic{Message: fmt.Sprintf("dropped %d duplicate GC cycle %s, first at line %d", p.dups, records, p.firstDup)})
+	}
+	p.stats.setComplete()
+}
+
+// readLine returns the next line of input without its line
+// terminator.
+func (p *Parser) readLine() (string, bool) {
+	if p.r == nil {
+		if len(p.data) == 0 {
+			return "", false
+		}
+		var line []byte
+		if i := bytes.IndexByte(p.data, '\n'); i >= 0 {
+			line, p.data = p.data[:i], p.data[i+1:]
+			p.consumed(i + 1)
+		} else {
+			line, p.data = p.data, nil
+			p.consumed(len(line))
+		}
+		p.line++
+		return string(bytes.TrimSuffix(line, []byte("\r"))), true
+	}
+
+	line, err := p.r.ReadString('\n')
+	if err == io.EOF {
+		if p.Follow {
+			p.partial += line
+			return "", false
+		}
+		if p.partial == "" && line == "" {
+			return "", false
+		}
+	} else if err != nil {
+		p.err = err
+		return "", false
+	}
+	line, p.partial = p.partial+line, ""
+	p.consumed(len(line))
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	p.line++
+	return line, true
+}
+
+// progressBatch is the number of bytes consumed between calls to
+// Parser.Progress.
+const progressBatch = 1 << 20
+
+// consumed records that n bytes of input were consumed.
+func (p *Parser) consumed(n int) {
+	if p.Progress == nil {
+		return
+	}
+	p.unreported += int64(n)
+	if p.unreported >= progressBatch {
+		p.flushProgress()
+	}
+}
+
+// flushProgress reports any consumed bytes to Progress.
+func (p *Parser) flushProgress() {
+	if p.unreported > 0 {
+		p.Progress(p.unreported)
+		p.unreported = 0
+	}
+}
+
+// addCycle adds a single GC cycle and its phases to p.stats. It
+// reports whether it added the cycle, which it may not if the cycle
+// overlaps the previous cycle. It reports a cycle that can't be added
+// as a *ParseError at line p.line without the line's text.
+func (p *Parser) addCycle(phases []Phase, cycle Cycle) (bool, error) {
+	s := p.stats
+	key := newCycleKey(phases, s.progTimes)
+	for i := 0; i < p.nrecent && i < dupWindow; i++ {
+		if p.recent[i] == key {
+			if p.dups == 0 {
+				p.firstDup = p.line
+			}
+			p.dups++
+			return false, nil
+		}
+	}
+	add := p.addBuf[:0]
+	if p.havePending {
+		prev := p.pending
+		if s.progTimes {
 			// Update duration time of last phase
-			prev := &log[len(log)-1]
 			prev.Duration = phases[0].Begin - prev.Begin
 
 			// Because of rounding, it's possible to
 			// appear to have slightly overlapping cycles.
-			// Scoot the cycle if this happens.
+			// Repair these according to the policy.
 			if prev.Duration < 0 {
 				delta := -prev.Duration
-				if delta > int64(5*time.Millisecond) {
-					return nil, fmt.Errorf("GC trace goes backward %dms between cycles %d and %d", delta/int64(time.Millisecond), prev.N, phases[0].N)
+				tolerance := p.OverlapTolerance
+				if tolerance == 0 {
+					tolerance = DefaultOverlapTolerance
+				}
+				if delta > int64(tolerance) {
+					return false, &ParseError{Line: p.line, Field: "@", Message: fmt.Sprintf("GC trace goes backward %dms between cycles %d and %d", delta/int64(time.Millisecond), prev.N, phases[0].N)}
+				}
+				overlap := fmt.Sprintf("cycle %d begins %s before cycle %d ends", phases[0].N, time.Duration(delta), prev.N)
+				switch p.OverlapPolicy {
+				case OverlapShift:
+					shiftPhases(phases, delta+1)
+					prev.Duration += delta + 1
+					s.addDiagnostic(Diagnostic{Line: p.line, Message: overlap + "; shifted later"})
+				case OverlapTruncate:
+					truncatePhases(phases, prev.Begin)
+					prev.Duration = 0
+					s.addDiagnostic(Diagnostic{Line: p.line, Message: fmt.Sprintf("%s; truncated cycle %d", overlap, phases[0].N)})
+				case OverlapDrop:
+					s.addDiagnostic(Diagnostic{Line: p.line, Message: overlap + "; dropped"})
+					return false, nil
+				default:
+					return false, &ParseError{Line: p.line, Field: "@", Message: overlap}
 				}
-				shiftPhases(phases, delta+1)
-				prev.Duration += delta + 1
 			}
 		}
-
-		log = append(log, phases...)
-		n += 1
+		add = append(add, prev)
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	// Hold back the unterminated end phase until we know its
+	// duration.
+	p.pending, p.havePending = phases[len(phases)-1], true
+	add = append(add, phases[:len(phases)-1]...)
+
+	cycle.N, cycle.Begin = phases[0].N, phases[0].Begin
+	cycle.Cause = p.cause(cycle)
+	s.appendCycle(add, cycle)
+	p.addBuf = add
+	p.recent[p.nrecent%dupWindow] = key
+	p.nrecent++
+	return true, nil
+}
+
+// dupWindow is the number of most recent cycles a new cycle is
+// compared against to detect duplicate records. Duplicates from
+// interleaved collectors may lag the original by a few cycles.
+const dupWindow = 8
+
+// cycleKey identifies the record of a GC cycle by its number and
+// begin time. Two records with the same key describe the same cycle,
+// even if their phase durations differ, as when collectors round them
+// differently.
+type cycleKey struct {
+	n     int
+	begin int64
+	// durs is a hash of the phase kinds and durations of a cycle
+	// without a begin time, which otherwise would be identified
+	// by its number alone.
+	durs uint64
+}
+
+// newCycleKey returns the key of the cycle consisting of phases.
+// progTimes indicates that the trace has begin times.
+func newCycleKey(phases []Phase, progTimes bool) cycleKey {
+	if progTimes {
+		return cycleKey{n: phases[0].N, begin: phases[0].Begin}
+	}
+	// FNV-1a over the kinds and durations of the phases.
+	h := uint64(14695981039346656037)
+	for _, ph := range phases {
+		for _, x := range [2]uint64{uint64(ph.Kind), uint64(ph.Duration)} {
+			h = (h ^ x) * 1099511628211
+		}
 	}
+	return cycleKey{n: phases[0].N, durs: h}
+}
 
-	// Remove unterminated end phase
-	if len(log) > 0 && log[len(log)-1].Duration == -1 {
-		log = log[:len(log)-1]
+// limitedGoalRatio is the fraction of the GOGC heap goal ratio below
+// which a cycle's heap goal is considered limited.
+const limitedGoalRatio = 0.9
+
+// minPacedHeap is the live heap below which the heap goal may be
+// raised to the runtime's minimum heap size, so the ratio of the goal
+// to the live heap doesn't reflect GOGC.
+const minPacedHeap = 4 << 20
+
+// cause infers what triggered cycle, which follows the last cycle in
+// p.stats.
+func (p *Parser) cause(cycle Cycle) Cause {
+	if cycle.Forced {
+		return CauseForced
+	}
+	cycles := p.stats.cycles
+	if len(cycles) == 0 || cycle.HeapGoal == 0 {
+		return CausePaced
 	}
+	prev := cycles[len(cycles)-1]
+	if prev.HeapLive < minPacedHeap {
+		return CausePaced
+	}
+	ratio := float64(cycle.HeapGoal) / float64(prev.HeapLive)
+	if ratio < limitedGoalRatio*p.goalRatio {
+		return CauseLimit
+	}
+	p.goalRatio = max(p.goalRatio, ratio)
+	return CausePaced
+}
 
-	return &GcStats{log, n, haveBegin}, nil
+// lineScanner scans the fields of a line of a GC trace. This is
+// much faster than matching regular expressions against every line.
+type lineScanner struct {
+	s string
 }
 
-func atoi(s string) int {
-	x, err := strconv.Atoi(s)
-	if err != nil {
-		panic(err)
+// literal consumes prefix if the remainder of the line begins with
+// it, and reports whether it did.
+func (l *lineScanner) literal(prefix string) bool {
+	if !strings.HasPrefix(l.s, prefix) {
+		return false
 	}
-	return x
+	l.s = l.s[len(prefix):]
+	return true
 }
 
-func atoi64(s string) int64 {
-	x, err := strconv.ParseInt(s, 10, 64)
-	if err != nil {
-		panic(err)
+// digits returns the number of leading decimal digits in s.
+func digits(s string) int {
+	i := 0
+	for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+		i++
 	}
-	return x
+	return i
 }
 
-func atof(s string) float64 {
-	x, err := strconv.ParseFloat(s, 64)
+// integer consumes a non-negative decimal integer.
+func (l *lineScanner) integer() (int64, bool) {
+	i := digits(l.s)
+	if i == 0 {
+		return 0, false
+	}
+	x, err := strconv.ParseInt(l.s[:i], 10, 64)
 	if err != nil {
-		panic(err)
+		return 0, false
 	}
-	return x
+	l.s = l.s[i:]
+	return x, true
 }
 
-// phasesFromLog14 parses the phases for a single Go 1.4 GC cycle.
-func phasesFromLog14(scanner *bufio.Scanner) (phases []Phase, haveBegin bool) {
-	sub := gc14Log.FindStringSubmatch(scanner.Text())
+// megabytes consumes a non-negative decimal number of megabytes and
+// returns it in bytes.
+func (l *lineScanner) megabytes() (int64, bool) {
+	mb, ok := l.integer()
+	if !ok || mb > math.MaxInt64>>20 {
+		return 0, false
+	}
+	return mb << 20, true
+}
 
-	n := atoi(sub[1])
-	stop, sweepTerm, markTerm, shrink := atoi(sub[2]), atoi(sub[3]), atoi(sub[4]), atoi(sub[5])
-	var begin int64
-	if sub[6] != "" {
-		begin = atoi64(sub[6][1:])
-		haveBegin = true
+// add returns the sum of non-negative integers a and b, or false if
+// the sum overflows.
+func add(a, b int64) (int64, bool) {
+	if a > math.MaxInt64-b {
+		return 0, false
 	}
+	return a + b, true
+}
 
-	phases = []Phase{
-		// Go 1.5 includes stoptheworld() in sweep termination.
-		{0, int64(stop+sweepTerm) * 1000, PhaseSweepTerm, n, 1, 1, true},
-		// Go 1.5 includes stack shrink in mark termination.
-		{0, int64(markTerm+shrink) * 1000, PhaseMarkTerm, n, 1, 1, true},
-		{0, -1, PhaseSweep, n, 1, 0, false},
+// fixed consumes a non-negative decimal number with an optional
+// fractional part and returns it scaled by 10^scale and rounded to
+// the nearest integer. This uses integer arithmetic, so, unlike
+// converting through a float64, times such as "0.29" milliseconds
+// convert to nanoseconds exactly.
+func (l *lineScanner) fixed(scale int) (int64, bool) {
+	i := digits(l.s)
+	if i == 0 {
+		return 0, false
+	}
+	whole, frac := l.s[:i], ""
+	if i < len(l.s) && l.s[i] == '.' {
+		if n := digits(l.s[i+1:]); n > 0 {
+			frac = l.s[i+1 : i+1+n]
+			i += 1 + n
+		}
 	}
+	l.s = l.s[i:]
 
-	if haveBegin {
-		for i := range phases {
-			phases[i].Begin += begin
-			begin += phases[i].Duration
+	// Round away digits below 10^-scale.
+	round := false
+	if len(frac) > scale {
+		round = frac[scale] >= '5'
+		frac = frac[:scale]
+	}
+	var x int64
+	for _, part := range [...]string{whole, frac} {
+		for j := 0; j < len(part); j++ {
+			d := int64(part[j] - '0')
+			if x > (math.MaxInt64-d)/10 {
+				return 0, false
+			}
+			x = x*10 + d
 		}
 	}
+	for j := len(frac); j < scale; j++ {
+		if x > math.MaxInt64/10 {
+			return 0, false
+		}
+		x *= 10
+	}
+	if round {
+		x++
+	}
+	return x, true
+}
+
+// times consumes a '+'-separated list of times in milliseconds and
+// stores them in nanoseconds in ts. If comps is non-nil, each time
+// may be a '/'-separated list of components, which are summed
+// excluding the third (idle time) and stored in comps. It returns the
+// length of the list, which may exceed len(ts).
+func (l *lineScanner) times(ts []int64, comps [][3]int64) (int, bool) {
+	n := 0
+	for {
+		var t int64
+		var c [3]int64
+		for j := 0; ; j++ {
+			// Times are in milliseconds.
+			ns, ok := l.fixed(6)
+			if !ok {
+				return 0, false
+			}
+			if j != 2 {
+				if t, ok = add(t, ns); !ok {
+					return 0, false
+				}
+			}
+			if j < len(c) {
+				c[j] = ns
+			}
+			if comps == nil || !l.literal("/") {
+				break
+			}
+		}
+		if n < len(ts) {
+			ts[n] = t
+		}
+		if n < len(comps) {
+			comps[n] = c
+		}
+		n++
+		if !l.literal("+") {
+			return n, true
+		}
+	}
+}
+
+// phasesFromLine parses the phases for a single GC cycle in any
+// supported format and appends them to phases. It appends nothing if
+// line is not a GC trace line. progTimes is false if the cycle lacks
+// program execution times. phasesFromLine fills in the heap sizes of
+// cycle; the caller is responsible for the other fields. If line is
+// in a supported format but malformed, it returns a *ParseError
+// without a line number.
+func phasesFromLine(phases []Phase, cycle *Cycle, line string) (out []Phase, progTimes bool, err error) {
+	out, haveBegin, ok, err := phasesFromLog14(phases, cycle, line)
+	if ok {
+		return out, haveBegin, err
+	}
+	out, err = phasesFromLog15(phases, cycle, line)
+	return out, true, err
+}
 
-	return
+// heap consumes a "<trigger>-><marked>-><live> MB" list of heap sizes
+// and returns them in bytes.
+func (l *lineScanner) heap() (sizes [3]int64, ok bool) {
+	for i := range sizes {
+		if i > 0 && !l.literal("->") {
+			return sizes, false
+		}
+		if sizes[i], ok = l.megabytes(); !ok {
+			return sizes, false
+		}
+	}
+	return sizes, l.literal(" MB")
 }
 
-// phasesFromLog parses the phases for a single Go 1.5 GC cycle.
-func phasesFromLog15(scanner *bufio.Scanner) ([]Phase, error) {
-	line := scanner.Text()
-	if strings.Contains(line, "(forced)") {
-		// Ignore forced GC.
-		return nil, nil
+// phasesFromLog14 parses the phases for a single Go 1.4 GC cycle and
+// appends them to phases. It returns ok == false if line is not a Go
+// 1.4 GC trace line, and a *ParseError if it is one whose times
+// overflow.
+func phasesFromLog14(phases []Phase, cycle *Cycle, line string) (out []Phase, haveBegin, ok bool, err error) {
+	// Go 1.4 GODEBUG=gctrace=1 format, with optional start time:
+	// gc<n>(<procs>): <stop>+<sweepTerm>+<markTerm>+<shrink> us, <before> -> <after> MB, ... [@<begin>]
+	l := lineScanner{line}
+	if !l.literal("gc") {
+		return
+	}
+	n, ok1 := l.integer()
+	if !ok1 || !l.literal("(") {
+		return
+	}
+	if _, ok1 = l.integer(); !ok1 || !l.literal("): ") {
+		return
+	}
+	// Times are in microseconds.
+	var t [4]int64
+	for i := range t {
+		if i > 0 && !l.literal("+") {
+			return
+		}
+		if t[i], ok1 = l.fixed(3); !ok1 {
+			return
+		}
+	}
+	if !l.literal(" us,") {
+		return
+	}
+	heap := l
+	if heap.literal(" ") {
+		before, ok1 := heap.megabytes()
+		if ok1 && heap.literal(" -> ") {
+			if after, ok1 := heap.megabytes(); ok1 && heap.literal(" MB") {
+				// Go 1.4 doesn't mark concurrently, so the
+				// heap doesn't grow during marking.
+				*cycle = Cycle{HeapTrigger: before, HeapMarked: before, HeapLive: after}
+			}
+		}
+	}
+	// The start time, if present, is the last field.
+	sp := strings.LastIndexByte(l.s, ' ')
+	if sp < 0 {
+		return
+	}
+	ok = true
+
+	var begin int64
+	l.s = l.s[sp+1:]
+	if l.literal("@") {
+		begin, haveBegin = l.integer()
 	}
 
-	parts := strings.SplitAfterN(line, ": ", 2)
-	head := parts[0]
-	parts = strings.Split(parts[1], ", ")
+	// Go 1.5 includes stoptheworld() in sweep termination and
+	// stack shrink in mark termination.
+	sweepTerm, ok1 := add(t[0], t[1])
+	markTerm, ok2 := add(t[2], t[3])
+	end, ok3 := add(begin, sweepTerm)
+	end, ok4 := add(end, markTerm)
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		return nil, false, true, &ParseError{Text: line, Format: formatGo14, Field: "us", Message: "times overflow"}
+	}
+	start := len(phases)
+	phases = append(phases,
+		Phase{0, sweepTerm, PhaseSweepTerm, int(n), 1, 1, true},
+		Phase{0, markTerm, PhaseMarkTerm, int(n), 1, 1, true},
+		Phase{0, -1, PhaseSweep, int(n), 1, 0, false},
+	)
+	if haveBegin {
+		// Otherwise, absolute times are unknown, so Begin
+		// remains 0.
+		phases[start].Begin = begin
+		phases[start+1].Begin = begin + sweepTerm
+		phases[start+2].Begin = end
+	}
+	return phases, haveBegin, ok, nil
+}
 
-	sub := gc15Head.FindStringSubmatch(head)
-	n, begin := atoi(sub[1]), int64(atof(sub[2])*float64(time.Second))
+// phasesFromLog15 parses the phases for a single GC cycle in the
+// format used since Go 1.5 and appends them to phases. The phases are
+// described by the registered PhaseLayout with as many phases as the
+// line has clock times. It appends nothing if line is not a Go 1.5
+// or later GC trace line, and returns a *ParseError if it is a
+// malformed one.
+func phasesFromLog15(phases []Phase, cycle *Cycle, line string) ([]Phase, error) {
+	// Go 1.5 GODEBUG=gctrace=1 format:
+	// gc #<n> @<begin>s ...: <part>, <part>, ...
+	l := lineScanner{line}
+	if !l.literal("gc ") {
+		return phases, nil
+	}
+	l.literal("#")
+	n, ok := l.integer()
+	if !ok || !l.literal(" @") {
+		return phases, nil
+	}
+	begin, ok := l.fixed(9)
+	if !ok || !l.literal("s") || !strings.Contains(l.s, ":") {
+		return phases, nil
+	}
+	forced := strings.Contains(line, "(forced)")
+	fieldError := func(field, format string, args ...interface{}) error {
+		return &ParseError{Text: line, Format: formatGo15, Field: field, Message: fmt.Sprintf(format, args...)}
+	}
+
+	i := strings.Index(l.s, ": ")
+	if i < 0 {
+		return nil, fieldError("", `missing ": " before the phase times`)
+	}
+	rest := l.s[i+2:]
 
-	var clock, cpu [5]int64
+	var clock, cpu [maxLayoutPhases]int64
+	var comps [maxLayoutPhases][3]int64
+	var nclock, ncpu int
 	var gomaxprocs int
 	var gotClock, gotCPU, gotGomaxprocs bool
 
 	// Process comma separated sections.
-	for _, part := range parts {
-		if sub = gc15Clocks.FindStringSubmatch(part); sub != nil {
-			clocks := strings.Split(sub[1], "+")
-			if len(clocks) != len(clock) {
-				return nil, fmt.Errorf("unexpected number of clock times: %s", line)
-			}
-			for i, ms := range clocks {
-				clock[i] = int64(atof(ms) * float64(time.Millisecond))
-			}
+	for rest != "" {
+		part := rest
+		if i := strings.Index(rest, ", "); i >= 0 {
+			part, rest = rest[:i], rest[i+2:]
+		} else {
+			rest = ""
+		}
+
+		var ts [maxLayoutPhases]int64
+		var tcomps [maxLayoutPhases][3]int64
+		l = lineScanner{part}
+		if n, ok := l.times(ts[:], nil); ok && l.literal(" ms clock") {
+			clock, nclock = ts, n
 			gotClock = true
-		} else if sub = gc15CPUs.FindStringSubmatch(part); sub != nil {
-			cpus := strings.Split(sub[1], "+")
-			if len(cpus) != len(cpu) {
-				return nil, fmt.Errorf("unexpected number of cpu times: %s", line)
-			}
-			for i, ms := range cpus {
-				for j, ms1 := range strings.Split(ms, "/") {
-					if j == 2 {
-						// Ignore idle time
-						continue
-					}
-					cpu[i] += int64(atof(ms1) * float64(time.Millisecond))
-				}
-			}
+			continue
+		}
+		l = lineScanner{part}
+		if n, ok := l.times(ts[:], tcomps[:]); ok && l.literal(" ms cpu") {
+			cpu, comps, ncpu = ts, tcomps, n
 			gotCPU = true
-		} else if sub = gc15Ps.FindStringSubmatch(part); sub != nil {
-			gomaxprocs = atoi(sub[1])
+			continue
+		}
+		l = lineScanner{part}
+		if procs, ok := l.integer(); ok && l.literal(" P") {
+			gomaxprocs = int(procs)
 			gotGomaxprocs = true
+			continue
+		}
+		// The remaining fields are optional, but a malformed
+		// required field is reported as such rather than as
+		// missing.
+		for _, field := range []string{"ms clock", "ms cpu", "P"} {
+			if strings.HasSuffix(part, " "+field) {
+				return nil, fieldError(field, "malformed %q", part)
+			}
+		}
+		l = lineScanner{part}
+		if heap, ok := l.heap(); ok {
+			cycle.HeapTrigger, cycle.HeapMarked, cycle.HeapLive = heap[0], heap[1], heap[2]
+			continue
+		}
+		l = lineScanner{part}
+		if goal, ok := l.megabytes(); ok && l.literal(" MB goal") {
+			cycle.HeapGoal = goal
 		}
 	}
 
-	if !gotClock || !gotCPU || !gotGomaxprocs {
-		return nil, fmt.Errorf("failed to parse: %s", line)
+	for _, f := range []struct {
+		field string
+		got   bool
+	}{{"ms clock", gotClock}, {"ms cpu", gotCPU}, {"P", gotGomaxprocs}} {
+		if !f.got {
+			return nil, fieldError(f.field, "missing")
+		}
+	}
+	layout, ok := layoutFor(nclock)
+	if !ok {
+		return nil, fieldError("ms clock", "%d times, but no registered phase layout has %d phases", nclock, nclock)
+	}
+	if ncpu != nclock {
+		return nil, fieldError("ms cpu", "%d times, but %d clock times", ncpu, nclock)
+	}
+	if forced {
+		if !layout.Forced {
+			// Ignore forced GC. Go 1.5 runs these with
+			// the world stopped, so the phase breakdown is
+			// meaningless.
+			*cycle = Cycle{}
+			return phases, nil
+		}
+		cycle.Forced = true
 	}
 
 	// Create phases from raw parts.
-	phases := make([]Phase, 6)
 	now := begin
-	for i, kind := range []PhaseKind{PhaseSweepTerm, PhaseScan, PhaseInstallWB, PhaseMark, PhaseMarkTerm} {
-		stw := kind == PhaseSweepTerm || kind == PhaseMarkTerm
+	for i, spec := range layout.Phases {
+		if spec.MarkCPU {
+			// Concurrent mark CPU time is split into
+			// assist, background, and idle time.
+			cycle.AssistCPU += comps[i][0]
+			cycle.BackgroundCPU += comps[i][1]
+			cycle.IdleCPU += comps[i][2]
+		}
 		// TODO: Report CPU and clock time instead of GCProcs.
 		var procs float64
 		if clock[i] == 0 {
-			if stw {
+			if spec.STW {
 				procs = float64(gomaxprocs)
 			}
 		} else {
 			procs = float64(cpu[i]) / float64(clock[i])
 		}
-		phases[i] = Phase{now, clock[i], kind, n, gomaxprocs, procs, stw}
-		now += clock[i]
+		phases = append(phases, Phase{now, clock[i], spec.Kind, int(n), gomaxprocs, procs, spec.STW})
+		if now, ok = add(now, clock[i]); !ok {
+			return nil, fieldError("ms clock", "times overflow")
+		}
 	}
-	phases[len(phases)-1] = Phase{now, -1, PhaseSweep, n, gomaxprocs, 0, false}
+	phases = append(phases, Phase{now, -1, PhaseSweep, int(n), gomaxprocs, 0, false})
 
 	return phases, nil
 }
@@ -223,3 +855,16 @@ func shiftPhases(phases []Phase, delta int64) {
 		phases[i].Begin += delta
 	}
 }
+
+// truncatePhases truncates the beginning of phases so none begins
+// before t. Phases that end before t are left with zero duration.
+func truncatePhases(phases []Phase, t int64) {
+	for i := range phases {
+		ph := &phases[i]
+		if ph.Begin >= t {
+			break
+		}
+		ph.Duration = max(ph.End()-t, 0)
+		ph.Begin = t
+	}
+}
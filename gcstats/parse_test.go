@@ -0,0 +1,602 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testParse(t *testing.T, log string, want []Phase) {
+	s, err := NewFromLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, s.Phases()) {
+		t.Errorf("want phases\n%v\ngot\n%v", want, s.Phases())
+	}
+}
+
+func TestParse14(t *testing.T) {
+	const log = `gc1(1): 0+12+0+3 us, 0 -> 0 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields @12345
+gc2(1): 5+12+7+3 us, 0 -> 0 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields @22345
+`
+	testParse(t, log, []Phase{
+		{12345, 12000, PhaseSweepTerm, 1, 1, 1, true},
+		{24345, 3000, PhaseMarkTerm, 1, 1, 1, true},
+		{27345, 1, PhaseSweep, 1, 1, 0, false},
+		{27346, 17000, PhaseSweepTerm, 2, 1, 1, true},
+		{44346, 10000, PhaseMarkTerm, 2, 1, 1, true},
+	})
+
+	// Without begin times, absolute times are unknown.
+	var cycle Cycle
+	phases, progTimes, err := phasesFromLine(nil, &cycle, "gc3(1): 5+12+7+3 us, 0 -> 0 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields")
+	if err != nil || progTimes || len(phases) != 3 {
+		t.Fatalf("want 3 phases without program times, got %v, %v, %v", phases, progTimes, err)
+	}
+	for _, p := range phases {
+		if p.Begin != 0 {
+			t.Errorf("want Begin 0 without a begin time, got %+v", p)
+		}
+	}
+}
+
+func TestParse15(t *testing.T) {
+	const log = `gc 1 @0.019s 5%: 0.11+1.1+2.2+1.4+0.59 ms clock, 0.22+1.1+0+1.5/1.0/2.1+1.1 ms cpu, 4->4->1 MB, 4 MB goal, 4 P
+unrelated output
+gc 2 @0.037s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+`
+	testParse(t, log, []Phase{
+		{19000000, 110000, PhaseSweepTerm, 1, 4, 2, true},
+		{19110000, 1100000, PhaseScan, 1, 4, 1, false},
+		{20210000, 2200000, PhaseInstallWB, 1, 4, 0, false},
+		{22410000, 1400000, PhaseMark, 1, 4, 2500000.0 / 1400000, false},
+		{23810000, 590000, PhaseMarkTerm, 1, 4, 1100000.0 / 590000, true},
+		{24400000, 12600000, PhaseSweep, 1, 4, 0, false},
+		{37000000, 39000, PhaseSweepTerm, 2, 4, 110000.0 / 39000, true},
+		{37039000, 800000, PhaseScan, 2, 4, 1, false},
+		{37839000, 2400000, PhaseInstallWB, 2, 4, 0, false},
+		{40239000, 1700000, PhaseMark, 2, 4, 1305000.0 / 1700000, false},
+		{41939000, 620000, PhaseMarkTerm, 2, 4, 1800000.0 / 620000, true},
+	})
+}
+
+func TestParseExact(t *testing.T) {
+	// Converting these times to nanoseconds through a float64
+	// truncates them, for example, to 2009999ns and 1000999999ns.
+	const log = `gc 1 @1.001s 5%: 2.01+4.02+2.01+4.02+2.01 ms clock, 2.01+4.02+0+2.01/4.02/2.01+2.01 ms cpu, 4->4->1 MB, 4 MB goal, 1 P
+`
+	testParse(t, log, []Phase{
+		{1001000000, 2010000, PhaseSweepTerm, 1, 1, 1, true},
+		{1003010000, 4020000, PhaseScan, 1, 1, 1, false},
+		{1007030000, 2010000, PhaseInstallWB, 1, 1, 0, false},
+		{1009040000, 4020000, PhaseMark, 1, 1, 6030000.0 / 4020000, false},
+		{1013060000, 2010000, PhaseMarkTerm, 1, 1, 1, true},
+	})
+}
+
+func TestFixed(t *testing.T) {
+	for _, test := range []struct {
+		in    string
+		scale int
+		want  int64
+		rest  string
+	}{
+		{"12", 3, 12000, ""},
+		{"0.29 ms", 6, 290000, " ms"},
+		{"1.", 2, 100, "."},
+		{"0.0015s", 3, 2, "s"},
+		{"0.0014s", 3, 1, "s"},
+		{"9223372036854775807", 0, math.MaxInt64, ""},
+	} {
+		l := lineScanner{test.in}
+		got, ok := l.fixed(test.scale)
+		if !ok || got != test.want || l.s != test.rest {
+			t.Errorf("fixed(%q, %d): want %d, %q; got %d, %q, %v", test.in, test.scale, test.want, test.rest, got, l.s, ok)
+		}
+	}
+	for _, in := range []string{"", "x", ".5", "9223372036854775808", "9223372036854775807.1"} {
+		l := lineScanner{in}
+		if got, ok := l.fixed(1); ok {
+			t.Errorf("fixed(%q): want failure, got %d", in, got)
+		}
+	}
+}
+
+func TestParseCycles(t *testing.T) {
+	const log = `gc1(1): 0+12+0+3 us, 3 -> 1 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields @12345
+gc 2 @0.037s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->5->2 MB, 6 MB goal, 4 P
+`
+	s, err := NewFromLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Cycle{
+		{1, 12345, 3 << 20, 3 << 20, 1 << 20, 0, 0, 0, 0, false, CausePaced},
+		{2, 37000000, 4 << 20, 5 << 20, 2 << 20, 6 << 20, 5000, 1300000, 7300000, false, CausePaced},
+	}
+	if !reflect.DeepEqual(want, s.Cycles()) {
+		t.Errorf("want cycles\n%v\ngot\n%v", want, s.Cycles())
+	}
+}
+
+func TestParserBytes(t *testing.T) {
+	data, err := ioutil.ReadFile("../media/runtime-compile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := NewFromLog(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewParserBytes(data)
+	for p.Next() {
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want.Phases(), p.Stats().Phases()) {
+		t.Errorf("want phases %v, got %v", want.Phases(), p.Stats().Phases())
+	}
+}
+
+func TestParserFollow(t *testing.T) {
+	data, err := ioutil.ReadFile("../media/runtime-compile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := NewFromLog(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Feed the log to a following parser in small chunks,
+	// computing analyses along the way so they're updated
+	// incrementally.
+	var buf bytes.Buffer
+	p := NewParser(&buf)
+	p.Follow = true
+	s := p.Stats()
+	for len(data) > 0 {
+		n := 100
+		if n > len(data) {
+			n = len(data)
+		}
+		buf.Write(data[:n])
+		data = data[n:]
+		for p.Next() {
+		}
+		if err := p.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if len(s.Phases()) > 0 {
+			s.MutatorUtilization()
+			s.MutatorUtilizationDistribution(10e6)
+		}
+	}
+
+	if want.Count() != s.Count() {
+		t.Errorf("want %d cycles, got %d", want.Count(), s.Count())
+	}
+	if !reflect.DeepEqual(want.Phases(), s.Phases()) {
+		t.Errorf("want phases %v, got %v", want.Phases(), s.Phases())
+	}
+	if w, g := want.MutatorUtilization(), s.MutatorUtilization(); w != g {
+		t.Errorf("want mutator utilization %v, got %v", w, g)
+	}
+	wmud, gmud := want.MutatorUtilizationDistribution(10e6), s.MutatorUtilizationDistribution(10e6)
+	for util := 0.0; util <= 1; util += 0.01 {
+		if w, g := wmud.CDF(util), gmud.CDF(util); math.Abs(w-g) > 1e-9 {
+			t.Errorf("want MUD CDF(%v)=%v, got %v", util, w, g)
+		}
+	}
+}
+
+func TestNewFromBytes(t *testing.T) {
+	compile, err := ioutil.ReadFile("../media/runtime-compile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := map[string]string{
+		"runtime-compile": string(compile),
+		"bench":           benchLog(100),
+		// Some cycles lack begin times.
+		"go1.4": `gc1(1): 0+12+0+3 us, 0 -> 0 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields @12345
+gc2(1): 5+12+7+3 us, 0 -> 0 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields @22345
+gc3(1): 5+12+7+3 us, 0 -> 0 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields
+gc4(1): 5+12+7+3 us, 0 -> 0 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields @42345
+`,
+		// Cycles overlap slightly and must be shifted.
+		"overlap": `gc 1 @0.010s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 2 @0.014s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 3 @0.018s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+`,
+		"backward": `gc 1 @0.100s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 1 unrecognized
+gc 2 @0.050s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+`,
+		"skipped": `gc 1 @0.010s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+unrelated output
+gc 2 @0.020s 5%: 0.1+0.2+0.3+0.4+0.5 ms clock, 0.1+0.2+0+0.3+0.5 ms cpu, 4->4->2 MB, 4 MB goal, 4 P (forced)
+gc 3 @0.030s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 4 unrecognized
+`,
+		// Some cycles are recorded twice.
+		"duplicates": `gc 1 @0.010s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 1 @0.010s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 2 @0.020s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 3 @0.030s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 2 @0.020s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+`,
+		"malformed": `gc 1 @0.100s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 1 unrecognized
+gc 2 @0.150s 5%: 0.039+0.80+2.4+1.7 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+`,
+		// Scheduler samples and environment lines precede
+		// errors in later chunks, and follow the last cycle.
+		"sched": `# gcstats env: GOGC=100
+gc 1 @0.010s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+SCHED 15ms: gomaxprocs=4 idleprocs=1 threads=6 spinningthreads=0 idlethreads=2 runqueue=0 [0 0 0 0]
+gc 2 @0.020s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+# gcstats env: GOMAXPROCS=4
+SCHED 25ms: gomaxprocs=4 idleprocs=2 threads=6 spinningthreads=0 idlethreads=2 runqueue=0 [0 0 0 0]
+`,
+		"sched-malformed": `# gcstats env: GOGC=100
+gc 1 @0.010s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+SCHED 15ms: gomaxprocs=4 idleprocs=1 threads=6 spinningthreads=0 idlethreads=2 runqueue=0 [0 0 0 0]
+gc 2 @0.020s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+# gcstats env: GOMAXPROCS=4
+SCHED 25ms: gomaxprocs=4 idleprocs=2 threads=6 spinningthreads=0 idlethreads=2 runqueue=0 [0 0 0 0]
+gc 3 @0.030s 5%: 0.039+0.80+2.4+1.7 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+# gcstats env: GODEBUG=x=1
+SCHED 35ms: gomaxprocs=4 idleprocs=2 threads=6 spinningthreads=0 idlethreads=2 runqueue=0 [0 0 0 0]
+`,
+		"sched-backward": `# gcstats env: GOGC=100
+gc 1 @0.100s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+SCHED 105ms: gomaxprocs=4 idleprocs=1 threads=6 spinningthreads=0 idlethreads=2 runqueue=0 [0 0 0 0]
+# gcstats env: GOMAXPROCS=4
+gc 2 @0.050s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+# gcstats env: GODEBUG=x=1
+SCHED 110ms: gomaxprocs=4 idleprocs=2 threads=6 spinningthreads=0 idlethreads=2 runqueue=0 [0 0 0 0]
+`,
+	}
+
+	for name, log := range logs {
+		serial := NewParser(strings.NewReader(log))
+		for serial.Next() {
+		}
+		want, wantErr := serial.Stats(), serial.Err()
+		for _, n := range []int{1, 2, 3, 8, 1000} {
+			p := NewParserBytes(nil)
+			err := p.parseChunks(splitLines([]byte(log), n, 1))
+			got := p.Stats()
+			if fmt.Sprint(wantErr) != fmt.Sprint(err) {
+				t.Errorf("%s in %d chunks: want error %v, got %v", name, n, wantErr, err)
+				continue
+			}
+			// Partial results remain usable after an error.
+			if !reflect.DeepEqual(want.Sched(), got.Sched()) {
+				t.Errorf("%s in %d chunks: want scheduler samples %v, got %v", name, n, want.Sched(), got.Sched())
+			}
+			if want.Environment() != got.Environment() {
+				t.Errorf("%s in %d chunks: want environment %v, got %v", name, n, want.Environment(), got.Environment())
+			}
+			if err != nil {
+				if !reflect.DeepEqual(want.Diagnostics(), got.Diagnostics()) {
+					t.Errorf("%s in %d chunks: want diagnostics %v, got %v", name, n, want.Diagnostics(), got.Diagnostics())
+				}
+				continue
+			}
+			if !reflect.DeepEqual(want.Phases(), got.Phases()) {
+				t.Errorf("%s in %d chunks: want phases %v, got %v", name, n, want.Phases(), got.Phases())
+			}
+			if !reflect.DeepEqual(want.Cycles(), got.Cycles()) {
+				t.Errorf("%s in %d chunks: want cycles %v, got %v", name, n, want.Cycles(), got.Cycles())
+			}
+			if !reflect.DeepEqual(want.Diagnostics(), got.Diagnostics()) {
+				t.Errorf("%s in %d chunks: want diagnostics %v, got %v", name, n, want.Diagnostics(), got.Diagnostics())
+			}
+			if want.Count() != got.Count() || want.HaveProgTimes() != got.HaveProgTimes() {
+				t.Errorf("%s in %d chunks: want %d cycles, prog times %v; got %d, %v", name, n, want.Count(), want.HaveProgTimes(), got.Count(), got.HaveProgTimes())
+			}
+		}
+	}
+}
+
+// benchLog returns a Go 1.5 GC log with n cycles.
+func benchLog(n int) string {
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, "gc %d @%.3fs 5%%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P\n", i+1, float64(i)*0.05)
+	}
+	return buf.String()
+}
+
+func BenchmarkParse(b *testing.B) {
+	const lines = 10000
+	log := benchLog(lines)
+	b.SetBytes(int64(len(log)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewFromLog(strings.NewReader(log)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N*lines)/b.Elapsed().Seconds(), "lines/s")
+}
+
+func BenchmarkParseParallel(b *testing.B) {
+	const lines = 10000
+	log := []byte(benchLog(lines))
+	b.SetBytes(int64(len(log)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := NewParserBytes(nil).parseChunks(splitLines(log, runtime.GOMAXPROCS(0), 1)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(b.N*lines)/b.Elapsed().Seconds(), "lines/s")
+}
+
+func TestDiagnostics(t *testing.T) {
+	const log = `gc 1 @0.010s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+unrelated output
+gc 2 @0.020s 5%: 0.1+0.2+0.3+0.4+0.5 ms clock, 0.1+0.2+0+0.3+0.5 ms cpu, 4->4->2 MB, 4 MB goal, 4 P (forced)
+gc 3 @0.014s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 4 unrecognized
+gc 5 @0.050s 5%: 0.039+0.80+2.4 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+`
+	lines := strings.Split(log, "\n")
+	p := NewParser(strings.NewReader(log))
+	for p.Next() {
+	}
+	if p.Err() == nil {
+		t.Fatal("want parse error")
+	}
+	want := []Diagnostic{
+		{Line: 3, Text: lines[2], Message: "ignored forced GC, which runs with the world stopped"},
+		{Line: 4, Message: "cycle 3 begins 1.559ms before cycle 1 ends; shifted later"},
+		{Line: 5, Text: lines[4], Message: "unrecognized GC trace line"},
+		{Line: 6, Text: lines[5], Error: true, Message: `Go 1.5 gctrace: field "ms cpu": 5 times, but 3 clock times`},
+	}
+	if got := p.Stats().Diagnostics(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want diagnostics\n%v\ngot\n%v", want, got)
+	}
+
+	s, err := NewFromLog(strings.NewReader(strings.Join(lines[:5], "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := s.Diagnostics()
+	if len(diags) != 4 || diags[3].String() != "dropped final Sweep phase of cycle 3 because its duration is unknown" {
+		t.Errorf("want dropped sweep diagnostic, got %v", diags)
+	}
+}
+
+func TestParserProgress(t *testing.T) {
+	// Large enough for several progress reports.
+	log := []byte(benchLog(30000))
+	for _, parallel := range []bool{false, true} {
+		p := NewParserBytes(log)
+		var total int64
+		calls := 0
+		p.Progress = func(n int64) {
+			total += n
+			calls++
+		}
+		var err error
+		if parallel {
+			err = p.parseChunks(splitLines(log, 4, 1))
+		} else {
+			for p.Next() {
+			}
+			err = p.Err()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != int64(len(log)) || calls < 2 {
+			t.Errorf("parallel=%v: want %d bytes in several calls, got %d in %d calls", parallel, len(log), total, calls)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	const good = "gc 1 @0.010s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P\n"
+	tests := []struct {
+		line, field, msg string
+	}{
+		{"gc 2 @0.020s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal", "P", "missing"},
+		{"gc 2 @0.020s 5%: 0.039+x+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P", "ms clock", `malformed "0.039+x+2.4+1.7+0.62 ms clock"`},
+		{"gc 2 @0.020s 5%: 1+2+3+4+5+6+7 ms clock, 1+2+3+4+5+6+7 ms cpu, 4->4->2 MB, 4 MB goal, 4 P", "ms clock", "7 times, but no registered phase layout has 7 phases"},
+		{"gc 2 @0.020s 5%:0.039+0.80+2.4+1.7+0.62 ms clock", "", `missing ": " before the phase times`},
+		{"gc 2 @0.002s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P", "@", "GC trace goes backward 13ms between cycles 1 and 2"},
+	}
+	for _, test := range tests {
+		// The bad line follows enough good lines that it
+		// falls in a later chunk when parsed in parallel.
+		log := strings.Repeat(good, 10) + test.line + "\n"
+		for _, parallel := range []bool{false, true} {
+			var err error
+			if parallel {
+				err = NewParserBytes(nil).parseChunks(splitLines([]byte(log), 4, 1))
+			} else {
+				_, err = NewFromLog(strings.NewReader(log))
+			}
+			want := &ParseError{Line: 11, Text: test.line, Format: "Go 1.5 gctrace", Field: test.field, Message: test.msg}
+			if !reflect.DeepEqual(err, want) {
+				t.Errorf("parallel=%v: want error\n%v\ngot\n%v", parallel, want, err)
+			}
+		}
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	// Cycle 1 is recorded twice in a row, and cycle 2 again after
+	// cycle 3. The final record has the same number and begin
+	// time as cycle 3 but different phase durations, so it is a
+	// near-duplicate.
+	const log = `gc 1 @0.010s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 1 @0.010s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 2 @0.020s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 3 @0.030s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 2 @0.020s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 3 @0.030s 5%: 0.04+0.8+2.4+1.7+0.6 ms clock, 0.1+0.8+0+0/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+`
+	// Without begin times, cycles are identified by their number
+	// and phase durations, so only the second record of cycle 2
+	// is a duplicate.
+	const log14 = `gc1(1): 0+12+0+3 us, 0 -> 0 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields
+gc2(1): 5+12+7+3 us, 0 -> 0 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields
+gc2(1): 5+12+7+3 us, 0 -> 0 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields
+gc2(1): 5+13+7+3 us, 0 -> 0 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields
+`
+	tests := []struct {
+		log   string
+		count int
+		diag  string
+	}{
+		{log, 3, "dropped 3 duplicate GC cycle records, first at line 2"},
+		{log14, 3, "dropped 1 duplicate GC cycle record, first at line 3"},
+	}
+	for _, test := range tests {
+		for _, parallel := range []bool{false, true} {
+			p := NewParserBytes([]byte(test.log))
+			var err error
+			if parallel {
+				err = p.ParseAll()
+			} else {
+				for p.Next() {
+				}
+				err = p.Err()
+			}
+			if err != nil {
+				t.Errorf("parallel=%v: %v", parallel, err)
+				continue
+			}
+			s := p.Stats()
+			if s.Count() != test.count {
+				t.Errorf("parallel=%v: want %d cycles, got %d", parallel, test.count, s.Count())
+			}
+			diags := s.Diagnostics()
+			if len(diags) == 0 || diags[len(diags)-1].Message != test.diag {
+				t.Errorf("parallel=%v: want diagnostic %q, got %v", parallel, test.diag, diags)
+			}
+		}
+	}
+}
+
+func TestOverlapPolicy(t *testing.T) {
+	// Cycle 2 begins 0.559ms before cycle 1's sweep phase.
+	const log = `gc 1 @0.010s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 2 @0.015s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 3 @0.030s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+`
+	tests := []struct {
+		policy    OverlapPolicy
+		tolerance time.Duration
+		count     int
+		diag      string
+		err       string
+	}{
+		{OverlapShift, 0, 3, "cycle 2 begins 559µs before cycle 1 ends; shifted later", ""},
+		{OverlapTruncate, 0, 3, "cycle 2 begins 559µs before cycle 1 ends; truncated cycle 2", ""},
+		{OverlapDrop, 0, 2, "cycle 2 begins 559µs before cycle 1 ends; dropped", ""},
+		{OverlapError, 0, 0, "", "cycle 2 begins 559µs before cycle 1 ends"},
+		{OverlapShift, 100 * time.Microsecond, 0, "", "GC trace goes backward 0ms between cycles 1 and 2"},
+	}
+	for _, test := range tests {
+		for _, parallel := range []bool{false, true} {
+			p := NewParserBytes([]byte(log))
+			p.OverlapPolicy, p.OverlapTolerance = test.policy, test.tolerance
+			var err error
+			if parallel {
+				err = p.ParseAll()
+			} else {
+				for p.Next() {
+				}
+				err = p.Err()
+			}
+			if test.err != "" {
+				if pe, ok := err.(*ParseError); !ok || pe.Line != 2 || pe.Message != test.err {
+					t.Errorf("%v: want error %q, got %v", test.policy, test.err, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Errorf("%v: %v", test.policy, err)
+				continue
+			}
+			s := p.Stats()
+			if s.Count() != test.count {
+				t.Errorf("%v: want %d cycles, got %d", test.policy, test.count, s.Count())
+			}
+			if diags := s.Diagnostics(); len(diags) == 0 || diags[0].Message != test.diag {
+				t.Errorf("%v: want diagnostic %q, got %v", test.policy, test.diag, diags)
+			}
+			// Repairs must leave the phases contiguous.
+			phases := s.Phases()
+			for i := 1; i < len(phases); i++ {
+				if phases[i-1].End() != phases[i].Begin {
+					t.Errorf("%v: phase %d ends at %d, but phase %d begins at %d", test.policy, i-1, phases[i-1].End(), i, phases[i].Begin)
+				}
+			}
+		}
+	}
+}
+
+func FuzzNewFromLog(f *testing.F) {
+	f.Add("gc1(1): 0+12+0+3 us, 0 -> 0 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields @12345\n")
+	f.Add("gc 1 @0.019s 5%: 0.11+1.1+2.2+1.4+0.59 ms clock, 0.22+1.1+0+1.5/1.0/2.1+1.1 ms cpu, 4->4->1 MB, 4 MB goal, 4 P\ngc 2 @0.037s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P\n")
+	f.Add("gc 1 @0.010s 2%: 0.026+0.39+0.10 ms clock, 0.21+0.88/0.52/0+0.84 ms cpu, 4->4->0 MB, 5 MB goal, 8 P (forced)\n")
+	f.Add("SCHED 56ms: gomaxprocs=4 idleprocs=1 threads=6 spinningthreads=0 idlethreads=2 runqueue=0 [0 0 0 0]\n")
+	f.Add("scvg0: inuse: 3, idle: 0, sys: 3, released: 0, consumed: 3 (MB)\n")
+	f.Fuzz(func(t *testing.T, log string) {
+		s, err := NewFromLog(strings.NewReader(log))
+		if err != nil {
+			return
+		}
+		for _, p := range s.Phases() {
+			if p.Duration < 0 || p.Begin < 0 {
+				t.Fatalf("phase has negative time: %+v", p)
+			}
+		}
+		for _, c := range s.Cycles() {
+			if c.HeapTrigger < 0 || c.HeapMarked < 0 || c.HeapLive < 0 || c.HeapGoal < 0 {
+				t.Fatalf("cycle has negative heap size: %+v", c)
+			}
+		}
+	})
+}
+
+func TestParseOverflow(t *testing.T) {
+	for _, log := range []string{
+		"gc 1 @9223372036.854775807s 5%: 9223372036854.775807+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P\n",
+		"gc 1 @0.010s 5%: 9223372036854.775807+9223372036854.775807+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P\n",
+		"gc1(1): 9223372036854775+9223372036854775+0+3 us, 0 -> 0 MB, 21 (21-0) objects, 2 goroutines, 15/0/0 sweeps, 0(0) handoff, 0(0) steal, 0/0/0 yields @12345\n",
+	} {
+		if _, err := NewFromLog(strings.NewReader(log)); err == nil || !strings.Contains(err.Error(), "overflow") {
+			t.Errorf("want overflow error, got %v for %s", err, log)
+		}
+	}
+
+	// Heap sizes that overflow aren't recognized.
+	const log = "gc 1 @0.010s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 99999999999999->4->2 MB, 99999999999999 MB goal, 4 P\n"
+	s, err := NewFromLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := s.Cycles()[0]; c.HeapTrigger != 0 || c.HeapGoal != 0 {
+		t.Errorf("want no heap sizes, got %+v", c)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"math"
+	"slices"
+)
+
+// PauseWindow summarizes the stop-the-world pauses that began in a
+// window of program execution.
+type PauseWindow struct {
+	// Begin is the beginning of the window in nanoseconds.
+	Begin int64
+
+	// Count is the number of pauses that began in the window.
+	Count int
+
+	// Percentiles is the requested percentiles of the durations
+	// of these pauses in nanoseconds, or nil if Count is 0.
+	Percentiles []int64
+}
+
+// PausePercentiles returns percentiles of the durations of pauses
+// beginning in windows of windowNS nanoseconds. The windows begin
+// every stepNS nanoseconds from the first logged GC until the last
+// window that contains the end of the log. Consecutive STW phases
+// count as a single pause, as in Stops. Each of pctiles must be in
+// [0, 1]; a percentile p is the smallest pause longer than or equal
+// to a fraction p of the pauses in the window.
+//
+// This will panic if the trace does not have program execution times.
+func (s *GcStats) PausePercentiles(windowNS, stepNS int64, pctiles []float64) []PauseWindow {
+	s.requireProgTimes()
+	if windowNS <= 0 || stepNS <= 0 {
+		panic("PausePercentiles window and step must be positive")
+	}
+	if len(s.log) == 0 {
+		return nil
+	}
+
+	stops := s.Stops()
+	first, end := s.log[0].Begin, s.log[len(s.log)-1].End()
+	var windows []PauseWindow
+	var durs []int64
+	lo, hi := 0, 0
+	for begin := first; ; begin += stepNS {
+		// Slide the window [lo, hi) of stops.
+		for lo < len(stops) && stops[lo].Begin < begin {
+			lo++
+		}
+		for hi < len(stops) && stops[hi].Begin < begin+windowNS {
+			hi++
+		}
+		w := PauseWindow{Begin: begin, Count: hi - lo}
+		if w.Count > 0 {
+			durs = durs[:0]
+			for _, stop := range stops[lo:hi] {
+				durs = append(durs, stop.Duration)
+			}
+			slices.Sort(durs)
+			w.Percentiles = make([]int64, len(pctiles))
+			for i, p := range pctiles {
+				rank := int(math.Ceil(p*float64(len(durs)))) - 1
+				w.Percentiles[i] = durs[max(rank, 0)]
+			}
+		}
+		windows = append(windows, w)
+		if begin+windowNS >= end {
+			break
+		}
+	}
+	return windows
+}
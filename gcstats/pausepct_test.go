@@ -0,0 +1,38 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPausePercentiles(t *testing.T) {
+	var phases []Phase
+	// Each cycle has a pause of 10*n ns.
+	for n := 1; n <= 10; n++ {
+		begin := int64(n-1) * 1000
+		phases = append(phases,
+			Phase{begin, int64(10 * n), PhaseSweepTerm, n, 1, 1, true},
+			Phase{begin + int64(10*n), 1000 - int64(10*n), PhaseSweep, n, 1, 0, false})
+	}
+	s := NewFromPhases(phases, 10)
+
+	got := s.PausePercentiles(4000, 3000, []float64{0, 0.5, 1})
+	want := []PauseWindow{
+		{0, 4, []int64{10, 20, 40}},
+		{3000, 4, []int64{40, 50, 70}},
+		{6000, 4, []int64{70, 80, 100}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	// Empty windows have no percentiles.
+	got = s.PausePercentiles(500, 500, []float64{1})
+	if len(got) != 20 || got[1].Count != 0 || got[1].Percentiles != nil || got[2].Percentiles[0] != 20 {
+		t.Errorf("bad windows %v", got)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"math"
+	"slices"
+
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+)
+
+// Pauses is the distribution of the durations of a set of STW pauses.
+// Pauses can be added incrementally, for example as a trace is
+// followed, and its percentiles are those of stats.Sample, as
+// reported by the gcstats command, so every report of a trace agrees.
+//
+// A Pauses is not safe for concurrent use.
+type Pauses struct {
+	// durs are the pause durations in nanoseconds. durs[:nsorted]
+	// is sorted.
+	durs    []float64
+	nsorted int
+	total   int64
+}
+
+// Add adds the pauses in phases, joining consecutive STW phases into
+// one pause as Stops does, and returns the joined pauses. To add the
+// phases appended to a growing trace, pass the phases added since the
+// last call: these never split a pause, since the phases of each new
+// cycle begin with the non-STW sweep phase of the previous cycle.
+func (p *Pauses) Add(phases []Phase) []Phase {
+	stops := JoinStops(phases)
+	for _, stop := range stops {
+		p.AddDuration(stop.Duration)
+	}
+	return stops
+}
+
+// AddDuration adds a pause of ns nanoseconds.
+func (p *Pauses) AddDuration(ns int64) {
+	p.durs = append(p.durs, float64(ns))
+	p.total += ns
+}
+
+// sort sorts p.durs by sorting the pauses added since the last sort
+// and merging them into the rest.
+func (p *Pauses) sort() {
+	if p.nsorted == len(p.durs) {
+		return
+	}
+	added := p.durs[p.nsorted:]
+	slices.Sort(added)
+	if p.nsorted > 0 && added[0] < p.durs[p.nsorted-1] {
+		merged := make([]float64, 0, len(p.durs))
+		a, b := p.durs[:p.nsorted], added
+		for len(a) > 0 && len(b) > 0 {
+			if a[0] <= b[0] {
+				merged, a = append(merged, a[0]), a[1:]
+			} else {
+				merged, b = append(merged, b[0]), b[1:]
+			}
+		}
+		p.durs = append(append(merged, a...), b...)
+	}
+	p.nsorted = len(p.durs)
+}
+
+// Count returns the number of pauses.
+func (p *Pauses) Count() int {
+	return len(p.durs)
+}
+
+// Total returns the total duration of the pauses in nanoseconds.
+func (p *Pauses) Total() int64 {
+	return p.total
+}
+
+// Durations returns the pause durations in nanoseconds in increasing
+// order. The caller must not modify the returned slice.
+func (p *Pauses) Durations() []float64 {
+	p.sort()
+	return p.durs
+}
+
+// Max returns the longest pause in nanoseconds, or 0 if there are no
+// pauses.
+func (p *Pauses) Max() int64 {
+	return p.Percentile(1)
+}
+
+// Percentile returns the pctile'th percentile pause duration in
+// nanoseconds, or 0 if there are no pauses.
+func (p *Pauses) Percentile(pctile float64) int64 {
+	if len(p.durs) == 0 {
+		return 0
+	}
+	p.sort()
+	return int64(math.Round(stats.Sample{Xs: p.durs, Sorted: true}.Percentile(pctile)))
+}
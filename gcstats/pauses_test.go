@@ -0,0 +1,69 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"math"
+	"os"
+	"testing"
+
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+)
+
+func TestPauses(t *testing.T) {
+	log, err := os.ReadFile("../media/runtime-compile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := NewFromBytes(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Add the phases in uneven batches that each begin with a
+	// sweep phase, as when following the trace, with some
+	// queries in between.
+	var p Pauses
+	phases := data.Phases()
+	batches := 0
+	for i, n := 0, 1; i < len(phases); n = n%3 + 1 {
+		// Split before the n'th following sweep phase.
+		j, sweeps := i+1, 0
+		for ; j < len(phases); j++ {
+			if phases[j].Kind == PhaseSweep {
+				if sweeps++; sweeps == n {
+					break
+				}
+			}
+		}
+		p.Add(phases[i:j])
+		batches++
+		p.Percentile(0.5)
+		i = j
+	}
+
+	if batches < 3 {
+		t.Fatalf("want several batches, got %d", batches)
+	}
+
+	var all stats.Sample
+	var total int64
+	for _, stop := range data.Stops() {
+		all.Xs = append(all.Xs, float64(stop.Duration))
+		total += stop.Duration
+	}
+	if p.Count() != len(all.Xs) || p.Total() != total || p.Max() != data.MaxPause() {
+		t.Errorf("want %d pauses totaling %d with max %d, got %d, %d, %d", len(all.Xs), total, data.MaxPause(), p.Count(), p.Total(), p.Max())
+	}
+	for _, pctile := range []float64{0, 0.5, 0.95, 0.99} {
+		if got, want := p.Percentile(pctile), int64(math.Round(all.Percentile(pctile))); got != want {
+			t.Errorf("%v percentile: want %d, got %d", pctile, want, got)
+		}
+	}
+
+	var empty Pauses
+	if empty.Count() != 0 || empty.Max() != 0 || empty.Percentile(0.99) != 0 {
+		t.Errorf("want zeros for no pauses")
+	}
+}
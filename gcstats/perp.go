@@ -0,0 +1,78 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import "math"
+
+// GCGoalUtilization is the fraction of CPU the garbage collector's
+// background workers aim to use during concurrent mark.
+const GCGoalUtilization = 0.25
+
+// SplitBackground estimates the dedicated and fractional worker CPU
+// time of cycle c, whose mark phase is mark. GC traces report these
+// combined, but the runtime runs floor(GOMAXPROCS/4) dedicated workers
+// for the whole mark phase, so the rest is attributed to the
+// fractional worker.
+func (c Cycle) SplitBackground(mark Phase) (dedicated, fractional int64) {
+	dedicated = min(c.BackgroundCPU, int64(float64(mark.Gomaxprocs)*GCGoalUtilization)*mark.Duration)
+	return dedicated, c.BackgroundCPU - dedicated
+}
+
+// PerPUtilization returns a copy of s in which the GCProcs of each
+// concurrent mark phase is the number of Ps that were not fully
+// available to the mutator, rather than the average number of Ps
+// used by GC. Computing mutator utilization from the result measures
+// the fraction of Ps the mutator could count on, which better
+// reflects latency when GOMAXPROCS is small.
+//
+// A P running a dedicated worker is unavailable for the whole phase.
+// The fractional worker runs on one P for part of the phase, but a
+// goroutine on that P may wait for it, so that P is counted as
+// unavailable if the fractional worker did any work. Assists move
+// between Ps, so they count by their average number of Ps. Idle
+// workers only use Ps the mutator had no work for, so they don't
+// count at all.
+//
+// Mark phases of cycles that don't report mark CPU time by worker
+// type (before Go 1.5), and all other phases, are unchanged.
+func (s *GcStats) PerPUtilization() *GcStats {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+
+	cycles := make(map[int]Cycle, len(s.cycles))
+	for _, c := range s.cycles {
+		if c.AssistCPU+c.BackgroundCPU+c.IdleCPU != 0 {
+			cycles[c.N] = c
+		}
+	}
+	out := &GcStats{
+		log:       make([]Phase, len(s.log)),
+		cycles:    s.cycles[:len(s.cycles):len(s.cycles)],
+		n:         s.n,
+		progTimes: s.progTimes,
+		estimated: s.estimated,
+		complete:  true,
+		diags:     s.diags[:len(s.diags):len(s.diags)],
+		env:       s.env,
+		sched:     s.sched,
+		scvg:      s.scvg,
+	}
+	copy(out.log, s.log)
+	for i := range out.log {
+		p := &out.log[i]
+		c, ok := cycles[p.N]
+		if p.Kind != PhaseMark || !ok || p.Duration <= 0 {
+			continue
+		}
+		dedicated, fractional := c.SplitBackground(*p)
+		procs := math.Ceil(float64(dedicated) / float64(p.Duration))
+		if fractional > 0 {
+			procs++
+		}
+		procs += float64(c.AssistCPU) / float64(p.Duration)
+		p.GCProcs = math.Min(procs, float64(p.Gomaxprocs))
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestPerPUtilization(t *testing.T) {
+	const log = `gc 1 @0.010s 5%: 0.1+0.2+0.3+2+0.5 ms clock, 0.4+0.2+0+0.85/2.3/4+2 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 2 @0.020s 5%: 0.1+0.2+0.3+2+0.5 ms clock, 0.2+0.2+0+0/0.5/1+1 ms cpu, 4->4->2 MB, 4 MB goal, 2 P
+gc 3 @0.030s 5%: 0.1+0.2+0.3+2+0.5 ms clock, 0.1+0.2+0+1/1.5/0+0.5 ms cpu, 4->4->2 MB, 4 MB goal, 1 P
+`
+	s, err := NewFromLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cycle 1 has one dedicated worker, a fractional worker, and
+	// assists using 0.425 Ps on average. Cycle 2 has only a
+	// fractional worker. Cycle 3 is capped at GOMAXPROCS.
+	want := map[int]float64{1: 2.425, 2: 1, 3: 1}
+
+	pp := s.PerPUtilization()
+	phases, ppPhases := s.Phases(), pp.Phases()
+	if len(phases) != len(ppPhases) {
+		t.Fatalf("want %d phases, got %d", len(phases), len(ppPhases))
+	}
+	for i, p := range ppPhases {
+		if p.Kind != PhaseMark {
+			if p != phases[i] {
+				t.Errorf("phase %d changed from %+v to %+v", i, phases[i], p)
+			}
+			continue
+		}
+		if math.Abs(p.GCProcs-want[p.N]) > 1e-9 {
+			t.Errorf("GC %d: want %v GC procs in mark, got %v", p.N, want[p.N], p.GCProcs)
+		}
+	}
+	if len(pp.Cycles()) != len(s.Cycles()) {
+		t.Errorf("want %d cycles, got %d", len(s.Cycles()), len(pp.Cycles()))
+	}
+	// Idle marking counts against utilization in the averaged
+	// model, but not in the per-P model.
+	if s.MMU(1e6) >= pp.MMU(1e6) {
+		t.Errorf("want per-P MMU above %v, got %v", s.MMU(1e6), pp.MMU(1e6))
+	}
+}
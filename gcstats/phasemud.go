@@ -0,0 +1,84 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+// PhaseMUD splits the mutator utilization distribution for windows
+// of size WindowNS by whether windows overlap phases of certain
+// kinds.
+type PhaseMUD struct {
+	WindowNS int
+
+	// In is the MUD of the windows that overlap a phase of one of
+	// the kinds, and Out is the MUD of the other windows. Either
+	// is nil if there are no such windows.
+	In, Out *MUD
+
+	// InFraction is the fraction of windows that overlap a phase
+	// of one of the kinds.
+	InFraction float64
+
+	// InLoss is the fraction of the total utilization loss (1 -
+	// utilization, summed over all windows) that is in the
+	// windows that overlap a phase of one of the kinds.
+	InLoss float64
+}
+
+// PhaseMutatorUtilizationDistribution returns the mutator utilization
+// distribution for windows of size windowNS, split by whether the
+// windows overlap a phase of any of kinds. For example, with kinds
+// PhaseMark, this separates the utilization of windows during
+// concurrent mark from the rest, and InLoss says how much of the
+// utilization loss happens during concurrent mark.
+//
+// Unlike MutatorUtilizationDistribution, the result is not cached.
+//
+// This will panic if the trace does not have program execution times.
+func (s *GcStats) PhaseMutatorUtilizationDistribution(windowNS int, kinds ...PhaseKind) *PhaseMUD {
+	s.requireProgTimes()
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+
+	log, us := s.log, s.utilSumsLocked()
+	want := make(map[PhaseKind]bool)
+	for _, kind := range kinds {
+		want[kind] = true
+	}
+	// count[i] is the number of phases of kinds with non-zero
+	// duration in log[:i].
+	count := make([]int, len(log)+1)
+	for i, p := range log {
+		count[i+1] = count[i]
+		if want[p.Kind] && p.Duration > 0 {
+			count[i+1]++
+		}
+	}
+	// A window that slides while its ends stay in the same
+	// phases overlaps every phase between them.
+	overlaps := func(beginPhase, endPhase int) bool {
+		return count[endPhase+1] > count[beginPhase]
+	}
+	in := &mudBuilder{windowNS: windowNS, acc: new(uniformSum), capped: -1, filter: overlaps}
+	out := &mudBuilder{windowNS: windowNS, acc: new(uniformSum), capped: -1, filter: func(beginPhase, endPhase int) bool {
+		return !overlaps(beginPhase, endPhase)
+	}}
+
+	d := &PhaseMUD{WindowNS: windowNS, In: in.compute(log, us, true), Out: out.compute(log, us, true)}
+	if len(log) == 0 {
+		d.In, d.Out = nil, nil
+		return d
+	}
+	if d.In != nil {
+		d.WindowNS = d.In.WindowNS
+	} else if d.Out != nil {
+		d.WindowNS = d.Out.WindowNS
+	}
+	if total := in.weight + out.weight; total > 0 {
+		d.InFraction = in.weight / total
+	}
+	if loss := in.loss + out.loss; loss > 0 {
+		d.InLoss = in.loss / loss
+	}
+	return d
+}
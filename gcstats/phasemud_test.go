@@ -0,0 +1,64 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPhaseMutatorUtilizationDistribution(t *testing.T) {
+	s := NewFromPhases([]Phase{
+		{Begin: 0, Duration: 10, Kind: PhaseSweep, Gomaxprocs: 1},
+		{Begin: 10, Duration: 2, Kind: PhaseSweepTerm, Gomaxprocs: 1, GCProcs: 1, STW: true},
+		{Begin: 12, Duration: 8, Kind: PhaseMark, Gomaxprocs: 1, GCProcs: 0.5},
+		{Begin: 20, Duration: 1, Kind: PhaseMarkTerm, Gomaxprocs: 1, GCProcs: 1, STW: true},
+		{Begin: 21, Duration: 19, Kind: PhaseSweep, Gomaxprocs: 1},
+	}, 1)
+
+	// Windows of 2ns begin in [0, 38) and overlap mark if they
+	// begin in (10, 20).
+	d := s.PhaseMutatorUtilizationDistribution(2, PhaseMark)
+	if d.In == nil || d.Out == nil {
+		t.Fatalf("want both MUDs, got %+v", d)
+	}
+	if want := 10.0 / 38; math.Abs(d.InFraction-want) > 1e-12 {
+		t.Errorf("want InFraction %v, got %v", want, d.InFraction)
+	}
+	// The split MUDs are a mixture of the whole MUD.
+	mud := s.MutatorUtilizationDistribution(2)
+	for _, util := range []float64{0, 0.25, 0.5, 0.75, 0.99, 1} {
+		got := d.InFraction*d.In.CDF(util) + (1-d.InFraction)*d.Out.CDF(util)
+		if want := mud.CDF(util); math.Abs(got-want) > 1e-9 {
+			t.Errorf("mixture CDF(%v): want %v, got %v", util, want, got)
+		}
+	}
+	// Every window during mark has utilization at most 1/2.
+	if got := d.In.CDF(0.5); math.Abs(got-1) > 1e-9 {
+		t.Errorf("want In.CDF(0.5) = 1, got %v", got)
+	}
+
+	// Outside of GC, every window has full utilization, so all of
+	// the loss is inside GC.
+	d = s.PhaseMutatorUtilizationDistribution(2, PhaseSweepTerm, PhaseMark, PhaseMarkTerm)
+	if d.Out == nil || d.Out.InvCDF(0) != 1 {
+		t.Errorf("want Out to have full utilization, got %+v", d.Out)
+	}
+	if math.Abs(d.InLoss-1) > 1e-12 {
+		t.Errorf("want InLoss 1, got %v", d.InLoss)
+	}
+
+	// Every window overlaps some phase.
+	d = s.PhaseMutatorUtilizationDistribution(2, PhaseSweep, PhaseSweepTerm, PhaseMark, PhaseMarkTerm)
+	if d.Out != nil || d.InFraction != 1 {
+		t.Errorf("want all windows in, got InFraction %v and Out %+v", d.InFraction, d.Out)
+	}
+
+	// A window spanning the whole log.
+	d = s.PhaseMutatorUtilizationDistribution(100, PhaseMark)
+	if d.In == nil || d.Out != nil || d.WindowNS != 40 {
+		t.Errorf("want a single 40ns window in, got %+v", d)
+	}
+}
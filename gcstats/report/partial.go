@@ -0,0 +1,278 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package report
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// Partial is the mergeable analysis of a shard of a trace, written by
+// "gcstats partial" and combined by "gcstats merge". Merging the
+// partials of consecutive shards of a trace approximates the analysis
+// of the whole trace: pause percentiles are accurate to the sketch's
+// relative accuracy, and mutator utilization ignores windows that
+// span shards.
+type Partial struct {
+	SchemaVersion int `json:"schema_version"`
+	// Shards is the number of shards merged into this partial.
+	Shards int `json:"shards"`
+	Cycles int `json:"cycles"`
+	// ProgTimes is set if every shard has program execution
+	// times. Otherwise, the utilization fields are omitted.
+	ProgTimes bool `json:"progTimes"`
+
+	Pauses       *Sketch `json:"pauses"`
+	PauseTotalNS int64   `json:"pauseTotalNS"`
+	MaxPauseNS   int64   `json:"maxPauseNS"`
+
+	// DurationNS is the total execution time of the shards and
+	// MutatorNS is the mutator's share of it.
+	DurationNS int64   `json:"durationNS,omitempty"`
+	MutatorNS  float64 `json:"mutatorNS,omitempty"`
+
+	// MUD maps window sizes, such as "10ms", to the mutator
+	// utilization distribution at that window size.
+	MUD map[string]*MUDFragment `json:"mud,omitempty"`
+}
+
+// NewPartial computes the partial analysis of s, with mutator
+// utilization distributions at each of windows if s has program
+// execution times.
+func NewPartial(s *gcstats.GcStats, windows []time.Duration) *Partial {
+	p := &Partial{
+		SchemaVersion: SchemaVersion,
+		Shards:        1,
+		Cycles:        s.Count(),
+		ProgTimes:     s.HaveProgTimes(),
+		Pauses:        NewSketch(DefaultSketchAccuracy),
+		MaxPauseNS:    s.MaxPause(),
+	}
+	for stop := range s.StopsSeq() {
+		p.Pauses.Add(float64(stop.Duration))
+		p.PauseTotalNS += stop.Duration
+	}
+	phases := s.Phases()
+	if !p.ProgTimes || len(phases) == 0 {
+		p.ProgTimes = false
+		return p
+	}
+	p.DurationNS = phases[len(phases)-1].End() - phases[0].Begin
+	p.MutatorNS = s.MutatorUtilization() * float64(p.DurationNS)
+	p.MUD = make(map[string]*MUDFragment)
+	for _, w := range windows {
+		if int64(w) > p.DurationNS {
+			continue
+		}
+		mud := s.MutatorUtilizationDistribution(int(w))
+		f := &MUDFragment{
+			WindowNS: int64(w),
+			MMU:      s.MMU(int(w)),
+			WeightNS: float64(p.DurationNS - int64(w)),
+			Bins:     make([]float64, MUDBins),
+		}
+		prev := 0.0
+		for i := range f.Bins {
+			cdf := mud.CDF(float64(i+1) / MUDBins)
+			f.Bins[i] = (cdf - prev) * f.WeightNS
+			prev = cdf
+		}
+		p.MUD[w.String()] = f
+	}
+	return p
+}
+
+// MergePartials merges partial analyses of shards into one, which can
+// itself be merged further. Mutator utilization distributions are
+// kept only for the window sizes present in every partial.
+func MergePartials(parts ...*Partial) (*Partial, error) {
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("no partials to merge")
+	}
+	out := &Partial{SchemaVersion: SchemaVersion, ProgTimes: true, Pauses: NewSketch(parts[0].Pauses.RelativeAccuracy), MUD: make(map[string]*MUDFragment)}
+	for key, f := range parts[0].MUD {
+		f2 := *f
+		f2.Bins, f2.WeightNS, f2.MMU = make([]float64, len(f.Bins)), 0, 1
+		out.MUD[key] = &f2
+	}
+	for i, p := range parts {
+		if p.SchemaVersion != SchemaVersion {
+			return nil, fmt.Errorf("partial %d has schema version %d, want %d", i+1, p.SchemaVersion, SchemaVersion)
+		}
+		if err := out.Pauses.Merge(p.Pauses); err != nil {
+			return nil, fmt.Errorf("partial %d: %s", i+1, err)
+		}
+		out.Shards += p.Shards
+		out.Cycles += p.Cycles
+		out.PauseTotalNS += p.PauseTotalNS
+		out.MaxPauseNS = max(out.MaxPauseNS, p.MaxPauseNS)
+		out.ProgTimes = out.ProgTimes && p.ProgTimes
+		out.DurationNS += p.DurationNS
+		out.MutatorNS += p.MutatorNS
+		for key, f := range out.MUD {
+			pf := p.MUD[key]
+			if pf == nil || len(pf.Bins) != len(f.Bins) {
+				delete(out.MUD, key)
+				continue
+			}
+			f.MMU = min(f.MMU, pf.MMU)
+			f.WeightNS += pf.WeightNS
+			for j, w := range pf.Bins {
+				f.Bins[j] += w
+			}
+		}
+	}
+	if !out.ProgTimes {
+		out.DurationNS, out.MutatorNS, out.MUD = 0, 0, nil
+	}
+	return out, nil
+}
+
+// MutatorUtilization returns the mean mutator utilization of p, or
+// NaN if p lacks program execution times.
+func (p *Partial) MutatorUtilization() float64 {
+	if p.DurationNS == 0 {
+		return math.NaN()
+	}
+	return p.MutatorNS / float64(p.DurationNS)
+}
+
+// MUDBins is the number of equal-width utilization bins in a
+// MUDFragment.
+const MUDBins = 1000
+
+// MUDFragment is a mutator utilization distribution for one window
+// size that can be merged with those of other shards.
+type MUDFragment struct {
+	WindowNS int64 `json:"windowNS"`
+	// MMU is the minimum mutator utilization at WindowNS.
+	MMU float64 `json:"mmu"`
+	// WeightNS is the total execution time of window positions.
+	// Bins[i] is the part of WeightNS during which windows have
+	// utilization in (i/len(Bins), (i+1)/len(Bins)], where the
+	// first bin also includes 0.
+	WeightNS float64   `json:"weightNS"`
+	Bins     []float64 `json:"bins"`
+}
+
+// InvCDF returns the pctile'th percentile mutator utilization of f,
+// interpolating within bins, or NaN if f is empty.
+func (f *MUDFragment) InvCDF(pctile float64) float64 {
+	if f.WeightNS <= 0 {
+		return math.NaN()
+	}
+	target := pctile * f.WeightNS
+	var sum float64
+	for i, w := range f.Bins {
+		if w > 0 && sum+w >= target {
+			lo := float64(i) / float64(len(f.Bins))
+			return lo + (target-sum)/w/float64(len(f.Bins))
+		}
+		sum += w
+	}
+	return 1
+}
+
+// DefaultSketchAccuracy is the relative accuracy of the pause sketches
+// of NewPartial.
+const DefaultSketchAccuracy = 0.01
+
+// Sketch is a mergeable summary of a distribution of positive values
+// that answers quantile queries to within a relative accuracy, as in
+// DDSketch. Values are counted in logarithmically sized buckets:
+// bucket i holds values in (γ^(i-1), γ^i], where
+// γ = (1+RelativeAccuracy)/(1-RelativeAccuracy).
+type Sketch struct {
+	RelativeAccuracy float64 `json:"relativeAccuracy"`
+	// Zeros counts values <= 0.
+	Zeros uint64 `json:"zeros"`
+	// Counts[j] is the count of bucket Offset+j.
+	Offset int      `json:"offset"`
+	Counts []uint64 `json:"counts"`
+}
+
+// NewSketch returns an empty Sketch with the given relative accuracy.
+func NewSketch(relativeAccuracy float64) *Sketch {
+	return &Sketch{RelativeAccuracy: relativeAccuracy}
+}
+
+func (k *Sketch) gamma() float64 {
+	return (1 + k.RelativeAccuracy) / (1 - k.RelativeAccuracy)
+}
+
+// Add adds x to k.
+func (k *Sketch) Add(x float64) {
+	if x <= 0 {
+		k.Zeros++
+		return
+	}
+	k.addBucket(int(math.Ceil(math.Log(x)/math.Log(k.gamma()))), 1)
+}
+
+func (k *Sketch) addBucket(i int, n uint64) {
+	if len(k.Counts) == 0 {
+		k.Offset = i
+	}
+	if i < k.Offset {
+		grow := make([]uint64, k.Offset-i, k.Offset-i+len(k.Counts))
+		k.Counts = append(grow, k.Counts...)
+		k.Offset = i
+	}
+	for i-k.Offset >= len(k.Counts) {
+		k.Counts = append(k.Counts, 0)
+	}
+	k.Counts[i-k.Offset] += n
+}
+
+// Merge adds the values counted by o to k. Both must have the same
+// relative accuracy.
+func (k *Sketch) Merge(o *Sketch) error {
+	if o.RelativeAccuracy != k.RelativeAccuracy {
+		return fmt.Errorf("cannot merge sketches with relative accuracies %g and %g", k.RelativeAccuracy, o.RelativeAccuracy)
+	}
+	k.Zeros += o.Zeros
+	for j, n := range o.Counts {
+		if n != 0 {
+			k.addBucket(o.Offset+j, n)
+		}
+	}
+	return nil
+}
+
+// Count returns the number of values in k.
+func (k *Sketch) Count() uint64 {
+	n := k.Zeros
+	for _, c := range k.Counts {
+		n += c
+	}
+	return n
+}
+
+// Quantile returns an estimate of the q'th quantile of the values in
+// k, where 0 <= q <= 1, or NaN if k is empty.
+func (k *Sketch) Quantile(q float64) float64 {
+	n := k.Count()
+	if n == 0 {
+		return math.NaN()
+	}
+	rank := uint64(q * float64(n-1))
+	if rank < k.Zeros {
+		return 0
+	}
+	seen, j := k.Zeros, 0
+	for ; j < len(k.Counts)-1; j++ {
+		seen += k.Counts[j]
+		if seen > rank {
+			break
+		}
+	}
+	// Return the value that minimizes the relative error over
+	// the bucket.
+	g := k.gamma()
+	return 2 * math.Pow(g, float64(k.Offset+j)) / (g + 1)
+}
@@ -0,0 +1,119 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package report
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+func TestSketch(t *testing.T) {
+	all, lo, hi := NewSketch(0.01), NewSketch(0.01), NewSketch(0.01)
+	for x := 1; x <= 1000; x++ {
+		all.Add(float64(x))
+		if x%2 == 0 {
+			lo.Add(float64(x))
+		} else {
+			hi.Add(float64(x))
+		}
+	}
+	all.Add(0)
+	hi.Add(0)
+	for _, q := range []float64{0.1, 0.5, 0.99, 1} {
+		want := math.Floor(q * 1000)
+		if got := all.Quantile(q); math.Abs(got-want) > 0.01*want+1 {
+			t.Errorf("quantile %v: want %v within 1%%, got %v", q, want, got)
+		}
+	}
+	if got := all.Quantile(0); got != 0 {
+		t.Errorf("quantile 0: want 0, got %v", got)
+	}
+	if err := lo.Merge(hi); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(lo, all) {
+		t.Errorf("merged halves differ from the whole:\n%+v\n%+v", lo, all)
+	}
+	if err := lo.Merge(NewSketch(0.02)); err == nil {
+		t.Errorf("want error merging sketches of different accuracies")
+	}
+	if q := NewSketch(0.01).Quantile(0.5); !math.IsNaN(q) {
+		t.Errorf("want NaN quantile of empty sketch, got %v", q)
+	}
+}
+
+// shardLog returns a GC trace of n cycles, 10ms apart, with varying
+// pauses.
+func shardLog(n int) []string {
+	var lines []string
+	for i := 1; i <= n; i++ {
+		term := 0.1 + float64(i%7)*0.3
+		lines = append(lines, fmt.Sprintf("gc %d @%.3fs 5%%: 0.039+0.80+2.4+1.7+%.2f ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P", i, float64(i)*0.01, term))
+	}
+	return lines
+}
+
+func parseShard(t *testing.T, lines []string) *gcstats.GcStats {
+	s, err := gcstats.NewFromLog(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestMergePartials(t *testing.T) {
+	lines := shardLog(200)
+	windows := []time.Duration{time.Millisecond, 10 * time.Millisecond}
+	whole := parseShard(t, lines)
+	wp := NewPartial(whole, windows)
+	p1 := NewPartial(parseShard(t, lines[:80]), windows)
+	p2 := NewPartial(parseShard(t, lines[80:]), windows[1:])
+	merged, err := MergePartials(p1, p2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Shards != 2 || merged.Cycles != wp.Cycles || merged.PauseTotalNS != wp.PauseTotalNS || merged.MaxPauseNS != wp.MaxPauseNS {
+		t.Errorf("merged %+v, whole %+v", merged, wp)
+	}
+	if !reflect.DeepEqual(merged.Pauses, wp.Pauses) {
+		t.Errorf("merged pause sketch differs from the whole's")
+	}
+
+	var pauses []float64
+	for stop := range whole.StopsSeq() {
+		pauses = append(pauses, float64(stop.Duration))
+	}
+	slices.Sort(pauses)
+	for _, q := range []float64{0.5, 0.99} {
+		want := pauses[int(q*float64(len(pauses)-1))]
+		if got := merged.Pauses.Quantile(q); math.Abs(got-want) > 0.01*want {
+			t.Errorf("pause quantile %v: want %v within 1%%, got %v", q, want, got)
+		}
+	}
+
+	if got, want := merged.MutatorUtilization(), whole.MutatorUtilization(); math.Abs(got-want) > 0.01 {
+		t.Errorf("mutator utilization: want %v, got %v", want, got)
+	}
+	if _, ok := merged.MUD["1ms"]; ok || len(merged.MUD) != 1 {
+		t.Errorf("want only the 10ms MUD, got %v", merged.MUD)
+	}
+	f, wf := merged.MUD["10ms"], wp.MUD["10ms"]
+	if f.MMU < wf.MMU {
+		t.Errorf("merged MMU %v is below the whole's %v", f.MMU, wf.MMU)
+	}
+	mud := whole.MutatorUtilizationDistribution(int(10 * time.Millisecond))
+	for _, p := range []float64{0.05, 0.5} {
+		if got, want := f.InvCDF(p), mud.InvCDF(p); math.Abs(got-want) > 0.02 {
+			t.Errorf("%v percentile utilization: want %v, got %v", p, want, got)
+		}
+	}
+}
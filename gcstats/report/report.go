@@ -0,0 +1,259 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package report defines the JSON documents produced by gcstats, its
+// HTTP handler, and its bus publisher.
+//
+// Every document has a schema_version field. Adding fields to a
+// document does not change its version, so consumers should ignore
+// fields they don't recognize. Removing or changing the meaning of a
+// field increments SchemaVersion.
+//
+// Documents that describe a trace have a labels field with the
+// name=value labels attached to the trace with -label, if any, such
+// as the Go version or GOGC setting of the run.
+//
+// Documents that describe a trace also have an env field with the
+// runtime settings recorded for the trace, if any, keyed by
+// environment variable name, such as GOGC and GOVERSION. See
+// gcstats.Environment.
+//
+// Times are in nanoseconds unless a field name says otherwise. Heap
+// sizes are in bytes unless a field name ends in MB.
+package report
+
+// SchemaVersion is the version of the documents defined by this
+// package.
+const SchemaVersion = 1
+
+// Summary is the summary statistics of a trace, served as
+// summary.json by gcstatshttp.
+type Summary struct {
+	SchemaVersion      int                    `json:"schema_version"`
+	Cycles             int                    `json:"cycles"`
+	ProgTimes          bool                   `json:"progTimes"`
+	EstimatedTimes     bool                   `json:"estimatedTimes,omitempty"`
+	MaxPauseNS         int64                  `json:"maxPauseNS"`
+	MutatorUtilization float64                `json:"mutatorUtilization,omitempty"`
+	LongestBlackoutNS  int64                  `json:"longestBlackoutNS,omitempty"`
+	Pacing             string                 `json:"pacing"` // heap, forced, or unknown; see gcstats.Pacing
+	Stops              map[string]StopSummary `json:"stops"`
+	Env                map[string]string      `json:"env,omitempty"`
+	Labels             map[string]string      `json:"labels,omitempty"`
+}
+
+// StopSummary summarizes the durations of a kind of STW phase.
+type StopSummary struct {
+	Count  int   `json:"count"`
+	MaxNS  int64 `json:"maxNS"`
+	MeanNS int64 `json:"meanNS"`
+	P50NS  int64 `json:"p50NS"`
+	P95NS  int64 `json:"p95NS"`
+	P99NS  int64 `json:"p99NS"`
+}
+
+// Phases is the phases of a trace, served as phases.json by
+// gcstatshttp.
+type Phases struct {
+	SchemaVersion int               `json:"schema_version"`
+	Phases        []Phase           `json:"phases"`
+	Env           map[string]string `json:"env,omitempty"`
+}
+
+// Phase is the JSON form of a gcstats.Phase.
+type Phase struct {
+	BeginNS    int64   `json:"beginNS"`
+	DurationNS int64   `json:"durationNS"`
+	Kind       string  `json:"kind"`
+	N          int     `json:"n"`
+	Gomaxprocs int     `json:"gomaxprocs"`
+	GCProcs    float64 `json:"gcProcs"`
+	STW        bool    `json:"stw"`
+}
+
+// Cycles is the GC cycles of a trace, served as cycles.json by
+// gcstatshttp.
+type Cycles struct {
+	SchemaVersion int               `json:"schema_version"`
+	Cycles        []Cycle           `json:"cycles"`
+	Env           map[string]string `json:"env,omitempty"`
+}
+
+// Cycle is the JSON form of a gcstats.Cycle.
+type Cycle struct {
+	N             int   `json:"n"`
+	BeginNS       int64 `json:"beginNS"`
+	HeapTriggerMB int64 `json:"heapTriggerMB"`
+	HeapMarkedMB  int64 `json:"heapMarkedMB"`
+	HeapLiveMB    int64 `json:"heapLiveMB"`
+	HeapGoalMB    int64 `json:"heapGoalMB,omitempty"`
+	// Cause is "paced", "forced", or "limit".
+	Cause string `json:"cause"`
+}
+
+// Curve is a sampled function.
+type Curve struct {
+	X []float64 `json:"x"`
+	Y []float64 `json:"y"`
+}
+
+// MMU is the minimum mutator utilization as a function of window
+// size in seconds, served as mmu.json by gcstatshttp.
+type MMU struct {
+	SchemaVersion int   `json:"schema_version"`
+	WindowSec     Curve `json:"windowSec"`
+}
+
+// MUD is a mutator utilization distribution, served as mud.json by
+// gcstatshttp. Percentiles maps percentiles such as "0.1" to the
+// mutator utilization at that percentile.
+type MUD struct {
+	SchemaVersion int                `json:"schema_version"`
+	WindowNS      int64              `json:"windowNS"`
+	CDF           Curve              `json:"cdf"`
+	Percentiles   map[string]float64 `json:"percentiles"`
+}
+
+// CycleRecord is one line of the JSON output of "gcstats cycles".
+type CycleRecord struct {
+	SchemaVersion int `json:"schema_version"`
+
+	GC int `json:"gc"`
+	// BeginNS is nil if the trace lacks program execution times.
+	BeginNS *int64 `json:"beginNS"`
+
+	SweepTermNS int64 `json:"sweepTermNS"`
+	ScanNS      int64 `json:"scanNS"`
+	InstallWBNS int64 `json:"installWBNS"`
+	MarkNS      int64 `json:"markNS"`
+	MarkTermNS  int64 `json:"markTermNS"`
+
+	PauseNS    int64 `json:"pauseNS"`
+	MaxPauseNS int64 `json:"maxPauseNS"`
+	Gomaxprocs int   `json:"gomaxprocs"`
+
+	HeapTrigger int64 `json:"heapTrigger"`
+	HeapMarked  int64 `json:"heapMarked"`
+	HeapLive    int64 `json:"heapLive"`
+	HeapGoal    int64 `json:"heapGoal"`
+
+	AssistCPUNS     int64 `json:"assistCPUNS"`
+	BackgroundCPUNS int64 `json:"backgroundCPUNS"`
+	IdleCPUNS       int64 `json:"idleCPUNS"`
+
+	Forced bool `json:"forced"`
+	// Cause is "paced", "forced", or "limit".
+	Cause string `json:"cause"`
+
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// BusRecord is the summary of a GC cycle published by gcstatsbus.
+type BusRecord struct {
+	SchemaVersion int `json:"schema_version"`
+
+	// Source identifies the trace. It is omitted if empty.
+	Source string `json:"source,omitempty"`
+
+	// GC is the GC cycle number.
+	GC int `json:"gc"`
+
+	// BeginNS is the time the cycle began, relative to program
+	// start. It is omitted if the trace lacks program execution
+	// times.
+	BeginNS int64 `json:"beginNS,omitempty"`
+
+	// PauseNS is the total stop-the-world time of the cycle and
+	// MaxPauseNS is its longest contiguous pause.
+	PauseNS    int64 `json:"pauseNS"`
+	MaxPauseNS int64 `json:"maxPauseNS"`
+
+	// PhasesNS is the duration of each phase of the cycle before
+	// the concurrent sweep, keyed by phase name (such as
+	// "SweepTerm"). Durations that are unknown are omitted.
+	PhasesNS map[string]int64 `json:"phasesNS"`
+
+	Gomaxprocs int `json:"gomaxprocs"`
+
+	// Heap sizes in bytes. See gcstats.Cycle.
+	HeapTrigger int64 `json:"heapTrigger"`
+	HeapMarked  int64 `json:"heapMarked"`
+	HeapLive    int64 `json:"heapLive"`
+	HeapGoal    int64 `json:"heapGoal,omitempty"`
+
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// CIBaseline is a baseline written by "gcstats ci -update". Metrics
+// maps metric names such as "pause_p99_ns" to their values.
+type CIBaseline struct {
+	SchemaVersion int                `json:"schema_version"`
+	Metrics       map[string]float64 `json:"metrics"`
+}
+
+// CIVerdict is the verdict written by "gcstats ci".
+type CIVerdict struct {
+	SchemaVersion int               `json:"schema_version"`
+	Pass          bool              `json:"pass"`
+	Metrics       []CIMetricCheck   `json:"metrics"`
+	Labels        map[string]string `json:"labels,omitempty"`
+}
+
+// CIMetricCheck is the comparison of one metric against the
+// baseline.
+type CIMetricCheck struct {
+	Name      string  `json:"name"`
+	Baseline  float64 `json:"baseline"`
+	Current   float64 `json:"current"`
+	Tolerance float64 `json:"tolerance"`
+	// Limit is the worst value of Current that is not a
+	// regression.
+	Limit     float64 `json:"limit"`
+	Regressed bool    `json:"regressed"`
+	// Missing indicates that the metric is in the baseline, but
+	// could not be computed for the current trace. This is
+	// treated as a regression.
+	Missing bool `json:"missing,omitempty"`
+}
+
+// Aggregate summarizes the metrics of "gcstats ci" across repeated
+// runs, as written by "gcstats aggregate -format json".
+type Aggregate struct {
+	SchemaVersion int                        `json:"schema_version"`
+	Runs          int                        `json:"runs"`
+	Metrics       map[string]AggregateMetric `json:"metrics"`
+	Labels        map[string]string          `json:"labels,omitempty"`
+}
+
+// AggregateMetric summarizes one metric across runs. N is the number
+// of runs for which the metric is available.
+type AggregateMetric struct {
+	N      int     `json:"n"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+// Alert is the body posted to the -alert-webhook of "gcstats -follow"
+// when a threshold is crossed.
+type Alert struct {
+	SchemaVersion int `json:"schema_version"`
+	// Condition is "pause" for an STW pause longer than the
+	// threshold, or "mu" for mutator utilization over the alert
+	// window falling below the threshold.
+	Condition string `json:"condition"`
+	Message   string `json:"message"`
+	// GC is the cycle that crossed the threshold.
+	GC int `json:"gc"`
+	// Value and Threshold are in nanoseconds for "pause" and
+	// fractions for "mu".
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	// TimeNS is the program execution time of the crossing, or
+	// 0 if the trace lacks program execution times.
+	TimeNS int64 `json:"timeNS"`
+
+	Labels map[string]string `json:"labels,omitempty"`
+}
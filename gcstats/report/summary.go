@@ -0,0 +1,65 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package report
+
+import (
+	"strings"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// Env returns the env field of documents describing s: its recorded
+// runtime settings keyed by variable name, or nil if there are none.
+func Env(s *gcstats.GcStats) map[string]string {
+	vars := s.Environment().Vars()
+	if len(vars) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(vars))
+	for _, kv := range vars {
+		name, val, _ := strings.Cut(kv, "=")
+		m[name] = val
+	}
+	return m
+}
+
+// NewSummary computes the summary statistics of s.
+func NewSummary(s *gcstats.GcStats) *Summary {
+	sum := &Summary{
+		SchemaVersion:  SchemaVersion,
+		Cycles:         s.Count(),
+		ProgTimes:      s.HaveProgTimes(),
+		EstimatedTimes: s.EstimatedTimes(),
+		MaxPauseNS:     s.MaxPause(),
+		Pacing:         s.Pacing().String(),
+		Env:            Env(s),
+		Stops:          make(map[string]StopSummary),
+	}
+	if sum.ProgTimes {
+		sum.MutatorUtilization = s.MutatorUtilization()
+		_, sum.LongestBlackoutNS = s.LongestBlackout(0)
+	}
+
+	byKind := make(map[string]*gcstats.Pauses)
+	for _, stop := range s.Stops() {
+		for _, kind := range []string{"all", stop.Kind.String()} {
+			if byKind[kind] == nil {
+				byKind[kind] = new(gcstats.Pauses)
+			}
+			byKind[kind].AddDuration(stop.Duration)
+		}
+	}
+	for kind, ps := range byKind {
+		sum.Stops[kind] = StopSummary{
+			Count:  ps.Count(),
+			MaxNS:  ps.Max(),
+			MeanNS: ps.Total() / int64(ps.Count()),
+			P50NS:  ps.Percentile(0.50),
+			P95NS:  ps.Percentile(0.95),
+			P99NS:  ps.Percentile(0.99),
+		}
+	}
+	return sum
+}
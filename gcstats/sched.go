@@ -0,0 +1,108 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import "strings"
+
+// SchedSample is a sample of scheduler state from a line of a
+// scheduler trace produced by GODEBUG=schedtrace=X, which may be
+// interleaved with a GC trace.
+type SchedSample struct {
+	// Time is the time of the sample in nanoseconds since the
+	// program started. Scheduler traces report this in whole
+	// milliseconds.
+	Time int64
+
+	// Gomaxprocs, IdleProcs, Threads, SpinningThreads, and
+	// IdleThreads are the number of Ps, idle Ps, OS threads,
+	// spinning threads, and idle threads.
+	Gomaxprocs, IdleProcs, Threads, SpinningThreads, IdleThreads int
+
+	// RunQueue is the length of the global run queue and
+	// LocalRunQueues are the lengths of the run queues of each P.
+	RunQueue       int
+	LocalRunQueues []int
+}
+
+// Runnable returns the number of runnable goroutines waiting in the
+// global and local run queues.
+func (s SchedSample) Runnable() int {
+	n := s.RunQueue
+	for _, q := range s.LocalRunQueues {
+		n += q
+	}
+	return n
+}
+
+// Sched returns the scheduler trace samples interleaved with the GC
+// trace of s, in order. These are empty if the program was not run
+// with GODEBUG=schedtrace=X.
+func (s *GcStats) Sched() []SchedSample {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	return s.sched[:len(s.sched):len(s.sched)]
+}
+
+func (s *GcStats) addSched(samples ...SchedSample) {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	s.sched = append(s.sched, samples...)
+}
+
+// parseSchedLine parses a scheduler trace line of the form
+//
+//	SCHED <t>ms: gomaxprocs=4 idleprocs=2 threads=5 ... runqueue=0 [0 0 0 0]
+//
+// Unknown fields are ignored, so this accepts the formats of all
+// versions of Go.
+func parseSchedLine(line string) (SchedSample, bool) {
+	var out SchedSample
+	l := lineScanner{line}
+	if !l.literal("SCHED ") {
+		return out, false
+	}
+	ns, ok := l.fixed(6)
+	if !ok || !l.literal("ms:") {
+		return out, false
+	}
+	out.Time = ns
+
+	fields, queues, _ := strings.Cut(l.s, "[")
+	for _, f := range strings.Fields(fields) {
+		key, val, ok := strings.Cut(f, "=")
+		if !ok {
+			continue
+		}
+		v := lineScanner{val}
+		x, ok := v.integer()
+		if !ok || v.s != "" {
+			return out, false
+		}
+		switch key {
+		case "gomaxprocs":
+			out.Gomaxprocs = int(x)
+		case "idleprocs":
+			out.IdleProcs = int(x)
+		case "threads":
+			out.Threads = int(x)
+		case "spinningthreads":
+			out.SpinningThreads = int(x)
+		case "idlethreads":
+			out.IdleThreads = int(x)
+		case "runqueue":
+			out.RunQueue = int(x)
+		}
+	}
+	queues, _, _ = strings.Cut(queues, "]")
+	for _, f := range strings.Fields(queues) {
+		v := lineScanner{f}
+		x, ok := v.integer()
+		if !ok || v.s != "" {
+			return out, false
+		}
+		out.LocalRunQueues = append(out.LocalRunQueues, int(x))
+	}
+	return out, true
+}
@@ -0,0 +1,63 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSchedLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want SchedSample
+		ok   bool
+	}{
+		{"SCHED 1004ms: gomaxprocs=4 idleprocs=1 threads=11 spinningthreads=1 needspinning=0 idlethreads=2 runqueue=3 [1 0 2 0]",
+			SchedSample{1004e6, 4, 1, 11, 1, 2, 3, []int{1, 0, 2, 0}}, true},
+		{"SCHED 0ms: gomaxprocs=1 idleprocs=0 threads=2 spinningthreads=0 idlethreads=0 runqueue=0 [0]",
+			SchedSample{0, 1, 0, 2, 0, 0, 0, []int{0}}, true},
+		{"SCHED 5ms: gomaxprocs=x", SchedSample{}, false},
+		{"SCHEDULE 5ms:", SchedSample{}, false},
+	}
+	for _, test := range tests {
+		got, ok := parseSchedLine(test.line)
+		if ok != test.ok || ok && !reflect.DeepEqual(test.want, got) {
+			t.Errorf("%q: want %v, %v; got %v, %v", test.line, test.want, test.ok, got, ok)
+		}
+	}
+	if n := tests[0].want.Runnable(); n != 6 {
+		t.Errorf("want 6 runnable goroutines, got %d", n)
+	}
+}
+
+func TestParseSched(t *testing.T) {
+	const log = `SCHED 5ms: gomaxprocs=4 idleprocs=1 threads=5 spinningthreads=0 idlethreads=0 runqueue=0 [0 0 0 0]
+gc 1 @0.010s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+SCHED 15ms: gomaxprocs=4 idleprocs=0 threads=6 spinningthreads=0 idlethreads=0 runqueue=2 [0 1 0 0]
+gc 2 @0.020s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+SCHED 22ms: gomaxprocs=4 idleprocs=4 threads=6 spinningthreads=0 idlethreads=2 runqueue=0 [0 0 0 0]
+`
+	want, err := NewFromLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want.Sched()) != 3 || want.Sched()[1].Runnable() != 3 {
+		t.Errorf("want 3 scheduler samples, got %v", want.Sched())
+	}
+	for _, n := range []int{1, 2, 4} {
+		p := NewParserBytes(nil)
+		if err := p.parseChunks(splitLines([]byte(log), n, 1)); err != nil {
+			t.Fatal(err)
+		}
+		if got := p.Stats().Sched(); !reflect.DeepEqual(want.Sched(), got) {
+			t.Errorf("in %d chunks: want %v, got %v", n, want.Sched(), got)
+		}
+	}
+	if got := want.SkipCycles(1).Sched(); len(got) != 1 || got[0].Time != 22e6 {
+		t.Errorf("after skipping 1 cycle, want 1 sample, got %v", got)
+	}
+}
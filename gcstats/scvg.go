@@ -0,0 +1,90 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import "strings"
+
+// ScvgSample is the state of the heap reported by the scavenger, which
+// returns unused memory to the OS, in a line of a trace produced by
+// GODEBUG=gctrace=1, such as
+//
+//	scvg0: inuse: 3, idle: 58, sys: 63, released: 55, consumed: 8 (MB)
+type ScvgSample struct {
+	// GC is the number of the GC cycle that most recently
+	// preceded the sample in the trace, or 0 if there was none.
+	GC int
+
+	// Time is the approximate time of the sample in nanoseconds:
+	// the end of mark termination of cycle GC. Scavenger lines
+	// don't record times. This is 0 if the trace doesn't have
+	// program execution times.
+	Time int64
+
+	// InUse is the heap memory in use in bytes. Idle is the heap
+	// memory that is unused, some of which may have been
+	// released to the OS. Sys is the heap memory obtained from
+	// the OS, Released is the memory returned to the OS, and
+	// Consumed is Sys - Released, which approximates the heap's
+	// contribution to the process's RSS.
+	//
+	// Traces report these in whole megabytes.
+	InUse, Idle, Sys, Released, Consumed int64
+}
+
+// Scavenges returns the scavenger samples interleaved with the GC
+// trace of s, in order.
+func (s *GcStats) Scavenges() []ScvgSample {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	return s.scvg[:len(s.scvg):len(s.scvg)]
+}
+
+// addScvg adds sample to p.stats, timing it by the most recent cycle.
+func (p *Parser) addScvg(sample ScvgSample) {
+	if p.havePending {
+		sample.GC = p.pending.N
+		if p.stats.progTimes {
+			sample.Time = p.pending.Begin
+		}
+	}
+	s := p.stats
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	s.scvg = append(s.scvg, sample)
+}
+
+// parseScvgLine parses a scavenger line of the form
+//
+//	scvg<n>: inuse: 3, idle: 58, sys: 63, released: 55, consumed: 8 (MB)
+//
+// Other scavenger lines, such as "scvg0: 0 MB released", carry no
+// heap state and are not parsed.
+func parseScvgLine(line string) (ScvgSample, bool) {
+	var out ScvgSample
+	l := lineScanner{line}
+	if !l.literal("scvg") {
+		return out, false
+	}
+	l.integer()
+	if !l.literal(": ") || !strings.HasSuffix(l.s, " (MB)") {
+		return out, false
+	}
+	fields := map[string]*int64{"inuse": &out.InUse, "idle": &out.Idle, "sys": &out.Sys, "released": &out.Released, "consumed": &out.Consumed}
+	for _, f := range strings.Split(strings.TrimSuffix(l.s, " (MB)"), ", ") {
+		key, val, ok := strings.Cut(f, ": ")
+		ptr := fields[key]
+		if !ok || ptr == nil {
+			return out, false
+		}
+		v := lineScanner{val}
+		x, ok := v.megabytes()
+		if !ok || v.s != "" {
+			return out, false
+		}
+		*ptr = x
+		delete(fields, key)
+	}
+	return out, len(fields) == 0
+}
@@ -0,0 +1,71 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseScvgLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want ScvgSample
+		ok   bool
+	}{
+		{"scvg0: inuse: 3, idle: 58, sys: 63, released: 55, consumed: 8 (MB)",
+			ScvgSample{InUse: 3 << 20, Idle: 58 << 20, Sys: 63 << 20, Released: 55 << 20, Consumed: 8 << 20}, true},
+		{"scvg: inuse: 1, idle: 0, sys: 1, released: 0, consumed: 1 (MB)",
+			ScvgSample{InUse: 1 << 20, Sys: 1 << 20, Consumed: 1 << 20}, true},
+		{"scvg0: 0 MB released", ScvgSample{}, false},
+		{"scvg0: inuse: 3, idle: 58 (MB)", ScvgSample{}, false},
+		{"scvg0: inuse: x, idle: 58, sys: 63, released: 55, consumed: 8 (MB)", ScvgSample{}, false},
+	}
+	for _, test := range tests {
+		got, ok := parseScvgLine(test.line)
+		if ok != test.ok || ok && test.want != got {
+			t.Errorf("%q: want %v, %v; got %v, %v", test.line, test.want, test.ok, got, ok)
+		}
+	}
+}
+
+func TestParseScvg(t *testing.T) {
+	const log = `scvg: inuse: 1, idle: 0, sys: 1, released: 0, consumed: 1 (MB)
+gc 1 @0.010s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+scvg0: inuse: 3, idle: 1, sys: 4, released: 0, consumed: 4 (MB)
+gc 2 @0.020s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+scvg1: 0 MB released
+scvg1: inuse: 2, idle: 2, sys: 4, released: 1, consumed: 3 (MB)
+`
+	want, err := NewFromLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scvg := want.Scavenges()
+	if len(scvg) != 3 {
+		t.Fatalf("want 3 scavenger samples, got %v", scvg)
+	}
+	if scvg[0].GC != 0 || scvg[1].GC != 1 || scvg[2].GC != 2 {
+		t.Errorf("want samples after cycles 0, 1, 2, got %v", scvg)
+	}
+	for _, p := range want.Phases() {
+		if p.N == 1 && p.Kind == PhaseSweep && scvg[1].Time != p.Begin {
+			t.Errorf("want sample 1 at the sweep of cycle 1 (%d), got time %d", p.Begin, scvg[1].Time)
+		}
+	}
+	for _, n := range []int{1, 2, 4} {
+		p := NewParserBytes(nil)
+		if err := p.parseChunks(splitLines([]byte(log), n, 1)); err != nil {
+			t.Fatal(err)
+		}
+		if got := p.Stats().Scavenges(); !reflect.DeepEqual(scvg, got) {
+			t.Errorf("in %d chunks: want %v, got %v", n, scvg, got)
+		}
+	}
+	if got := want.SkipCycles(1).Scavenges(); len(got) != 1 || got[0].GC != 2 {
+		t.Errorf("after skipping 1 cycle, want 1 sample, got %v", got)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+// A Segment is a run of consecutive GC cycles with the same
+// GOMAXPROCS.
+type Segment struct {
+	Gomaxprocs int
+
+	// First and Last are the numbers of the first and last GC
+	// cycles of the segment.
+	First, Last int
+
+	// Stats consists of the cycles of the segment. It is
+	// complete, even if the trace it was split from is not.
+	Stats *GcStats
+}
+
+// GomaxprocsSegments splits s into runs of consecutive cycles with
+// the same GOMAXPROCS. Pause and utilization statistics are not
+// comparable across different GOMAXPROCS, so they should be computed
+// per segment if GOMAXPROCS changes. If GOMAXPROCS never changes,
+// this returns a single segment spanning s.
+func (s *GcStats) GomaxprocsSegments() []Segment {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+
+	var segs []Segment
+	start, ncycles := 0, 0
+	cycles := s.cycles
+	flush := func(end int) {
+		log := s.log[start:end]
+		seg := Segment{
+			Gomaxprocs: log[0].Gomaxprocs,
+			First:      log[0].N,
+			Last:       log[len(log)-1].N,
+			Stats:      &GcStats{log: log, n: ncycles, progTimes: s.progTimes, estimated: s.estimated, complete: true, env: s.env},
+		}
+		// Each cycle's phases are numbered with the cycle
+		// number, so take the cycle records in the same
+		// range.
+		i := 0
+		for i < len(cycles) && cycles[i].N < seg.First {
+			i++
+		}
+		j := i
+		for j < len(cycles) && cycles[j].N <= seg.Last {
+			j++
+		}
+		seg.Stats.cycles, cycles = cycles[i:j], cycles[j:]
+		segs = append(segs, seg)
+	}
+	for i, p := range s.log {
+		if i > 0 && p.N == s.log[i-1].N {
+			continue
+		}
+		// p is the first phase of a cycle.
+		if i > 0 && p.Gomaxprocs != s.log[start].Gomaxprocs {
+			flush(i)
+			start, ncycles = i, 0
+		}
+		ncycles++
+	}
+	if len(s.log) > 0 {
+		flush(len(s.log))
+	}
+	return segs
+}
@@ -0,0 +1,58 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestGomaxprocsSegments(t *testing.T) {
+	var log strings.Builder
+	procs := []int{4, 4, 4, 8, 8, 4}
+	for i, p := range procs {
+		fmt.Fprintf(&log, "gc %d @%d.000s 5%%: 0.1+1+1+1+0.5 ms clock, 0.2+1+0+1/1/1+1 ms cpu, 4->4->2 MB, 4 MB goal, %d P\n", i+1, i, p)
+	}
+	s, err := NewFromLog(strings.NewReader(log.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	segs := s.GomaxprocsSegments()
+	want := []struct{ procs, first, last int }{{4, 1, 3}, {8, 4, 5}, {4, 6, 6}}
+	if len(segs) != len(want) {
+		t.Fatalf("want %d segments, got %d", len(want), len(segs))
+	}
+	for i, w := range want {
+		seg := segs[i]
+		if seg.Gomaxprocs != w.procs || seg.First != w.first || seg.Last != w.last {
+			t.Errorf("segment %d: want GOMAXPROCS %d cycles %d-%d, got %d cycles %d-%d", i, w.procs, w.first, w.last, seg.Gomaxprocs, seg.First, seg.Last)
+		}
+		n := w.last - w.first + 1
+		if seg.Stats.Count() != n || len(seg.Stats.Cycles()) != n || seg.Stats.Cycles()[0].N != w.first {
+			t.Errorf("segment %d: want %d cycles starting at %d, got %d", i, n, w.first, seg.Stats.Count())
+		}
+		for _, p := range seg.Stats.Phases() {
+			if p.Gomaxprocs != w.procs {
+				t.Errorf("segment %d: phase %+v has wrong GOMAXPROCS", i, p)
+				break
+			}
+		}
+	}
+
+	// A trace with constant GOMAXPROCS is one segment.
+	data, err := ioutil.ReadFile("../media/runtime-compile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err = NewFromBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	if segs := s.GomaxprocsSegments(); len(segs) != 1 || segs[0].Stats.Count() != s.Count() {
+		t.Errorf("want 1 segment of %d cycles, got %d segments", s.Count(), len(segs))
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Snapshot returns a GcStats of what has been parsed into s so far.
+// Since parsing only appends to s, the snapshot shares its contents
+// with s and is unaffected by later parsing, so it can be analyzed
+// while parsing continues. Snapshot must not be called concurrently
+// with parsing into s.
+func (s *GcStats) Snapshot() *GcStats {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+	return &GcStats{
+		log:       s.log[:len(s.log):len(s.log)],
+		n:         s.n,
+		cycles:    s.cycles[:len(s.cycles):len(s.cycles)],
+		diags:     s.diags[:len(s.diags):len(s.diags)],
+		sched:     s.sched[:len(s.sched):len(s.sched)],
+		scvg:      s.scvg[:len(s.scvg):len(s.scvg)],
+		env:       s.env,
+		progTimes: s.progTimes,
+		estimated: s.estimated,
+		complete:  true,
+	}
+}
+
+// Slice returns a GcStats consisting of the part of s in the time
+// window [begin, end) in nanoseconds, so any analysis can be applied
+// to a range of the run. Phases that straddle the edges of the window
+// are clipped to it. The result includes the records of the cycles
+// with phases in the window and the scheduler and scavenger samples
+// taken in it. Its diagnostics record the slicing.
+//
+// This will panic if the trace does not have program execution times.
+func (s *GcStats) Slice(begin, end int64) *GcStats {
+	s.requireProgTimes()
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+
+	out := &GcStats{
+		progTimes: true,
+		estimated: s.estimated,
+		complete:  true,
+		diags:     s.diags[:len(s.diags):len(s.diags)],
+		env:       s.env,
+	}
+	lo := sort.Search(len(s.log), func(i int) bool { return s.log[i].End() > begin })
+	hi := sort.Search(len(s.log), func(i int) bool { return s.log[i].Begin >= end })
+	if lo < hi {
+		out.log = make([]Phase, hi-lo)
+		copy(out.log, s.log[lo:hi])
+		if first := &out.log[0]; first.Begin < begin {
+			first.Duration -= begin - first.Begin
+			first.Begin = begin
+		}
+		if last := &out.log[len(out.log)-1]; last.End() > end {
+			last.Duration = end - last.Begin
+		}
+	}
+
+	// Keep the cycles with phases in the window.
+	for i, p := range out.log {
+		if i == 0 || p.N != out.log[i-1].N {
+			out.n++
+		}
+	}
+	if len(out.log) > 0 {
+		firstN, lastN := out.log[0].N, out.log[len(out.log)-1].N
+		for _, c := range s.cycles {
+			if firstN <= c.N && c.N <= lastN {
+				out.cycles = append(out.cycles, c)
+			}
+		}
+	}
+	for _, sample := range s.sched {
+		if begin <= sample.Time && sample.Time < end {
+			out.sched = append(out.sched, sample)
+		}
+	}
+	for _, sample := range s.scvg {
+		if begin <= sample.Time && sample.Time < end {
+			out.scvg = append(out.scvg, sample)
+		}
+	}
+	out.diags = append(out.diags, Diagnostic{Message: fmt.Sprintf("sliced to %v-%v", time.Duration(begin), time.Duration(end))})
+	return out
+}
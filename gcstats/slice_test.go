@@ -0,0 +1,72 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSlice(t *testing.T) {
+	s, err := NewFromLog(strings.NewReader(benchLog(100)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cycles begin every 50ms, so this slice clips phases at
+	// both ends.
+	const begin, end = 1.0101e9, 2.0003e9
+	sl := s.Slice(begin, end)
+	phases := sl.Phases()
+	if len(phases) == 0 || phases[0].Begin != begin || phases[len(phases)-1].End() != end {
+		t.Fatalf("want phases spanning [%d, %d), got %v", int64(begin), int64(end), phases)
+	}
+	for i := 1; i < len(phases); i++ {
+		if phases[i-1].End() != phases[i].Begin {
+			t.Errorf("phase %d ends at %d, but phase %d begins at %d", i-1, phases[i-1].End(), i, phases[i].Begin)
+		}
+	}
+	if got, want := sl.MutatorUtilizationBetween(0, math.MaxInt64), s.MutatorUtilizationBetween(begin, end); math.Abs(got-want) > 1e-9 {
+		t.Errorf("want utilization %v, got %v", want, got)
+	}
+	// Cycle 21 began at 1s and is still sweeping at 1.0101s.
+	if sl.Count() != 21 || len(sl.Cycles()) != 21 || sl.Cycles()[0].N != 21 {
+		t.Errorf("want cycles 21 through 41, got %d cycles starting with %+v", sl.Count(), sl.Cycles()[0])
+	}
+	// The original is unchanged.
+	if s.Count() != 100 || s.Phases()[0].Begin != 0 {
+		t.Errorf("Slice modified the original")
+	}
+
+	if empty := s.Slice(100e9, 200e9); len(empty.Phases()) != 0 || empty.Count() != 0 {
+		t.Errorf("want empty slice after the run, got %d phases", len(empty.Phases()))
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	lines := strings.SplitAfter(benchLog(20), "\n")
+	var buf bytes.Buffer
+	buf.WriteString(strings.Join(lines[:10], ""))
+	p := NewParser(&buf)
+	p.Follow = true
+	for p.Next() {
+	}
+	snap := p.Stats().Snapshot()
+	count, mu := snap.Count(), snap.MutatorUtilization()
+
+	buf.WriteString(strings.Join(lines[10:], ""))
+	for p.Next() {
+	}
+	if err := p.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if p.Stats().Count() <= count {
+		t.Fatalf("want more than %d cycles after parsing more, got %d", count, p.Stats().Count())
+	}
+	if snap.Count() != count || len(snap.Phases()) == len(p.Stats().Phases()) || snap.MutatorUtilization() != mu {
+		t.Errorf("snapshot changed by later parsing")
+	}
+}
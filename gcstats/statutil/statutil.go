@@ -0,0 +1,73 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package statutil provides the statistics helpers that gcstats uses
+// internally, for building custom analyses of GC traces.
+//
+// The types here are aliases of those in the copy of
+// github.com/aclements/go-moremath vendored by gcstats, so values
+// can be passed between this package and gcstats without
+// conversion, and users don't need to vendor go-moremath themselves.
+package statutil
+
+import (
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+	"github.com/aclements/go-gcstats/internal/go-moremath/vec"
+)
+
+// A Sample is a collection of possibly weighted data points. See
+// Sample.Percentile, Sample.Mean, and related methods.
+type Sample = stats.Sample
+
+// A KDE is a kernel density estimate of a Sample.
+type KDE = stats.KDE
+
+// KDEKernel is the kernel of a KDE.
+type KDEKernel = stats.KDEKernel
+
+const (
+	EpanechnikovKernel = stats.EpanechnikovKernel
+	GaussianKernel     = stats.GaussianKernel
+	DeltaKernel        = stats.DeltaKernel
+)
+
+// KDEBoundaryMethod is the boundary correction method of a KDE.
+type KDEBoundaryMethod = stats.KDEBoundaryMethod
+
+const (
+	BoundaryReflect = stats.BoundaryReflect
+)
+
+// BandwidthScott and BandwidthSilverman estimate the bandwidth of a
+// KDE from its sample.
+var (
+	BandwidthScott     = stats.BandwidthScott
+	BandwidthSilverman = stats.BandwidthSilverman
+)
+
+// Linspace returns num values spaced evenly between lo and hi,
+// inclusive.
+func Linspace(lo, hi float64, num int) []float64 {
+	return vec.Linspace(lo, hi, num)
+}
+
+// Logspace returns num values spaced evenly on a logarithmic scale
+// between base**lo and base**hi, inclusive.
+func Logspace(lo, hi float64, num int, base float64) []float64 {
+	return vec.Logspace(lo, hi, num, base)
+}
+
+// Durations returns a sorted Sample of the durations of phases in
+// nanoseconds, skipping phases whose duration is unknown. For
+// example, Durations(s.Stops()) is the distribution of pause times.
+func Durations(phases []gcstats.Phase) *Sample {
+	s := &Sample{}
+	for _, p := range phases {
+		if p.Duration >= 0 {
+			s.Xs = append(s.Xs, float64(p.Duration))
+		}
+	}
+	return s.Sort()
+}
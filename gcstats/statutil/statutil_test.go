@@ -0,0 +1,32 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package statutil
+
+import (
+	"math"
+	"testing"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+func TestDurations(t *testing.T) {
+	phases := []gcstats.Phase{
+		{Begin: 0, Duration: 300, STW: true},
+		{Begin: 300, Duration: 100, STW: true},
+		{Begin: 400, Duration: -1},
+	}
+	s := Durations(phases)
+	if len(s.Xs) != 2 || s.Percentile(0) != 100 || s.Percentile(1) != 300 || s.Mean() != 200 {
+		t.Errorf("want sorted durations [100 300], got %v", s.Xs)
+	}
+
+	kde := &KDE{Sample: *s, Kernel: GaussianKernel, Bandwidth: BandwidthSilverman(s)}
+	if p := kde.PDF(200); !(p > 0) || math.IsInf(p, 0) {
+		t.Errorf("bad KDE density %v", p)
+	}
+	if xs := Logspace(0, 2, 3, 10); xs[2] != 100 {
+		t.Errorf("Logspace: want [1 10 100], got %v", xs)
+	}
+}
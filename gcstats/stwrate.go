@@ -0,0 +1,56 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+// STWBin summarizes the stop-the-world pauses during an interval of
+// program execution.
+type STWBin struct {
+	// Begin is the beginning of the interval in nanoseconds.
+	Begin int64
+
+	// Count is the number of pauses that began in the interval.
+	Count int
+
+	// Duration is the total time in nanoseconds during the
+	// interval that the world was stopped. Pauses that span
+	// intervals are split between them.
+	Duration int64
+}
+
+// STWRate divides program execution into intervals of width
+// nanoseconds and returns the stop-the-world pauses in each interval,
+// from the interval containing the first logged GC to the interval
+// containing the end of the log. Intervals are aligned to multiples
+// of width from the start of the program. Consecutive STW phases
+// count as a single pause, as in Stops.
+//
+// This will panic if the trace does not have program execution times.
+func (s *GcStats) STWRate(width int64) []STWBin {
+	s.requireProgTimes()
+	if width <= 0 {
+		panic("STWRate width must be positive")
+	}
+	if len(s.log) == 0 {
+		return nil
+	}
+
+	first := s.log[0].Begin / width * width
+	last := s.log[len(s.log)-1]
+	bins := make([]STWBin, (last.Begin+last.Duration-first)/width+1)
+	for i := range bins {
+		bins[i].Begin = first + int64(i)*width
+	}
+	for _, stop := range s.Stops() {
+		i := (stop.Begin - first) / width
+		bins[i].Count++
+		// Split the pause across the intervals it overlaps.
+		for begin, end := stop.Begin, stop.Begin+stop.Duration; begin < end; i++ {
+			binEnd := int64Min(end, bins[i].Begin+width)
+			bins[i].Duration += binEnd - begin
+			begin = binEnd
+		}
+	}
+	return bins
+}
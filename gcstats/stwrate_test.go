@@ -0,0 +1,55 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestSTWRate(t *testing.T) {
+	s := NewFromPhases([]Phase{
+		{1500, 100, PhaseSweepTerm, 1, 1, 1, true},
+		{1600, 300, PhaseMark, 1, 1, 0.25, false},
+		{1900, 200, PhaseMarkTerm, 1, 1, 1, true},
+		{2100, 800, PhaseSweep, 1, 1, 0, false},
+		{2900, 50, PhaseSweepTerm, 2, 1, 1, true},
+		{2950, 10, PhaseMarkTerm, 2, 1, 1, true},
+		{2960, 1540, PhaseSweep, 2, 1, 0, false},
+	}, 2)
+	// The MarkTerm pause of cycle 1 spans the first two bins,
+	// and the two STW phases of cycle 2 are one pause.
+	want := []STWBin{
+		{1000, 2, 100 + 100},
+		{2000, 1, 100 + 60},
+		{3000, 0, 0},
+		{4000, 0, 0},
+	}
+	if got := s.STWRate(1000); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	// The total pause time is preserved for the real trace.
+	data, err := ioutil.ReadFile("../media/runtime-compile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, err = NewFromBytes(data); err != nil {
+		t.Fatal(err)
+	}
+	var total, binned int64
+	for _, stop := range s.Stops() {
+		total += stop.Duration
+	}
+	count := 0
+	for _, bin := range s.STWRate(10e6) {
+		binned += bin.Duration
+		count += bin.Count
+	}
+	if binned != total || count != len(s.Stops()) {
+		t.Errorf("want %d pauses totaling %d, got %d totaling %d", len(s.Stops()), total, count, binned)
+	}
+}
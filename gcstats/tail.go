@@ -0,0 +1,150 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/aclements/go-gcstats/internal/go-moremath/stats"
+)
+
+// A TailModel is a generalized Pareto distribution fit to the
+// durations exceeding a threshold. This models the tail of a
+// distribution of pause times well enough to extrapolate quantiles
+// beyond those observed in a trace.
+type TailModel struct {
+	// Threshold is the duration in nanoseconds above which the
+	// model applies.
+	Threshold float64
+
+	// Rate is the fraction of durations that exceed Threshold.
+	Rate float64
+
+	// Shape and Scale are the parameters of the generalized
+	// Pareto distribution of the excess over Threshold. If Shape
+	// is positive, the tail is heavy and unbounded; if it is
+	// negative, the tail is bounded.
+	Shape, Scale float64
+
+	// N is the total number of durations and Exceedances is the
+	// number that exceed Threshold.
+	N, Exceedances int
+}
+
+// A TailQuantile is a quantile estimated from a TailModel with a 95%
+// confidence interval.
+type TailQuantile struct {
+	P                float64
+	Value, Low, High float64
+}
+
+// minTailN is the minimum number of durations FitTail requires.
+const minTailN = 20
+
+// FitTail fits a TailModel to the longest 10% of durations, or the
+// longest 10 if that is more. It requires at least 20 durations.
+//
+// The model is fit using probability-weighted moments, which are
+// more robust than maximum likelihood for the small number of
+// exceedances in a typical trace.
+//
+// Hosking, J. R. M. and Wallis, J. R. (1987) Parameter and Quantile
+// Estimation for the Generalized Pareto Distribution.
+func FitTail(durations []int64) (TailModel, error) {
+	if len(durations) < minTailN {
+		return TailModel{}, errors.New("too few durations to fit tail")
+	}
+	xs := make([]float64, len(durations))
+	for i, d := range durations {
+		xs[i] = float64(d)
+	}
+	sort.Float64s(xs)
+	m, ok := fitTailSorted(xs)
+	if !ok {
+		return TailModel{}, errors.New("tail durations do not vary")
+	}
+	return m, nil
+}
+
+func fitTailSorted(xs []float64) (TailModel, bool) {
+	n := len(xs)
+	nu := max(n/10, 10)
+	u := xs[n-nu-1]
+	// Probability-weighted moments a0 = E[Y] and
+	// a1 = E[Y(1-F(Y))] of the excesses Y.
+	var a0, a1 float64
+	for i, x := range xs[n-nu:] {
+		y := x - u
+		a0 += y
+		a1 += y * (1 - (float64(i+1)-0.35)/float64(nu))
+	}
+	a0, a1 = a0/float64(nu), a1/float64(nu)
+	if a0 <= 0 || a0-2*a1 <= 0 {
+		return TailModel{}, false
+	}
+	return TailModel{
+		Threshold:   u,
+		Rate:        float64(nu) / float64(n),
+		Shape:       2 - a0/(a0-2*a1),
+		Scale:       2 * a0 * a1 / (a0 - 2*a1),
+		N:           n,
+		Exceedances: nu,
+	}, true
+}
+
+// Quantile returns the p'th quantile of the modeled distribution.
+// p must be at least 1-m.Rate, since the model only covers the
+// tail. Quantile(1) is the upper bound of the distribution, which is
+// +Inf unless m.Shape is negative.
+func (m TailModel) Quantile(p float64) float64 {
+	q := (1 - p) / m.Rate
+	if math.Abs(m.Shape) < 1e-9 {
+		return m.Threshold - m.Scale*math.Log(q)
+	}
+	return m.Threshold + m.Scale/m.Shape*(math.Pow(q, -m.Shape)-1)
+}
+
+// EstimateTail fits a TailModel to durations as described by
+// FitTail and estimates the quantiles ps from it. Confidence
+// intervals are computed by bootstrap resampling of durations.
+func EstimateTail(durations []int64, ps []float64) (TailModel, []TailQuantile, error) {
+	m, err := FitTail(durations)
+	if err != nil {
+		return m, nil, err
+	}
+
+	const resamples = 1000
+	rnd := rand.New(rand.NewSource(1))
+	boot := make([]stats.Sample, len(ps))
+	xs := make([]float64, len(durations))
+	for r := 0; r < resamples; r++ {
+		for i := range xs {
+			xs[i] = float64(durations[rnd.Intn(len(durations))])
+		}
+		sort.Float64s(xs)
+		bm, ok := fitTailSorted(xs)
+		if !ok {
+			continue
+		}
+		for i, p := range ps {
+			if q := bm.Quantile(p); !math.IsNaN(q) {
+				boot[i].Xs = append(boot[i].Xs, q)
+			}
+		}
+	}
+
+	qs := make([]TailQuantile, len(ps))
+	for i, p := range ps {
+		qs[i] = TailQuantile{P: p, Value: m.Quantile(p), Low: math.NaN(), High: math.NaN()}
+		if len(boot[i].Xs) > 0 {
+			boot[i].Sort()
+			qs[i].Low, qs[i].High = boot[i].Percentile(0.025), boot[i].Percentile(0.975)
+		}
+	}
+	return m, qs, nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"math"
+	"testing"
+)
+
+// quantileSample returns n durations at evenly spaced quantiles of
+// the distribution with inverse CDF invCDF.
+func quantileSample(n int, invCDF func(p float64) float64) []int64 {
+	ds := make([]int64, n)
+	for i := range ds {
+		ds[i] = int64(invCDF((float64(i) + 0.5) / float64(n)))
+	}
+	return ds
+}
+
+func TestEstimateTailExponential(t *testing.T) {
+	// The tail of an exponential distribution is exponential,
+	// which is a generalized Pareto distribution with shape 0.
+	ds := quantileSample(1000, func(p float64) float64 { return -1e6 * math.Log(1-p) })
+	m, qs, err := EstimateTail(ds, []float64{0.999, 0.9999})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(m.Shape) > 0.1 || math.Abs(m.Scale-1e6) > 0.1e6 || m.Exceedances != 100 {
+		t.Errorf("want shape 0 and scale 1e6 from 100 exceedances, got %+v", m)
+	}
+	for _, q := range qs {
+		want := -1e6 * math.Log(1-q.P)
+		if math.Abs(q.Value-want) > 0.15*want || !(q.Low <= want && want <= q.High) {
+			t.Errorf("p%v: want %v, got %v (CI %v-%v)", q.P*100, want, q.Value, q.Low, q.High)
+		}
+	}
+}
+
+func TestFitTailUniform(t *testing.T) {
+	// The tail of a uniform distribution is bounded, with shape
+	// -1 and an upper bound at the maximum.
+	ds := quantileSample(200, func(p float64) float64 { return 1e6 * p })
+	m, err := FitTail(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(m.Shape+1) > 0.1 {
+		t.Errorf("want shape -1, got %+v", m)
+	}
+	if max := m.Quantile(1); math.Abs(max-1e6) > 0.02e6 {
+		t.Errorf("want upper bound 1e6, got %v", max)
+	}
+}
+
+func TestFitTailErrors(t *testing.T) {
+	if _, err := FitTail(make([]int64, 10)); err == nil {
+		t.Errorf("want error for too few durations")
+	}
+	ds := make([]int64, 100)
+	for i := range ds {
+		ds[i] = 1000
+	}
+	if _, err := FitTail(ds); err == nil {
+		t.Errorf("want error for constant durations")
+	}
+}
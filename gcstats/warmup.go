@@ -0,0 +1,129 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import "sort"
+
+// Warmup returns the number of leading cycles of s during which the
+// garbage collector had not yet reached a steady state, based on
+// when the heap goal and the interval between cycles stop trending.
+// Startup cycles typically have small heaps and short intervals that
+// skew statistics over the whole trace. Warmup returns 0 if s has
+// too few cycles to tell or its cycles weren't paced by heap growth,
+// as reported by Pacing.
+//
+// The warm-up prefix is chosen using the marginal standard error
+// rule (MSER): for each series, it is the truncation point that
+// minimizes the standard error of the mean of the remaining cycles,
+// considering at most the first half of the trace. The result is
+// the latest truncation point of any series.
+func (s *GcStats) Warmup() int {
+	cycles := s.Cycles()
+	const minCycles = 10
+	if len(cycles) < minCycles || s.Pacing() != PacingHeap {
+		return 0
+	}
+
+	var goals, intervals []float64
+	for i, c := range cycles {
+		goal := c.HeapGoal
+		if goal == 0 {
+			goal = c.HeapTrigger
+		}
+		goals = append(goals, float64(goal))
+		if s.progTimes && i+1 < len(cycles) {
+			intervals = append(intervals, float64(cycles[i+1].Begin-c.Begin))
+		}
+	}
+	warmup := mser(goals)
+	if len(intervals) >= minCycles {
+		warmup = max(warmup, mser(intervals))
+	}
+	return warmup
+}
+
+// mser returns the number of leading elements of xs to discard to
+// minimize the marginal standard error of the remaining elements,
+// considering at most half of xs.
+func mser(xs []float64) int {
+	// Compute suffix sums so each truncation point is O(1).
+	n := len(xs)
+	sum, sumsq := make([]float64, n+1), make([]float64, n+1)
+	for i := n - 1; i >= 0; i-- {
+		sum[i] = sum[i+1] + xs[i]
+		sumsq[i] = sumsq[i+1] + xs[i]*xs[i]
+	}
+	best, bestD := 0.0, 0
+	for d := 0; d <= n/2; d++ {
+		m := float64(n - d)
+		// Sum of squared deviations from the mean of xs[d:].
+		ss := sumsq[d] - sum[d]*sum[d]/m
+		if se := ss / (m * m); d == 0 || se < best {
+			best, bestD = se, d
+		}
+	}
+	return bestD
+}
+
+// SkipCycles returns a GcStats consisting of the cycles of s after
+// the first n, such as to exclude the warm-up cycles reported by
+// Warmup. The returned GcStats is complete, even if s is not.
+func (s *GcStats) SkipCycles(n int) *GcStats {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+
+	n = min(n, s.n)
+	out := &GcStats{n: s.n - n, progTimes: s.progTimes, estimated: s.estimated, complete: true, sched: s.sched, scvg: s.scvg, env: s.env}
+	if n < len(s.cycles) {
+		out.cycles = s.cycles[n:]
+	}
+	if n == 0 {
+		out.log = s.log
+		return out
+	}
+	// Find the first phase of the first remaining cycle. Each
+	// cycle's phases are numbered with the cycle number,
+	// including its trailing sweep phase.
+	lastSkipped := -1
+	if n <= len(s.cycles) {
+		lastSkipped = s.cycles[n-1].N
+	}
+	i := 0
+	for skipped := 0; i < len(s.log); i++ {
+		p := s.log[i]
+		if lastSkipped >= 0 {
+			if p.N > lastSkipped {
+				break
+			}
+			continue
+		}
+		// Without cycle records, count cycles by their
+		// first phase.
+		if i == 0 || p.N != s.log[i-1].N {
+			if skipped == n {
+				break
+			}
+			skipped++
+		}
+	}
+	out.log = s.log[i:]
+	if s.progTimes && i < len(s.log) {
+		// Keep the scheduler samples from the first remaining
+		// cycle on.
+		begin := s.log[i].Begin
+		j := sort.Search(len(s.sched), func(j int) bool { return s.sched[j].Time >= begin })
+		out.sched = s.sched[j:]
+	}
+	if n < len(s.cycles) {
+		// Keep the scavenger samples from the first remaining
+		// cycle on.
+		first := s.cycles[n].N
+		j := sort.Search(len(s.scvg), func(j int) bool { return s.scvg[j].GC >= first })
+		out.scvg = s.scvg[j:]
+	} else {
+		out.scvg = nil
+	}
+	return out
+}
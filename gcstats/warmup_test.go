@@ -0,0 +1,63 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// warmupLog returns a Go 1.5 trace of 20 cycles whose heap goal and
+// interval grow over the first 4 cycles and are then constant.
+func warmupLog() string {
+	var log strings.Builder
+	t := 0.0
+	for n := 1; n <= 20; n++ {
+		goal, interval := 64, 0.1
+		if n <= 4 {
+			goal, interval = 4<<(n-1), 0.01*float64(n)
+		}
+		fmt.Fprintf(&log, "gc %d @%.3fs 5%%: 0.1+1+1+1+0.5 ms clock, 0.2+1+0+1/1/1+1 ms cpu, %d->%d->%d MB, %d MB goal, 4 P\n", n, t, goal, goal, goal/2, goal)
+		t += interval
+	}
+	return log.String()
+}
+
+func TestWarmup(t *testing.T) {
+	s, err := NewFromLog(strings.NewReader(warmupLog()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Warmup(); got != 4 {
+		t.Errorf("want warm-up of 4 cycles, got %d", got)
+	}
+
+	s2 := s.SkipCycles(4)
+	if s2.Count() != 16 || len(s2.Cycles()) != 16 || s2.Cycles()[0].N != 5 {
+		t.Fatalf("after skipping 4 cycles, want 16 cycles starting at 5, got %d starting at %d", len(s2.Cycles()), s2.Cycles()[0].N)
+	}
+	if p := s2.Phases()[0]; p.N != 5 || p.Kind != PhaseSweepTerm {
+		t.Errorf("want first phase SweepTerm of cycle 5, got %+v", p)
+	}
+	if got := s2.Warmup(); got != 0 {
+		t.Errorf("want no warm-up after skipping it, got %d", got)
+	}
+}
+
+func TestMSER(t *testing.T) {
+	for _, test := range []struct {
+		xs   []float64
+		want int
+	}{
+		{[]float64{5, 5, 5, 5, 5, 5}, 0},
+		{[]float64{1, 2, 3, 5, 5, 5, 5, 5, 5, 5}, 3},
+		{[]float64{1, 1, 1, 1, 1, 1, 5, 5}, 0},
+	} {
+		if got := mser(test.xs); got != test.want {
+			t.Errorf("mser(%v): want %d, got %d", test.xs, test.want, got)
+		}
+	}
+}
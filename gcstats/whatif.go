@@ -0,0 +1,89 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ScaleSTW returns a copy of s in which the duration of every STW
+// phase is multiplied by factor, to estimate what pause times and
+// mutator utilization would be if the runtime's pauses were shorter
+// (or longer) and nothing else changed. Phases, cycles, and samples
+// after each scaled phase move earlier or later by the change in its
+// duration, so concurrent phases and the time between cycles keep
+// their durations.
+//
+// The result's diagnostics record the scaling.
+func (s *GcStats) ScaleSTW(factor float64) (*GcStats, error) {
+	if factor < 0 || math.IsNaN(factor) || math.IsInf(factor, 0) {
+		return nil, fmt.Errorf("STW scale factor must be a non-negative number, not %v", factor)
+	}
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+
+	out := &GcStats{
+		log:       make([]Phase, len(s.log)),
+		cycles:    make([]Cycle, len(s.cycles)),
+		n:         s.n,
+		progTimes: s.progTimes,
+		estimated: s.estimated,
+		complete:  true,
+		diags:     s.diags[:len(s.diags):len(s.diags)],
+		env:       s.env,
+	}
+	copy(out.log, s.log)
+	copy(out.cycles, s.cycles)
+
+	// ends[i] and shifts[i] are the original end of the i'th
+	// scaled phase and the total shift of everything after it.
+	var ends, shifts []int64
+	var shift int64
+	for i := range out.log {
+		p := &out.log[i]
+		if s.progTimes {
+			p.Begin += shift
+		}
+		if !p.STW || p.Duration <= 0 {
+			continue
+		}
+		scaled := int64(math.Round(float64(p.Duration) * factor))
+		ends = append(ends, s.log[i].End())
+		shift += scaled - p.Duration
+		shifts = append(shifts, shift)
+		p.Duration = scaled
+	}
+	// move returns the new time of original time t. Times inside
+	// a scaled phase move with its beginning.
+	move := func(t int64) int64 {
+		i := sort.Search(len(ends), func(i int) bool { return ends[i] > t })
+		if i == 0 {
+			return t
+		}
+		return t + shifts[i-1]
+	}
+
+	if s.progTimes {
+		for i := range out.cycles {
+			out.cycles[i].Begin = move(out.cycles[i].Begin)
+		}
+		out.sched = make([]SchedSample, len(s.sched))
+		for i, sample := range s.sched {
+			sample.Time = move(sample.Time)
+			out.sched[i] = sample
+		}
+		out.scvg = make([]ScvgSample, len(s.scvg))
+		for i, sample := range s.scvg {
+			sample.Time = move(sample.Time)
+			out.scvg[i] = sample
+		}
+	} else {
+		out.sched, out.scvg = s.sched, s.scvg
+	}
+	out.diags = append(out.diags, Diagnostic{Message: fmt.Sprintf("STW phase durations are scaled by %v", factor)})
+	return out, nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstats
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScaleSTW(t *testing.T) {
+	const log = `gc 1 @0.010s 5%: 2+0+0+10+4 ms clock, 2+0+0+1/2/0+4 ms cpu, 4->4->2 MB, 4 MB goal, 1 P
+gc 2 @0.100s 5%: 6+0+0+10+2 ms clock, 6+0+0+1/2/0+2 ms cpu, 4->4->2 MB, 4 MB goal, 1 P
+`
+	s, err := NewFromLog(strings.NewReader(log))
+	if err != nil {
+		t.Fatal(err)
+	}
+	half, err := s.ScaleSTW(0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := half.MaxPause(), s.MaxPause()/2; got != want {
+		t.Errorf("want max pause %d, got %d", want, got)
+	}
+	// Each phase ends where the next begins.
+	phases := half.Phases()
+	for i := 1; i < len(phases); i++ {
+		if phases[i-1].End() != phases[i].Begin {
+			t.Errorf("phase %d ends at %d, but phase %d begins at %d", i-1, phases[i-1].End(), i, phases[i].Begin)
+		}
+	}
+	// The first cycle saved 3ms of STW before the second.
+	orig, scaled := s.Cycles(), half.Cycles()
+	if got, want := scaled[1].Begin, orig[1].Begin-3e6; got != want {
+		t.Errorf("want second cycle to begin at %d, got %d", want, got)
+	}
+	if got, want := scaled[0].Begin, orig[0].Begin; got != want {
+		t.Errorf("want first cycle to begin at %d, got %d", want, got)
+	}
+	// Shorter pauses raise the MMU.
+	if half.MMU(5e6) <= s.MMU(5e6) {
+		t.Errorf("want MMU above %v, got %v", s.MMU(5e6), half.MMU(5e6))
+	}
+
+	if _, err := s.ScaleSTW(-1); err == nil {
+		t.Errorf("want error for negative factor")
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gcstatsbus publishes one record per GC cycle of a GC trace
+// to a message bus as the trace is parsed, so GC telemetry pipelines
+// can consume gcstats output directly.
+//
+// Records can be published to a NATS server with NATSSink, or written
+// as JSON lines with JSONSink. The latter can be piped into a producer
+// for other buses, such as kcat for Kafka:
+//
+//	gcstats -publish - trace | kcat -P -b broker -t gc
+package gcstatsbus
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/gcstats/report"
+)
+
+// Record is the published summary of a GC cycle. Its fields are
+// documented by report.BusRecord.
+type Record = report.BusRecord
+
+// A Sink receives published records.
+type Sink interface {
+	Publish(r *Record) error
+	Close() error
+}
+
+// A Streamer parses a GC trace and publishes a Record to a Sink for
+// each cycle as soon as it is parsed.
+type Streamer struct {
+	// Source and Labels are copied into each Record.
+	Source string
+	Labels map[string]string
+
+	p    *gcstats.Parser
+	sink Sink
+	err  error
+
+	// ncycles and nphases are the number of cycles published and
+	// phases examined so far.
+	ncycles, nphases int
+}
+
+// NewStreamer returns a Streamer that reads the trace from p and
+// publishes to sink.
+func NewStreamer(p *gcstats.Parser, sink Sink) *Streamer {
+	return &Streamer{p: p, sink: sink}
+}
+
+// Next parses the next GC cycle using the Parser and publishes it.
+// Like Parser.Next, it returns false at the end of the available
+// input or on error.
+func (st *Streamer) Next() bool {
+	if st.err != nil {
+		return false
+	}
+	more := st.p.Next()
+	s := st.p.Stats()
+	cycles, phases := s.Cycles(), s.Phases()
+	for ; st.ncycles < len(cycles); st.ncycles++ {
+		c := cycles[st.ncycles]
+		// The cycle's phases are all parsed except its final
+		// sweep phase, which is held back until the next cycle
+		// begins.
+		r := &Record{
+			SchemaVersion: report.SchemaVersion,
+			Source:        st.Source,
+			Labels:        st.Labels,
+			GC:            c.N,
+			BeginNS:       c.Begin,
+			PhasesNS:      make(map[string]int64),
+			HeapTrigger:   c.HeapTrigger,
+			HeapMarked:    c.HeapMarked,
+			HeapLive:      c.HeapLive,
+			HeapGoal:      c.HeapGoal,
+		}
+		for st.nphases < len(phases) && phases[st.nphases].N < c.N {
+			st.nphases++
+		}
+		first := st.nphases
+		for ; st.nphases < len(phases) && phases[st.nphases].N == c.N; st.nphases++ {
+			p := phases[st.nphases]
+			r.Gomaxprocs = p.Gomaxprocs
+			if p.Duration >= 0 {
+				r.PhasesNS[p.Kind.String()[len("Phase"):]] += p.Duration
+			}
+			if p.STW {
+				r.PauseNS += p.Duration
+			}
+		}
+		for _, stop := range gcstats.JoinStops(phases[first:st.nphases]) {
+			r.MaxPauseNS = max(r.MaxPauseNS, stop.Duration)
+		}
+		if err := st.sink.Publish(r); err != nil {
+			st.err = err
+			return false
+		}
+	}
+	return more
+}
+
+// Err returns the first error encountered by the Parser or the Sink,
+// if any.
+func (st *Streamer) Err() error {
+	if st.err != nil {
+		return st.err
+	}
+	return st.p.Err()
+}
+
+// JSONSink writes each record to an io.Writer as a line of JSON.
+type JSONSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	w   io.Writer
+}
+
+// NewJSONSink returns a JSONSink that writes to w. If w is an
+// io.Closer, closing the JSONSink closes w.
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(w), w: w}
+}
+
+func (s *JSONSink) Publish(r *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(r)
+}
+
+func (s *JSONSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
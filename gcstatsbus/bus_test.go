@@ -0,0 +1,155 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstatsbus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/gcstats/report"
+)
+
+// stream publishes the test trace to sink and returns its GcStats.
+func stream(t *testing.T, sink Sink) *gcstats.GcStats {
+	f, err := os.Open("../media/runtime-compile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	p := gcstats.NewParser(f)
+	st := NewStreamer(p, sink)
+	st.Source = "compile"
+	st.Labels = map[string]string{"gogc": "200"}
+	for st.Next() {
+	}
+	if err := st.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return p.Stats()
+}
+
+func checkRecords(t *testing.T, s *gcstats.GcStats, recs []Record) {
+	if len(recs) != s.Count() {
+		t.Fatalf("want %d records, got %d", s.Count(), len(recs))
+	}
+	var pause, maxPause int64
+	for i, r := range recs {
+		c := s.Cycles()[i]
+		if r.SchemaVersion != report.SchemaVersion || r.Source != "compile" || r.Labels["gogc"] != "200" || r.GC != c.N || r.BeginNS != c.Begin || r.HeapLive != c.HeapLive {
+			t.Errorf("record %d: %+v does not match cycle %+v", i, r, c)
+		}
+		if r.PhasesNS["SweepTerm"] == 0 || r.Gomaxprocs == 0 {
+			t.Errorf("record %d: missing phases: %+v", i, r)
+		}
+		pause += r.PauseNS
+		maxPause = max(maxPause, r.MaxPauseNS)
+	}
+	var want int64
+	for _, stop := range s.Stops() {
+		want += stop.Duration
+	}
+	if pause != want {
+		t.Errorf("want total pause %d, got %d", want, pause)
+	}
+	if maxPause != s.MaxPause() {
+		t.Errorf("want max pause %d, got %d", s.MaxPause(), maxPause)
+	}
+}
+
+func TestJSONSink(t *testing.T) {
+	var buf bytes.Buffer
+	s := stream(t, NewJSONSink(&buf))
+
+	var recs []Record
+	dec := json.NewDecoder(&buf)
+	for {
+		var r Record
+		if err := dec.Decode(&r); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, r)
+	}
+	checkRecords(t, s, recs)
+}
+
+// fakeNATS runs a minimal NATS server that accepts a single
+// connection and sends the payload of each message published to
+// subject on msgs.
+func fakeNATS(t *testing.T, subject string, msgs chan<- []byte) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		defer close(msgs)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte(`INFO {"server_id":"test","max_payload":1048576}` + "\r\n"))
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			f := strings.Fields(line)
+			switch {
+			case len(f) == 0:
+			case f[0] == "PING":
+				conn.Write([]byte("PONG\r\n"))
+			case f[0] == "PUB" && len(f) == 3:
+				n, _ := strconv.Atoi(f[2])
+				data := make([]byte, n+2)
+				if _, err := io.ReadFull(r, data); err != nil {
+					return
+				}
+				if f[1] != subject {
+					conn.Write([]byte("-ERR 'bad subject'\r\n"))
+					return
+				}
+				msgs <- data[:n]
+			}
+		}
+	}()
+	return ln
+}
+
+func TestNATSSink(t *testing.T) {
+	msgs := make(chan []byte, 100)
+	ln := fakeNATS(t, "gc.test", msgs)
+	defer ln.Close()
+
+	sink, err := DialNATS("nats://" + ln.Addr().String() + "/gc.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := stream(t, sink)
+
+	var recs []Record
+	for msg := range msgs {
+		var r Record
+		if err := json.Unmarshal(msg, &r); err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, r)
+	}
+	checkRecords(t, s, recs)
+}
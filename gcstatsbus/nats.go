@@ -0,0 +1,205 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstatsbus
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultNATSSubject is the subject NATSSink publishes to if the URL
+// does not specify one.
+const DefaultNATSSubject = "gcstats"
+
+// natsTimeout bounds how long DialNATS and Close wait for the server
+// to acknowledge the connection.
+const natsTimeout = 10 * time.Second
+
+// NATSSink publishes records as JSON messages to a NATS server using
+// the NATS client protocol.
+type NATSSink struct {
+	subject string
+	conn    net.Conn
+
+	// mu protects w and err. err is the first error from the
+	// server or the connection.
+	mu  sync.Mutex
+	w   *bufio.Writer
+	err error
+
+	// pongs receives a value for each PONG from the server and
+	// is closed when the connection is lost.
+	pongs chan struct{}
+}
+
+// DialNATS connects to the NATS server at rawurl, which has the form
+//
+//	nats://[user[:password]@]host[:port][/subject]
+//
+// If the port is omitted, it defaults to 4222. If the subject is
+// omitted, it defaults to DefaultNATSSubject. TLS connections are not
+// supported.
+func DialNATS(rawurl string) (*NATSSink, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "nats" {
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	subject := strings.TrimPrefix(u.Path, "/")
+	if subject == "" {
+		subject = DefaultNATSSubject
+	}
+	if strings.ContainsAny(subject, " \t\r\n") {
+		return nil, fmt.Errorf("bad NATS subject %q", subject)
+	}
+
+	conn, err := net.DialTimeout("tcp", host, natsTimeout)
+	if err != nil {
+		return nil, err
+	}
+	s := &NATSSink{subject: subject, conn: conn, w: bufio.NewWriter(conn), pongs: make(chan struct{}, 1)}
+
+	// The server begins with an INFO message.
+	conn.SetReadDeadline(time.Now().Add(natsTimeout))
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	var info struct {
+		TLSRequired bool `json:"tls_required"`
+	}
+	infoJSON, ok := strings.CutPrefix(strings.TrimSpace(line), "INFO ")
+	if !ok || json.Unmarshal([]byte(infoJSON), &info) != nil {
+		conn.Close()
+		return nil, fmt.Errorf("bad NATS greeting %q", line)
+	}
+	if info.TLSRequired {
+		conn.Close()
+		return nil, errors.New("NATS server requires TLS")
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	opts := map[string]interface{}{"verbose": false, "pedantic": false, "name": "gcstats", "lang": "go"}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			opts["user"], opts["pass"] = u.User.Username(), pass
+		} else {
+			opts["auth_token"] = u.User.Username()
+		}
+	}
+	connect, _ := json.Marshal(opts)
+	fmt.Fprintf(s.w, "CONNECT %s\r\n", connect)
+
+	go s.read(r)
+
+	// Wait for the server to accept the connection.
+	if err := s.flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// read processes messages from the server until the connection is
+// closed.
+func (s *NATSSink) read(r *bufio.Reader) {
+	var err error
+	for {
+		var line string
+		line, err = r.ReadString('\n')
+		if err != nil {
+			break
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PING":
+			s.mu.Lock()
+			s.w.WriteString("PONG\r\n")
+			s.w.Flush()
+			s.mu.Unlock()
+		case line == "PONG":
+			s.pongs <- struct{}{}
+		case strings.HasPrefix(line, "-ERR"):
+			err = fmt.Errorf("NATS server: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+		if err != nil {
+			break
+		}
+	}
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+	close(s.pongs)
+}
+
+// flush sends a PING and waits for the server's PONG, which
+// indicates that the server has processed everything sent before it.
+func (s *NATSSink) flush() error {
+	s.mu.Lock()
+	s.w.WriteString("PING\r\n")
+	err := s.w.Flush()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	select {
+	case _, ok := <-s.pongs:
+		if ok {
+			return nil
+		}
+	case <-time.After(natsTimeout):
+		return errors.New("timed out waiting for NATS server")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Publish publishes r as a JSON message.
+func (s *NATSSink) Publish(r *Record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	fmt.Fprintf(s.w, "PUB %s %d\r\n", s.subject, len(data))
+	s.w.Write(data)
+	s.w.WriteString("\r\n")
+	if err := s.w.Flush(); err != nil {
+		s.err = err
+	}
+	return s.err
+}
+
+// Close waits for the server to receive all published records and
+// closes the connection.
+func (s *NATSSink) Close() error {
+	err := s.flush()
+	if cerr := s.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
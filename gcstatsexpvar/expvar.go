@@ -0,0 +1,170 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gcstatsexpvar publishes live statistics from a GC trace
+// via expvar, so they can be scraped from /debug/vars.
+//
+// A typical use follows a growing trace:
+//
+//	p := gcstats.NewParser(f)
+//	p.Follow = true
+//	pub := gcstatsexpvar.Publish("gc", p)
+//	for {
+//		for pub.Next() {
+//		}
+//		if pub.Err() != nil {
+//			...
+//		}
+//		time.Sleep(time.Second)
+//	}
+package gcstatsexpvar
+
+import (
+	"encoding/json"
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// DefaultMMUWindows are the windows at which a Publisher publishes
+// the minimum mutator utilization if MMUWindows is nil.
+var DefaultMMUWindows = []time.Duration{time.Millisecond, 10 * time.Millisecond, 100 * time.Millisecond}
+
+// mmuInterval is the minimum time between updates of the utilization
+// figures while a Publisher is catching up on a trace. Computing
+// these takes time linear in the length of the trace.
+const mmuInterval = time.Second
+
+// A Publisher parses a GC trace and publishes its statistics as an
+// expvar.Var. The published value is a JSON object of the form
+//
+//	{
+//		"gcs": 17,
+//		"pauses": {"count": 33, "totalNS": ..., "maxNS": ..., "p50NS": ..., "p95NS": ..., "p99NS": ...},
+//		"mutatorUtilization": 0.89,
+//		"mmu": {"10ms": 0.51, ...}
+//	}
+//
+// where the utilization figures are present only if the trace has
+// program execution times.
+type Publisher struct {
+	// MMUWindows are the windows at which to publish the minimum
+	// mutator utilization. If nil, DefaultMMUWindows is used.
+	MMUWindows []time.Duration
+
+	p *gcstats.Parser
+
+	// nphases is the number of phases of the trace incorporated
+	// into pauses.
+	nphases int
+	pauses  gcstats.Pauses
+
+	// lastMMU is when the utilization figures were last updated.
+	lastMMU time.Time
+
+	// mu protects snap, which is read by the expvar.
+	mu   sync.Mutex
+	snap snapshot
+}
+
+type snapshot struct {
+	GCs                int                `json:"gcs"`
+	Pauses             pauses             `json:"pauses"`
+	MutatorUtilization *float64           `json:"mutatorUtilization,omitempty"`
+	MMU                map[string]float64 `json:"mmu,omitempty"`
+}
+
+type pauses struct {
+	Count   int   `json:"count"`
+	TotalNS int64 `json:"totalNS"`
+	MaxNS   int64 `json:"maxNS"`
+	P50NS   int64 `json:"p50NS"`
+	P95NS   int64 `json:"p95NS"`
+	P99NS   int64 `json:"p99NS"`
+}
+
+// NewPublisher returns a Publisher that reads the trace from p. The
+// caller must publish it with expvar.Publish.
+func NewPublisher(p *gcstats.Parser) *Publisher {
+	return &Publisher{p: p}
+}
+
+// Publish returns a Publisher that reads the trace from p and
+// publishes it as an expvar with the given name. Like
+// expvar.Publish, this panics if name is already registered.
+func Publish(name string, p *gcstats.Parser) *Publisher {
+	pub := NewPublisher(p)
+	expvar.Publish(name, pub)
+	return pub
+}
+
+// Next parses the next GC cycle using the Parser and updates the
+// published statistics. Like Parser.Next, it returns false at the
+// end of the available input or on error. The utilization figures
+// are updated when Next returns false, and otherwise at most once a
+// second.
+func (pub *Publisher) Next() bool {
+	more := pub.p.Next()
+	pub.update(!more || time.Since(pub.lastMMU) >= mmuInterval)
+	return more
+}
+
+// Err returns the first error encountered by the Parser, if any.
+func (pub *Publisher) Err() error {
+	return pub.p.Err()
+}
+
+// update incorporates newly parsed phases into the published
+// statistics. If utilization is true, it also recomputes the
+// utilization figures.
+func (pub *Publisher) update(utilization bool) {
+	s := pub.p.Stats()
+	phases := s.Phases()
+	if len(phases) == pub.nphases && !utilization {
+		return
+	}
+	pub.pauses.Add(phases[pub.nphases:])
+	pub.nphases = len(phases)
+
+	ps := &pub.pauses
+	snap := snapshot{GCs: s.Count()}
+	snap.Pauses = pauses{ps.Count(), ps.Total(), ps.Max(), ps.Percentile(0.5), ps.Percentile(0.95), ps.Percentile(0.99)}
+
+	// Only update modifies pub.snap, so it's safe to read without
+	// holding mu.
+	snap.MutatorUtilization, snap.MMU = pub.snap.MutatorUtilization, pub.snap.MMU
+	if !s.HaveProgTimes() {
+		snap.MutatorUtilization, snap.MMU = nil, nil
+	} else if utilization && len(phases) > 0 {
+		mu := s.MutatorUtilization()
+		snap.MutatorUtilization = &mu
+		windows := pub.MMUWindows
+		if windows == nil {
+			windows = DefaultMMUWindows
+		}
+		snap.MMU = make(map[string]float64, len(windows))
+		for _, w := range windows {
+			snap.MMU[w.String()] = s.MMU(int(w))
+		}
+		pub.lastMMU = time.Now()
+	}
+
+	pub.mu.Lock()
+	pub.snap = snap
+	pub.mu.Unlock()
+}
+
+// String returns the published statistics as JSON. This implements
+// expvar.Var.
+func (pub *Publisher) String() string {
+	pub.mu.Lock()
+	defer pub.mu.Unlock()
+	b, err := json.Marshal(pub.snap)
+	if err != nil {
+		return "null"
+	}
+	return string(b)
+}
@@ -0,0 +1,94 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstatsexpvar
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+func TestPublisher(t *testing.T) {
+	f, err := os.Open("../media/runtime-compile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	pub := Publish("gcstats-test", gcstats.NewParser(f))
+	if got := expvar.Get("gcstats-test").String(); got != `{"gcs":0,"pauses":{"count":0,"totalNS":0,"maxNS":0,"p50NS":0,"p95NS":0,"p99NS":0}}` {
+		t.Errorf("before parsing, got %s", got)
+	}
+	for pub.Next() {
+	}
+	if err := pub.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal([]byte(expvar.Get("gcstats-test").String()), &snap); err != nil {
+		t.Fatal(err)
+	}
+	s := pub.p.Stats()
+	if snap.GCs != s.Count() {
+		t.Errorf("want %d GCs, got %d", s.Count(), snap.GCs)
+	}
+	if want := len(s.Stops()); snap.Pauses.Count != want {
+		t.Errorf("want %d pauses, got %d", want, snap.Pauses.Count)
+	}
+	if want := s.MaxPause(); snap.Pauses.MaxNS != want {
+		t.Errorf("want max pause %d, got %d", want, snap.Pauses.MaxNS)
+	}
+	if snap.MutatorUtilization == nil || *snap.MutatorUtilization != s.MutatorUtilization() {
+		t.Errorf("want mutator utilization %v, got %v", s.MutatorUtilization(), snap.MutatorUtilization)
+	}
+	if want := s.MMU(10e6); snap.MMU["10ms"] != want {
+		t.Errorf("want 10ms MMU %v, got %v", want, snap.MMU["10ms"])
+	}
+}
+
+func TestPublisherOverlap(t *testing.T) {
+	// Cycle 2 begins before cycle 1 ends. Truncating it must not
+	// change the pauses of cycle 1, which have already been
+	// published when cycle 2 is parsed.
+	const log = `gc 1 @0.010s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 2 @0.015s 5%: 0.60+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+gc 3 @0.030s 5%: 0.039+0.80+2.4+1.7+0.62 ms clock, 0.11+0.80+0+0.005/1.3/7.3+1.8 ms cpu, 4->4->2 MB, 4 MB goal, 4 P
+`
+	// Follow the log as it grows a line at a time.
+	var buf bytes.Buffer
+	p := gcstats.NewParser(&buf)
+	p.Follow, p.OverlapPolicy = true, gcstats.OverlapTruncate
+	pub := NewPublisher(p)
+	for _, line := range strings.SplitAfter(log, "\n") {
+		buf.WriteString(line)
+		for pub.Next() {
+		}
+		if err := pub.Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	whole := gcstats.NewParserBytes([]byte(log))
+	whole.OverlapPolicy = gcstats.OverlapTruncate
+	for whole.Next() {
+	}
+	if err := whole.Err(); err != nil {
+		t.Fatal(err)
+	}
+	var ps gcstats.Pauses
+	for _, stop := range whole.Stats().Stops() {
+		ps.AddDuration(stop.Duration)
+	}
+	want := pauses{ps.Count(), ps.Total(), ps.Max(), ps.Percentile(0.5), ps.Percentile(0.95), ps.Percentile(0.99)}
+	if pub.snap.Pauses != want {
+		t.Errorf("want pauses %+v, got %+v", want, pub.snap.Pauses)
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstatshttp
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+// GrafanaTrace is a GC trace served by a GrafanaHandler.
+type GrafanaTrace struct {
+	// Name identifies the trace in metric names.
+	Name string
+
+	Stats *gcstats.GcStats
+
+	// Start is the wall-clock time at which the traced program
+	// started. Times in the trace are relative to Start.
+	Start time.Time
+}
+
+// grafanaMetrics are the time series served for each trace.
+var grafanaMetrics = []struct {
+	name     string
+	progTime bool // requires program execution times
+}{
+	{"pause_ms", false},
+	{"heap_trigger_mb", false},
+	{"heap_live_mb", false},
+	{"heap_goal_mb", false},
+	{"gcs", false},
+	{"utilization", true},
+}
+
+// GrafanaHandler is an http.Handler implementing the Grafana
+// SimpleJSON datasource API for a set of GC traces. Configure a
+// SimpleJSON (or compatible) datasource with the URL of the handler.
+//
+// Each trace provides the metrics <name>:pause_ms (total STW time of
+// each cycle), <name>:heap_trigger_mb, <name>:heap_live_mb,
+// <name>:heap_goal_mb, <name>:gcs (GC cycles per query interval), and
+// <name>:utilization (mean mutator utilization per query interval).
+// Table queries for <name>:cycles return one row per GC cycle.
+type GrafanaHandler struct {
+	traces []GrafanaTrace
+}
+
+// NewGrafanaHandler returns a GrafanaHandler serving traces.
+func NewGrafanaHandler(traces ...GrafanaTrace) *GrafanaHandler {
+	return &GrafanaHandler{traces}
+}
+
+func (h *GrafanaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "", "/":
+		// Grafana tests the datasource with a GET of /.
+		w.Write([]byte("OK\n"))
+	case "/search":
+		h.serveSearch(w, r)
+	case "/query":
+		h.serveQuery(w, r)
+	case "/annotations":
+		writeJSON(w, []struct{}{})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *GrafanaHandler) serveSearch(w http.ResponseWriter, r *http.Request) {
+	names := []string{}
+	for _, tr := range h.traces {
+		for _, m := range grafanaMetrics {
+			if !m.progTime || tr.Stats.HaveProgTimes() {
+				names = append(names, tr.Name+":"+m.name)
+			}
+		}
+		names = append(names, tr.Name+":cycles")
+	}
+	writeJSON(w, names)
+}
+
+type grafanaQuery struct {
+	Range struct {
+		From, To time.Time
+	}
+	IntervalMs    int64
+	MaxDataPoints int
+	Targets       []struct {
+		Target string
+		Type   string
+	}
+}
+
+// grafanaSeries is a time series response. Each datapoint is a
+// [value, Unix milliseconds] pair.
+type grafanaSeries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+type grafanaTable struct {
+	Type    string          `json:"type"`
+	Columns []grafanaColumn `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+type grafanaColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+func (h *GrafanaHandler) serveQuery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var q grafanaQuery
+	if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.IntervalMs <= 0 {
+		q.IntervalMs = 1000
+	}
+
+	results := []interface{}{}
+	for _, target := range q.Targets {
+		name, metric, _ := strings.Cut(target.Target, ":")
+		var tr *GrafanaTrace
+		for i := range h.traces {
+			if h.traces[i].Name == name {
+				tr = &h.traces[i]
+			}
+		}
+		if tr == nil {
+			http.Error(w, "unknown trace: "+name, http.StatusBadRequest)
+			return
+		}
+		if metric == "cycles" {
+			results = append(results, tr.cycleTable(q))
+			continue
+		}
+		series, ok := tr.series(metric, q)
+		if !ok {
+			http.Error(w, "unknown metric: "+target.Target, http.StatusBadRequest)
+			return
+		}
+		results = append(results, grafanaSeries{target.Target, series})
+	}
+	writeJSON(w, results)
+}
+
+// ms returns the wall-clock time of trace time t in Unix milliseconds.
+func (tr *GrafanaTrace) ms(t int64) float64 {
+	return float64(tr.Start.UnixNano()+t) / 1e6
+}
+
+// trange returns the query range in trace time.
+func (tr *GrafanaTrace) trange(q grafanaQuery) (from, to int64) {
+	return q.Range.From.Sub(tr.Start).Nanoseconds(), q.Range.To.Sub(tr.Start).Nanoseconds()
+}
+
+// cyclePauses returns the total STW time of each GC cycle, indexed
+// by cycle number.
+func (tr *GrafanaTrace) cyclePauses() map[int]int64 {
+	pauses := make(map[int]int64)
+	for _, p := range tr.Stats.Phases() {
+		if p.STW {
+			pauses[p.N] += p.Duration
+		}
+	}
+	return pauses
+}
+
+func (tr *GrafanaTrace) series(metric string, q grafanaQuery) ([][2]float64, bool) {
+	s := tr.Stats
+	from, to := tr.trange(q)
+	points := [][2]float64{}
+
+	var perCycle func(c gcstats.Cycle) float64
+	switch metric {
+	case "pause_ms":
+		pauses := tr.cyclePauses()
+		perCycle = func(c gcstats.Cycle) float64 { return float64(pauses[c.N]) / 1e6 }
+	case "heap_trigger_mb":
+		perCycle = func(c gcstats.Cycle) float64 { return float64(c.HeapTrigger) / (1 << 20) }
+	case "heap_live_mb":
+		perCycle = func(c gcstats.Cycle) float64 { return float64(c.HeapLive) / (1 << 20) }
+	case "heap_goal_mb":
+		perCycle = func(c gcstats.Cycle) float64 { return float64(c.HeapGoal) / (1 << 20) }
+	}
+	if perCycle != nil {
+		cycles := s.Cycles()
+		i := sort.Search(len(cycles), func(i int) bool { return cycles[i].Begin >= from })
+		for ; i < len(cycles) && cycles[i].Begin <= to; i++ {
+			points = append(points, [2]float64{perCycle(cycles[i]), tr.ms(cycles[i].Begin)})
+		}
+		return downsample(points, q.MaxDataPoints), true
+	}
+
+	interval := q.IntervalMs * 1e6
+	switch metric {
+	case "gcs":
+		counts := make(map[int64]int)
+		for _, c := range s.Cycles() {
+			if c.Begin >= from && c.Begin <= to {
+				counts[(c.Begin-from)/interval]++
+			}
+		}
+		for t := from; t <= to; t += interval {
+			points = append(points, [2]float64{float64(counts[(t-from)/interval]), tr.ms(t)})
+		}
+	case "utilization":
+		if !s.HaveProgTimes() {
+			return nil, false
+		}
+		for t := from; t <= to; t += interval {
+			if mu := s.MutatorUtilizationBetween(t, t+interval); !math.IsNaN(mu) {
+				points = append(points, [2]float64{mu, tr.ms(t)})
+			}
+		}
+	default:
+		return nil, false
+	}
+	return points, true
+}
+
+// downsample reduces points to at most max points by taking the
+// maximum of consecutive groups, so spikes remain visible.
+func downsample(points [][2]float64, max int) [][2]float64 {
+	if max <= 0 || len(points) <= max {
+		return points
+	}
+	group := (len(points) + max - 1) / max
+	out := points[:0]
+	for i := 0; i < len(points); i += group {
+		p := points[i]
+		for _, p2 := range points[i:min(i+group, len(points))] {
+			p[0] = math.Max(p[0], p2[0])
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+func (tr *GrafanaTrace) cycleTable(q grafanaQuery) grafanaTable {
+	from, to := tr.trange(q)
+	t := grafanaTable{
+		Type: "table",
+		Columns: []grafanaColumn{
+			{"Time", "time"}, {"GC", "number"}, {"Pause (ms)", "number"},
+			{"Heap trigger (MB)", "number"}, {"Heap live (MB)", "number"}, {"Heap goal (MB)", "number"},
+		},
+		Rows: [][]interface{}{},
+	}
+	pauses := tr.cyclePauses()
+	for _, c := range tr.Stats.Cycles() {
+		if c.Begin < from || c.Begin > to {
+			continue
+		}
+		t.Rows = append(t.Rows, []interface{}{tr.ms(c.Begin), c.N, float64(pauses[c.N]) / 1e6, c.HeapTrigger >> 20, c.HeapLive >> 20, c.HeapGoal >> 20})
+	}
+	return t
+}
@@ -0,0 +1,117 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstatshttp
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+func TestGrafanaHandler(t *testing.T) {
+	data, err := ioutil.ReadFile("../media/runtime-compile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := gcstats.NewFromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2015, 8, 1, 0, 0, 0, 0, time.UTC)
+	h := NewGrafanaHandler(GrafanaTrace{"compile", s, start})
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(body)))
+		return w
+	}
+
+	if w := get(t, h, "/"); w.Code != http.StatusOK {
+		t.Errorf("GET /: status %d", w.Code)
+	}
+
+	var names []string
+	if err := json.Unmarshal(post("/search", `{"target":""}`).Body.Bytes(), &names); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != len(grafanaMetrics)+1 || names[0] != "compile:pause_ms" {
+		t.Errorf("bad search result %v", names)
+	}
+
+	// Query the whole trace.
+	last := s.Phases()[len(s.Phases())-1]
+	end := start.Add(time.Duration(last.Begin + last.Duration))
+	query := `{"range":{"from":"` + start.Format(time.RFC3339Nano) + `","to":"` + end.Format(time.RFC3339Nano) + `"},"intervalMs":100,"maxDataPoints":1000,"targets":[{"target":"compile:pause_ms"},{"target":"compile:gcs"},{"target":"compile:utilization"},{"target":"compile:cycles","type":"table"}]}`
+	w := post("/query", query)
+	if w.Code != http.StatusOK {
+		t.Fatalf("POST /query: status %d: %s", w.Code, w.Body)
+	}
+	var res []json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 4 {
+		t.Fatalf("want 4 results, got %d", len(res))
+	}
+	var pauses, gcs, util grafanaSeries
+	var table grafanaTable
+	json.Unmarshal(res[0], &pauses)
+	json.Unmarshal(res[1], &gcs)
+	json.Unmarshal(res[2], &util)
+	json.Unmarshal(res[3], &table)
+
+	if len(pauses.Datapoints) != s.Count() {
+		t.Errorf("want %d pause points, got %d", s.Count(), len(pauses.Datapoints))
+	}
+	var maxPause, total float64
+	for _, p := range pauses.Datapoints {
+		maxPause = max(maxPause, p[0])
+	}
+	if want := float64(s.MaxPause()) / 1e6; maxPause < want {
+		t.Errorf("max pause %gms less than longest STW phase %gms", maxPause, want)
+	}
+	for _, p := range gcs.Datapoints {
+		total += p[0]
+	}
+	if int(total) != s.Count() {
+		t.Errorf("want %d GCs, got %g", s.Count(), total)
+	}
+	for _, p := range util.Datapoints {
+		if p[0] < 0 || p[0] > 1 {
+			t.Errorf("utilization %g out of range at %g", p[0], p[1])
+		}
+	}
+	if len(util.Datapoints) == 0 {
+		t.Errorf("no utilization points")
+	}
+	if len(table.Rows) != s.Count() {
+		t.Errorf("want %d table rows, got %d", s.Count(), len(table.Rows))
+	}
+
+	// Downsampling keeps the maximum pause.
+	w = post("/query", strings.Replace(query, `"maxDataPoints":1000`, `"maxDataPoints":4`, 1))
+	json.Unmarshal(w.Body.Bytes(), &res)
+	json.Unmarshal(res[0], &pauses)
+	if len(pauses.Datapoints) > 4 {
+		t.Errorf("want at most 4 points, got %d", len(pauses.Datapoints))
+	}
+	maxPause = 0
+	for _, p := range pauses.Datapoints {
+		maxPause = max(maxPause, p[0])
+	}
+	if want := float64(s.MaxPause()) / 1e6; maxPause < want {
+		t.Errorf("downsampled max pause %gms less than %gms", maxPause, want)
+	}
+
+	if w := post("/query", `{"targets":[{"target":"nope:pause_ms"}]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown trace: want status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,398 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gcstatshttp serves analyses of Go garbage collection traces
+// over HTTP.
+//
+// A Handler serves an HTML page summarizing a trace, along with the
+// individual analyses as JSON and SVG plots. The trace can be
+// configured when the Handler is created or uploaded by users. A
+// Handler can be mounted at any path prefix using http.StripPrefix:
+//
+//	http.Handle("/debug/gc/", http.StripPrefix("/debug/gc", gcstatshttp.NewHandler(nil)))
+package gcstatshttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/gcstats/report"
+)
+
+// DefaultMaxUploadBytes is the default limit on the size of uploaded
+// traces.
+const DefaultMaxUploadBytes = 64 << 20
+
+// Handler is an http.Handler that serves analyses of a GC trace. It
+// serves the following paths:
+//
+//	/               HTML overview of the trace and upload form
+//	/summary.json   summary statistics
+//	/phases.json    all phases of the trace; takes ?max=
+//	/cycles.json    all GC cycles of the trace, including heap sizes; takes ?max=
+//	/timeline       interactive timeline of GC cycles and heap size
+//	/mmu.json       minimum mutator utilization curve
+//	/mmu.svg        plot of /mmu.json
+//	/mud.json       mutator utilization distribution; takes ?window=
+//	/mud.svg        plot of /mud.json
+//
+// Every path but / and /timeline also takes ?begin= and ?end= in
+// nanoseconds, which restrict the analysis to that range of the run
+// using gcstats.GcStats.Slice. The timeline selects ranges this way.
+//
+// With ?max=n, phases.json and cycles.json describe the trace
+// downsampled by gcstats.GcStats.Downsample to at most n phases.
+//
+// The JSON documents are defined by package report and carry a
+// schema_version field.
+//
+// If uploads are enabled, a POST to / replaces the trace with the
+// request body, which is either the raw trace or a multipart form
+// with the trace in the "trace" field.
+type Handler struct {
+	// AllowUpload enables replacing the trace by uploading a new
+	// one. NewHandler enables this if it's given no trace.
+	AllowUpload bool
+
+	// MaxUploadBytes limits the size of uploaded traces. If 0,
+	// DefaultMaxUploadBytes is used.
+	MaxUploadBytes int64
+
+	// Labels are name=value labels describing the trace, which
+	// are included in summary.json.
+	Labels map[string]string
+
+	mu    sync.Mutex
+	stats *gcstats.GcStats
+}
+
+// NewHandler returns a Handler that serves analyses of s. If s is
+// nil, the Handler has no trace until one is uploaded.
+func NewHandler(s *gcstats.GcStats) *Handler {
+	return &Handler{AllowUpload: s == nil, stats: s}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var f http.HandlerFunc
+	switch r.URL.Path {
+	case "", "/":
+		if r.Method == "POST" {
+			h.serveUpload(w, r)
+			return
+		}
+		f = h.serveIndex
+	case "/summary.json":
+		f = h.withStats(h.serveSummary)
+	case "/phases.json":
+		f = h.withStats(h.servePhases)
+	case "/cycles.json":
+		f = h.withStats(h.serveCycles)
+	case "/timeline":
+		f = h.withProgTimes(h.serveTimeline)
+	case "/mmu.json", "/mmu.svg":
+		f = h.withProgTimes(h.serveMMU)
+	case "/mud.json", "/mud.svg":
+		f = h.withProgTimes(h.serveMUD)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	f(w, r)
+}
+
+// Stats returns the trace currently served by h, or nil if there is
+// none.
+func (h *Handler) Stats() *gcstats.GcStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.stats
+}
+
+// SetStats replaces the trace served by h.
+func (h *Handler) SetStats(s *gcstats.GcStats) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stats = s
+}
+
+type statsHandler func(w http.ResponseWriter, r *http.Request, s *gcstats.GcStats)
+
+// withStats wraps f to fail if h has no trace and to pass it the
+// range of the trace selected by the request's begin and end
+// parameters, if any.
+func (h *Handler) withStats(f statsHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s := h.Stats()
+		if s == nil || len(s.Phases()) == 0 {
+			http.Error(w, "no GC trace loaded", http.StatusNotFound)
+			return
+		}
+		bs, es := r.FormValue("begin"), r.FormValue("end")
+		if bs != "" || es != "" {
+			if !s.HaveProgTimes() {
+				http.Error(w, "GC trace lacks program execution times", http.StatusUnprocessableEntity)
+				return
+			}
+			begin, end := int64(math.MinInt64), int64(math.MaxInt64)
+			var err error
+			if bs != "" {
+				begin, err = strconv.ParseInt(bs, 10, 64)
+			}
+			if err == nil && es != "" {
+				end, err = strconv.ParseInt(es, 10, 64)
+			}
+			if err != nil || begin >= end {
+				http.Error(w, "bad range: "+bs+" to "+es, http.StatusBadRequest)
+				return
+			}
+			if s = s.Slice(begin, end); len(s.Phases()) == 0 {
+				http.Error(w, "no GC trace in range", http.StatusNotFound)
+				return
+			}
+		}
+		f(w, r, s)
+	}
+}
+
+// withProgTimes wraps f to fail if h's trace has no program
+// execution times, which are required for mutator utilization
+// analyses.
+func (h *Handler) withProgTimes(f statsHandler) http.HandlerFunc {
+	return h.withStats(func(w http.ResponseWriter, r *http.Request, s *gcstats.GcStats) {
+		if !s.HaveProgTimes() {
+			http.Error(w, "GC trace lacks program execution times", http.StatusUnprocessableEntity)
+			return
+		}
+		f(w, r, s)
+	})
+}
+
+func (h *Handler) serveUpload(w http.ResponseWriter, r *http.Request) {
+	if !h.AllowUpload {
+		http.Error(w, "uploads are disabled", http.StatusForbidden)
+		return
+	}
+	max := h.MaxUploadBytes
+	if max == 0 {
+		max = DefaultMaxUploadBytes
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, max)
+
+	var trace io.Reader = r.Body
+	if f, _, err := r.FormFile("trace"); err == nil {
+		defer f.Close()
+		trace = f
+	} else if err != http.ErrNotMultipart {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s, err := gcstats.NewFromLog(trace)
+	if err != nil {
+		http.Error(w, "error parsing log: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(s.Phases()) == 0 {
+		http.Error(w, "no GC recorded; did you set GODEBUG=gctrace=1?", http.StatusBadRequest)
+		return
+	}
+	h.SetStats(s)
+	http.Redirect(w, r, "./", http.StatusSeeOther)
+}
+
+var indexTemplate = template.Must(template.New("index").Funcs(template.FuncMap{
+	"mul100": func(x float64) float64 { return 100 * x },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><title>GC trace analysis</title></head>
+<body>
+{{with .Summary}}
+<h1>GC trace analysis</h1>
+<p>{{.Cycles}} GCs, max pause {{$.Duration .MaxPauseNS}}{{if .ProgTimes}}, mean mutator utilization {{printf "%.1f%%" (mul100 .MutatorUtilization)}}{{end}}.
+<a href="summary.json">summary.json</a>, <a href="phases.json">phases.json</a>, <a href="cycles.json">cycles.json</a>{{if .ProgTimes}}, <a href="timeline">timeline</a>{{end}}</p>
+{{with .Env}}<p>Recorded with{{range $name, $val := .}} {{$name}}={{$val}}{{end}}.</p>{{end}}
+<table>
+<tr><th>Stop</th><th>Count</th><th>Max</th><th>99%ile</th><th>95%ile</th><th>Mean</th></tr>
+{{range $kind, $stop := .Stops}}<tr><td>{{$kind}}</td><td>{{$stop.Count}}</td><td>{{$.Duration $stop.MaxNS}}</td><td>{{$.Duration $stop.P99NS}}</td><td>{{$.Duration $stop.P95NS}}</td><td>{{$.Duration $stop.MeanNS}}</td></tr>
+{{end}}</table>
+{{if .ProgTimes}}
+<h2>Minimum mutator utilization</h2>
+<p><img src="mmu.svg"> <a href="mmu.json">mmu.json</a></p>
+<h2>Mutator utilization distribution</h2>
+<p><img src="mud.svg"> <a href="mud.json">mud.json</a></p>
+{{end}}
+{{else}}
+<p>No GC trace loaded.</p>
+{{end}}
+{{if .AllowUpload}}
+<form method="post" enctype="multipart/form-data">
+Upload a GODEBUG=gctrace=1 trace: <input type="file" name="trace"> <input type="submit" value="Analyze">
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+type indexData struct {
+	Summary     *report.Summary
+	AllowUpload bool
+}
+
+func (indexData) Duration(ns int64) time.Duration {
+	return time.Duration(ns)
+}
+
+func (h *Handler) serveIndex(w http.ResponseWriter, r *http.Request) {
+	data := indexData{AllowUpload: h.AllowUpload}
+	if s := h.Stats(); s != nil && len(s.Phases()) > 0 {
+		data.Summary = report.NewSummary(s)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := indexTemplate.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) serveSummary(w http.ResponseWriter, r *http.Request, s *gcstats.GcStats) {
+	sum := report.NewSummary(s)
+	sum.Labels = h.Labels
+	writeJSON(w, sum)
+}
+
+// downsampleStats returns s downsampled to the number of phases given by
+// the request's max parameter, if any. It reports an error to w and
+// returns nil if the parameter is malformed.
+func downsampleStats(w http.ResponseWriter, r *http.Request, s *gcstats.GcStats) *gcstats.GcStats {
+	ms := r.FormValue("max")
+	if ms == "" {
+		return s
+	}
+	n, err := strconv.Atoi(ms)
+	if err != nil || n < 2 {
+		http.Error(w, "bad max: "+ms, http.StatusBadRequest)
+		return nil
+	}
+	return s.Downsample(n)
+}
+
+func (h *Handler) servePhases(w http.ResponseWriter, r *http.Request, s *gcstats.GcStats) {
+	if s = downsampleStats(w, r, s); s == nil {
+		return
+	}
+	phases := make([]report.Phase, len(s.Phases()))
+	for i, p := range s.Phases() {
+		phases[i] = report.Phase{
+			BeginNS:    p.Begin,
+			DurationNS: p.Duration,
+			Kind:       p.Kind.String(),
+			N:          p.N,
+			Gomaxprocs: p.Gomaxprocs,
+			GCProcs:    p.GCProcs,
+			STW:        p.STW,
+		}
+	}
+	writeJSON(w, report.Phases{SchemaVersion: report.SchemaVersion, Phases: phases, Env: report.Env(s)})
+}
+
+func (h *Handler) serveCycles(w http.ResponseWriter, r *http.Request, s *gcstats.GcStats) {
+	if s = downsampleStats(w, r, s); s == nil {
+		return
+	}
+	cycles := make([]report.Cycle, len(s.Cycles()))
+	for i, c := range s.Cycles() {
+		cycles[i] = report.Cycle{
+			N:             c.N,
+			BeginNS:       c.Begin,
+			HeapTriggerMB: c.HeapTrigger >> 20,
+			HeapMarkedMB:  c.HeapMarked >> 20,
+			HeapLiveMB:    c.HeapLive >> 20,
+			HeapGoalMB:    c.HeapGoal >> 20,
+			Cause:         c.Cause.String(),
+		}
+	}
+	writeJSON(w, report.Cycles{SchemaVersion: report.SchemaVersion, Cycles: cycles, Env: report.Env(s)})
+}
+
+func (h *Handler) serveMMU(w http.ResponseWriter, r *http.Request, s *gcstats.GcStats) {
+	// Sample the MMU at logarithmically spaced windows from 1ms
+	// to 1s.
+	const samples = 100
+	var c report.Curve
+	windows := make([]int, samples)
+	for i := range windows {
+		window := math.Pow(10, -3+3*float64(i)/(samples-1))
+		c.X = append(c.X, window)
+		windows[i] = int(window * 1e9)
+	}
+	c.Y = s.MMUs(windows)
+
+	if r.URL.Path == "/mmu.svg" {
+		p := &svgPlot{XLabel: "window (sec)", YLabel: "mutator utilization", LogX: true}
+		p.add("MMU", c.X, c.Y)
+		writeSVG(w, p)
+		return
+	}
+	writeJSON(w, report.MMU{SchemaVersion: report.SchemaVersion, WindowSec: c})
+}
+
+func (h *Handler) serveMUD(w http.ResponseWriter, r *http.Request, s *gcstats.GcStats) {
+	window := 10 * time.Millisecond
+	if ws := r.FormValue("window"); ws != "" {
+		var err error
+		window, err = time.ParseDuration(ws)
+		if err != nil || window <= 0 {
+			http.Error(w, "bad window: "+ws, http.StatusBadRequest)
+			return
+		}
+	}
+	mud := s.MutatorUtilizationDistribution(int(window))
+
+	var cdf report.Curve
+	for i := 0; i <= 100; i++ {
+		util := float64(i) / 100
+		cdf.X = append(cdf.X, util)
+		cdf.Y = append(cdf.Y, mud.CDF(util))
+	}
+
+	if r.URL.Path == "/mud.svg" {
+		p := &svgPlot{XLabel: fmt.Sprintf("mutator utilization at %s", window), YLabel: "cumulative probability"}
+		p.add("", cdf.X, cdf.Y)
+		writeSVG(w, p)
+		return
+	}
+	pctiles := make(map[string]float64)
+	for _, p := range []float64{0, 0.001, 0.01, 0.1, 0.5} {
+		pctiles[strconv.FormatFloat(100*p, 'g', -1, 64)] = mud.InvCDF(p)
+	}
+	writeJSON(w, report.MUD{
+		SchemaVersion: report.SchemaVersion,
+		WindowNS:      int64(window),
+		CDF:           cdf,
+		Percentiles:   pctiles,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	enc.Encode(v)
+}
+
+func writeSVG(w http.ResponseWriter, p *svgPlot) {
+	w.Header().Set("Content-Type", "image/svg+xml")
+	p.writeTo(w)
+}
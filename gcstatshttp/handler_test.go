@@ -0,0 +1,141 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstatshttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aclements/go-gcstats/gcstats"
+	"github.com/aclements/go-gcstats/gcstats/report"
+)
+
+func get(t *testing.T, h http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	data, err := ioutil.ReadFile("../media/runtime-compile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := gcstats.NewFromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(s)
+	h.Labels = map[string]string{"gogc": "200"}
+
+	for _, path := range []string{"/", "/summary.json", "/phases.json", "/cycles.json", "/timeline", "/mmu.json", "/mmu.svg", "/mud.json", "/mud.svg?window=50ms"} {
+		w := get(t, h, path)
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s: status %d: %s", path, w.Code, w.Body)
+		}
+	}
+
+	var sum report.Summary
+	if err := json.Unmarshal(get(t, h, "/summary.json").Body.Bytes(), &sum); err != nil {
+		t.Fatal(err)
+	}
+	if sum.SchemaVersion != report.SchemaVersion || sum.Cycles != s.Count() || sum.MaxPauseNS != s.MaxPause() || sum.Stops["all"].MaxNS != s.MaxPause() || sum.Labels["gogc"] != "200" || sum.Pacing != "heap" {
+		t.Errorf("bad summary %+v", sum)
+	}
+
+	var cycles report.Cycles
+	if err := json.Unmarshal(get(t, h, "/cycles.json").Body.Bytes(), &cycles); err != nil {
+		t.Fatal(err)
+	}
+	if cycles.SchemaVersion != report.SchemaVersion || len(cycles.Cycles) != s.Count() {
+		t.Errorf("bad cycles.json: version %d, %d cycles", cycles.SchemaVersion, len(cycles.Cycles))
+	}
+
+	if w := get(t, h, "/mud.json?window=bad"); w.Code != http.StatusBadRequest {
+		t.Errorf("GET with bad window: want status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	var phases report.Phases
+	if err := json.Unmarshal(get(t, h, "/phases.json?max=8").Body.Bytes(), &phases); err != nil {
+		t.Fatal(err)
+	}
+	if len(phases.Phases) == 0 || len(phases.Phases) > 8 {
+		t.Errorf("want 1 to 8 downsampled phases, got %d", len(phases.Phases))
+	}
+	for _, max := range []string{"0", "1"} {
+		if w := get(t, h, "/phases.json?max="+max); w.Code != http.StatusBadRequest {
+			t.Errorf("GET with max=%s: want status %d, got %d", max, http.StatusBadRequest, w.Code)
+		}
+	}
+
+	all := s.Phases()
+	mid := all[len(all)/2].Begin
+	var half report.Summary
+	if err := json.Unmarshal(get(t, h, fmt.Sprintf("/summary.json?begin=0&end=%d", mid)).Body.Bytes(), &half); err != nil {
+		t.Fatal(err)
+	}
+	if half.Cycles == 0 || half.Cycles >= s.Count() {
+		t.Errorf("want some of %d cycles in the first half, got %d", s.Count(), half.Cycles)
+	}
+	for _, path := range []string{"/summary.json?begin=5&end=1", "/mmu.svg?begin=x"} {
+		if w := get(t, h, path); w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: want status %d, got %d", path, http.StatusBadRequest, w.Code)
+		}
+	}
+
+	// Uploads are disabled for a configured trace.
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/", bytes.NewReader(data)))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("POST: want status %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+func TestHandlerUpload(t *testing.T) {
+	data, err := ioutil.ReadFile("../media/runtime-compile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(nil)
+	if w := get(t, h, "/summary.json"); w.Code != http.StatusNotFound {
+		t.Errorf("GET with no trace: want status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	if w := get(t, h, "/"); !strings.Contains(w.Body.String(), "<form") {
+		t.Errorf("index page missing upload form:\n%s", w.Body)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("trace", "trace.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(data)
+	mw.Close()
+	req := httptest.NewRequest("POST", "/", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("POST: want status %d, got %d: %s", http.StatusSeeOther, w.Code, w.Body)
+	}
+	if s := h.Stats(); s == nil || s.Count() != 17 {
+		t.Errorf("uploaded trace not loaded")
+	}
+
+	// Raw uploads that aren't GC traces are rejected.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/", strings.NewReader("hello\n")))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("POST non-trace: want status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstatshttp
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+)
+
+// svgPlot is a simple line plot rendered as SVG. The Y axis always
+// spans [0, 1].
+type svgPlot struct {
+	XLabel, YLabel string
+	LogX           bool
+
+	series []svgSeries
+}
+
+type svgSeries struct {
+	label  string
+	xs, ys []float64
+}
+
+// Plot geometry in pixels.
+const (
+	svgWidth, svgHeight = 640, 400
+	svgLeft, svgRight   = 60, 20
+	svgTop, svgBottom   = 20, 50
+)
+
+var svgColors = []string{"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728"}
+
+func (p *svgPlot) add(label string, xs, ys []float64) {
+	p.series = append(p.series, svgSeries{label, xs, ys})
+}
+
+func (p *svgPlot) writeTo(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	// Compute the X range across all series.
+	xlo, xhi := math.Inf(1), math.Inf(-1)
+	for _, s := range p.series {
+		for _, x := range s.xs {
+			xlo, xhi = math.Min(xlo, x), math.Max(xhi, x)
+		}
+	}
+	tx := func(x float64) float64 { return x }
+	if p.LogX {
+		tx = math.Log10
+	}
+	xlo, xhi = tx(xlo), tx(xhi)
+	if !(xlo < xhi) {
+		xlo, xhi = xlo-1, xhi+1
+	}
+	pw := float64(svgWidth - svgLeft - svgRight)
+	ph := float64(svgHeight - svgTop - svgBottom)
+	px := func(x float64) float64 { return svgLeft + (tx(x)-xlo)/(xhi-xlo)*pw }
+	py := func(y float64) float64 { return svgTop + (1-y)*ph }
+
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="12">`+"\n", svgWidth, svgHeight)
+	fmt.Fprintf(bw, `<rect x="%d" y="%d" width="%g" height="%g" fill="none" stroke="black"/>`+"\n", svgLeft, svgTop, pw, ph)
+
+	// Y ticks.
+	for i := 0; i <= 4; i++ {
+		y := float64(i) / 4
+		fmt.Fprintf(bw, `<line x1="%d" y1="%g" x2="%g" y2="%g" stroke="#ddd"/>`+"\n", svgLeft, py(y), svgLeft+pw, py(y))
+		fmt.Fprintf(bw, `<text x="%d" y="%g" text-anchor="end" dominant-baseline="middle">%g</text>`+"\n", svgLeft-5, py(y), y)
+	}
+	// X ticks at each power of 10 for log scales, or at quarters
+	// for linear scales.
+	var xticks []float64
+	if p.LogX {
+		for e := math.Ceil(xlo); e <= xhi; e++ {
+			xticks = append(xticks, math.Pow(10, e))
+		}
+	} else {
+		for i := 0; i <= 4; i++ {
+			xticks = append(xticks, xlo+(xhi-xlo)*float64(i)/4)
+		}
+	}
+	for _, x := range xticks {
+		fmt.Fprintf(bw, `<line x1="%g" y1="%d" x2="%g" y2="%g" stroke="#ddd"/>`+"\n", px(x), svgTop, px(x), svgTop+ph)
+		fmt.Fprintf(bw, `<text x="%g" y="%g" text-anchor="middle">%g</text>`+"\n", px(x), svgTop+ph+15, x)
+	}
+
+	// Axis labels.
+	fmt.Fprintf(bw, `<text x="%g" y="%d" text-anchor="middle">%s</text>`+"\n", svgLeft+pw/2, svgHeight-10, html.EscapeString(p.XLabel))
+	fmt.Fprintf(bw, `<text transform="translate(15,%g) rotate(-90)" text-anchor="middle">%s</text>`+"\n", svgTop+ph/2, html.EscapeString(p.YLabel))
+
+	// Series.
+	for i, s := range p.series {
+		color := svgColors[i%len(svgColors)]
+		fmt.Fprintf(bw, `<polyline fill="none" stroke="%s" stroke-width="2" points="`, color)
+		for j := range s.xs {
+			fmt.Fprintf(bw, "%.1f,%.1f ", px(s.xs[j]), py(s.ys[j]))
+		}
+		fmt.Fprint(bw, `"/>`+"\n")
+		if s.label != "" {
+			fmt.Fprintf(bw, `<text x="%d" y="%d" fill="%s">%s</text>`+"\n", svgLeft+10, svgTop+15*(i+1), color, html.EscapeString(s.label))
+		}
+	}
+
+	fmt.Fprint(bw, "</svg>\n")
+	return bw.Flush()
+}
@@ -0,0 +1,247 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gcstatshttp
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/aclements/go-gcstats/gcstats"
+)
+
+func (h *Handler) serveTimeline(w http.ResponseWriter, r *http.Request, s *gcstats.GcStats) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, timelineHTML)
+}
+
+// timelineHTML is a self-contained page that renders phases.json
+// and cycles.json as a zoomable timeline. Scrolling zooms around the
+// cursor, dragging pans, and double-clicking resets the view.
+// Shift-dragging selects a range of the run, for which the page shows
+// the pause statistics, MMU, and MUD below the timeline.
+const timelineHTML = `<!DOCTYPE html>
+<html>
+<head>
+<title>GC timeline</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+canvas { border: 1px solid #ccc; cursor: grab; }
+#tip { position: absolute; background: #ffe; border: 1px solid #999; padding: 2px 4px; font-size: 12px; pointer-events: none; display: none; }
+.key span { display: inline-block; width: 1em; height: 1em; vertical-align: middle; margin: 0 0.2em 0 1em; }
+</style>
+</head>
+<body>
+<h1>GC timeline</h1>
+<p>Scroll to zoom, drag to pan, shift-drag to analyze a range, double-click to reset. <a href="./">Back to summary</a></p>
+<p class="key" id="key"></p>
+<canvas id="tl" width="1200" height="330"></canvas>
+<div id="tip"></div>
+<h2 id="range">Whole run</h2>
+<p id="pauses"></p>
+<p><img id="mmu"> <img id="mud"></p>
+<script>
+"use strict";
+const kinds = ["SweepTerm", "Scan", "InstallWB", "Mark", "MarkTerm", "Sweep", "Multiple"];
+const colors = ["#d62728", "#1f77b4", "#9467bd", "#2ca02c", "#d62728", "#dddddd", "#7f7f7f"];
+// Cycles alternate between two shades of the color of their cause.
+const causeColors = {paced: ["#6b9fd4", "#aec7e8"], forced: ["#e6550d", "#fdae6b"], limit: ["#756bb1", "#bcbddc"]};
+// Long traces are downsampled so they stay responsive, keeping
+// their longest pauses.
+const maxPhases = 20000;
+const canvas = document.getElementById("tl"), ctx = canvas.getContext("2d");
+const tip = document.getElementById("tip");
+const heapTop = 10, heapH = 170, cycleTop = 195, cycleH = 20, phaseTop = 225, phaseH = 40, axisTop = 275;
+let phases = [], cycles = [], heap = [], t0 = 0, t1 = 1, tMin = 0, tMax = 1, heapMax = 1;
+// sel is the selected range of the run, or null.
+let sel = null;
+
+const key = document.getElementById("key");
+kinds.slice(0, 6).forEach((k, i) => { if (k != "MarkTerm") key.innerHTML += '<span style="background:' + colors[i] + '"></span>' + (k == "SweepTerm" ? "STW" : k); });
+for (const c in causeColors) key.innerHTML += '<span style="background:' + causeColors[c][0] + '"></span>' + c + " GC";
+
+function fmtNS(ns) {
+	const units = [["ns", 1], ["µs", 1e3], ["ms", 1e6], ["s", 1e9]];
+	let u = units[0];
+	for (const v of units) if (Math.abs(ns) >= v[1]) u = v;
+	return (ns / u[1]).toPrecision(3) + u[0];
+}
+
+function x(t) { return (t - t0) / (t1 - t0) * canvas.width; }
+function t(x) { return t0 + x / canvas.width * (t1 - t0); }
+
+// first returns the index of the first phase ending after time tt.
+function first(tt) {
+	let lo = 0, hi = phases.length;
+	while (lo < hi) {
+		const mid = (lo + hi) >> 1;
+		if (phases[mid].beginNS + phases[mid].durationNS <= tt) lo = mid + 1; else hi = mid;
+	}
+	return lo;
+}
+
+function draw() {
+	ctx.clearRect(0, 0, canvas.width, canvas.height);
+	ctx.font = "11px sans-serif";
+	if (sel) {
+		ctx.fillStyle = "#fff3b0";
+		ctx.fillRect(x(sel.begin), 0, Math.max(1, x(sel.end) - x(sel.begin)), axisTop);
+	}
+
+	// Heap size.
+	const hy = mb => heapTop + heapH * (1 - mb / heapMax);
+	ctx.fillStyle = "#000";
+	ctx.fillText("heap (max " + heapMax + " MB)", 4, heapTop + 10);
+	ctx.strokeStyle = "#1f77b4";
+	ctx.beginPath();
+	heap.forEach((p, i) => { if (i == 0) ctx.moveTo(x(p[0]), hy(p[1])); else ctx.lineTo(x(p[0]), hy(p[1])); });
+	ctx.stroke();
+	ctx.strokeStyle = "#ff7f0e";
+	ctx.setLineDash([4, 3]);
+	ctx.beginPath();
+	cycles.forEach((c, i) => {
+		if (!c.heapGoalMB) return;
+		const end = i + 1 < cycles.length ? cycles[i + 1].beginNS : tMax;
+		ctx.moveTo(x(c.beginNS), hy(c.heapGoalMB));
+		ctx.lineTo(x(end), hy(c.heapGoalMB));
+	});
+	ctx.stroke();
+	ctx.setLineDash([]);
+
+	// GC cycles, from the beginning of sweep termination to the
+	// end of mark termination.
+	for (const c of cycles) {
+		if (c.endNS < t0 || c.beginNS > t1) continue;
+		ctx.fillStyle = (causeColors[c.cause] || causeColors.paced)[c.n % 2];
+		ctx.fillRect(x(c.beginNS), cycleTop, Math.max(1, x(c.endNS) - x(c.beginNS)), cycleH);
+	}
+
+	// Phases. STW phases are always drawn at least a pixel wide.
+	for (let i = first(t0); i < phases.length && phases[i].beginNS <= t1; i++) {
+		const p = phases[i], w = x(p.beginNS + p.durationNS) - x(p.beginNS);
+		if (w < 0.5 && !p.stw) continue;
+		ctx.fillStyle = p.stw ? "#d62728" : colors[kinds.indexOf(p.kind)];
+		ctx.fillRect(x(p.beginNS), p.stw ? phaseTop : phaseTop + 8, Math.max(w, 1), p.stw ? phaseH : phaseH - 16);
+	}
+
+	// Time axis.
+	ctx.fillStyle = "#000";
+	ctx.fillRect(0, axisTop, canvas.width, 1);
+	const span = t1 - t0;
+	let step = Math.pow(10, Math.floor(Math.log10(span / 8)));
+	if (span / step > 20) step *= 5; else if (span / step > 10) step *= 2;
+	for (let tt = Math.ceil(t0 / step) * step; tt <= t1; tt += step) {
+		ctx.fillRect(x(tt), axisTop, 1, 5);
+		ctx.fillText(fmtNS(tt), x(tt) + 2, axisTop + 16);
+	}
+}
+
+function zoom(factor, cx) {
+	const tc = t(cx);
+	let span = Math.min(Math.max((t1 - t0) * factor, 1000), (tMax - tMin) * 1.1);
+	t0 = tc - (tc - t0) / (t1 - t0) * span;
+	t1 = t0 + span;
+	draw();
+}
+
+canvas.addEventListener("wheel", e => {
+	e.preventDefault();
+	zoom(Math.pow(1.002, e.deltaY), e.offsetX);
+});
+
+// showRange shows the analyses of the range r of the run, or the
+// whole run if r is null. The server recomputes them from the full
+// trace, even if the timeline is downsampled.
+function showRange(r) {
+	const q = r ? "?begin=" + Math.floor(r.begin) + "&end=" + Math.ceil(r.end) : "";
+	document.getElementById("range").textContent = r ? "Range " + fmtNS(r.begin) + " to " + fmtNS(r.end) : "Whole run";
+	document.getElementById("mmu").src = "mmu.svg" + q;
+	document.getElementById("mud").src = "mud.svg" + q;
+	const pauses = document.getElementById("pauses");
+	fetch("summary.json" + q).then(resp => resp.ok ? resp.json() : resp.text().then(t => Promise.reject(t))).then(sum => {
+		const st = sum.stops.all || {count: 0};
+		pauses.textContent = sum.cycles + " GCs, mean mutator utilization " + (100 * sum.mutatorUtilization).toFixed(1) + "%, " + st.count + " pauses" +
+			(st.count ? ": max " + fmtNS(st.maxNS) + ", 99%ile " + fmtNS(st.p99NS) + ", 95%ile " + fmtNS(st.p95NS) + ", mean " + fmtNS(st.meanNS) : "");
+	}, err => { pauses.textContent = err; });
+}
+
+let drag = null;
+canvas.addEventListener("mousedown", e => {
+	if (e.shiftKey) {
+		drag = { select: t(e.offsetX) };
+		return;
+	}
+	drag = { x: e.offsetX, t0: t0, t1: t1 };
+	canvas.style.cursor = "grabbing";
+});
+window.addEventListener("mouseup", () => {
+	if (drag && drag.select !== undefined && sel) showRange(sel);
+	drag = null;
+	canvas.style.cursor = "grab";
+});
+canvas.addEventListener("mousemove", e => {
+	if (drag && drag.select !== undefined) {
+		const tt = t(e.offsetX);
+		sel = { begin: Math.max(Math.min(drag.select, tt), tMin), end: Math.min(Math.max(drag.select, tt), tMax) };
+		draw();
+		return;
+	}
+	if (drag) {
+		const dt = (e.offsetX - drag.x) / canvas.width * (drag.t1 - drag.t0);
+		t0 = drag.t0 - dt; t1 = drag.t1 - dt;
+		draw();
+		return;
+	}
+	// Show the phase or cycle under the cursor.
+	const tt = t(e.offsetX);
+	let text = "";
+	if (e.offsetY >= phaseTop && e.offsetY < phaseTop + phaseH) {
+		const i = first(tt);
+		if (i < phases.length && phases[i].beginNS <= tt) {
+			const p = phases[i];
+			text = "gc " + p.n + " " + p.kind + (p.stw ? " (STW)" : "") + ": " + fmtNS(p.durationNS) + ", " + p.gcProcs.toFixed(2) + "/" + p.gomaxprocs + " procs";
+		}
+	} else if (e.offsetY >= heapTop && e.offsetY < cycleTop + cycleH) {
+		const c = cycles.find(c => c.beginNS <= tt && tt < c.nextNS);
+		if (c) text = "gc " + c.n + " @" + fmtNS(c.beginNS) + ": " + c.heapTriggerMB + "->" + c.heapMarkedMB + "->" + c.heapLiveMB + " MB" + (c.heapGoalMB ? ", " + c.heapGoalMB + " MB goal" : "") + ", " + c.cause;
+	}
+	tip.style.display = text ? "block" : "none";
+	tip.textContent = text;
+	tip.style.left = (e.pageX + 12) + "px";
+	tip.style.top = (e.pageY + 12) + "px";
+});
+canvas.addEventListener("mouseleave", () => { tip.style.display = "none"; });
+canvas.addEventListener("dblclick", () => {
+	if (sel) {
+		sel = null;
+		showRange(null);
+	}
+	t0 = tMin; t1 = tMax;
+	draw();
+});
+
+Promise.all([fetch("phases.json?max=" + maxPhases).then(r => r.json()), fetch("cycles.json?max=" + maxPhases).then(r => r.json())]).then(([ps, cs]) => {
+	phases = ps.phases;
+	cycles = cs.cycles;
+	tMin = phases[0].beginNS;
+	const last = phases[phases.length - 1];
+	tMax = last.beginNS + last.durationNS;
+
+	// Find the end of mark termination of each cycle.
+	const ends = {};
+	for (const p of phases) if (p.kind == "MarkTerm") ends[p.n] = p.beginNS + p.durationNS;
+	cycles.forEach((c, i) => {
+		c.endNS = ends[c.n] || c.beginNS;
+		c.nextNS = i + 1 < cycles.length ? cycles[i + 1].beginNS : tMax;
+		heap.push([c.beginNS, c.heapTriggerMB], [c.endNS, c.heapMarkedMB], [c.endNS, c.heapLiveMB]);
+		heapMax = Math.max(heapMax, c.heapMarkedMB, c.heapGoalMB || 0, c.heapTriggerMB);
+	});
+	t0 = tMin; t1 = tMax;
+	draw();
+	showRange(null);
+});
+</script>
+</body>
+</html>
+`